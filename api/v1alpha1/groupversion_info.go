@@ -0,0 +1,20 @@
+// Package v1alpha1 contains the NetworkVerification API served by the verifier's operator mode
+// +kubebuilder:object:generate=true
+// +groupName=network-verifier.openshift.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the NetworkVerification API
+	GroupVersion = schema.GroupVersion{Group: "network-verifier.openshift.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the API's types with a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the API's types to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
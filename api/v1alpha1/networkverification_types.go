@@ -0,0 +1,139 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of a NetworkVerification
+const (
+	// ConditionComplete is true once the verification of the current spec has finished
+	ConditionComplete = "Complete"
+	// ConditionSucceeded is true if the finished verification found every check passing
+	ConditionSucceeded = "Succeeded"
+)
+
+// NetworkVerificationSpec describes the verification of egress from a subnet, in the terms of the egress command's flags
+type NetworkVerificationSpec struct {
+	// Platform is the cloud provider, aws or gcp, or rosa-hcp or osd-gcp to verify the endpoints of ROSA with hosted
+	// control planes or OSD on GCP as well
+	// +kubebuilder:validation:Enum=aws;gcp;rosa-hcp;osd-gcp
+	Platform string `json:"platform"`
+	// SubnetID is the subnet to verify egress from, a subnetwork name or self-link on GCP
+	SubnetID string `json:"subnetID"`
+	// Region is the subnet's region, defaulted on AWS and taken from the self-link on GCP
+	// +optional
+	Region string `json:"region,omitempty"`
+	// ImageID is the cloud image the probe instance boots from
+	// +optional
+	ImageID string `json:"imageID,omitempty"`
+	// InstanceType is the probe instance's type, or a comma-separated preference list
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+	// SecurityGroupID is the security group the probe instance runs in (AWS only)
+	// +optional
+	SecurityGroupID string `json:"securityGroupID,omitempty"`
+	// KMSKeyID encrypts the probe instance's volume (AWS only)
+	// +optional
+	KMSKeyID string `json:"kmsKeyID,omitempty"`
+	// Timeout is the timeout of individual egress requests
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Proxy is the cluster-wide proxy egress goes through, if any
+	// +optional
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+	// CloudTags are assigned to the cloud resources created for the verification
+	// +optional
+	CloudTags map[string]string `json:"cloudTags,omitempty"`
+}
+
+// ProxySpec is a cluster-wide proxy
+type ProxySpec struct {
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// CACert is the PEM-encoded CA certificate of a TLS-intercepting proxy
+	// +optional
+	CACert string `json:"caCert,omitempty"`
+	// NoTLS skips verifying the endpoints' certificates
+	// +optional
+	NoTLS bool `json:"noTLS,omitempty"`
+}
+
+// EndpointStatus is the outcome of verifying egress to a single endpoint
+type EndpointStatus struct {
+	Endpoint string `json:"endpoint"`
+	Success  bool   `json:"success"`
+	// +optional
+	Note string `json:"note,omitempty"`
+	// +optional
+	RequiredBy string `json:"requiredBy,omitempty"`
+	// +optional
+	LastHop string `json:"lastHop,omitempty"`
+	// TLSIssuer is the issuer of the untrusted certificate presented, e.g. by a proxy intercepting TLS
+	// +optional
+	TLSIssuer string `json:"tlsIssuer,omitempty"`
+}
+
+// NetworkVerificationStatus is the progress and outcome of the verification of the current spec
+type NetworkVerificationStatus struct {
+	// ObservedGeneration is the spec generation the status describes
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// VerificationID identifies the running verification with the operator
+	// +optional
+	VerificationID string `json:"verificationID,omitempty"`
+	// Phase is Pending, Running, Passed or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// Verdict is a one-line description of the outcome
+	// +optional
+	Verdict string `json:"verdict,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Endpoints holds the per-endpoint results
+	// +optional
+	Endpoints []EndpointStatus `json:"endpoints,omitempty"`
+	// Failures, Exceptions and Errors are what failed the verification
+	// +optional
+	Failures []string `json:"failures,omitempty"`
+	// +optional
+	Exceptions []string `json:"exceptions,omitempty"`
+	// +optional
+	Errors []string `json:"errors,omitempty"`
+	// Warnings are risks found that don't fail the verification
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+	// Conditions are the Complete and Succeeded conditions
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NetworkVerification requests the verification of egress from a subnet, declaratively
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=nv
+// +kubebuilder:printcolumn:name="Subnet",type=string,JSONPath=`.spec.subnetID`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Verdict",type=string,JSONPath=`.status.verdict`
+type NetworkVerification struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NetworkVerificationSpec   `json:"spec,omitempty"`
+	Status NetworkVerificationStatus `json:"status,omitempty"`
+}
+
+// NetworkVerificationList is a list of NetworkVerifications
+// +kubebuilder:object:root=true
+type NetworkVerificationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NetworkVerification `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NetworkVerification{}, &NetworkVerificationList{})
+}
@@ -67,7 +67,7 @@ func NewCmdValidateDns() *cobra.Command {
 			}
 
 			out := cli.VerifyDns(ctx, config.vpcID)
-			out.Summary(config.debug)
+			out.Summary(os.Stdout, config.debug)
 			if !out.IsSuccessful() {
 				logger.Error(ctx, "Failure!")
 				os.Exit(1)
@@ -80,7 +80,7 @@ func NewCmdValidateDns() *cobra.Command {
 	validateDnsCmd.Flags().StringVar(&config.vpcID, "vpc-id", "", "ID of the VPC under test")
 	validateDnsCmd.Flags().StringVar(&config.region, "region", getDefaultRegion(), fmt.Sprintf("Region to validate. Defaults to exported var %[1]v or '%[2]v' if not %[1]v set", regionEnvVarStr, regionDefault))
 	validateDnsCmd.Flags().BoolVar(&config.debug, "debug", false, "If true, enable additional debug-level logging")
-	validateDnsCmd.Flags().StringVar(&config.awsProfile, "profile", "", "(optional) AWS profile. If present, any credentials passed with CLI will be ignored.")
+	validateDnsCmd.Flags().StringVar(&config.awsProfile, "profile", "", "(optional) AWS profile of the shared config, including SSO profiles and profiles assuming a role with MFA, whose token is prompted for. If present, any credentials passed with CLI will be ignored.")
 
 	if err := validateDnsCmd.MarkFlagRequired("vpc-id"); err != nil {
 		validateDnsCmd.PrintErr(err)
@@ -0,0 +1,90 @@
+package egress
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySubnets(t *testing.T) {
+	subnetIDs := []string{"subnet-1", "subnet-2", "subnet-3", "subnet-4", "subnet-5", "subnet-6", "subnet-7"}
+	const maxParallel = 3
+
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+		finished       []string
+		otherDone      = make(chan struct{})
+		once           sync.Once
+	)
+	results := verifySubnets(subnetIDs, maxParallel, func(subnetID string) *output.Output {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+
+		// The first subnet finishes only after another, so the results come in out of order
+		if subnetID == "subnet-1" {
+			<-otherDone
+		} else {
+			time.Sleep(10 * time.Millisecond)
+			defer once.Do(func() { close(otherDone) })
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight--
+		finished = append(finished, subnetID)
+		out := &output.Output{}
+		out.AddWarning(subnetID)
+		return out
+	})
+
+	assert.Equal(t, maxParallel, peak, "maxParallel verifications, and no more, run at once")
+	assert.NotEqual(t, "subnet-1", finished[0])
+	assert.Equal(t, subnetIDs, results.Targets(), "the results keep the order of the subnets given")
+	for _, subnetID := range subnetIDs {
+		assert.Equal(t, []string{subnetID}, results.Target(subnetID).Warnings())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := egressConfig{
+		outputFormat:    outputText,
+		maxParallel:     1,
+		repeat:          1,
+		attempts:        1,
+		soakInterval:    time.Minute,
+		launchTimeout:   time.Minute,
+		consoleInterval: time.Second,
+		consoleTimeout:  time.Minute,
+		cpuArch:         probe.ArchitectureX86_64,
+		resultChannel:   probe.ResultChannelConsole,
+	}
+	assert.NoError(t, validate(valid))
+
+	tests := map[string]func(c *egressConfig){
+		"unknown report format":      func(c *egressConfig) { c.reportFormat = "pdf" },
+		"no parallelism":             func(c *egressConfig) { c.maxParallel = 0 },
+		"parallelism above the cap":  func(c *egressConfig) { c.maxInstances, c.maxParallel = 2, 3 },
+		"poll interval past timeout": func(c *egressConfig) { c.consoleInterval = 2 * time.Minute },
+		"plain HTTP callback": func(c *egressConfig) {
+			c.resultChannel, c.callbackURL = probe.ResultChannelCallback, "http://10.0.0.5/results"
+		},
+		"marker the script expands":   func(c *egressConfig) { c.beginMarker = "BEGIN $RUN" },
+		"run timeout within teardown": func(c *egressConfig) { c.runTimeout = time.Second },
+	}
+	for name, invalidate := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := valid
+			invalidate(&config)
+			assert.Error(t, validate(config))
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package egress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// shellSafeRe matches values that need no quoting in a shell command
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+=,-]+$`)
+
+// prompter asks the questions of the interactive mode, taking the default answer on an empty line or end of input
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// eof is set once the input has ended, after which every question takes its default answer
+	eof bool
+}
+
+// ask returns the answer to a free-form question
+func (p *prompter) ask(question, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil {
+		p.eof = true
+		fmt.Fprintln(p.out)
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return defaultValue
+	}
+
+	return line
+}
+
+// choose returns one of the options, which may also be picked by their number
+func (p *prompter) choose(question string, options []string, defaultValue string) string {
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	for {
+		answer := p.ask(question, defaultValue)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1]
+		}
+		for _, option := range options {
+			if answer == option {
+				return option
+			}
+		}
+		if p.eof {
+			return defaultValue
+		}
+		fmt.Fprintf(p.out, "Please pick one of 1-%d\n", len(options))
+	}
+}
+
+// confirm returns the answer to a yes/no question
+func (p *prompter) confirm(question string, defaultYes bool) bool {
+	defaultValue := "n"
+	if defaultYes {
+		defaultValue = "y"
+	}
+	answer := strings.ToLower(p.ask(question+" (y/n)", defaultValue))
+
+	return answer == "y" || answer == "yes"
+}
+
+// runWizard walks first-time users through the choices the egress command's flags make: the platform, credentials,
+// region, subnet and proxy. The answers are set as the flags they stand for, and the equivalent non-interactive
+// command is printed. It returns false if the user chose not to run the verification.
+func runWizard(ctx context.Context, logger ocmlog.Logger, cmd *cobra.Command, config *egressConfig, in io.Reader, out io.Writer) (bool, error) {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+	set := func(name, value string) error {
+		if value == "" {
+			return nil
+		}
+		return cmd.Flags().Set(name, value)
+	}
+	// Environment variables the GCP client is configured with, printed ahead of the equivalent command
+	env := map[string]string{}
+	setenv := func(name, value string) {
+		if value != "" && value != os.Getenv(name) {
+			os.Setenv(name, value)
+			env[name] = value
+		}
+	}
+
+	fmt.Fprintln(out, "This walks you through verifying egress from a subnet. Press enter to take the suggested answer.")
+	fmt.Fprintln(out)
+
+	// Platform, suggested by the credentials found
+	suggested, warning, err := cloudclient.DetectPlatform(os.Getenv, config.awsProfile, config.vpcSubnetID)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		suggested = cloudclient.PlatformAWS
+	} else if warning != "" {
+		fmt.Fprintln(out, warning)
+	} else {
+		fmt.Fprintf(out, "Credentials found suggest the %s platform.\n", suggested)
+	}
+	if config.platform != "" {
+		suggested = config.platform
+	}
+	platform := p.choose("Platform", cloudclient.SupportedPlatforms, suggested)
+	if err := set("platform", platform); err != nil {
+		return false, err
+	}
+	provider := cloudclient.Provider(platform)
+
+	// Credentials and region
+	var creds interface{}
+	if provider == cloudclient.PlatformAWS {
+		if os.Getenv("AWS_ACCESS_KEY_ID") != "" && config.awsProfile == "" {
+			fmt.Fprintln(out, "Using the AWS credentials of AWS_ACCESS_KEY_ID.")
+			creds = credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
+		} else {
+			profile := config.awsProfile
+			if profile == "" {
+				profile = os.Getenv("AWS_PROFILE")
+			}
+			if profile = p.ask("AWS profile", profile); profile != "" {
+				if err := set("profile", profile); err != nil {
+					return false, err
+				}
+				creds = profile
+			}
+		}
+	} else {
+		if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+			fmt.Fprintf(out, "Using the GCP credentials of %s.\n", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+		} else {
+			fmt.Fprintln(out, "GOOGLE_APPLICATION_CREDENTIALS is not set, the project's attached service account is used.")
+		}
+		setenv("GCP_PROJECT_ID", p.ask("GCP project ID", os.Getenv("GCP_PROJECT_ID")))
+		setenv("GCP_VPC_NAME", p.ask("VPC network name", os.Getenv("GCP_VPC_NAME")))
+	}
+
+	region := config.region
+	if region == "" {
+		region = getDefaultRegion(provider)
+	}
+	if err := set("region", p.ask("Region", region)); err != nil {
+		return false, err
+	}
+
+	// Subnet, picked from the VPC's private subnets when they can be listed
+	if provider == cloudclient.PlatformAWS && config.vpcSubnetID == "" {
+		if vpcID := p.ask("VPC ID, to pick from its private subnets (leave empty to enter a subnet ID)", config.vpcID); vpcID != "" {
+			subnetID, err := pickSubnet(ctx, logger, p, creds, config.region, vpcID)
+			switch {
+			case err != nil:
+				fmt.Fprintf(out, "Unable to list the private subnets of %s: %s\n", vpcID, err)
+			case subnetID == "":
+				if err := set("vpc-id", vpcID); err != nil {
+					return false, err
+				}
+			default:
+				if err := set("subnet-id", subnetID); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+	if config.vpcSubnetID == "" && config.vpcID == "" {
+		subnetID := ""
+		for subnetID == "" && !p.eof {
+			subnetID = p.ask("Subnet ID", "")
+		}
+		if subnetID == "" {
+			return false, fmt.Errorf("no subnet given")
+		}
+		if err := set("subnet-id", subnetID); err != nil {
+			return false, err
+		}
+	}
+
+	// Proxy
+	if p.confirm("Does the cluster egress through a proxy?", config.httpProxy != "" || config.httpsProxy != "") {
+		if err := set("http-proxy", p.ask("HTTP proxy URL", config.httpProxy)); err != nil {
+			return false, err
+		}
+		if err := set("https-proxy", p.ask("HTTPS proxy URL", config.httpsProxy)); err != nil {
+			return false, err
+		}
+		if err := set("cacert", p.ask("File of the proxy's CA certificate (leave empty if it has none)", config.CaCert)); err != nil {
+			return false, err
+		}
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "The equivalent command is:")
+	fmt.Fprintf(out, "  %s\n", equivalentCommand(cmd, env))
+	fmt.Fprintln(out)
+
+	return p.confirm("Run it now?", true), nil
+}
+
+// pickSubnet lists the private subnets of the VPC to pick one from, returning an empty subnet ID if all of them were
+// picked
+func pickSubnet(ctx context.Context, logger ocmlog.Logger, p *prompter, creds interface{}, region, vpcID string) (string, error) {
+	if creds == nil {
+		creds = credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
+	}
+	// The use of t3.micro here is arbitrary; we just need to provide any valid machine type
+	cli, err := cloudclient.NewClient(ctx, logger, creds, region, "t3.micro", nil)
+	if err != nil {
+		return "", err
+	}
+	subnetZones, err := cli.DescribePrivateSubnets(ctx, vpcID)
+	if err != nil {
+		return "", err
+	}
+	if len(subnetZones) == 0 {
+		return "", fmt.Errorf("no private subnets found")
+	}
+
+	const all = "all of them, one per availability zone at a time"
+	var subnetIDs []string
+	for subnetID := range subnetZones {
+		subnetIDs = append(subnetIDs, subnetID)
+	}
+	sort.Strings(subnetIDs)
+	options := make([]string, 0, len(subnetIDs)+1)
+	for _, subnetID := range subnetIDs {
+		options = append(options, fmt.Sprintf("%s (%s)", subnetID, subnetZones[subnetID]))
+	}
+	options = append(options, all)
+
+	choice := p.choose("Subnet", options, options[0])
+	if choice == all {
+		return "", nil
+	}
+
+	return strings.Fields(choice)[0], nil
+}
+
+// equivalentCommand renders the command line setting every flag changed, preceded by the environment variables set
+func equivalentCommand(cmd *cobra.Command, env map[string]string) string {
+	var args []string
+	var names []string
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, name+"="+shellQuote(env[name]))
+	}
+
+	args = append(args, os.Args[0], cmd.Name())
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Name == "interactive" {
+			return
+		}
+		value := f.Value.String()
+		switch f.Value.Type() {
+		case "bool":
+			args = append(args, "--"+f.Name+"="+value)
+			return
+		case "stringSlice", "stringToString":
+			// Rendered as [a,b], but given as a,b
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		}
+		args = append(args, "--"+f.Name, shellQuote(value))
+	})
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes a value for a POSIX shell, if needed
+func shellQuote(value string) string {
+	if shellSafeRe.MatchString(value) {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
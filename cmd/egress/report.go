@@ -0,0 +1,76 @@
+package egress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+var supportedReportFormats = []string{"html", "csv"}
+
+func isSupportedReportFormat(format string) bool {
+	for _, f := range supportedReportFormats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeReport writes the results of every verified subnet to reportFile in the given format
+func writeReport(format, reportFile string, outputs []*output.Output) error {
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "html":
+		err = output.WriteHTMLReport(f, outputs...)
+	case "csv":
+		err = output.WriteCSVReport(f, outputs...)
+	default:
+		err = fmt.Errorf("unsupported report format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// writePacketCaptures writes each packet capture collected by the probe to its own file in dir
+func writePacketCaptures(dir string, out *output.Output) ([]string, error) {
+	var files []string
+	for endpoint, capture := range out.PacketCaptures() {
+		name := fmt.Sprintf("%s-%s.pcap", out.Metadata().InstanceID, strings.NewReplacer(":", "_", "/", "_").Replace(endpoint))
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, capture, 0600); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// writeValidatorOutput writes the validator container's own output collected by the probe to a file in dir, returning
+// an empty path if there was none
+func writeValidatorOutput(dir string, out *output.Output) (string, error) {
+	validatorOutput := out.ValidatorOutput()
+	if validatorOutput == "" {
+		return "", nil
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-validator.log", out.Metadata().InstanceID))
+	if err := os.WriteFile(path, []byte(validatorOutput+"\n"), 0600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
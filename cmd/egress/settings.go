@@ -0,0 +1,66 @@
+package egress
+
+import (
+	"fmt"
+
+	"github.com/openshift/osd-network-verifier/pkg/installconfig"
+	"github.com/openshift/osd-network-verifier/pkg/terraform"
+)
+
+// networkSettings are the settings of the network to verify found in a file, e.g. terraform outputs or an
+// install-config.yaml
+type networkSettings struct {
+	platform   string
+	subnetIDs  []string
+	vpc        string
+	project    string
+	region     string
+	httpProxy  string
+	httpsProxy string
+	caCert     string
+	// warnings are problems found with the settings that verifying egress won't catch
+	warnings []string
+}
+
+// loadNetworkSettings reads the --from-terraform or --install-config file, if either is given
+func loadNetworkSettings(config egressConfig) (*networkSettings, error) {
+	switch {
+	case config.fromTerraform != "" && config.installConfig != "":
+		return nil, fmt.Errorf("--from-terraform and --install-config can't be used together")
+	case config.fromTerraform != "":
+		network, err := terraform.Load(config.fromTerraform)
+		if err != nil {
+			return nil, err
+		}
+		return &networkSettings{
+			platform:   network.Platform,
+			subnetIDs:  network.SubnetIDs,
+			vpc:        network.VPC,
+			region:     network.Region,
+			httpProxy:  network.HTTPProxy,
+			httpsProxy: network.HTTPSProxy,
+			caCert:     network.CACert,
+		}, nil
+	case config.installConfig != "":
+		ic, err := installconfig.Load(config.installConfig)
+		if err != nil {
+			return nil, err
+		}
+		settings := &networkSettings{
+			platform:  ic.PlatformName(),
+			subnetIDs: ic.SubnetIDs(),
+			region:    ic.Region(),
+			caCert:    ic.AdditionalTrustBundle,
+			warnings:  ic.Networking.Overlaps(),
+		}
+		if ic.Platform.GCP != nil {
+			settings.vpc, settings.project = ic.Platform.GCP.Network, ic.Platform.GCP.ProjectID
+		}
+		if ic.Proxy != nil {
+			settings.httpProxy, settings.httpsProxy = ic.Proxy.HTTPProxy, ic.Proxy.HTTPSProxy
+		}
+		return settings, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,186 @@
+package egresslist
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/egresslist"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/spf13/cobra"
+)
+
+// listConfig selects the endpoints of the effective list, like the egress command's flags of the same names
+type listConfig struct {
+	platform   string
+	ocpVersion string
+	region     string
+}
+
+// addFlags adds the flags selecting the effective list
+func (c *listConfig) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&c.platform, "platform", cloudclient.PlatformAWS, fmt.Sprintf("(optional) cloud platform, one of %v", cloudclient.SupportedPlatforms))
+	cmd.Flags().StringVar(&c.ocpVersion, "ocp-version", "", fmt.Sprintf("(optional) OpenShift version, one of %v, defaults to the newest", endpoints.OCPVersions()))
+	cmd.Flags().StringVar(&c.region, "region", "", "(optional) region of the regional endpoints. Defaults to us-east-2 on AWS and us-east1 on GCP")
+}
+
+// effective returns the effective list, exiting on invalid flags
+func (c *listConfig) effective() *egresslist.List {
+	provider := cloudclient.Provider(c.platform)
+	if provider != cloudclient.PlatformAWS && provider != cloudclient.PlatformGCP {
+		fmt.Fprintf(os.Stderr, "unsupported platform %s, must be one of %v\n", c.platform, cloudclient.SupportedPlatforms)
+		os.Exit(1)
+	}
+	ocpVersion, err := endpoints.OCPVersion(c.ocpVersion)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	region := c.region
+	if region == "" {
+		region = "us-east-2"
+		if provider == cloudclient.PlatformGCP {
+			region = "us-east1"
+		}
+	}
+
+	var preset string
+	if provider != c.platform {
+		preset = c.platform
+	}
+	list, err := egresslist.Effective(preset, ocpVersion, region)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return list
+}
+
+// NewCmdEgressList groups the commands working on egress list files
+func NewCmdEgressList() *cobra.Command {
+	egressListCmd := &cobra.Command{
+		Use:   "egress-list",
+		Short: "Work with egress list files",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				cmd.PrintErr(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	egressListCmd.AddCommand(newCmdValidate())
+	egressListCmd.AddCommand(newCmdExport())
+	egressListCmd.AddCommand(newCmdCompare())
+
+	return egressListCmd
+}
+
+func newCmdValidate() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate FILE",
+		Short: "Check an egress list file for schema errors, malformed or duplicate endpoints and uncovered categories",
+		Example: `# Validate a contribution to the endpoint data, exiting non-zero on any problem
+./osd-network-verifier egress-list validate egress-list.yaml`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			list, err := egresslist.Load(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			problems := list.Validate()
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], problem)
+			}
+			if len(problems) > 0 {
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s: %d endpoints, valid\n", args[0], len(list.Endpoints))
+		},
+	}
+}
+
+func newCmdExport() *cobra.Command {
+	var (
+		config listConfig
+		format string
+		file   string
+	)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the endpoints probed beyond the validator's own list, after the platform's and OpenShift version's",
+		Example: `# Hand the endpoints of a ROSA HCP cluster to a firewall team as CSV
+./osd-network-verifier egress-list export --platform rosa-hcp --region us-west-2 --format csv --output endpoints.csv`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			list := config.effective()
+
+			var w io.Writer = os.Stdout
+			if file != "" {
+				f, err := os.Create(file)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				w = f
+			}
+			if err := list.Write(w, format); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	config.addFlags(exportCmd)
+	exportCmd.Flags().StringVar(&format, "format", egresslist.FormatYAML, fmt.Sprintf("(optional) format of the export, %s or %s", egresslist.FormatYAML, egresslist.FormatCSV))
+	exportCmd.Flags().StringVar(&file, "output", "", "(optional) file to write the export to. Defaults to stdout")
+
+	return exportCmd
+}
+
+func newCmdCompare() *cobra.Command {
+	var config listConfig
+
+	compareCmd := &cobra.Command{
+		Use:   "compare ALLOWLIST",
+		Short: "Report the required endpoints a firewall allowlist is missing, without launching anything",
+		Long: `Report the required endpoints a firewall allowlist is missing, without launching anything.
+
+The allowlist has one rule per line: a hostname, or a domain suffix written as *.example.com or .example.com,
+optionally followed by :port. Blank lines and # comments are ignored, and only the first comma-separated field of a
+line is used, so an export in CSV can be compared as is.`,
+		Example: `# Check a customer's proxy allowlist before verifying egress
+./osd-network-verifier egress-list compare allowlist.txt --platform aws --ocp-version 4.11`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			list := config.effective()
+			allowlist, err := egresslist.LoadAllowlist(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			missing := list.Missing(allowlist)
+			for _, e := range missing {
+				fmt.Printf("missing %s:%d, required by %s, see %s\n", e.Host, e.Port, e.Category, endpoints.Lookup(e.Host).DocsURL)
+			}
+			if len(missing) > 0 {
+				fmt.Printf("%s is missing %d of %d required endpoints\n", args[0], len(missing), len(list.Endpoints))
+				os.Exit(1)
+			}
+
+			fmt.Printf("%s allows all %d required endpoints\n", args[0], len(list.Endpoints))
+		},
+	}
+
+	config.addFlags(compareCmd)
+
+	return compareCmd
+}
@@ -0,0 +1,196 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2/google"
+)
+
+var (
+	// defaultTags are the egress command's, so build instances are told apart, capped and cleaned up like probes
+	defaultTags = map[string]string{"osd-network-verifier": "owned", "red-hat-managed": "true", "Name": "osd-network-verifier"}
+
+	awsRegionEnvVarStr = "AWS_REGION"
+	awsRegionDefault   = "us-east-2"
+	gcpRegionEnvVarStr = "GCP_REGION"
+	gcpRegionDefault   = "us-east1"
+)
+
+type buildConfig struct {
+	platform        string
+	vpcSubnetID     string
+	region          string
+	baseImageID     string
+	name            string
+	validatorImage  string
+	instanceType    string
+	instanceProfile string
+	cpuArch         string
+	cloudTags       map[string]string
+	awsProfile      string
+	impersonate     []string
+	debug           bool
+}
+
+// NewCmdImage groups the commands working on the cloud images the probe instance boots from
+func NewCmdImage() *cobra.Command {
+	imageCmd := &cobra.Command{
+		Use:   "image",
+		Short: "Work with the cloud images the probe instance boots from",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				cmd.PrintErr(err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	imageCmd.AddCommand(newCmdBuild())
+
+	return imageCmd
+}
+
+func newCmdBuild() *cobra.Command {
+	config := buildConfig{}
+
+	buildCmd := &cobra.Command{
+		Use:   "build",
+		Short: "Bake a cloud image with the validator container image pre-pulled",
+		Long: `Bake a cloud image with the validator container image pre-pulled, in the region of the subnet, so
+verifications in bandwidth-constrained or registry-restricted environments don't depend on pulling the
+validator image at probe boot. A build instance is launched into the subnet, which must reach the validator
+image's registry, and deleted once the image is built. Pass the image printed to egress with --image-id.`,
+		Example: `./osd-network-verifier image build --subnet-id $SUBNET_ID
+./osd-network-verifier image build --platform gcp --subnet-id $SUBNET_NAME --name verifier-v1`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.TODO()
+
+			builder := ocmlog.NewStdLoggerBuilder()
+			builder.Debug(config.debug)
+			logger, err := builder.Build()
+			if err != nil {
+				fmt.Printf("Unable to build logger: %s\n", err.Error())
+				os.Exit(1)
+			}
+
+			if config.cpuArch != probe.ArchitectureX86_64 && config.cpuArch != probe.ArchitectureArm64 {
+				logger.Error(ctx, "--cpu-arch must be one of %s or %s", probe.ArchitectureX86_64, probe.ArchitectureArm64)
+				os.Exit(1)
+			}
+			if len(config.impersonate) > 0 && cloudclient.Provider(config.platform) != cloudclient.PlatformGCP {
+				logger.Error(ctx, "--impersonate-service-account is only supported on GCP")
+				os.Exit(1)
+			}
+			if config.name == "" {
+				config.name = fmt.Sprintf("osd-network-verifier-%s", time.Now().UTC().Format("20060102-150405"))
+			}
+
+			var creds interface{}
+			switch cloudclient.Provider(config.platform) {
+			case cloudclient.PlatformAWS:
+				if config.region == "" {
+					config.region = regionFromEnv(awsRegionEnvVarStr, awsRegionDefault)
+				}
+				if config.awsProfile == "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+					config.awsProfile = os.Getenv("AWS_PROFILE")
+				}
+				if config.awsProfile != "" {
+					creds = config.awsProfile
+					logger.Info(ctx, "Using AWS profile: %s", config.awsProfile)
+				} else {
+					creds = credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
+				}
+			case cloudclient.PlatformGCP:
+				// A subnetwork self-link carries its own region, which takes precedence over the default
+				if config.region, err = gcpCloudClient.RegionFromSubnet(config.vpcSubnetID, config.region); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				if config.region == "" {
+					config.region = regionFromEnv(gcpRegionEnvVarStr, gcpRegionDefault)
+				}
+				if os.Getenv("GCP_VPC_NAME") == "" || os.Getenv("GCP_PROJECT_ID") == "" {
+					logger.Error(ctx, "please set environment variables GCP_VPC_NAME and GCP_PROJECT_ID to the name and project ID of the VPC")
+					os.Exit(1)
+				}
+				creds = &google.Credentials{ProjectID: os.Getenv("GCP_PROJECT_ID")}
+				if len(config.impersonate) > 0 {
+					logger.Info(ctx, "Impersonating service account %s", strings.Join(config.impersonate, " -> "))
+					if creds, err = gcpCloudClient.ImpersonatedCredentials(ctx, os.Getenv("GCP_PROJECT_ID"), config.impersonate); err != nil {
+						logger.Error(ctx, err.Error())
+						os.Exit(1)
+					}
+				}
+			default:
+				logger.Error(ctx, "unsupported platform %s, must be one of %v", config.platform, cloudclient.SupportedPlatforms)
+				os.Exit(1)
+			}
+
+			logger.Info(ctx, "Using region: %s", config.region)
+			cli, err := cloudclient.NewClient(ctx, logger, creds, config.region, config.instanceType, config.cloudTags)
+			if err != nil {
+				logger.Error(ctx, err.Error())
+				os.Exit(1)
+			}
+			imageBuilder, ok := cli.(cloudclient.ImageBuilder)
+			if !ok {
+				logger.Error(ctx, "building images is not supported on %s", config.platform)
+				os.Exit(1)
+			}
+
+			image, err := imageBuilder.BuildImage(ctx, config.vpcSubnetID, config.baseImageID, config.name, probe.Options{
+				ValidatorImage:      config.validatorImage,
+				InstanceProfile:     config.instanceProfile,
+				CPUArchitecture:     config.cpuArch,
+				RequireSubnetRegion: cmd.Flags().Changed("region"),
+			})
+			if err != nil {
+				logger.Error(ctx, "Unable to build the image: %v", err)
+				os.Exit(1)
+			}
+
+			logger.Info(ctx, "Built image %s, pass it to egress with --image-id %s", image, image)
+			fmt.Println(image)
+		},
+	}
+
+	buildCmd.Flags().StringVar(&config.platform, "platform", cloudclient.PlatformAWS, fmt.Sprintf("(optional) cloud platform, one of %v", cloudclient.SupportedPlatforms))
+	buildCmd.Flags().StringVar(&config.vpcSubnetID, "subnet-id", "", "subnet ID the build instance is launched into, which must reach the validator image's registry. For GCP, a subnetwork self-link may be given, in which case the region is taken from it")
+	buildCmd.Flags().StringVar(&config.region, "region", "", fmt.Sprintf("(optional) region to build the image in. If absent, environment var %[1]v = %[2]v and %[3]v = %[4]v will be used", awsRegionEnvVarStr, awsRegionDefault, gcpRegionEnvVarStr, gcpRegionDefault))
+	buildCmd.Flags().StringVar(&config.baseImageID, "base-image-id", "", "(optional) cloud image the build instance boots from, as egress' --image-id. Defaults to the probe's default image")
+	buildCmd.Flags().StringVar(&config.name, "name", "", "(optional) name of the image built. Defaults to osd-network-verifier-<UTC timestamp>")
+	buildCmd.Flags().StringVar(&config.validatorImage, "validator-image", "", "(optional) validator container image to pre-pull. Defaults to the image pinned for the cloud provider")
+	buildCmd.Flags().StringVar(&config.instanceType, "instance-type", "", "(optional) build instance type. If absent, the first default type for --cpu-arch offered in the region is used")
+	buildCmd.Flags().StringVar(&config.cpuArch, "cpu-arch", probe.ArchitectureX86_64, fmt.Sprintf("(optional) CPU architecture of the image, one of %s or %s. AWS requires --base-image-id for %[2]s", probe.ArchitectureX86_64, probe.ArchitectureArm64))
+	buildCmd.Flags().StringVar(&config.instanceProfile, "instance-profile", "", "(optional) IAM instance profile the AWS build instance runs with")
+	buildCmd.Flags().StringToStringVar(&config.cloudTags, "cloud-tags", defaultTags, "(optional) comma-separated list of tags to assign to the build instance and the image e.g. --cloud-tags key1=value1,key2=value2")
+	buildCmd.Flags().StringVar(&config.awsProfile, "profile", "", "(optional) AWS profile of the shared config, including SSO profiles and profiles assuming a role with MFA, whose token is prompted for. If present, any credentials passed with CLI will be ignored.")
+	buildCmd.Flags().StringSliceVar(&config.impersonate, "impersonate-service-account", nil, "(optional) GCP only, service account to impersonate, or a comma-separated chain of them, as egress' --impersonate-service-account")
+	buildCmd.Flags().BoolVar(&config.debug, "debug", false, "(optional) if true, enable additional debug-level logging")
+
+	if err := buildCmd.MarkFlagRequired("subnet-id"); err != nil {
+		buildCmd.PrintErr(err)
+		os.Exit(1)
+	}
+
+	return buildCmd
+}
+
+// regionFromEnv returns the region set in the environment variable, or the default
+func regionFromEnv(envVar, defaultRegion string) string {
+	if region, ok := os.LookupEnv(envVar); ok {
+		return region
+	}
+
+	return defaultRegion
+}
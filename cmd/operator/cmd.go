@@ -0,0 +1,118 @@
+package operator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	"github.com/openshift/osd-network-verifier/api/v1alpha1"
+	"github.com/openshift/osd-network-verifier/pkg/operator"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/service"
+)
+
+type operatorConfig struct {
+	metricsAddr   string
+	probeAddr     string
+	leaderElect   bool
+	namespace     string
+	maxConcurrent int
+	debug         bool
+}
+
+func NewCmdOperator() *cobra.Command {
+	config := operatorConfig{}
+
+	operatorCmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Run a controller verifying egress for NetworkVerification resources.",
+		Long: `Run a controller reconciling NetworkVerification custom resources: each generation of a resource's spec, holding
+the provider, subnet and proxy settings, is verified and the per-endpoint results and conditions are reported in its
+status. Verifications use the controller's cloud credentials from the environment. See deploy/operator for the CRD
+and an example deployment.`,
+		Example: `# Run the controller against the current kubeconfig context, watching all namespaces
+./osd-network-verifier operator`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := ctrl.SetupSignalHandler()
+
+			// Create logger
+			builder := ocmlog.NewStdLoggerBuilder()
+			builder.Debug(config.debug)
+			stdLogger, err := builder.Build()
+			if err != nil {
+				fmt.Printf("Unable to build logger: %s\n", err.Error())
+				os.Exit(1)
+			}
+			// Proxy credentials and tokens are masked in everything logged
+			logger := redact.Logger(stdLogger)
+
+			if config.maxConcurrent < 1 {
+				logger.Error(ctx, "--max-concurrent must be at least 1")
+				os.Exit(1)
+			}
+
+			scheme := runtime.NewScheme()
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				logger.Error(ctx, err.Error())
+				os.Exit(1)
+			}
+
+			restConfig, err := ctrl.GetConfig()
+			if err != nil {
+				logger.Error(ctx, "unable to load a kubeconfig: %s", err)
+				os.Exit(1)
+			}
+			mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+				Scheme:                 scheme,
+				Namespace:              config.namespace,
+				MetricsBindAddress:     config.metricsAddr,
+				HealthProbeBindAddress: config.probeAddr,
+				LeaderElection:         config.leaderElect,
+				LeaderElectionID:       "osd-network-verifier.network-verifier.openshift.io",
+			})
+			if err != nil {
+				logger.Error(ctx, "unable to create the controller manager: %s", err)
+				os.Exit(1)
+			}
+
+			// Results are copied to the NetworkVerification's status once finished, so they're kept briefly
+			reconciler := &operator.NetworkVerificationReconciler{
+				Client:  mgr.GetClient(),
+				Manager: service.NewManager(ctx, logger, service.RunWithEnvironmentCredentials, config.maxConcurrent, time.Hour),
+			}
+			if err := reconciler.SetupWithManager(mgr); err != nil {
+				logger.Error(ctx, "unable to set up the NetworkVerification controller: %s", err)
+				os.Exit(1)
+			}
+			if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+				logger.Error(ctx, err.Error())
+				os.Exit(1)
+			}
+			if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+				logger.Error(ctx, err.Error())
+				os.Exit(1)
+			}
+
+			logger.Info(ctx, "Starting the NetworkVerification controller")
+			if err := mgr.Start(ctx); err != nil {
+				logger.Error(ctx, err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	operatorCmd.Flags().StringVar(&config.metricsAddr, "metrics-bind-address", ":8383", "(optional) address the controller's metrics are served on, 0 to not serve them")
+	operatorCmd.Flags().StringVar(&config.probeAddr, "health-probe-bind-address", ":8384", "(optional) address the controller's health probes are served on")
+	operatorCmd.Flags().BoolVar(&config.leaderElect, "leader-elect", false, "(optional) if true, elect a leader so only one of several replicas runs verifications")
+	operatorCmd.Flags().StringVar(&config.namespace, "namespace", "", "(optional) namespace to watch NetworkVerifications in, all namespaces if absent")
+	operatorCmd.Flags().IntVar(&config.maxConcurrent, "max-concurrent", 4, "(optional) maximum number of verifications, and so probe instances, running at once. The rest are queued")
+	operatorCmd.Flags().BoolVar(&config.debug, "debug", false, "(optional) if true, enable additional debug-level logging")
+
+	return operatorCmd
+}
@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"flag"
-	"fmt"
 	"os"
 
 	byovpc "github.com/openshift/osd-network-verifier/cmd/byovpc"
 	"github.com/openshift/osd-network-verifier/cmd/dns"
 	"github.com/openshift/osd-network-verifier/cmd/egress"
+	"github.com/openshift/osd-network-verifier/cmd/egresslist"
+	"github.com/openshift/osd-network-verifier/cmd/image"
+	"github.com/openshift/osd-network-verifier/cmd/operator"
+	"github.com/openshift/osd-network-verifier/cmd/serve"
+	versionCmd "github.com/openshift/osd-network-verifier/cmd/version"
+	"github.com/openshift/osd-network-verifier/pkg/version"
 	"github.com/spf13/cobra"
 )
 
@@ -19,10 +24,13 @@ var Version string
 
 // NewCmdRoot represents the base command when called without any subcommands
 func NewCmdRoot() *cobra.Command {
+	// The build sets the version here, share it with the packages reporting it
+	version.Version, version.GitCommit = Version, GitCommit
+
 	rootCmd := &cobra.Command{
 		Use:     "osd-network-verifier",
 		Example: "./osd-network-verifier [command] [flags]",
-		Version: fmt.Sprintf("%s, GitCommit: %s", Version, GitCommit),
+		Version: version.String(),
 		Short:   "OSD network verifier CLI",
 		Long: `CLI tool for pre-flight verification of VPC configuration against OSD requirements. 
 For more information see https://github.com/openshift/osd-network-verifier/blob/main/README.md`,
@@ -35,7 +43,12 @@ For more information see https://github.com/openshift/osd-network-verifier/blob/
 	// add sub commands
 	rootCmd.AddCommand(byovpc.NewCmdByovpc())
 	rootCmd.AddCommand(egress.NewCmdValidateEgress())
+	rootCmd.AddCommand(egresslist.NewCmdEgressList())
 	rootCmd.AddCommand(dns.NewCmdValidateDns())
+	rootCmd.AddCommand(image.NewCmdImage())
+	rootCmd.AddCommand(serve.NewCmdServe())
+	rootCmd.AddCommand(operator.NewCmdOperator())
+	rootCmd.AddCommand(versionCmd.NewCmdVersion())
 
 	return rootCmd
 }
@@ -0,0 +1,174 @@
+package serve
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/service"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// shutdownTimeout bounds the wait for in-flight API calls when shutting down
+const shutdownTimeout = 10 * time.Second
+
+// tokenEnvVar holds the API token when --token-file isn't given
+const tokenEnvVar = "OSD_NETWORK_VERIFIER_API_TOKEN"
+
+type serveConfig struct {
+	grpcListen    string
+	httpListen    string
+	maxConcurrent int
+	retention     time.Duration
+	tokenFile     string
+	tlsCert       string
+	tlsKey        string
+	tlsClientCA   string
+	debug         bool
+}
+
+func NewCmdServe() *cobra.Command {
+	config := serveConfig{}
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an API for running egress verifications.",
+		Long: `Serve a gRPC API (StartVerification, GetStatus, StreamEvents, GetResult) and a REST API
+(POST /verifications, GET /verifications/{id}) for running egress verifications, so other platforms can trigger
+them programmatically and poll for their results. Verifications run in the background, each with its own cloud
+client, using the server's cloud credentials from the environment.
+
+Clients authenticate with a bearer token, read from --token-file or the ` + tokenEnvVar + `
+environment variable, or with client certificates issued by --tls-client-ca. The APIs listen on localhost unless
+served over TLS with --tls-cert and --tls-key.`,
+		Example: `# Serve the gRPC API on port 9090 and the REST API on port 8080 of localhost, running up to 4 verifications at once
+OSD_NETWORK_VERIFIER_API_TOKEN=$(openssl rand -hex 32) ./osd-network-verifier serve --max-concurrent 4
+
+# Serve the REST API only, over TLS on every interface
+./osd-network-verifier serve --grpc-listen "" --http-listen :8080 --token-file token --tls-cert tls.crt --tls-key tls.key`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Stop serving, and cancel the running verifications, on interrupt
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// Create logger
+			builder := ocmlog.NewStdLoggerBuilder()
+			builder.Debug(config.debug)
+			stdLogger, err := builder.Build()
+			if err != nil {
+				fmt.Printf("Unable to build logger: %s\n", err.Error())
+				os.Exit(1)
+			}
+			// Proxy credentials and tokens are masked in everything logged
+			logger := redact.Logger(stdLogger)
+
+			if config.maxConcurrent < 1 {
+				logger.Error(ctx, "--max-concurrent must be at least 1")
+				os.Exit(1)
+			}
+			if config.grpcListen == "" && config.httpListen == "" {
+				logger.Error(ctx, "at least one of --grpc-listen or --http-listen is required")
+				os.Exit(1)
+			}
+
+			token := os.Getenv(tokenEnvVar)
+			if config.tokenFile != "" {
+				if token, err = service.ReadToken(config.tokenFile); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+			}
+			if token == "" && config.tlsClientCA == "" {
+				logger.Error(ctx, "an API token, from --token-file or %s, or client certificates, with --tls-client-ca, are required to authenticate clients", tokenEnvVar)
+				os.Exit(1)
+			}
+			if (config.tlsCert == "") != (config.tlsKey == "") || (config.tlsClientCA != "" && config.tlsCert == "") {
+				logger.Error(ctx, "--tls-cert and --tls-key are required together, and by --tls-client-ca")
+				os.Exit(1)
+			}
+			var tlsConfig *tls.Config
+			if config.tlsCert != "" {
+				if tlsConfig, err = service.TLSConfig(config.tlsCert, config.tlsKey, config.tlsClientCA); err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+			}
+			// Without TLS, the token and the verifications' proxy settings would cross the network in plaintext
+			for _, listen := range []string{config.grpcListen, config.httpListen} {
+				if listen != "" && tlsConfig == nil && !service.IsLoopback(listen) {
+					logger.Error(ctx, "%s isn't a localhost address, serving on it requires --tls-cert and --tls-key", listen)
+					os.Exit(1)
+				}
+			}
+
+			manager := service.NewManager(ctx, logger, service.RunWithEnvironmentCredentials, config.maxConcurrent, config.retention)
+			errs := make(chan error, 2)
+
+			if config.grpcListen != "" {
+				listener, err := net.Listen("tcp", config.grpcListen)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				server := grpc.NewServer(service.GRPCServerOptions(token, tlsConfig)...)
+				service.RegisterVerifierServer(server, manager)
+				defer server.GracefulStop()
+
+				logger.Info(ctx, "Serving the gRPC API on %s", listener.Addr())
+				go func() { errs <- server.Serve(listener) }()
+			}
+
+			if config.httpListen != "" {
+				listener, err := net.Listen("tcp", config.httpListen)
+				if err != nil {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+				if tlsConfig != nil {
+					listener = tls.NewListener(listener, tlsConfig)
+				}
+				server := &http.Server{Handler: service.NewRESTHandler(manager, token), ReadHeaderTimeout: 10 * time.Second}
+				defer func() {
+					shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+					defer cancel()
+					_ = server.Shutdown(shutdownCtx)
+				}()
+
+				logger.Info(ctx, "Serving the REST API on %s", listener.Addr())
+				go func() { errs <- server.Serve(listener) }()
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Info(context.Background(), "Shutting down")
+			case err := <-errs:
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error(ctx, err.Error())
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	serveCmd.Flags().StringVar(&config.grpcListen, "grpc-listen", "127.0.0.1:9090", "(optional) address to serve the gRPC API on, empty to not serve it. Addresses other than localhost require --tls-cert and --tls-key")
+	serveCmd.Flags().StringVar(&config.httpListen, "http-listen", "127.0.0.1:8080", "(optional) address to serve the REST API on, empty to not serve it. Addresses other than localhost require --tls-cert and --tls-key")
+	serveCmd.Flags().IntVar(&config.maxConcurrent, "max-concurrent", 4, "(optional) maximum number of verifications, and so probe instances, running at once. The rest are queued")
+	serveCmd.Flags().DurationVar(&config.retention, "retention", 24*time.Hour, "(optional) how long finished verifications, with their results, are kept for clients to fetch. 0 keeps them until the server stops")
+	serveCmd.Flags().StringVar(&config.tokenFile, "token-file", "", fmt.Sprintf("(optional) file holding the bearer token clients authenticate with. Defaults to the %s environment variable", tokenEnvVar))
+	serveCmd.Flags().StringVar(&config.tlsCert, "tls-cert", "", "(optional) PEM certificate to serve the APIs over TLS with")
+	serveCmd.Flags().StringVar(&config.tlsKey, "tls-key", "", "(optional) PEM private key of --tls-cert")
+	serveCmd.Flags().StringVar(&config.tlsClientCA, "tls-client-ca", "", "(optional) PEM CA certificates clients must present a certificate issued by, authenticating them in place of, or along with, the token")
+	serveCmd.Flags().BoolVar(&config.debug, "debug", false, "(optional) if true, enable additional debug-level logging, including in the verifications' events")
+
+	return serveCmd
+}
@@ -0,0 +1,25 @@
+package version
+
+import (
+	"fmt"
+
+	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdVersion prints the versions support needs to confirm what a customer ran
+func NewCmdVersion() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the verifier, validator image and egress list versions",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("osd-network-verifier: %s\n", version.String())
+			fmt.Printf("AWS default validator image: %s\n", awsCloudClient.DefaultValidatorImage())
+			fmt.Printf("GCP default validator image: %s\n", gcpCloudClient.DefaultValidatorImage())
+			fmt.Printf("egress list: %s\n", endpoints.CatalogVersion)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/openshift/osd-network-verifier/pkg/verifier"
+)
+
+func validateEgressWithVerifierAPI() {
+	ctx := context.TODO()
+	//---------initialize required args---------
+	// Read AWS creds from environment
+	key, _ := os.LookupEnv("AWS_ACCESS_KEY_ID")
+	secret, _ := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
+	session, _ := os.LookupEnv("AWS_SESSION_TOKEN")
+
+	//---------ONV stable API usage---------
+	v, err := verifier.New(ctx, verifier.Config{
+		AWS:    &verifier.AWSCredentials{AccessKeyID: key, SecretAccessKey: secret, SessionToken: session},
+		Region: "us-east-1",
+		Tags:   map[string]string{"key1": "val1"},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Call egress validator, the timeout defaulting to verifier.DefaultTimeout
+	result := v.ValidateEgress(ctx, verifier.EgressInput{
+		SubnetID: "vpcSubnetID",
+		Proxy:    verifier.ProxyConfig{HTTPSProxy: "http://proxy.example.com:3128"},
+		Options:  verifier.ProbeOptions{RetryFailedEndpoints: true},
+	})
+	if !result.IsSuccessful() {
+		fmt.Println(result.Failures)
+		fmt.Println(result.Exceptions)
+		for _, err := range result.Errors {
+			if errors.Is(err, verifier.ErrNoSubnet) {
+				fmt.Println("a subnet is required")
+			}
+		}
+	}
+	for _, result := range result.Endpoints {
+		fmt.Printf("%s reachable: %t\n", result.Endpoint, result.Success)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
 	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	"github.com/openshift/osd-network-verifier/pkg/proxy"
 )
 
@@ -31,7 +32,7 @@ func extendValidateEgressV1() {
 	//---------ONV egress verifier usage---------
 	cli, _ := cloudclient.NewClient(context.TODO(), logger, *creds, region, instanceType, tags)
 	// Call egress validator
-	out := cli.ValidateEgress(context.TODO(), "vpcSubnetID", "cloudImageID", "kmsKeyID", "securityGroupId", 3*time.Second, proxy.ProxyConfig{})
+	out := cli.ValidateEgress(context.TODO(), "vpcSubnetID", "cloudImageID", "kmsKeyID", "securityGroupId", 3*time.Second, proxy.ProxyConfig{}, probe.Options{})
 	if !out.IsSuccessful() {
 		// Retrieve errors
 		failures, exceptions, errors := out.Parse()
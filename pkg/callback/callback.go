@@ -0,0 +1,174 @@
+package callback
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxResultsSize bounds the results a probe can post, which include base64-encoded packet captures
+const maxResultsSize = 16 << 20
+
+// results is what the probe posts once it's done
+type results struct {
+	RunID  string `json:"run_id"`
+	Output string `json:"output"`
+}
+
+// Receiver is an HTTPS endpoint probes post their results to once they're done, sparing the wait for their console
+// output. It serves a self-signed certificate for the host of its URL, which the probes are given to verify it with,
+// and the probes authenticate with a per-receiver token.
+type Receiver struct {
+	url      string
+	token    string
+	certPEM  string
+	listener net.Listener
+	server   *http.Server
+
+	mu      sync.Mutex
+	results map[string]string
+}
+
+// NewReceiver starts a Receiver listening on listenAddr, e.g. ":8443". url is where the probes reach it, e.g.
+// "https://10.0.0.5:8443/results" for a verifier running in a peered VPC, it must be an HTTPS URL.
+func NewReceiver(listenAddr, receiverURL string) (*Receiver, error) {
+	parsed, err := url.Parse(receiverURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("the results callback URL %q must be an https:// URL", receiverURL)
+	}
+	cert, err := selfSignedCertificate(parsed.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a certificate for the results callback: %w", err)
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("unable to create a token for the results callback: %w", err)
+	}
+
+	listener, err := tls.Listen("tcp", listenAddr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the results callback on %s: %w", listenAddr, err)
+	}
+
+	r := &Receiver{
+		url:      receiverURL,
+		token:    hex.EncodeToString(token),
+		certPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})),
+		listener: listener,
+		results:  map[string]string{},
+	}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+
+	return r, nil
+}
+
+// URL returns where the probes post their results
+func (r *Receiver) URL() string {
+	return r.url
+}
+
+// Token returns the bearer token the probes authenticate with
+func (r *Receiver) Token() string {
+	return r.token
+}
+
+// CACertificate returns the PEM certificate the Receiver serves, which the probes verify it with
+func (r *Receiver) CACertificate() string {
+	return r.certPEM
+}
+
+// Addr returns the address the Receiver listens on
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+// Result returns the output the probe of the given run posted, if it has yet
+func (r *Receiver) Result(runID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	output, ok := r.results[runID]
+	return output, ok
+}
+
+// Close stops the Receiver
+func (r *Receiver) Close() error {
+	return r.server.Close()
+}
+
+// ServeHTTP records the results posted by a probe
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+r.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var posted results
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxResultsSize)).Decode(&posted); err != nil {
+		http.Error(w, fmt.Sprintf("invalid results: %v", err), http.StatusBadRequest)
+		return
+	}
+	output, err := base64.StdEncoding.DecodeString(posted.Output)
+	if err != nil || posted.RunID == "" {
+		http.Error(w, "invalid results: a run_id and base64-encoded output are required", http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	r.results[posted.RunID] = string(output)
+	r.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// selfSignedCertificate creates a short-lived certificate for the Receiver, valid for host, an IP address or DNS name
+func selfSignedCertificate(host string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "osd-network-verifier"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
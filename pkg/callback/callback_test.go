@@ -0,0 +1,67 @@
+package callback
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReceiver(t *testing.T) {
+	if _, err := NewReceiver("127.0.0.1:0", "http://127.0.0.1/results"); err == nil {
+		t.Errorf("expected plain HTTP URLs to be rejected")
+	}
+
+	r, err := NewReceiver("127.0.0.1:0", "https://127.0.0.1/results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// The receiver is verified against the certificate the probes are given, as curl --cacert does
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(r.CACertificate())) {
+		t.Fatalf("expected a PEM certificate, got %q", r.CACertificate())
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	post := func(token, body string) int {
+		req, err := http.NewRequest(http.MethodPost, "https://"+r.Addr().String()+"/results", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "wrong token", token: "nope", body: `{"run_id":"a","output":"VVNFUkRBVEEgRU5E"}`, wantStatus: http.StatusUnauthorized},
+		{name: "not base64", token: r.Token(), body: `{"run_id":"a","output":"USERDATA END"}`, wantStatus: http.StatusBadRequest},
+		{name: "no run ID", token: r.Token(), body: `{"output":"VVNFUkRBVEEgRU5E"}`, wantStatus: http.StatusBadRequest},
+		{name: "results", token: r.Token(), body: `{"run_id":"a","output":"VVNFUkRBVEEgRU5E"}`, wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := post(tt.token, tt.body); status != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, status)
+			}
+		})
+	}
+
+	if output, ok := r.Result("a"); !ok || output != "USERDATA END" {
+		t.Errorf("expected the posted results, got %q, %v", output, ok)
+	}
+	if _, ok := r.Result("b"); ok {
+		t.Errorf("expected no results for another run")
+	}
+}
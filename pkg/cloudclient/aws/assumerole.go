@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName names the sessions of an assumed role, as seen in CloudTrail, unless given
+const DefaultRoleSessionName = "osd-network-verifier"
+
+// originAccountTagKey tags the resources created with an assumed role with the account the role was assumed from,
+// e.g. the SRE jump account, so they're traced back to it from the target account
+const originAccountTagKey = "osd-network-verifier-origin-account"
+
+// callerIdentity is who a set of credentials belongs to
+type callerIdentity struct {
+	account string
+	arn     string
+}
+
+// identifyCaller returns who the credentials of cfg belong to
+func identifyCaller(ctx context.Context, cfg aws.Config) (callerIdentity, error) {
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return callerIdentity{}, err
+	}
+
+	return callerIdentity{account: aws.ToString(identity.Account), arn: aws.ToString(identity.Arn)}, nil
+}
+
+// AssumeRoleCredentials are the credentials of a role assumed, e.g. the customer's or the support role, with the
+// source credentials, an AWS profile name or static credentials as taken by NewClient. The role's credentials are
+// refreshed before they expire, so long runs outlive the role's maximum session duration.
+type AssumeRoleCredentials struct {
+	Source  interface{}
+	RoleARN string
+	// IntermediateRoleARN, if set, is assumed with the source credentials first and assumes RoleARN in turn, e.g. a
+	// role of the jump account trusted by the target account's role
+	IntermediateRoleARN string
+	// ExternalID is passed to assume roles whose trust policy requires one, as cross-account roles commonly do. It is
+	// only passed assuming RoleARN.
+	ExternalID string
+	// SessionName defaults to DefaultRoleSessionName
+	SessionName string
+}
+
+// sessionName returns the name of the role's sessions
+func (r AssumeRoleCredentials) sessionName() string {
+	if r.SessionName == "" {
+		return DefaultRoleSessionName
+	}
+
+	return r.SessionName
+}
+
+// assumeRole replaces the source credentials of cfg with the role's, through the intermediate role if any, returning
+// who the source credentials belong to. The roles are assumed once right away, so a role that can't be assumed fails
+// the client's creation rather than its first call.
+func (r AssumeRoleCredentials) assumeRole(ctx context.Context, cfg *aws.Config) (callerIdentity, error) {
+	identity, err := identifyCaller(ctx, *cfg)
+	if err != nil {
+		return callerIdentity{}, fmt.Errorf("unable to identify the caller assuming role %s: %w", r.RoleARN, err)
+	}
+
+	if r.IntermediateRoleARN != "" {
+		if err := r.chain(ctx, cfg, r.IntermediateRoleARN, ""); err != nil {
+			return callerIdentity{}, err
+		}
+	}
+	if err := r.chain(ctx, cfg, r.RoleARN, r.ExternalID); err != nil {
+		return callerIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+// chain replaces the credentials of cfg with the role's, assumed with them
+func (r AssumeRoleCredentials) chain(ctx context.Context, cfg *aws.Config, roleARN, externalID string) error {
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(*cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = r.sessionName()
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+	}))
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return fmt.Errorf("unable to assume role %s: %w", roleARN, err)
+	}
+
+	return nil
+}
@@ -1,3 +1,5 @@
+// Package aws verifies the network of an AWS account from a probe EC2 instance. It's internal to the verifier, with
+// no API compatibility promised across releases: Go consumers pass pkg/verifier an AWSCredentials instead.
 package aws
 
 import (
@@ -8,8 +10,14 @@ import (
 	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
-	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
 	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	proxy "github.com/openshift/osd-network-verifier/pkg/proxy"
 )
 
@@ -18,25 +26,97 @@ const ClientIdentifier string = "AWS"
 
 // Client represents an AWS Client
 type Client struct {
-	ec2Client    EC2Client
-	region       string
-	instanceType string
-	tags         map[string]string
-	logger       ocmlog.Logger
-	output       output.Output
+	ec2Client EC2Client
+	// regionalEC2Client builds an EC2Client for another region, used when discovering a subnet's region
+	regionalEC2Client func(region string) EC2Client
+	// regionalLogsClient builds a CloudWatchLogsClient for the probe's region, used to read results reported there
+	regionalLogsClient func(region string) CloudWatchLogsClient
+	// regionalSSMClient builds an SSMClient for the probe's region, used to re-run the probe on a pooled instance
+	regionalSSMClient func(region string) SSMClient
+	// regionalKMSClient builds a KMSClient for the probe's region, used to check the root volume's KMS key is usable
+	regionalKMSClient func(region string) KMSClient
+	// pooledCommandID is the SSM command re-running the probe on a pooled instance, whose output stands in for the
+	// console output
+	pooledCommandID string
+	// poolLease is this run's lease on the pooled instance it uses, released once the probe's results are collected
+	poolLease string
+	// console is the console output read so far
+	console consoleBuffer
+	// runID identifies the run's results in channels shared between runs, e.g. CloudWatch Logs
+	runID string
+	// batchID identifies the verifications the run was launched together with, see probe.Options.BatchID
+	batchID string
+	// markers tell the run's output apart in the console, see helpers.RunMarkers
+	markers helpers.Markers
+	// originAccount is the account the role verifying was assumed from, if any
+	originAccount string
+	// createdBy is the ARN of the caller the client's credentials belong to, or the role was assumed by
+	createdBy string
+	// expiresAt is when the resources of the run can be deemed leaked
+	expiresAt time.Time
+	// resultsViaConsole is set once the requested result channel turned out to be unusable
+	resultsViaConsole bool
+	region            string
+	instanceType      string
+	tags              map[string]string
+	logger            logging.Logger
+	output            output.Output
 }
 
 // Extend EC2Client so that we can mock them all for testing
 // to re-generate mockfile once another interface is added for testing:
 // mockgen -source=pkg/cloudclient/aws/aws.go -package mocks -destination=pkg/cloudclient/mocks/mock_aws.go
 type EC2Client interface {
-	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
 	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error)
 	DescribeInstanceStatus(ctx context.Context, input *ec2.DescribeInstanceStatusInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error)
 	DescribeInstanceTypes(ctx context.Context, input *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
 	GetConsoleOutput(ctx context.Context, input *ec2.GetConsoleOutputInput, optFns ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error)
 	TerminateInstances(ctx context.Context, input *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	CreateImage(ctx context.Context, input *ec2.CreateImageInput, optFns ...func(*ec2.Options)) (*ec2.CreateImageOutput, error)
+	DescribeImages(ctx context.Context, input *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+	DescribeInstances(ctx context.Context, input *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumes(ctx context.Context, input *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DescribeNetworkInterfaces(ctx context.Context, input *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
 	DescribeVpcAttribute(ctx context.Context, input *ec2.DescribeVpcAttributeInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcAttributeOutput, error)
+	DescribeSubnets(ctx context.Context, input *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+	DescribeRegions(ctx context.Context, input *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+	DescribeVpcEndpoints(ctx context.Context, input *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error)
+	DescribeRouteTables(ctx context.Context, input *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error)
+	DescribeTransitGatewayAttachments(ctx context.Context, input *ec2.DescribeTransitGatewayAttachmentsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayAttachmentsOutput, error)
+	DescribeTransitGatewayVpcAttachments(ctx context.Context, input *ec2.DescribeTransitGatewayVpcAttachmentsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayVpcAttachmentsOutput, error)
+	DescribeVpcPeeringConnections(ctx context.Context, input *ec2.DescribeVpcPeeringConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcPeeringConnectionsOutput, error)
+	DescribeVpcs(ctx context.Context, input *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+	DescribeNetworkAcls(ctx context.Context, input *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error)
+	DescribeSecurityGroups(ctx context.Context, input *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeNatGateways(ctx context.Context, input *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error)
+	SearchTransitGatewayRoutes(ctx context.Context, input *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error)
+	CreateTags(ctx context.Context, input *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DeleteTags(ctx context.Context, input *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
+}
+
+// CloudWatchLogsClient reads the probe's results when they're reported through CloudWatch Logs
+type CloudWatchLogsClient interface {
+	GetLogEventsWithContext(ctx context.Context, input *cloudwatchlogs.GetLogEventsInput, opts ...request.Option) (*cloudwatchlogs.GetLogEventsOutput, error)
+}
+
+// SSMClient re-runs the probe on a pooled instance, and reads its output, through Systems Manager Run Command. The
+// probe script is handed to the instance through Parameter Store rather than in the command.
+type SSMClient interface {
+	PutParameterWithContext(ctx context.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error)
+	DeleteParametersWithContext(ctx context.Context, input *ssm.DeleteParametersInput, opts ...request.Option) (*ssm.DeleteParametersOutput, error)
+	SendCommandWithContext(ctx context.Context, input *ssm.SendCommandInput, opts ...request.Option) (*ssm.SendCommandOutput, error)
+	GetCommandInvocationWithContext(ctx context.Context, input *ssm.GetCommandInvocationInput, opts ...request.Option) (*ssm.GetCommandInvocationOutput, error)
+}
+
+// KMSClient checks the KMS key encrypting the probe instance's root volume is usable before the launch
+type KMSClient interface {
+	DescribeKeyWithContext(ctx context.Context, input *kms.DescribeKeyInput, opts ...request.Option) (*kms.DescribeKeyOutput, error)
+	GenerateDataKeyWithoutPlaintextWithContext(ctx context.Context, input *kms.GenerateDataKeyWithoutPlaintextInput, opts ...request.Option) (*kms.GenerateDataKeyWithoutPlaintextOutput, error)
+}
+
+// DefaultValidatorImage returns the validator container image reference the probe instance runs by default
+func DefaultValidatorImage() string {
+	return defaultNetworkValidatorImage
 }
 
 func (c *Client) ByoVPCValidator(ctx context.Context) error {
@@ -44,16 +124,37 @@ func (c *Client) ByoVPCValidator(ctx context.Context) error {
 	return nil
 }
 
-func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig) *output.Output {
-	return c.validateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy)
+func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig, opts probe.Options) *output.Output {
+	return c.validateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy, opts)
+}
+
+// BuildImage bakes an AMI with the validator image pre-pulled, see cloudclient.ImageBuilder
+func (c *Client) BuildImage(ctx context.Context, vpcSubnetID, baseImage, name string, opts probe.Options) (string, error) {
+	return c.buildImage(ctx, vpcSubnetID, baseImage, name, opts)
 }
 
 func (c *Client) VerifyDns(ctx context.Context, vpcID string) *output.Output {
-	return c.verifyDns(ctx, vpcID)
+	// API failures and disabled attributes are both recorded in the output
+	c.verifyDns(ctx, vpcID)
+	return &c.output
+}
+
+func (c *Client) DescribeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error) {
+	return c.describeSubnetZones(ctx, subnetIDs)
+}
+
+func (c *Client) DescribePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error) {
+	return c.describePrivateSubnets(ctx, vpcID)
 }
 
 // NewClient creates a new CloudClient for use with AWS.
-func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, region, instanceType string, tags map[string]string) (client *Client, err error) {
+// The credentials are an AWS profile name, static credentials, or AssumeRoleCredentials assuming a role with either.
+func NewClient(ctx context.Context, logger logging.Logger, creds interface{}, region, instanceType string, tags map[string]string) (client *Client, err error) {
+	var role AssumeRoleCredentials
+	if r, ok := creds.(AssumeRoleCredentials); ok {
+		role, creds = r, r.Source
+	}
+
 	switch c := creds.(type) {
 	case string:
 		client, err = newClient(
@@ -66,6 +167,7 @@ func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, reg
 			instanceType,
 			tags,
 			creds.(string),
+			role,
 		)
 	case awscredsv1.Credentials:
 		var value awscredsv1.Value
@@ -80,6 +182,7 @@ func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, reg
 				instanceType,
 				tags,
 				"",
+				role,
 			)
 		}
 	case awscredsv2.StaticCredentialsProvider:
@@ -93,6 +196,7 @@ func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, reg
 			instanceType,
 			tags,
 			"",
+			role,
 		)
 
 	default:
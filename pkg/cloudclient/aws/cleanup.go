@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+)
+
+// runIDTagKey tags the resources of a run with the run's ID
+const runIDTagKey = "osd-network-verifier-run-id"
+
+// newRunID returns the ID of a new run, unique enough to tell apart the runs of an account
+var newRunID = func() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// createdByTagKey tags the resources with who created them, so they're attributed to them in audits and cost reports
+const createdByTagKey = "osd-network-verifier-created-by"
+
+// expiresAtTagKey tags the resources with when, in seconds since the epoch, they can be deemed leaked and reclaimed
+const expiresAtTagKey = "osd-network-verifier-expires-at"
+
+// cleanupPollInterval is how often the run's resources are looked up after the teardown, until they're gone
+var cleanupPollInterval = 10 * time.Second
+
+// verifyCleanup waits for the resources tagged with the run's ID, the probe instances and the volumes and network
+// interfaces created with them, to be gone after the teardown, recording whether they are in the run metadata. Those
+// still there after the teardown timeout are reported as an error, so leaks don't go unnoticed.
+func (c *Client) verifyCleanup(ctx context.Context) {
+	c.logger.Info(ctx, "Verifying the resources of run %s were cleaned up", c.runID)
+	// Verify even when the run's context is done, as the teardown does
+	ctx, cancel := helpers.TeardownContext()
+	defer cancel()
+
+	var remaining []string
+	err := helpers.PollImmediateWithContext(ctx, cleanupPollInterval, helpers.TeardownTimeout, func() (bool, error) {
+		var err error
+		remaining, err = c.runResources(ctx)
+		return len(remaining) == 0, err
+	})
+
+	verified := err == nil
+	c.output.Metadata().CleanupVerified = &verified
+	switch {
+	case len(remaining) > 0:
+		c.output.AddError(fmt.Errorf("resources of run %s survived the teardown, delete them manually: %s", c.runID, strings.Join(remaining, ", ")))
+	case err != nil:
+		c.output.AddError(fmt.Errorf("unable to verify the resources of run %s were cleaned up: %w", c.runID, err))
+	}
+}
+
+// runResources returns the resources tagged with the run's ID that still exist, described as e.g. "instance i-1"
+func (c *Client) runResources(ctx context.Context) ([]string, error) {
+	runFilter := ec2Types.Filter{Name: aws.String("tag:" + runIDTagKey), Values: []string{c.runID}}
+	var resources []string
+
+	instances, err := c.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2Types.Filter{runFilter, {
+			Name:   aws.String("instance-state-name"),
+			Values: []string{"pending", "running", "shutting-down", "stopping", "stopped"},
+		}},
+	})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+	for _, r := range instances.Reservations {
+		for _, i := range r.Instances {
+			resources = append(resources, "instance "+aws.ToString(i.InstanceId))
+		}
+	}
+
+	volumes, err := c.ec2Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{Filters: []ec2Types.Filter{runFilter}})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+	for _, v := range volumes.Volumes {
+		resources = append(resources, "volume "+aws.ToString(v.VolumeId))
+	}
+
+	interfaces, err := c.ec2Client.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{Filters: []ec2Types.Filter{runFilter}})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+	for _, i := range interfaces.NetworkInterfaces {
+		resources = append(resources, "network interface "+aws.ToString(i.NetworkInterfaceId))
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+// classifyEgressPath records how the probe's traffic reached the internet, based on the proxy configuration and
+// the subnet's default route, and checks it against the observed egress IP. routeTable may be nil if it couldn't
+// be determined.
+func (c *Client) classifyEgressPath(ctx context.Context, routeTable *ec2Types.RouteTable, p proxy.ProxyConfig) {
+	metadata := c.output.Metadata()
+	if p.HttpProxy != "" || p.HttpsProxy != "" {
+		metadata.EgressPath = output.EgressPathProxy
+		return
+	}
+	if routeTable == nil {
+		return
+	}
+
+	route := defaultRoute(routeTable)
+	switch {
+	case route == nil:
+		return
+	case route.NatGatewayId != nil:
+		metadata.EgressPath = output.EgressPathNATGateway
+		if err := c.verifyNatGatewayIP(ctx, aws.ToString(route.NatGatewayId), metadata.EgressIP); err != nil {
+			c.output.AddError(handledErrors.NewGenericError(err))
+		}
+	case strings.HasPrefix(aws.ToString(route.GatewayId), "igw-"):
+		metadata.EgressPath = output.EgressPathPublicIP
+		c.output.AddWarning(fmt.Sprintf("the probe reached the internet through its public IP via internet gateway %s, "+
+			"cluster nodes without public IPs in this subnet would need a NAT gateway or proxy, so these results may not reflect theirs",
+			aws.ToString(route.GatewayId)))
+	case route.TransitGatewayId != nil:
+		metadata.EgressPath = output.EgressPathTransitGateway
+	case route.VpcPeeringConnectionId != nil:
+		metadata.EgressPath = output.EgressPathPeering
+	default:
+		metadata.EgressPath = output.EgressPathOther
+	}
+}
+
+// verifyNatGatewayIP warns if the observed egress IP isn't one of the NAT gateway's public IPs, which means traffic
+// is translated again further along its path, e.g. by a firewall appliance
+func (c *Client) verifyNatGatewayIP(ctx context.Context, natGatewayID, egressIP string) error {
+	if egressIP == "" {
+		return nil
+	}
+
+	out, err := c.ec2Client.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{NatGatewayIds: []string{natGatewayID}})
+	if err != nil {
+		return err
+	}
+
+	var publicIPs []string
+	for _, natGateway := range out.NatGateways {
+		for _, address := range natGateway.NatGatewayAddresses {
+			if aws.ToString(address.PublicIp) == egressIP {
+				return nil
+			}
+			publicIPs = append(publicIPs, aws.ToString(address.PublicIp))
+		}
+	}
+	c.output.AddWarning(fmt.Sprintf("the probe egressed from %s, which is not a public IP of NAT gateway %s (%s), "+
+		"firewalls must allow the address traffic finally leaves from", egressIP, natGatewayID, strings.Join(publicIPs, ", ")))
+
+	return nil
+}
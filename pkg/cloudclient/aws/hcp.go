@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// hcpInterfaceEndpointServices are the services whose interface endpoints, if the VPC has any, HCP worker nodes
+// reach instead of the public regional endpoints
+var hcpInterfaceEndpointServices = []string{"sts", "ec2", "ecr.api", "ecr.dkr"}
+
+// verifyHCPVpcEndpoints checks the interface endpoints a ROSA HCP cluster's nodes would use in the VPC. An endpoint
+// that isn't available, or doesn't override the service's public DNS name, leaves the nodes unable to reach the
+// service without egress to its public endpoint, which is then the probe's to verify.
+func (c *Client) verifyHCPVpcEndpoints(ctx context.Context, vpcID string) {
+	var serviceNames []string
+	for _, service := range hcpInterfaceEndpointServices {
+		serviceNames = append(serviceNames, fmt.Sprintf("com.amazonaws.%s.%s", c.region, service))
+	}
+
+	endpointsOut, err := c.ec2Client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("service-name"), Values: serviceNames},
+			{Name: aws.String("vpc-endpoint-type"), Values: []string{string(ec2Types.VpcEndpointTypeInterface)}},
+		},
+	})
+	if err != nil {
+		c.output.AddError(handledErrors.NewGenericError(err))
+		return
+	}
+	if len(endpointsOut.VpcEndpoints) == 0 {
+		c.logger.Info(ctx, "No interface endpoints for %v in VPC %s, the nodes reach them through egress", hcpInterfaceEndpointServices, vpcID)
+		return
+	}
+
+	for _, endpoint := range endpointsOut.VpcEndpoints {
+		endpointID, serviceName := aws.ToString(endpoint.VpcEndpointId), aws.ToString(endpoint.ServiceName)
+		switch {
+		case endpoint.State != ec2Types.StateAvailable:
+			c.output.AddFailure(handledErrors.NewVPCEndpointError(fmt.Sprintf("interface endpoint %s for %s is %s rather than available", endpointID, serviceName, endpoint.State)))
+		case !aws.ToBool(endpoint.PrivateDnsEnabled):
+			c.output.AddFailure(handledErrors.NewVPCEndpointError(fmt.Sprintf("interface endpoint %s for %s does not have private DNS enabled, so the nodes won't use it", endpointID, serviceName)))
+		default:
+			c.logger.Info(ctx, "VPC %s reaches %s through interface endpoint %s", vpcID, serviceName, endpointID)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+const (
+	// validatorImageTagKey tags a built image with the validator image pre-pulled on it
+	validatorImageTagKey = "osd-network-verifier-validator-image"
+	// imageAvailableTimeout bounds the wait for a new AMI to become available, its snapshot taking several minutes
+	imageAvailableTimeout = 30 * time.Minute
+)
+
+// imageAvailablePollInterval is how often a new AMI is checked for having become available
+var imageAvailablePollInterval = 15 * time.Second
+
+// buildImage launches a build instance from baseImage, or the region's default AMI, into the subnet, pulls the
+// validator image on it and creates an AMI named name from it, returning the AMI's ID once it's available. The build
+// instance is terminated whichever way the build ends.
+func (c *Client) buildImage(ctx context.Context, subnetID, baseImage, name string, opts probe.Options) (string, error) {
+	if _, err := c.discoverSubnet(ctx, subnetID, opts.RequireSubnetRegion); err != nil {
+		return "", err
+	}
+	if c.instanceType == "" {
+		if opts.Architecture() != probe.ArchitectureX86_64 && baseImage == "" {
+			return "", fmt.Errorf("the default AMIs are %s only, please specify an %s AMI with `--base-image-id`", probe.ArchitectureX86_64, opts.Architecture())
+		}
+		instanceType, err := c.selectDefaultInstanceType(ctx, opts.Architecture())
+		if err != nil {
+			return "", err
+		}
+		c.instanceType = instanceType
+	}
+	amiID, err := c.setCloudImage(baseImage)
+	if err != nil {
+		return "", err
+	}
+	if err := c.applyResourcePolicy(opts.ResourcePolicy); err != nil {
+		return "", err
+	}
+	if err := c.verifyInstanceCap(ctx, opts.MaxInstancesOrDefault()); err != nil {
+		return "", err
+	}
+
+	validatorImage := opts.ValidatorImageOrDefault(defaultNetworkValidatorImage)
+	c.runID = newRunID()
+	// The build instance is terminated only once the image is available
+	c.expiresAt = helpers.ResourceExpiry(opts, time.Now()).Add(imageAvailableTimeout)
+	c.logger.Info(ctx, "Building an image from %s with %s pre-pulled", amiID, validatorImage)
+	instanceID, err := c.createEC2Instance(ctx, &createEC2InstanceInput{
+		amiId:           amiID,
+		subnetId:        subnetID,
+		userdata:        base64.StdEncoding.EncodeToString([]byte(helpers.ImageBuildUserdata(validatorImage))),
+		instanceCount:   instanceCount,
+		instanceProfile: opts.InstanceProfile,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
+			c.logger.Error(ctx, "Unable to terminate build instance %s, terminate it manually: %v", instanceID, err)
+		}
+	}()
+
+	if err := c.waitForEC2InstanceCompletion(ctx, instanceID, opts.LaunchTimeoutOrDefault()); err != nil {
+		return "", err
+	}
+	if err := c.waitForValidatorPull(ctx, instanceID, opts); err != nil {
+		return "", err
+	}
+
+	resp, err := c.ec2Client.CreateImage(ctx, &ec2.CreateImageInput{
+		InstanceId:        aws.String(instanceID),
+		Name:              aws.String(name),
+		Description:       aws.String(fmt.Sprintf("%s with %s pre-pulled, built by osd-network-verifier", amiID, validatorImage)),
+		TagSpecifications: c.imageTagSpecifications(validatorImage),
+	})
+	if err != nil {
+		return "", handledErrors.NewGenericError(err)
+	}
+	imageID := aws.ToString(resp.ImageId)
+	c.logger.Info(ctx, "Creating image %s from build instance %s", imageID, instanceID)
+
+	return imageID, c.waitForImageAvailable(ctx, imageID)
+}
+
+// waitForValidatorPull waits, up to the console timeout, for the build instance to report it pulled the validator
+// image in its console output
+func (c *Client) waitForValidatorPull(ctx context.Context, instanceID string, opts probe.Options) error {
+	var pullErr error
+	err := helpers.PollImmediateWithContext(ctx, opts.ConsolePollIntervalOrDefault(), opts.ConsoleTimeoutOrDefault(), func() (bool, error) {
+		b64ConsoleLogs, err := c.consoleOutput(ctx, instanceID)
+		if err != nil {
+			return false, err
+		}
+		consoleLogs, err := base64.StdEncoding.DecodeString(b64ConsoleLogs)
+		if err != nil {
+			return false, nil
+		}
+		done, err := helpers.ParseImageBuild(string(consoleLogs))
+		pullErr = err
+		return done, nil
+	})
+	if errors.Is(err, helpers.ErrPollTimeout) {
+		return fmt.Errorf("the build instance did not pull the validator image within the console timeout of %s: %w", opts.ConsoleTimeoutOrDefault(), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return pullErr
+}
+
+// imageTagSpecifications tags the image, and its snapshot, with the client's tags and the validator image pre-pulled
+// on it
+func (c *Client) imageTagSpecifications(validatorImage string) []ec2Types.TagSpecification {
+	tags := make(map[string]string, len(c.tags)+1)
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	tags[validatorImageTagKey] = validatorImage
+
+	return []ec2Types.TagSpecification{
+		{ResourceType: ec2Types.ResourceTypeImage, Tags: buildTags(tags)},
+		{ResourceType: ec2Types.ResourceTypeSnapshot, Tags: buildTags(tags)},
+	}
+}
+
+// waitForImageAvailable waits for the new AMI to become available, failing if its creation did
+func (c *Client) waitForImageAvailable(ctx context.Context, imageID string) error {
+	err := helpers.PollImmediateWithContext(ctx, imageAvailablePollInterval, imageAvailableTimeout, func() (bool, error) {
+		resp, err := c.ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{imageID}})
+		if err != nil {
+			return false, handledErrors.NewGenericError(err)
+		}
+		if len(resp.Images) == 0 {
+			// Not visible yet right after its creation
+			return false, nil
+		}
+
+		switch image := resp.Images[0]; image.State {
+		case ec2Types.ImageStateAvailable:
+			return true, nil
+		case ec2Types.ImageStatePending:
+			c.WriteDebugLogs(ctx, fmt.Sprintf("Image %s is still pending", imageID))
+			return false, nil
+		default:
+			reason := ""
+			if image.StateReason != nil {
+				reason = aws.ToString(image.StateReason.Message)
+			}
+			return false, fmt.Errorf("image %s is %s: %s", imageID, image.State, reason)
+		}
+	})
+	if errors.Is(err, helpers.ErrPollTimeout) {
+		return fmt.Errorf("image %s did not become available within %s: %w", imageID, imageAvailableTimeout, err)
+	}
+
+	return err
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// verifierTagKey is the tag the verifier's probe instances carry by default, besides the run ID tag
+const verifierTagKey = "osd-network-verifier"
+
+// batchIDTagKey tags the probe instances of verifications launched together with the batch's ID
+const batchIDTagKey = "osd-network-verifier-batch-id"
+
+// existingProbeInstances returns the IDs of the verifier's probe instances in the client's region that haven't been
+// terminated, told apart by their tags, and how many more belong to the run's batch
+func (c *Client) existingProbeInstances(ctx context.Context) ([]string, int, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("tag-key"), Values: []string{runIDTagKey, verifierTagKey}},
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running", "stopping", "stopped"}},
+		},
+	}
+	var ids []string
+	batch := 0
+	for {
+		resp, err := c.ec2Client.DescribeInstances(ctx, input)
+		if err != nil {
+			return nil, 0, handledErrors.NewGenericError(err)
+		}
+		for _, r := range resp.Reservations {
+			for _, i := range r.Instances {
+				inBatch := false
+				for _, t := range i.Tags {
+					inBatch = inBatch || (c.batchID != "" && aws.ToString(t.Key) == batchIDTagKey && aws.ToString(t.Value) == c.batchID)
+				}
+				if inBatch {
+					batch++
+					continue
+				}
+				ids = append(ids, aws.ToString(i.InstanceId))
+			}
+		}
+		if aws.ToString(resp.NextToken) == "" {
+			return ids, batch, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// verifyInstanceCap refuses to launch another probe instance when as many as the cap already exist, e.g. left behind
+// by a buggy automation loop, returning an error listing them along with the command to terminate them. Those of the
+// run's batch still in flight count towards the cap, but aren't listed. The check is skipped with a warning when the
+// instances can't be listed, e.g. without the ec2:DescribeInstances permission.
+func (c *Client) verifyInstanceCap(ctx context.Context, maxInstances int) error {
+	if maxInstances < 0 {
+		return nil
+	}
+
+	ids, batch, err := c.existingProbeInstances(ctx)
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to count the existing probe instances to enforce the cap of %d: %v", maxInstances, err))
+		return nil
+	}
+	if len(ids)+batch < maxInstances {
+		return nil
+	}
+
+	remedy := "raise the cap with --max-instances"
+	if len(ids) > 0 {
+		remedy = fmt.Sprintf("terminate those left behind with `aws ec2 terminate-instances --region %s --instance-ids %s`, or %s", c.region, strings.Join(ids, " "), remedy)
+	}
+
+	var batchNote string
+	if batch > 0 {
+		batchNote = fmt.Sprintf(" (%d of them this batch's, verifying other subnets)", batch)
+	}
+
+	return fmt.Errorf("refusing to launch another probe instance, as %d already exist in region %s%s, at or beyond the cap of %d: %s",
+		len(ids)+batch, c.region, batchNote, maxInstances, remedy)
+}
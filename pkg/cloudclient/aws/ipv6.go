@@ -0,0 +1,35 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const defaultIPv6RouteCidr = "::/0"
+
+// subnetHasIPv6 returns whether the subnet has an IPv6 CIDR block associated, i.e. is dual-stack or IPv6-only, so the
+// probe instance can be given an IPv6 address in it
+func subnetHasIPv6(subnet *ec2Types.Subnet) bool {
+	for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && association.Ipv6CidrBlockState.State == ec2Types.SubnetCidrBlockStateCodeAssociated {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyIPv6Route warns if the route table has no IPv6 default route, so egress over IPv6 can't reach the internet
+// however the IPv4 path fares. IPv6 has no NAT, its default route goes to an internet or egress-only internet gateway.
+func (c *Client) verifyIPv6Route(subnet *ec2Types.Subnet, routeTable *ec2Types.RouteTable) {
+	for _, route := range routeTable.Routes {
+		if aws.ToString(route.DestinationIpv6CidrBlock) == defaultIPv6RouteCidr && route.State != ec2Types.RouteStateBlackhole {
+			return
+		}
+	}
+
+	c.output.AddWarning(fmt.Sprintf("Subnet %s has IPv6 addresses, but its route table %s has no active %s route, egress over IPv6 is bound to fail",
+		aws.ToString(subnet.SubnetId), aws.ToString(routeTable.RouteTableId), defaultIPv6RouteCidr))
+}
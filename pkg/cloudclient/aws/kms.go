@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// kmsAccessDenied is the error code KMS denies an operation on a key with, whether by IAM or by the key policy
+const kmsAccessDenied = "AccessDeniedException"
+
+// newRegionalKMSClient builds KMS clients from the session, in the region of the probe's subnet
+func newRegionalKMSClient(sess *session.Session) func(region string) KMSClient {
+	return func(region string) KMSClient {
+		return kms.New(sess, awsv1.NewConfig().WithRegion(region))
+	}
+}
+
+// verifyKMSKey checks, before the launch, that the KMS key given to encrypt the probe instance's root volume can be:
+// it must exist in the subnet's region, be an enabled symmetric encryption key, and the caller be allowed to describe
+// it and generate data keys with it, as EBS does on the caller's behalf. Generating a data key without its plaintext
+// stands in for a dry run, the data key being discarded. Failures are recorded and false returned; when KMS can't be
+// queried otherwise, the error is recorded and the launch left to tell.
+func (c *Client) verifyKMSKey(ctx context.Context, keyID string) bool {
+	if parsed, err := arn.Parse(keyID); err == nil && parsed.Region != c.region {
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is in region %s, EBS volumes in %s can only be encrypted with keys in the same region", keyID, parsed.Region, c.region)))
+		return false
+	}
+
+	client := c.regionalKMSClient(c.region)
+	resp, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{KeyId: awsv1.String(keyID)})
+	if err != nil {
+		return c.kmsKeyError(keyID, "kms:DescribeKey", err)
+	}
+
+	key := resp.KeyMetadata
+	usable := true
+	if state := awsv1.StringValue(key.KeyState); state != kms.KeyStateEnabled {
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is %s, it must be %s", keyID, state, kms.KeyStateEnabled)))
+		usable = false
+	}
+	if usage, spec := awsv1.StringValue(key.KeyUsage), awsv1.StringValue(key.KeySpec); usage != kms.KeyUsageTypeEncryptDecrypt || (spec != "" && spec != kms.KeySpecSymmetricDefault) {
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is a %s key for %s, EBS volumes can only be encrypted with %s keys for %s", keyID, spec, usage, kms.KeySpecSymmetricDefault, kms.KeyUsageTypeEncryptDecrypt)))
+		usable = false
+	}
+	if !usable {
+		return false
+	}
+	c.logger.Debug(ctx, "KMS key %s resolves to %s", keyID, awsv1.StringValue(key.Arn))
+
+	if _, err := client.GenerateDataKeyWithoutPlaintextWithContext(ctx, &kms.GenerateDataKeyWithoutPlaintextInput{
+		KeyId:   key.Arn,
+		KeySpec: awsv1.String(kms.DataKeySpecAes256),
+	}); err != nil {
+		return c.kmsKeyError(keyID, "kms:GenerateDataKeyWithoutPlaintext", err)
+	}
+
+	return true
+}
+
+// kmsKeyError records the error of the operation on the key, returning whether the key may still be usable, i.e.
+// whether the error says nothing about the key itself
+func (c *Client) kmsKeyError(keyID, operation string, err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		c.output.AddError(handledErrors.NewGenericError(err))
+		return true
+	}
+
+	switch awsErr.Code() {
+	case kms.ErrCodeNotFoundException, kms.ErrCodeInvalidArnException:
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s does not exist in region %s", keyID, c.region)))
+	case kms.ErrCodeDisabledException, kms.ErrCodeInvalidStateException:
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is not enabled: %s", keyID, awsErr.Message())))
+	case kmsAccessDenied:
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("the caller is not allowed %s on key %s, which EBS needs to encrypt the root volume, check the key policy and the caller's IAM policies: %s", operation, keyID, strings.TrimSpace(awsErr.Message()))))
+	default:
+		c.output.AddError(handledErrors.NewGenericError(err))
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,205 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+// ephemeralPort stands in for the ephemeral port range return traffic is sent to when checking network ACLs
+const ephemeralPort int32 = 32768
+
+// verifyPeeringRoute checks a default route through a VPC peering connection to a hub VPC: that the hub routes
+// the subnet back through the peering connection, and that the network ACLs on both sides let the traffic through
+func (c *Client) verifyPeeringRoute(ctx context.Context, subnet *ec2Types.Subnet, peeringID string, p proxy.ProxyConfig, path routePath) error {
+	path = append(path, peeringID)
+
+	peerings, err := c.ec2Client.DescribeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: []string{peeringID},
+	})
+	if err != nil {
+		return err
+	}
+	if len(peerings.VpcPeeringConnections) == 0 {
+		return fmt.Errorf("unable to find VPC peering connection %s", peeringID)
+	}
+
+	peering := peerings.VpcPeeringConnections[0]
+	if peering.Status == nil || peering.Status.Code != ec2Types.VpcPeeringConnectionStateReasonCodeActive {
+		c.output.AddFailure(path.failure("the peering connection is not active"))
+		return nil
+	}
+
+	hub := peering.AccepterVpcInfo
+	if aws.ToString(hub.VpcId) == aws.ToString(subnet.VpcId) {
+		hub = peering.RequesterVpcInfo
+	}
+	hubVpcID := aws.ToString(hub.VpcId)
+	path = append(path, hubVpcID)
+
+	if p.HttpProxy == "" && p.HttpsProxy == "" {
+		c.output.AddFailure(path.failure("VPC peering doesn't forward traffic to the internet or NAT gateways of the peer VPC, " +
+			"egress through a peered hub VPC requires a proxy in it"))
+	}
+
+	hubClient := c.ec2Client
+	if region := aws.ToString(hub.Region); region != "" && region != c.region && c.regionalEC2Client != nil {
+		hubClient = c.regionalEC2Client(region)
+	}
+
+	subnetCidr := aws.ToString(subnet.CidrBlock)
+	if err := c.verifyPeeringReturnRoutes(ctx, hubClient, hubVpcID, subnetCidr, peeringID, path); err != nil {
+		return err
+	}
+
+	return c.verifyPeeringNetworkAcls(ctx, hubClient, subnet, hubVpcID, aws.ToString(hub.CidrBlock), proxyPort(p), path)
+}
+
+// verifyPeeringReturnRoutes checks the hub VPC's route tables send traffic for the subnet back through the peering
+// connection. Route tables sending it elsewhere are called out as an asymmetric routing risk.
+func (c *Client) verifyPeeringReturnRoutes(ctx context.Context, hubClient EC2Client, hubVpcID, subnetCidr, peeringID string, path routePath) error {
+	routeTables, err := hubClient.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: []string{hubVpcID}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	returning := 0
+	var asymmetric []string
+	for i := range routeTables.RouteTables {
+		route := longestPrefixMatch(&routeTables.RouteTables[i], subnetCidr)
+		switch {
+		case route == nil:
+			continue
+		case aws.ToString(route.VpcPeeringConnectionId) == peeringID:
+			returning++
+		default:
+			asymmetric = append(asymmetric, aws.ToString(routeTables.RouteTables[i].RouteTableId))
+		}
+	}
+
+	if returning == 0 {
+		c.output.AddFailure(path.failure(fmt.Sprintf("no route table of the hub VPC routes %s back through the peering connection", subnetCidr)))
+	}
+	if len(asymmetric) > 0 {
+		c.output.AddWarning(fmt.Sprintf("asymmetric routing risk: route tables %s of hub VPC %s route %s through something other than peering connection %s, "+
+			"return traffic from their subnets bypasses it and is likely dropped by stateful firewalls", strings.Join(asymmetric, ", "), hubVpcID, subnetCidr, peeringID))
+	}
+
+	return nil
+}
+
+// verifyPeeringNetworkAcls checks the network ACLs of the subnet and the hub VPC allow traffic from the subnet to
+// port in the hub, and the return traffic. Security groups are stateful and left to the probe.
+func (c *Client) verifyPeeringNetworkAcls(ctx context.Context, hubClient EC2Client, subnet *ec2Types.Subnet, hubVpcID, hubCidr string, port int32, path routePath) error {
+	subnetID, subnetCidr := aws.ToString(subnet.SubnetId), aws.ToString(subnet.CidrBlock)
+
+	subnetAcls, err := c.ec2Client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{subnetID}}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, acl := range subnetAcls.NetworkAcls {
+		if !naclAllows(acl.Entries, true, hubCidr, port) || !naclAllows(acl.Entries, false, hubCidr, ephemeralPort) {
+			c.output.AddFailure(path.failure(fmt.Sprintf("network ACL %s of subnet %s doesn't allow TCP port %d to %s and the return traffic",
+				aws.ToString(acl.NetworkAclId), subnetID, port, hubCidr)))
+		}
+	}
+
+	hubAcls, err := hubClient.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: []string{hubVpcID}}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, acl := range hubAcls.NetworkAcls {
+		if naclAllows(acl.Entries, false, subnetCidr, port) && naclAllows(acl.Entries, true, subnetCidr, ephemeralPort) {
+			return nil
+		}
+	}
+	c.output.AddFailure(path.failure(fmt.Sprintf("no network ACL of the hub VPC allows TCP port %d from %s and the return traffic", port, subnetCidr)))
+
+	return nil
+}
+
+// naclAllows evaluates network ACL entries like AWS does, in rule number order with the first match winning, for
+// TCP traffic to port from or to (egress) cidr
+func naclAllows(entries []ec2Types.NetworkAclEntry, egress bool, cidr string, port int32) bool {
+	sorted := make([]ec2Types.NetworkAclEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return aws.ToInt32(sorted[i].RuleNumber) < aws.ToInt32(sorted[j].RuleNumber) })
+
+	for _, e := range sorted {
+		if aws.ToBool(e.Egress) != egress || e.CidrBlock == nil || !cidrContains(aws.ToString(e.CidrBlock), cidr) {
+			continue
+		}
+		if protocol := aws.ToString(e.Protocol); protocol != "-1" && protocol != "6" {
+			continue
+		}
+		if e.PortRange != nil && (port < aws.ToInt32(e.PortRange.From) || port > aws.ToInt32(e.PortRange.To)) {
+			continue
+		}
+		return e.RuleAction == ec2Types.RuleActionAllow
+	}
+
+	return false
+}
+
+// longestPrefixMatch returns the most specific route of the route table covering cidr, or nil
+func longestPrefixMatch(routeTable *ec2Types.RouteTable, cidr string) *ec2Types.Route {
+	var match *ec2Types.Route
+	matchOnes := -1
+	for i, route := range routeTable.Routes {
+		destination := aws.ToString(route.DestinationCidrBlock)
+		if !cidrContains(destination, cidr) {
+			continue
+		}
+		if _, network, _ := net.ParseCIDR(destination); network != nil {
+			if ones, _ := network.Mask.Size(); ones > matchOnes {
+				match, matchOnes = &routeTable.Routes[i], ones
+			}
+		}
+	}
+
+	return match
+}
+
+// cidrContains reports whether the outer CIDR block contains the whole inner CIDR block
+func cidrContains(outer, inner string) bool {
+	_, outerNet, err := net.ParseCIDR(outer)
+	if err != nil {
+		return false
+	}
+	innerIP, innerNet, err := net.ParseCIDR(inner)
+	if err != nil {
+		return false
+	}
+
+	outerOnes, _ := outerNet.Mask.Size()
+	innerOnes, _ := innerNet.Mask.Size()
+	return outerNet.Contains(innerIP) && outerOnes <= innerOnes
+}
+
+// proxyPort returns the port egress traffic is sent to, the proxy's port if one is configured
+func proxyPort(p proxy.ProxyConfig) int32 {
+	for _, proxyURL := range []string{p.HttpsProxy, p.HttpProxy} {
+		if u, err := url.Parse(proxyURL); err == nil && u.Port() != "" {
+			if port, err := strconv.ParseInt(u.Port(), 10, 32); err == nil {
+				return int32(port)
+			}
+		}
+	}
+
+	return 443
+}
@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// policyDocument is the subset of an IAM policy document needed to evaluate resource policies
+type policyDocument struct {
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect    string          `json:"Effect"`
+	Principal json.RawMessage `json:"Principal"`
+	Action    stringOrSlice   `json:"Action"`
+	Resource  stringOrSlice   `json:"Resource"`
+	Condition json.RawMessage `json:"Condition"`
+}
+
+// stringOrSlice unmarshals policy elements that may be either a single string or a list of strings
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = []string{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*s = multiple
+
+	return nil
+}
+
+// parsePolicyDocument parses a policy document, which the EC2 API may return URL-encoded
+func parsePolicyDocument(document string) (*policyDocument, error) {
+	if decoded, err := url.QueryUnescape(document); err == nil {
+		document = decoded
+	}
+
+	doc := &policyDocument{}
+	if err := json.Unmarshal([]byte(document), doc); err != nil {
+		return nil, fmt.Errorf("unable to parse policy document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// allows reports whether the policy allows any principal to perform action on resource. Statements with
+// conditions are treated as not applying, as they can't be evaluated without the request context.
+func (d *policyDocument) allows(action, resource string) bool {
+	allowed := false
+	for _, s := range d.Statement {
+		if !s.matches(action, resource) {
+			continue
+		}
+		switch s.Effect {
+		case "Deny":
+			// An explicit deny always wins
+			return false
+		case "Allow":
+			if len(s.Condition) == 0 && s.appliesToAnyPrincipal() {
+				allowed = true
+			}
+		}
+	}
+
+	return allowed
+}
+
+func (s policyStatement) matches(action, resource string) bool {
+	return matchesAny(s.Action, action) && matchesAny(s.Resource, resource)
+}
+
+func (s policyStatement) appliesToAnyPrincipal() bool {
+	if len(s.Principal) == 0 {
+		// Identity policies have no principal
+		return true
+	}
+
+	principal := strings.TrimSpace(string(s.Principal))
+	return principal == `"*"` || principal == `{"AWS":"*"}` || principal == `{"AWS":["*"]}`
+}
+
+// matchesAny reports whether value matches any of the IAM-style patterns, which use * and ? wildcards
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		// path.Match treats "/" specially, which IAM wildcards don't
+		if ok, _ := path.Match(strings.ReplaceAll(strings.ToLower(p), "/", "\x00"), strings.ReplaceAll(strings.ToLower(value), "/", "\x00")); ok {
+			return true
+		}
+	}
+
+	return false
+}
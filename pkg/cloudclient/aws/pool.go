@@ -0,0 +1,408 @@
+package aws
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// poolTagKey tags a pooled probe instance with the key of the verifications it may be reused for
+	poolTagKey = "osd-network-verifier-pool"
+	// poolExpiresTagKey tags a pooled probe instance with when its window passes, in RFC 3339, after which it shuts
+	// itself down
+	poolExpiresTagKey = "osd-network-verifier-pool-expires"
+	// poolLeaseTagKey tags a pooled probe instance with the run using it and when, in seconds since the epoch, its
+	// lease expires, as "<run ID> <expiry>"
+	poolLeaseTagKey = "osd-network-verifier-pool-lease"
+	// poolLockPath is the lock file runs on a pooled instance take turns through
+	poolLockPath = "/var/lock/osd-network-verifier-pool.lock"
+
+	// probeScriptPath is where cloud-init writes the probe's script on launch, and where it's replaced to re-run it
+	probeScriptPath = "/run-container.sh"
+	// ssmOutputLimit is the most of a command's output SSM returns, the rest being cut off
+	ssmOutputLimit = 24000
+	// probeScriptParameterPrefix prefixes the names of the parameters a run's probe script is handed to a pooled
+	// instance in, followed by the run's ID
+	probeScriptParameterPrefix = "/osd-network-verifier/"
+	// parameterValueLimit is the most characters a standard-tier parameter holds
+	parameterValueLimit = 4096
+)
+
+// newRegionalSSMClient builds Systems Manager clients from the session, in the region of the probe's subnet
+func newRegionalSSMClient(sess *session.Session) func(region string) SSMClient {
+	return func(region string) SSMClient {
+		return ssm.New(sess, awsv1.NewConfig().WithRegion(region))
+	}
+}
+
+// poolMinutes returns the whole minutes, rounded up, a pooled probe instance is kept for before it shuts itself down,
+// or an empty string when pooling is disabled
+func poolMinutes(window time.Duration) string {
+	if window <= 0 {
+		return ""
+	}
+
+	return strconv.Itoa(int(math.Ceil(window.Minutes())))
+}
+
+// poolKey identifies the verifications a pooled instance may be reused for: those that would launch the same instance
+// into the same subnet
+func (c *Client) poolKey(input *createEC2InstanceInput) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s\n%t\n%t", input.subnetId, input.securityGroupId,
+		input.amiId, input.kmsKeyId, c.instanceType, input.instanceProfile, input.ipv6, input.spot)))
+
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// joinPool tags the instance about to be launched for the pool, with its key and when its window passes, and has it
+// terminated when it shuts itself down at the end of the window. It's launched leased to this run, so others don't
+// re-run the probe on it while cloud-init runs this run's.
+func (c *Client) joinPool(input *createEC2InstanceInput, key string, opts probe.Options) {
+	tags := make(map[string]string, len(c.tags)+3)
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	tags[poolTagKey] = key
+	tags[poolExpiresTagKey] = time.Now().Add(opts.PoolWindow).UTC().Format(time.RFC3339)
+	c.poolLease = c.newPoolLease(opts)
+	tags[poolLeaseTagKey] = c.poolLease
+	c.tags = tags
+	input.pooled = true
+}
+
+// newPoolLease returns a lease on a pooled instance for this run, lasting as long as launching an instance and
+// waiting for the probe's results could take. The lease is released once the results are collected, its expiry only
+// frees instances of runs that didn't get to.
+func (c *Client) newPoolLease(opts probe.Options) string {
+	return fmt.Sprintf("%s %d", c.runID, time.Now().Add(opts.LaunchTimeoutOrDefault()+opts.ConsoleTimeoutOrDefault()).Unix())
+}
+
+// leaseHeld reports whether a pool lease tag value is unexpired
+func leaseHeld(lease string) bool {
+	fields := strings.Fields(lease)
+	if len(fields) != 2 {
+		return false
+	}
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+
+	return err == nil && time.Now().Unix() < expires
+}
+
+// pooledInstances returns the running pooled instances for the key whose window doesn't pass within needed, so the
+// probe can finish on them, and which no other run holds the lease on
+func (c *Client) pooledInstances(ctx context.Context, key string, needed time.Duration) ([]string, error) {
+	resp, err := c.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("tag:" + poolTagKey), Values: []string{key}},
+			{Name: aws.String("instance-state-name"), Values: []string{string(ec2Types.InstanceStateNameRunning)}},
+		},
+	})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+
+	var instanceIDs []string
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			var expires time.Time
+			var lease string
+			for _, t := range i.Tags {
+				switch aws.ToString(t.Key) {
+				case poolExpiresTagKey:
+					expires, _ = time.Parse(time.RFC3339, aws.ToString(t.Value))
+				case poolLeaseTagKey:
+					lease = aws.ToString(t.Value)
+				}
+			}
+			if time.Now().Add(needed).Before(expires) && !leaseHeld(lease) {
+				instanceIDs = append(instanceIDs, aws.ToString(i.InstanceId))
+			}
+		}
+	}
+
+	return instanceIDs, nil
+}
+
+// claimPooledInstance tags the pooled instance with the lease and reads it back, reporting whether the lease is this
+// run's rather than that of a run claiming the instance at the same time. Runs that still both believe they hold it
+// are serialised on the instance, see rerunProbe.
+func (c *Client) claimPooledInstance(ctx context.Context, instanceID, lease string) (bool, error) {
+	if _, err := c.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{instanceID},
+		Tags:      []ec2Types.Tag{{Key: aws.String(poolLeaseTagKey), Value: aws.String(lease)}},
+	}); err != nil {
+		return false, handledErrors.NewGenericError(err)
+	}
+
+	resp, err := c.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return false, handledErrors.NewGenericError(err)
+	}
+	for _, r := range resp.Reservations {
+		for _, i := range r.Instances {
+			for _, t := range i.Tags {
+				if aws.ToString(t.Key) == poolLeaseTagKey {
+					return aws.ToString(t.Value) == lease, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// releasePooledInstance removes this run's lease from the pooled instance it used, leaving a lease another run has
+// taken since in place
+func (c *Client) releasePooledInstance(ctx context.Context, instanceID string) {
+	if c.poolLease == "" {
+		return
+	}
+	if _, err := c.ec2Client.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: []string{instanceID},
+		Tags:      []ec2Types.Tag{{Key: aws.String(poolLeaseTagKey), Value: aws.String(c.poolLease)}},
+	}); err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to release pooled instance %s, it can't be reused until its lease expires: %v", instanceID, err))
+		return
+	}
+	c.poolLease = ""
+}
+
+// probeScript returns the probe script of the generated userdata, which cloud-init writes to probeScriptPath on launch
+func probeScript(userData string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		return "", err
+	}
+	var cloudConfig struct {
+		WriteFiles []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"write_files"`
+	}
+	if err := yaml.Unmarshal(data, &cloudConfig); err != nil {
+		return "", fmt.Errorf("unable to parse the userdata: %w", err)
+	}
+	for _, f := range cloudConfig.WriteFiles {
+		if f.Path == probeScriptPath {
+			return f.Content, nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s in the userdata", probeScriptPath)
+}
+
+// scriptParameterValues splits the gzipped, base64-encoded script into values small enough for standard parameters
+func scriptParameterValues(script string) ([]string, error) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(script)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(compressed.Bytes())
+	var values []string
+	for len(encoded) > parameterValueLimit {
+		values = append(values, encoded[:parameterValueLimit])
+		encoded = encoded[parameterValueLimit:]
+	}
+
+	return append(values, encoded), nil
+}
+
+// putScriptParameters stores the probe script in SecureString parameters for the pooled instance to fetch, as it
+// carries this run's proxy credentials and callback token, which SSM would keep in the command's history. The names
+// of the parameters are returned.
+func (c *Client) putScriptParameters(ctx context.Context, client SSMClient, script string) ([]string, error) {
+	values, err := scriptParameterValues(script)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for i, value := range values {
+		name := fmt.Sprintf("%s%s/%d", probeScriptParameterPrefix, c.runID, i)
+		if _, err := client.PutParameterWithContext(ctx, &ssm.PutParameterInput{
+			Name:        awsv1.String(name),
+			Description: awsv1.String("osd-network-verifier probe script of run " + c.runID),
+			Type:        awsv1.String(ssm.ParameterTypeSecureString),
+			Tier:        awsv1.String(ssm.ParameterTierStandard),
+			Value:       awsv1.String(value),
+			Overwrite:   awsv1.Bool(true),
+		}); err != nil {
+			c.deleteScriptParameters(ctx, client, names)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// deleteScriptParameters deletes the parameters the probe script was handed to the pooled instance in
+func (c *Client) deleteScriptParameters(ctx context.Context, client SSMClient, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	if _, err := client.DeleteParametersWithContext(ctx, &ssm.DeleteParametersInput{Names: awsv1.StringSlice(names)}); err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to delete the parameters holding the probe script, delete %s manually: %v", strings.Join(names, ", "), err))
+	}
+}
+
+// rerunCommands returns the commands re-running the probe on a pooled instance, fetching its script from the
+// parameters. Runs on the same instance take turns through a lock file, as they share the script's path and output.
+func (c *Client) rerunCommands(names []string) []string {
+	commands := []string{
+		"exec 9>" + poolLockPath,
+		"flock 9",
+		`command -v aws >/dev/null || { echo "the AWS CLI is needed to fetch the probe script from Parameter Store" >&2; exit 1; }`,
+		": > /tmp/osd-network-verifier-script",
+	}
+	for _, name := range names {
+		commands = append(commands, fmt.Sprintf("aws ssm get-parameter --region %s --with-decryption --name %s --query Parameter.Value --output text >> /tmp/osd-network-verifier-script || exit 1", c.region, name))
+	}
+
+	return append(commands,
+		fmt.Sprintf("base64 -d /tmp/osd-network-verifier-script | gunzip > %s || exit 1", probeScriptPath),
+		"rm -f /tmp/osd-network-verifier-script",
+		"chmod 755 "+probeScriptPath,
+		": > /var/log/userdata-output",
+		probeScriptPath,
+		"cat /var/log/userdata-output",
+	)
+}
+
+// rerunProbe re-runs the probe on the pooled instance through SSM Run Command, with this run's script. The command's
+// output stands in for the instance's console output from then on. The names of the parameters holding the script are
+// returned, to be deleted once the probe's done.
+func (c *Client) rerunProbe(ctx context.Context, instanceID, userData string) ([]string, error) {
+	if c.regionalSSMClient == nil {
+		return nil, errors.New("no SSM client configured")
+	}
+	script, err := probeScript(userData)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.regionalSSMClient(c.region)
+	names, err := c.putScriptParameters(ctx, client, script)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.SendCommandWithContext(ctx, &ssm.SendCommandInput{
+		DocumentName: awsv1.String("AWS-RunShellScript"),
+		InstanceIds:  awsv1.StringSlice([]string{instanceID}),
+		Comment:      awsv1.String("osd-network-verifier run " + c.runID),
+		Parameters:   map[string][]*string{"commands": awsv1.StringSlice(c.rerunCommands(names))},
+	})
+	if err != nil {
+		c.deleteScriptParameters(ctx, client, names)
+		return nil, err
+	}
+	c.pooledCommandID = awsv1.StringValue(resp.Command.CommandId)
+
+	return names, nil
+}
+
+// commandOutput returns the base64-encoded output of the command re-running the probe on the pooled instance, or an
+// empty string until it's done
+func (c *Client) commandOutput(ctx context.Context, instanceID string) (string, error) {
+	resp, err := c.regionalSSMClient(c.region).GetCommandInvocationWithContext(ctx, &ssm.GetCommandInvocationInput{
+		CommandId:  awsv1.String(c.pooledCommandID),
+		InstanceId: awsv1.String(instanceID),
+	})
+	if err != nil {
+		// The invocation isn't found for a moment after the command is sent
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == ssm.ErrCodeInvocationDoesNotExist {
+			return "", nil
+		}
+		return "", handledErrors.NewGenericError(err)
+	}
+
+	switch status := awsv1.StringValue(resp.Status); status {
+	case ssm.CommandInvocationStatusSuccess:
+		output := awsv1.StringValue(resp.StandardOutputContent)
+		if !c.markers.Ended(output) && len(output) >= ssmOutputLimit {
+			return "", fmt.Errorf("the probe's output on pooled instance %s is longer than the %d characters SSM returns, use --result-channel %s instead", instanceID, ssmOutputLimit, probe.ResultChannelCloudWatch)
+		}
+		return base64.StdEncoding.EncodeToString([]byte(output)), nil
+	case ssm.CommandInvocationStatusPending, ssm.CommandInvocationStatusInProgress, ssm.CommandInvocationStatusDelayed:
+		return "", nil
+	default:
+		return "", fmt.Errorf("re-running the probe on pooled instance %s ended %s: %s", instanceID, status, awsv1.StringValue(resp.StandardErrorContent))
+	}
+}
+
+// reusePooledInstance re-runs the probe on a pooled instance launched for the same verification, if there's one free,
+// and collects its results, returning the instance's ID. The instance is leased to this run first, so concurrent runs
+// don't re-run the probe on it at the same time. Otherwise, an empty string is returned and the instance about to be
+// launched joins the pool instead. A pooled instance the probe can't be re-run on, e.g. without the SSM agent
+// registered, is passed over with a warning.
+func (c *Client) reusePooledInstance(ctx context.Context, input *createEC2InstanceInput, userData, subnetID string, opts probe.Options) string {
+	key := c.poolKey(input)
+	instanceIDs, err := c.pooledInstances(ctx, key, opts.ConsoleTimeoutOrDefault())
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up pooled probe instances, launching one: %v", err))
+	}
+
+	for _, instanceID := range instanceIDs {
+		c.poolLease = c.newPoolLease(opts)
+		claimed, err := c.claimPooledInstance(ctx, instanceID, c.poolLease)
+		if err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to lease pooled instance %s: %v", instanceID, err))
+		}
+		if !claimed {
+			// Taken by another run in the meantime
+			c.poolLease = ""
+			continue
+		}
+
+		names, err := c.rerunProbe(ctx, instanceID, userData)
+		if err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to re-run the probe on pooled instance %s: %v", instanceID, err))
+			c.releasePooledInstance(ctx, instanceID)
+			continue
+		}
+		c.logger.Info(ctx, "Re-running the probe on pooled instance %s", instanceID)
+		c.output.Metadata().InstanceID = instanceID
+		if err := c.findUnreachableEndpoints(ctx, instanceID, subnetID, opts); err != nil {
+			if errors.Is(err, helpers.ErrPollTimeout) {
+				c.recordPartialResults(subnetID, opts)
+			}
+			c.output.AddError(err)
+		}
+		c.deleteScriptParameters(ctx, c.regionalSSMClient(c.region), names)
+		return instanceID
+	}
+
+	if len(instanceIDs) > 0 {
+		c.logger.Info(ctx, "No pooled instance could be used, launching one into the pool")
+	}
+	c.joinPool(input, key, opts)
+	return ""
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,12 +18,20 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 
-	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
 	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/events"
 	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
 	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/remediation"
+	"github.com/openshift/osd-network-verifier/pkg/resourcepolicy"
+	"github.com/openshift/osd-network-verifier/pkg/version"
 )
 
 type createEC2InstanceInput struct {
@@ -30,17 +41,29 @@ type createEC2InstanceInput struct {
 	userdata        string
 	kmsKeyId        string
 	instanceCount   int32
+	spot            bool
+	instanceProfile string
+	// ipv6 gives the probe instance an IPv6 address as well, in a subnet with IPv6 addresses
+	ipv6 bool
+	// pooled has the instance terminated, rather than stopped, when it shuts itself down at the end of its pool window
+	pooled bool
 }
 
 const (
 	instanceCount int32 = 1
 
-	// TODO find a location for future docker images
-	networkValidatorImage = "quay.io/app-sre/osd-network-verifier:v0.1.212-5f88b83"
-	userdataEndVerifier   = "USERDATA END"
+	// defaultNetworkValidatorImage is run by the probe unless overridden, e.g. with --validator-image
+	defaultNetworkValidatorImage = "quay.io/app-sre/osd-network-verifier:v0.1.212-5f88b83"
 )
 
 var (
+	// defaultInstanceTypes are tried in order when no instance type was requested, by CPU architecture.
+	// All are nitro-based and small, as the probe only runs a container for a few minutes.
+	defaultInstanceTypes = map[string][]string{
+		probe.ArchitectureX86_64: {"t3.micro", "t3a.micro", "m5.large"},
+		probe.ArchitectureArm64:  {"t4g.micro", "m6g.medium"},
+	}
+
 	defaultAmi = map[string]string{
 		// using AMI from
 		"af-south-1":     "ami-0305ce24a63f7cd96",
@@ -67,15 +90,12 @@ var (
 	}
 )
 
-func newClient(ctx context.Context, logger ocmlog.Logger, accessID, accessSecret, sessiontoken, region,
-	instanceType string, tags map[string]string, profile string) (*Client, error) {
+func newClient(ctx context.Context, logger logging.Logger, accessID, accessSecret, sessiontoken, region,
+	instanceType string, tags map[string]string, profile string, role AssumeRoleCredentials) (*Client, error) {
 	var cfg aws.Config
 	var err error
 	if profile != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithSharedConfigProfile(profile),
-			config.WithRegion(region),
-		)
+		cfg, err = loadProfileConfig(ctx, profile, region)
 	} else {
 		cfg, err = config.LoadDefaultConfig(ctx,
 			config.WithRegion(region),
@@ -89,20 +109,56 @@ func newClient(ctx context.Context, logger ocmlog.Logger, accessID, accessSecret
 	if err != nil {
 		return nil, err
 	}
+	var caller callerIdentity
+	var originAccount string
+	if role.RoleARN != "" {
+		if role.IntermediateRoleARN != "" {
+			logger.Info(ctx, "Assuming role %s through role %s", role.RoleARN, role.IntermediateRoleARN)
+		} else {
+			logger.Info(ctx, "Assuming role %s", role.RoleARN)
+		}
+		if caller, err = role.assumeRole(ctx, &cfg); err != nil {
+			return nil, err
+		}
+		originAccount = caller.account
+	} else if caller, err = identifyCaller(ctx, cfg); err != nil {
+		// Only the created-by tag depends on it, the permissions are checked by the calls that need them
+		logger.Debug(ctx, "Unable to identify the caller, the resources won't be tagged with their creator: %s", err)
+	}
+
+	sess, err := newSessionV1(region, profile, cfg.Credentials)
+	if err != nil {
+		return nil, err
+	}
 
 	c := &Client{
-		ec2Client:    ec2.NewFromConfig(cfg),
-		region:       region,
-		instanceType: instanceType,
-		tags:         tags,
-		logger:       logger,
-		output:       output.Output{},
+		ec2Client: ec2.NewFromConfig(cfg),
+		regionalEC2Client: func(region string) EC2Client {
+			return ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		},
+		regionalLogsClient: newRegionalLogsClient(sess),
+		regionalSSMClient:  newRegionalSSMClient(sess),
+		regionalKMSClient:  newRegionalKMSClient(sess),
+		originAccount:      originAccount,
+		createdBy:          caller.arn,
+		region:             region,
+		tags:               tags,
+		logger:             redact.Logger(logger),
+		output:             output.Output{},
 	}
 
-	// Validates the provided instance type will work with the verifier
+	// Selects the first of the provided instance types that will work with the verifier
 	// NOTE a "nitro" EC2 instance type is required to be used
-	if err := c.validateInstanceType(ctx); err != nil {
-		return nil, err
+	// Without one, a default is selected once the subnet's region and the CPU architecture are known
+	if preferred := helpers.SplitInstanceTypes(instanceType); len(preferred) > 0 {
+		selected, err := c.selectInstanceType(ctx, preferred)
+		if err != nil {
+			return nil, err
+		}
+		if selected != preferred[0] {
+			c.logger.Info(ctx, "Instance type %s is unavailable in %s, falling back to %s", preferred[0], region, selected)
+		}
+		c.instanceType = selected
 	}
 
 	return c, nil
@@ -117,16 +173,85 @@ func buildTags(tags map[string]string) []ec2Types.Tag {
 		}
 		tagList = append(tagList, t)
 	}
+	sort.Slice(tagList, func(i, j int) bool { return *tagList[i].Key < *tagList[j].Key })
 
 	return tagList
 }
 
-func (c *Client) validateInstanceType(ctx context.Context) error {
+// defaultInstanceName names the probe instance, by its Name tag, when a resource policy applies and the tags don't
+// name it
+const defaultInstanceName = "osd-network-verifier"
+
+// applyResourcePolicy names and tags the probe instance as the policy requires, its volume and network interface
+// being tagged along with it. The instance's name is its Name tag. A nil policy changes nothing.
+func (c *Client) applyResourcePolicy(policy *resourcepolicy.Policy) error {
+	if policy == nil {
+		return nil
+	}
+
+	name := c.tags["Name"]
+	if name == "" {
+		name = defaultInstanceName
+	}
+	r, err := policy.Apply(resourcepolicy.Resource{Kind: resourcepolicy.KindInstance, Name: name, Tags: c.tags})
+	if err != nil {
+		return err
+	}
+	c.tags = r.Tags
+	c.tags["Name"] = r.Name
+
+	return nil
+}
+
+// tagSpecifications tags the instance, and its root volume and network interface, as RunInstances creates them, so
+// accounts whose SCPs require tags on creation don't reject the launch, and cleanup tooling finds every resource of
+// the probe. The run's ID is added to the tags, so what survives the teardown can be found, and so is the account a
+// role was assumed from, so the resources are traced back to it. Who created the resources and when they can be
+// deemed leaked are added too, for audit and cost attribution. These take precedence over the tags given for the
+// client. The spot request is tagged
+// too when launching on spot capacity. There are none without tags, as RunInstances rejects empty tag specifications.
+func (c *Client) tagSpecifications(spot bool) []ec2Types.TagSpecification {
+	tags := make(map[string]string, len(c.tags)+5)
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	if c.runID != "" {
+		tags[runIDTagKey] = c.runID
+	}
+	if c.batchID != "" {
+		tags[batchIDTagKey] = c.batchID
+	}
+	if c.originAccount != "" {
+		tags[originAccountTagKey] = c.originAccount
+	}
+	if c.createdBy != "" {
+		tags[createdByTagKey] = c.createdBy
+	}
+	if !c.expiresAt.IsZero() {
+		tags[expiresAtTagKey] = strconv.FormatInt(c.expiresAt.Unix(), 10)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	resourceTypes := []ec2Types.ResourceType{ec2Types.ResourceTypeInstance, ec2Types.ResourceTypeVolume, ec2Types.ResourceTypeNetworkInterface}
+	if spot {
+		resourceTypes = append(resourceTypes, ec2Types.ResourceTypeSpotInstancesRequest)
+	}
+	specs := make([]ec2Types.TagSpecification, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		specs = append(specs, ec2Types.TagSpecification{ResourceType: resourceType, Tags: buildTags(tags)})
+	}
+
+	return specs
+}
+
+func (c *Client) validateInstanceType(ctx context.Context, instanceType string) error {
 	descInput := ec2.DescribeInstanceTypesInput{
-		InstanceTypes: []ec2Types.InstanceType{ec2Types.InstanceType(c.instanceType)},
+		InstanceTypes: []ec2Types.InstanceType{ec2Types.InstanceType(instanceType)},
 	}
 
-	c.WriteDebugLogs(ctx, fmt.Sprintf("Gathering description of instance type %s from EC2", c.instanceType))
+	c.WriteDebugLogs(ctx, fmt.Sprintf("Gathering description of instance type %s from EC2", instanceType))
 	descOut, err := c.ec2Client.DescribeInstanceTypes(ctx, &descInput)
 	if err != nil {
 		return handledErrors.NewGenericError(err)
@@ -137,19 +262,52 @@ func (c *Client) validateInstanceType(ctx context.Context) error {
 	// an array of InstanceTypes which could return multiple matches.
 	if len(descOut.InstanceTypes) != 1 {
 		c.WriteDebugLogs(ctx, fmt.Sprintf("matched instance types: %v", descOut.InstanceTypes))
-		return fmt.Errorf("expected one instance type match for %s, got %d", c.instanceType, len(descOut.InstanceTypes))
+		return fmt.Errorf("expected one instance type match for %s, got %d", instanceType, len(descOut.InstanceTypes))
 	}
 
-	if string(descOut.InstanceTypes[0].InstanceType) == c.instanceType {
+	if string(descOut.InstanceTypes[0].InstanceType) == instanceType {
 		if descOut.InstanceTypes[0].Hypervisor != ec2Types.InstanceTypeHypervisorNitro {
-			return fmt.Errorf("instance type %s must use hypervisor type 'nitro' to support reliable result collection, using %s", c.instanceType, descOut.InstanceTypes[0].Hypervisor)
+			return fmt.Errorf("instance type %s must use hypervisor type 'nitro' to support reliable result collection, using %s", instanceType, descOut.InstanceTypes[0].Hypervisor)
 		}
 	}
 
 	return nil
 }
 
-// createEC2Instance attempts to create a single EC2 instance, tags it, and returns its id
+// selectInstanceType returns the first instance type of the preference list that's usable in the client's region
+func (c *Client) selectInstanceType(ctx context.Context, preferred []string) (string, error) {
+	var reasons []string
+	for _, instanceType := range preferred {
+		if err := c.validateInstanceType(ctx, instanceType); err != nil {
+			c.WriteDebugLogs(ctx, fmt.Sprintf("Instance type %s is not usable in %s: %s", instanceType, c.region, err))
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		return instanceType, nil
+	}
+
+	return "", fmt.Errorf("none of the instance types %v are usable in %s: %s", preferred, c.region, strings.Join(reasons, "; "))
+}
+
+// selectDefaultInstanceType returns the first of the default instance types for the CPU architecture that's
+// offered in the client's region
+func (c *Client) selectDefaultInstanceType(ctx context.Context, architecture string) (string, error) {
+	candidates, ok := defaultInstanceTypes[architecture]
+	if !ok {
+		return "", fmt.Errorf("unsupported CPU architecture %s", architecture)
+	}
+
+	instanceType, err := c.selectInstanceType(ctx, candidates)
+	if err != nil {
+		return "", fmt.Errorf("%v, please specify one with `--instance-type`", err)
+	}
+	c.WriteDebugLogs(ctx, fmt.Sprintf("Selected default instance type %s, the first of %v offered for %s in %s", instanceType, candidates, architecture, c.region))
+
+	return instanceType, nil
+}
+
+// createEC2Instance attempts to create a single EC2 instance, tagged along with its volume and network interface, and
+// returns its id
 func (c *Client) createEC2Instance(ctx context.Context, input *createEC2InstanceInput) (string, error) {
 	ebsBlockDevice := &ec2Types.EbsBlockDevice{
 		DeleteOnTermination: aws.Bool(true),
@@ -171,6 +329,9 @@ func (c *Client) createEC2Instance(ctx context.Context, input *createEC2Instance
 	if input.securityGroupId != "" {
 		eniSpecification.Groups = []string{input.securityGroupId}
 	}
+	if input.ipv6 {
+		eniSpecification.Ipv6AddressCount = aws.Int32(1)
+	}
 
 	// Build our request, converting the go base types into the pointers required by the SDK
 	instanceReq := ec2.RunInstancesInput{
@@ -187,7 +348,17 @@ func (c *Client) createEC2Instance(ctx context.Context, input *createEC2Instance
 				Ebs:        ebsBlockDevice,
 			},
 		},
-		UserData: aws.String(input.userdata),
+		UserData:          aws.String(input.userdata),
+		TagSpecifications: c.tagSpecifications(input.spot),
+	}
+	if input.spot {
+		instanceReq.InstanceMarketOptions = spotMarketOptions()
+	}
+	if input.pooled {
+		instanceReq.InstanceInitiatedShutdownBehavior = ec2Types.ShutdownBehaviorTerminate
+	}
+	if input.instanceProfile != "" {
+		instanceReq.IamInstanceProfile = &ec2Types.IamInstanceProfileSpecification{Name: aws.String(input.instanceProfile)}
 	}
 	// Finally, we make our request
 	instanceResp, err := c.ec2Client.RunInstances(ctx, &instanceReq)
@@ -204,22 +375,7 @@ func (c *Client) createEC2Instance(ctx context.Context, input *createEC2Instance
 		return "", handledErrors.NewGenericError(errors.New("unexpectedly found 0 instances after creation, please try again"))
 	}
 
-	instanceID := *instanceResp.Instances[0].InstanceId
-	if err := c.createTags(ctx, instanceID); err != nil {
-		// Unable to tag the instance
-		return "", handledErrors.NewGenericError(err)
-	}
-
-	return instanceID, nil
-}
-
-func (c *Client) createTags(ctx context.Context, ids ...string) error {
-	_, err := c.ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
-		Resources: ids,
-		Tags:      buildTags(c.tags),
-	})
-
-	return err
+	return *instanceResp.Instances[0].InstanceId, nil
 }
 
 // describeEC2Instances returns the instance state name of an EC2 instance
@@ -257,11 +413,11 @@ func (c *Client) describeEC2Instances(ctx context.Context, instanceID string) (*
 	return &result.InstanceStatuses[0].InstanceState.Name, nil
 }
 
-// waitForEC2InstanceCompletion checks every 15s for up to 2 minutes for an instance to be in the running state
-func (c *Client) waitForEC2InstanceCompletion(ctx context.Context, instanceID string) error {
+// waitForEC2InstanceCompletion checks every 15s for up to timeout for an instance to be in the running state
+func (c *Client) waitForEC2InstanceCompletion(ctx context.Context, instanceID string, timeout time.Duration) error {
 	c.WriteDebugLogs(ctx, fmt.Sprintf("Waiting for EC2 instance %s to be running", instanceID))
 
-	return helpers.PollImmediate(15*time.Second, 2*time.Minute, func() (bool, error) {
+	return helpers.PollImmediateWithContext(ctx, 15*time.Second, timeout, func() (bool, error) {
 		instanceState, descError := c.describeEC2Instances(ctx, instanceID)
 		if descError != nil {
 			return false, descError
@@ -286,6 +442,10 @@ func (c *Client) waitForEC2InstanceCompletion(ctx context.Context, instanceID st
 
 func generateUserData(variables map[string]string) (string, error) {
 	variableMapper := func(varName string) string {
+		// "$$" escapes a literal "$", keeping the probe's own shell variables out of the expansion
+		if varName == "$" {
+			return "$"
+		}
 		return variables[varName]
 	}
 	data := os.Expand(helpers.UserdataTemplate, variableMapper)
@@ -293,96 +453,115 @@ func generateUserData(variables map[string]string) (string, error) {
 	return base64.StdEncoding.EncodeToString([]byte(data)), nil
 }
 
-func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceID string) error {
+func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceID, subnetID string, opts probe.Options) error {
 	var (
 		b64ConsoleLogs string
 		consoleLogs    string
 	)
 	// Compile the regular expressions once
-	reUserDataComplete := regexp.MustCompile(userdataEndVerifier)
-	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
-	reGenericFailure := regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
+	failurePatterns := opts.FailurePatternsOrDefault(defaultNetworkValidatorImage)
 	reDockerFailure := regexp.MustCompile(`(?m)(docker)`)
-
-	input := &ec2.GetConsoleOutputInput{
-		InstanceId: aws.String(instanceID),
-		Latest:     aws.Bool(true),
-	}
+	reValidatorImage := regexp.MustCompile(`VALIDATOR_IMAGE_DIGEST (\S+@\S+)`)
 
 	c.WriteDebugLogs(ctx, "Scraping console output and waiting for user data script to complete...")
+	soakRoundsLogged := 0
 
 	// Periodically scrape console output and analyze the logs for any errors or a successful completion
-	err := helpers.PollImmediate(30*time.Second, 4*time.Minute, func() (bool, error) {
-		consoleOutput, err := c.ec2Client.GetConsoleOutput(ctx, input)
+	err := helpers.PollImmediateWithContext(ctx, opts.ConsolePollIntervalOrDefault(), opts.ConsoleTimeoutOrDefault(), func() (bool, error) {
+		// The probe's output, from the EC2 console unless another result channel was requested
+		consoleOutput, err := c.probeOutput(ctx, instanceID, opts)
 		if err != nil {
-			return false, handledErrors.NewGenericError(err)
+			return false, err
 		}
 
-		if consoleOutput.Output != nil {
-			// In the early stages, an ec2 instance may be running but the console is not populated with any data
-			if len(*consoleOutput.Output) == 0 {
-				c.WriteDebugLogs(ctx, "EC2 console consoleOutput not yet populated with data, continuing to wait...")
-				return false, nil
-			}
+		// In the early stages, an ec2 instance may be running but the console is not populated with any data
+		if len(consoleOutput) == 0 {
+			c.WriteDebugLogs(ctx, "EC2 console consoleOutput not yet populated with data, continuing to wait...")
+			return false, nil
+		}
 
-			// Store base64-encoded output for debug logs
-			b64ConsoleLogs = *consoleOutput.Output
+		// The console consoleOutput starts out base64 encoded
+		scriptOutput, err := base64.StdEncoding.DecodeString(consoleOutput)
+		if err != nil {
+			c.WriteDebugLogs(ctx, fmt.Sprintf("Error decoding console consoleOutput, will retry on next check interval: %s", err))
+			return false, nil
+		}
 
-			// The console consoleOutput starts out base64 encoded
-			scriptOutput, err := base64.StdEncoding.DecodeString(*consoleOutput.Output)
-			if err != nil {
-				c.WriteDebugLogs(ctx, fmt.Sprintf("Error decoding console consoleOutput, will retry on next check interval: %s", err))
-				return false, nil
+		consoleLogs = string(scriptOutput)
+		c.output.SetConsoleLogs(consoleLogs)
+		// Store the sanitized output base64-encoded for debug logs, its secrets hidden from the logger in the encoding
+		b64ConsoleLogs = base64.StdEncoding.EncodeToString([]byte(c.output.ConsoleLogs()))
+		if match := reValidatorImage.FindStringSubmatch(consoleLogs); match != nil {
+			c.output.Metadata().ValidatorImageDigest = match[1]
+		}
+		// Stream the soak rounds as they're reported, rather than once the probe is done
+		for _, round := range helpers.ParseSoakRounds(consoleLogs) {
+			if round.Round > soakRoundsLogged {
+				c.logger.Info(ctx, "%s", round)
+				soakRoundsLogged = round.Round
 			}
+		}
 
-			consoleLogs = string(scriptOutput)
-
-			// Check for the specific string we consoleOutput in the generated userdata file at the end to verify the userdata script has run
-			// It is possible we get EC2 console consoleOutput, but the userdata script has not yet completed.
-			userDataComplete := reUserDataComplete.FindString(consoleLogs)
-			if len(userDataComplete) < 1 {
-				c.WriteDebugLogs(ctx, "EC2 console consoleOutput contains data, but end of userdata script not seen, continuing to wait...")
-				return false, nil
-			}
+		// Check for the specific string we consoleOutput in the generated userdata file at the end to verify the userdata script has run
+		// It is possible we get EC2 console consoleOutput, but the userdata script has not yet completed.
+		// The done marker printed after the output stands in for it when the console cut it off.
+		completion := helpers.CheckConsoleCompletion(consoleLogs, c.markers)
+		if !completion.Done {
+			c.WriteDebugLogs(ctx, "EC2 console consoleOutput contains data, but end of userdata script not seen, continuing to wait...")
+			return false, nil
+		}
+		if completion.Truncated {
+			c.output.AddWarning("the probe's console output does not match its checksum, part of it was cut off or interleaved with other console output, so some results may be missing")
+		}
 
-			// Check consoleOutput for failures, report as exceptions if they occurred
-			genericFailures := reGenericFailure.FindAllStringSubmatch(consoleLogs, -1)
-			if len(genericFailures) > 0 {
-				c.WriteDebugLogs(ctx, fmt.Sprint(genericFailures))
-
-				dockerFailures := reDockerFailure.FindAllString(consoleLogs, -1)
-				if len(dockerFailures) > 0 {
-					// Should be resolved by OSD-13003 and OSD-13007
-					c.output.AddException(handledErrors.NewGenericError(errors.New("docker was unable to install or run. Further investigation needed")))
-					c.output.AddError(handledErrors.NewGenericError(fmt.Errorf("%v", dockerFailures)))
-				} else {
-					// TODO: Flesh out generic issues, for now we only know about Docker
-					c.output.AddException(handledErrors.NewGenericError(errors.New("egress tests were not run due to an uncaught error in setup or execution. Further investigation needed")))
-					c.output.AddError(handledErrors.NewGenericError(fmt.Errorf("%v", genericFailures)))
-				}
+		// Check consoleOutput for failures, report as exceptions if they occurred
+		genericFailures := failurePatterns.Failures(consoleLogs)
+		if len(genericFailures) > 0 {
+			c.WriteDebugLogs(ctx, fmt.Sprint(genericFailures))
+
+			dockerFailures := reDockerFailure.FindAllString(consoleLogs, -1)
+			if len(dockerFailures) > 0 {
+				// Should be resolved by OSD-13003 and OSD-13007
+				c.output.AddException(handledErrors.NewGenericError(errors.New("docker was unable to install or run. Further investigation needed")))
+				c.output.AddError(handledErrors.NewGenericError(fmt.Errorf("%v", dockerFailures)))
+			} else {
+				// TODO: Flesh out generic issues, for now we only know about Docker
+				c.output.AddException(handledErrors.NewGenericError(errors.New("egress tests were not run due to an uncaught error in setup or execution. Further investigation needed")))
+				c.output.AddError(handledErrors.NewGenericError(fmt.Errorf("%v", genericFailures)))
 			}
-
-			// If debug logging is enabled, consoleOutput the full console log that appears to include the full userdata run
-			c.WriteDebugLogs(ctx, fmt.Sprintf("base64-encoded console logs:\n---\n%s\n---", b64ConsoleLogs))
-
-			c.output.SetEgressFailures(reUnreachableErrors.FindAllString(string(scriptOutput), -1))
-			return true, nil
+		} else if !failurePatterns.Succeeded(consoleLogs) {
+			c.output.AddException(handledErrors.NewGenericError(errors.New("egress tests were not run: the probe's output matches none of the success patterns. Further investigation needed")))
 		}
 
-		if len(b64ConsoleLogs) > 0 {
-			c.WriteDebugLogs(ctx, fmt.Sprintf("base64-encoded console logs:\n---\n%s\n---", b64ConsoleLogs))
-		}
+		// If debug logging is enabled, consoleOutput the full console log that appears to include the full userdata run
+		c.WriteDebugLogs(ctx, fmt.Sprintf("base64-encoded console logs:\n---\n%s\n---", b64ConsoleLogs))
 
-		return false, nil
+		helpers.ParseProbeResults(&c.output, consoleLogs, subnetID)
+		return true, nil
 	})
 
+	if errors.Is(err, helpers.ErrPollTimeout) {
+		return fmt.Errorf("the probe did not report its results within the console timeout of %s, slow proxies or large endpoint lists may need a larger --console-timeout: %w", opts.ConsoleTimeoutOrDefault(), err)
+	}
+
 	return err
 }
 
+// recordPartialResults reports whatever the probe got through before the console timeout, marking the run incomplete
+func (c *Client) recordPartialResults(subnetID string, opts probe.Options) {
+	if logs := c.output.ConsoleLogs(); logs != "" {
+		helpers.ParseProbeResults(&c.output, logs, subnetID)
+	}
+	c.output.SetIncomplete(fmt.Sprintf("the probe did not finish within the console timeout of %s", opts.ConsoleTimeoutOrDefault()))
+}
+
 // terminateEC2Instance terminates target ec2 instance
 // uses c.output to store result of the execution
 func (c *Client) terminateEC2Instance(ctx context.Context, instanceID string) error {
 	c.logger.Info(ctx, "Terminating ec2 instance with id %s", instanceID)
+	// Terminate even when the run's context is done, e.g. --run-timeout passed
+	ctx, cancel := helpers.TeardownContext()
+	defer cancel()
 	input := ec2.TerminateInstancesInput{
 		InstanceIds: []string{instanceID},
 	}
@@ -405,28 +584,371 @@ func (c *Client) setCloudImage(cloudImageID string) (string, error) {
 	return cloudImageID, nil
 }
 
+// describeSubnet returns the subnet with the given ID, or nil if it does not exist in the client's region
+func (c *Client) describeSubnet(ctx context.Context, ec2Client EC2Client, subnetID string) (*ec2Types.Subnet, error) {
+	resp, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: []string{subnetID},
+	})
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "InvalidSubnetID.NotFound" {
+			return nil, nil
+		}
+		return nil, handledErrors.NewGenericError(err)
+	}
+
+	if len(resp.Subnets) == 0 {
+		return nil, nil
+	}
+
+	return &resp.Subnets[0], nil
+}
+
+// discoverSubnet looks up the subnet in the configured region and, if it isn't found there, in every other
+// region enabled for the account. When found elsewhere, the client is reconfigured to use the subnet's region, unless
+// requireRegion is set, in which case the region it's in is reported instead.
+func (c *Client) discoverSubnet(ctx context.Context, subnetID string, requireRegion bool) (*ec2Types.Subnet, error) {
+	c.WriteDebugLogs(ctx, fmt.Sprintf("Describing subnet %s in region %s", subnetID, c.region))
+	subnet, err := c.describeSubnet(ctx, c.ec2Client, subnetID)
+	if err != nil || subnet != nil {
+		return subnet, err
+	}
+
+	if c.regionalEC2Client == nil {
+		return nil, handledErrors.NewSubnetError(fmt.Sprintf("subnet %s not found in region %s, check its ID and region", subnetID, c.region))
+	}
+
+	c.logger.Info(ctx, "Subnet %s not found in region %s, searching other regions", subnetID, c.region)
+	regions, err := c.ec2Client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+
+	for _, r := range regions.Regions {
+		region := aws.ToString(r.RegionName)
+		if region == c.region {
+			continue
+		}
+
+		regionalClient := c.regionalEC2Client(region)
+		subnet, err := c.describeSubnet(ctx, regionalClient, subnetID)
+		if err != nil {
+			c.WriteDebugLogs(ctx, fmt.Sprintf("Unable to describe subnet %s in region %s: %s", subnetID, region, err))
+			continue
+		}
+		if subnet != nil && requireRegion {
+			return nil, handledErrors.NewSubnetError(fmt.Sprintf("subnet %s is in region %s, not the requested region %s", subnetID, region, c.region))
+		}
+		if subnet != nil {
+			c.logger.Info(ctx, "Found subnet %s in region %s, using it instead of %s", subnetID, region, c.region)
+			c.region = region
+			c.ec2Client = regionalClient
+			return subnet, nil
+		}
+	}
+
+	return nil, handledErrors.NewSubnetError(fmt.Sprintf("subnet %s not found in any region, check its ID and that the credentials are for its account", subnetID))
+}
+
+// describeSubnetZones maps each of the given subnets to its availability zone
+func (c *Client) describeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error) {
+	resp, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: subnetIDs,
+	})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+
+	zones := make(map[string]string, len(resp.Subnets))
+	for _, subnet := range resp.Subnets {
+		zones[aws.ToString(subnet.SubnetId)] = aws.ToString(subnet.AvailabilityZone)
+	}
+
+	return zones, nil
+}
+
+// describePrivateSubnets maps each private subnet of the VPC to its availability zone. Subnets whose route table
+// (explicitly associated, or else the VPC's main one) sends the default route to an internet gateway are public, the
+// rest egress through a NAT gateway, firewall, transit gateway or the like and are private.
+func (c *Client) describePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error) {
+	vpcFilter := []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}}
+	subnets, err := c.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: vpcFilter})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+	if len(subnets.Subnets) == 0 {
+		return nil, fmt.Errorf("no subnets found in VPC %s", vpcID)
+	}
+	routeTables, err := c.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{Filters: vpcFilter})
+	if err != nil {
+		return nil, handledErrors.NewGenericError(err)
+	}
+
+	var mainRouteTable *ec2Types.RouteTable
+	subnetRouteTables := map[string]*ec2Types.RouteTable{}
+	for i, routeTable := range routeTables.RouteTables {
+		for _, association := range routeTable.Associations {
+			if aws.ToBool(association.Main) {
+				mainRouteTable = &routeTables.RouteTables[i]
+			} else if association.SubnetId != nil {
+				subnetRouteTables[aws.ToString(association.SubnetId)] = &routeTables.RouteTables[i]
+			}
+		}
+	}
+
+	zones := map[string]string{}
+	for _, subnet := range subnets.Subnets {
+		routeTable, ok := subnetRouteTables[aws.ToString(subnet.SubnetId)]
+		if !ok {
+			routeTable = mainRouteTable
+		}
+		if routeTable != nil {
+			if internetGateway(routeTable) != "" {
+				c.WriteDebugLogs(ctx, fmt.Sprintf("Skipping public subnet %s", aws.ToString(subnet.SubnetId)))
+				continue
+			}
+		}
+		zones[aws.ToString(subnet.SubnetId)] = aws.ToString(subnet.AvailabilityZone)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no private subnets found in VPC %s, every subnet routes straight to an internet gateway", vpcID)
+	}
+
+	return zones, nil
+}
+
+// launchProbe creates the probe instance, waits for it to run and collects the probe's results. A spot instance
+// interrupted along the way is terminated and errSpotInterrupted returned, so the run can be retried on on-demand
+// capacity. Otherwise, the returned instance is left running for the caller to terminate.
+func (c *Client) launchProbe(ctx context.Context, input *createEC2InstanceInput, subnetID string, opts probe.Options) (string, error) {
+	instanceID, err := c.createEC2Instance(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	c.output.Metadata().InstanceID = instanceID
+	opts.Emit(events.Event{Type: events.TypeInstanceLaunched, Subnet: subnetID, InstanceID: instanceID})
+
+	if instanceReadyErr := c.waitForEC2InstanceCompletion(ctx, instanceID, opts.LaunchTimeoutOrDefault()); instanceReadyErr != nil {
+		interrupted := input.spot && c.spotInterrupted(ctx, instanceID)
+		// try to terminate the created instance
+		if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
+			c.output.AddError(err)
+		}
+		if interrupted {
+			return "", fmt.Errorf("%w while starting: %v", errSpotInterrupted, instanceReadyErr)
+		}
+		return "", instanceReadyErr
+	}
+	opts.Emit(events.Event{Type: events.TypeInstanceRunning, Subnet: subnetID, InstanceID: instanceID})
+
+	if err := c.findUnreachableEndpoints(ctx, instanceID, subnetID, opts); err != nil {
+		if input.spot && c.spotInterrupted(ctx, instanceID) {
+			if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
+				c.output.AddError(err)
+			}
+			return "", fmt.Errorf("%w before the probe finished: %v", errSpotInterrupted, err)
+		}
+		if errors.Is(err, helpers.ErrPollTimeout) {
+			c.recordPartialResults(subnetID, opts)
+		}
+		c.output.AddError(err)
+	}
+
+	return instanceID, nil
+}
+
 // validateEgress performs validation process for egress
 // Basic workflow is:
 // - prepare for ec2 instance creation
 // - create instance and wait till it gets ready, wait for userdata script execution
 // - find unreachable endpoints & parse output, then terminate instance
 // - return `c.output` which stores the execution results
-func (c *Client) validateEgress(ctx context.Context, subnetId, amiId, kmsKeyId, securityGroupId string, timeout time.Duration, p proxy.ProxyConfig) *output.Output {
+func (c *Client) validateEgress(ctx context.Context, subnetId, amiId, kmsKeyId, securityGroupId string, timeout time.Duration, p proxy.ProxyConfig, opts probe.Options) *output.Output {
+	c.output.SetConsoleSanitizer(opts.ConsoleSanitizer)
+	opts.Emit(events.Event{Type: events.TypeStarted, Subnet: subnetId})
+	defer func() { helpers.EmitFinished(opts, subnetId, &c.output) }()
+	metadata := c.output.Metadata()
+	metadata.Provider = ClientIdentifier
+	metadata.Subnet = subnetId
+	metadata.InstanceType = c.instanceType
+	metadata.ValidatorImage = opts.ValidatorImageOrDefault(defaultNetworkValidatorImage)
+	metadata.VerifierVersion = version.String()
+	metadata.EgressListVersion = endpoints.CatalogVersion
+	metadata.StartTime = time.Now()
+	defer func() { metadata.EndTime = time.Now() }()
+
+	ocpVersion, err := endpoints.OCPVersion(opts.OCPVersion)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	metadata.OCPVersion = ocpVersion
+	c.output.SetCategories(opts.LogForwarding.Categories())
+	if err := c.applyResourcePolicy(opts.ResourcePolicy); err != nil {
+		return c.output.AddError(err) // fatal
+	}
+
 	c.WriteDebugLogs(ctx, fmt.Sprintf("Using configured timeout of %s for each egress request", timeout.String()))
+
+	// Pre-flight: a bad CA bundle fails every TLS probe, which would be reported as the network's fault
+	caFailures, caWarnings := p.VerifyCACert(time.Now())
+	for _, warning := range caWarnings {
+		c.output.AddWarning(warning)
+	}
+	if len(caFailures) > 0 {
+		for _, failure := range caFailures {
+			c.output.AddFailure(failure)
+		}
+		c.logger.Error(ctx, "The CA bundle is unusable, not launching the probe instance")
+		return &c.output
+	}
+
+	// Discover the subnet's AZ, VPC and region, this must happen before anything region-specific is computed
+	subnet, err := c.discoverSubnet(ctx, subnetId, opts.RequireSubnetRegion)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	c.logger.Info(ctx, "Using subnet %s in VPC %s, availability zone %s", subnetId, aws.ToString(subnet.VpcId), aws.ToString(subnet.AvailabilityZone))
+	metadata.Region = c.region
+	metadata.Zone = aws.ToString(subnet.AvailabilityZone)
+
+	// Pre-flight: the subnet must be the one meant, and have room for the probe instance
+	if !c.verifySubnet(subnet, opts) {
+		c.logger.Error(ctx, "Subnet %s is unsuitable, not launching the probe instance", subnetId)
+		return &c.output
+	}
+
+	// Pre-flight: clusters can't install into a VPC without DNS support and hostnames, and the probe can't tell
+	if ok, err := c.verifyDns(ctx, aws.ToString(subnet.VpcId)); err != nil {
+		c.logger.Error(ctx, "Unable to check the DNS attributes of VPC %s, not launching the probe instance: %v", aws.ToString(subnet.VpcId), err)
+		return &c.output
+	} else if !ok {
+		c.logger.Error(ctx, "VPC %s does not meet the DNS requirements, not launching the probe instance", aws.ToString(subnet.VpcId))
+		return &c.output
+	}
+	c.recordVpcCidr(ctx, aws.ToString(subnet.VpcId))
+
+	// Pre-flight checks of the subnet's routing, these pinpoint problems the probe can only observe as timeouts,
+	// and S3 gateway endpoint problems which the probe doesn't exercise at all
+	routeTable, err := c.subnetRouteTable(ctx, aws.ToString(subnet.VpcId), subnetId)
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up the route table of subnet %s, skipping the routing pre-flight checks: %v", subnetId, err))
+	} else {
+		if opts.RequirePrivateSubnet && !c.verifySubnetPrivate(subnet, routeTable) {
+			c.logger.Error(ctx, "Subnet %s is public, not launching the probe instance", subnetId)
+			return &c.output
+		}
+		c.verifyEgressRoute(ctx, subnet, routeTable, p)
+		if opts.VerifiesIPv6(subnetHasIPv6(subnet)) {
+			c.verifyIPv6Route(subnet, routeTable)
+		}
+		c.verifyS3GatewayEndpoint(ctx, aws.ToString(subnet.VpcId), subnetId, aws.ToString(routeTable.RouteTableId))
+	}
+
+	// Pre-flight: security groups are allow-only, so egress the probe's group doesn't allow never leaves the instance
+	var probeGroups []string
+	if securityGroupId != "" {
+		probeGroups = []string{securityGroupId}
+	}
+	if ok, err := c.verifySecurityGroupEgress(ctx, aws.ToString(subnet.VpcId), probeGroups, p); err != nil {
+		c.output.AddError(handledErrors.NewGenericError(err))
+	} else if !ok {
+		c.logger.Error(ctx, "The probe's security group blocks egress, not launching the probe instance")
+		return &c.output
+	}
+	if len(opts.WorkerSecurityGroups) > 0 {
+		if _, err := c.verifySecurityGroupEgress(ctx, aws.ToString(subnet.VpcId), opts.WorkerSecurityGroups, p); err != nil {
+			c.output.AddError(handledErrors.NewGenericError(err))
+		}
+	}
+	if opts.Preset == endpoints.PresetROSAHCP {
+		c.verifyHCPVpcEndpoints(ctx, aws.ToString(subnet.VpcId))
+	}
+
+	// Pre-flight: an unusable KMS key only surfaces as a cryptic launch error, or an instance terminated right away
+	if kmsKeyId != "" && !c.verifyKMSKey(ctx, kmsKeyId) {
+		c.logger.Error(ctx, "KMS key %s is unusable, not launching the probe instance", kmsKeyId)
+		return &c.output
+	}
+
+	// Subnets with IPv6 addresses are verified over IPv6 as well, unless pinned, the probe instance given an IPv6
+	// address
+	ipv6 := opts.VerifiesIPv6(subnetHasIPv6(subnet))
+	metadata.IPVersions = opts.IPVersions(subnetHasIPv6(subnet))
+	metadata.IPVersionPinned = opts.IPVersion != ""
+
+	// Select a default instance type now the region is known, as instance type offerings differ by region
+	if c.instanceType == "" {
+		if opts.Architecture() != probe.ArchitectureX86_64 && amiId == "" {
+			return c.output.AddError(fmt.Errorf("the default AMIs are %s only, please specify an %s AMI with `--image-id`", probe.ArchitectureX86_64, opts.Architecture())) // fatal
+		}
+		instanceType, err := c.selectDefaultInstanceType(ctx, opts.Architecture())
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		c.instanceType = instanceType
+		metadata.InstanceType = instanceType
+	}
+
 	// Generate the userData file
 	// As expand replaces all ${var} (using empty srting for unknown ones), adding the env variables used in userdata.yaml
+	// Identifies the results of this run when they're reported through a shared channel
+	c.runID = newRunID()
+	c.batchID = opts.BatchID
+	c.markers = helpers.RunMarkers(opts, c.runID)
+	c.expiresAt = helpers.ResourceExpiry(opts, time.Now())
+	if opts.ResultChannel != "" && opts.ResultChannel != probe.ResultChannelConsole {
+		metadata.RunID = c.runID
+	}
+	if opts.VerifyCleanup {
+		// Deferred to run after the probe instance is terminated, whichever way the run ends
+		defer c.verifyCleanup(ctx)
+	}
+	var callbackURL, callbackToken, callbackCA string
+	if opts.ResultReceiver != nil {
+		callbackURL, callbackToken = opts.ResultReceiver.URL(), opts.ResultReceiver.Token()
+		// The certificate is passed base64-encoded, on a single line of the script
+		callbackCA = base64.StdEncoding.EncodeToString([]byte(opts.ResultReceiver.CACertificate()))
+	}
+
 	userDataVariables := map[string]string{
 		"AWS_REGION":               c.region,
-		"USERDATA_BEGIN":           "USERDATA BEGIN",
-		"USERDATA_END":             userdataEndVerifier,
+		"USERDATA_BEGIN":           c.markers.Begin,
+		"USERDATA_END":             c.markers.End,
+		"USERDATA_DONE":            c.markers.Done,
 		"VALIDATOR_START_VERIFIER": "VALIDATOR START",
 		"VALIDATOR_END_VERIFIER":   "VALIDATOR END",
-		"VALIDATOR_IMAGE":          networkValidatorImage,
+		"RESULTS_SCHEMA_VERSIONS":  helpers.SupportedSchemaVersions,
+		"VALIDATOR_IMAGE":          metadata.ValidatorImage,
 		"TIMEOUT":                  timeout.String(),
+		"ENDPOINT_TIMEOUTS":        strings.Join(endpoints.Timeouts(timeout), " "),
+		"RETRY_FAILED":             strconv.FormatBool(opts.RetryFailedEndpoints),
+		"RETRY_DELAY_SECONDS":      strconv.Itoa(int(helpers.RetryDelay.Seconds())),
+		"RETRY_TIMEOUT_SECONDS":    strconv.Itoa(int(math.Ceil(timeout.Seconds()))),
 		"HTTP_PROXY":               p.HttpProxy,
 		"HTTPS_PROXY":              p.HttpsProxy,
 		"CACERT":                   base64.StdEncoding.EncodeToString([]byte(p.Cacert)),
 		"NOTLS":                    strconv.FormatBool(p.NoTls),
+		"TRACEROUTE_MAX_ENDPOINTS": strconv.Itoa(helpers.TracerouteMaxEndpoints),
+		"TLS_DIAG_MAX_ENDPOINTS":   strconv.Itoa(helpers.TLSDiagnosticsMaxEndpoints),
+		"PCAP":                     strconv.FormatBool(opts.CapturePackets),
+		"PCAP_MAX_ENDPOINTS":       strconv.Itoa(helpers.PcapMaxEndpoints),
+		"PCAP_MAX_PACKETS":         strconv.Itoa(helpers.PcapMaxPackets),
+		"DNS_SERVERS":              strings.Join(opts.DNSServers, " "),
+		"NAMESERVERS":              strings.Join(opts.Nameservers, " "),
+		"DNS_DOMAINS":              strings.Join(endpoints.Hostnames(), " "),
+		"EXTRA_ENDPOINTS":          strings.Join(append(endpoints.Extra(opts.Preset, ocpVersion, c.region), opts.LogForwarding.Endpoints()...), " "),
+		"EXTRA_TIMEOUT_SECONDS":    strconv.Itoa(int(math.Ceil(timeout.Seconds()))),
+		"RESULT_CHANNEL":           opts.ResultChannel,
+		"RESULT_LOG_GROUP":         opts.ResultLogGroupOrDefault(),
+		"RUN_ID":                   c.runID,
+		"IP_VERSIONS":              strings.Join(metadata.IPVersions, " "),
+		"SOAK_SECONDS":             strconv.Itoa(int(opts.SoakDuration.Seconds())),
+		"SOAK_INTERVAL_SECONDS":    strconv.Itoa(int(opts.SoakIntervalOrDefault().Seconds())),
+		"ATTEMPTS":                 strconv.Itoa(opts.Attempts),
+		"CALLBACK_URL":             callbackURL,
+		"CALLBACK_TOKEN":           callbackToken,
+		"CALLBACK_CA":              callbackCA,
+		"POOL_MINUTES":             poolMinutes(opts.PoolWindow),
 		"IMAGE":                    "$IMAGE",
 		"VALIDATOR_REFERENCE":      "$VALIDATOR_REFERENCE",
 	}
@@ -434,42 +956,71 @@ func (c *Client) validateEgress(ctx context.Context, subnetId, amiId, kmsKeyId,
 	if err != nil {
 		return c.output.AddError(err)
 	}
-	c.WriteDebugLogs(ctx, fmt.Sprintf("base64-encoded generated userdata script:\n---\n%s\n---", userData))
+	// The userdata logged is generated again with its secrets masked, as they'd be hidden in the base64 encoding
+	redactedUserData, _ := generateUserData(redact.Variables(userDataVariables))
+	c.WriteDebugLogs(ctx, fmt.Sprintf("base64-encoded generated userdata script:\n---\n%s\n---", redactedUserData))
 
 	amiId, err = c.setCloudImage(amiId)
 	if err != nil {
 		return c.output.AddError(err) // fatal
 	}
 	c.logger.Debug(ctx, "Using AMI: %s", amiId)
+	metadata.Image = amiId
 
-	instanceID, err := c.createEC2Instance(ctx, &createEC2InstanceInput{
+	launchInput := &createEC2InstanceInput{
 		amiId:           amiId,
 		subnetId:        subnetId,
 		securityGroupId: securityGroupId,
 		userdata:        userData,
 		kmsKeyId:        kmsKeyId,
 		instanceCount:   instanceCount,
-	})
-	if err != nil {
-		return c.output.AddError(err) // fatal
+		spot:            opts.Spot,
+		instanceProfile: opts.InstanceProfile,
+		ipv6:            ipv6,
+	}
+	metadata.CapacityType = output.CapacityOnDemand
+	if opts.Spot {
+		metadata.CapacityType = output.CapacitySpot
 	}
 
-	if instanceReadyErr := c.waitForEC2InstanceCompletion(ctx, instanceID); instanceReadyErr != nil {
-		// try to terminate the created instance
-		if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
-			c.output.AddError(err)
-		}
-		return c.output.AddError(instanceReadyErr) // fatal
+	// Re-run the probe on an instance kept from an earlier verification rather than launch one, when pooling
+	var instanceID string
+	if opts.PoolWindow > 0 {
+		instanceID = c.reusePooledInstance(ctx, launchInput, userData, subnetId, opts)
+		metadata.Pooled = true
 	}
 
-	if err := c.findUnreachableEndpoints(ctx, instanceID); err != nil {
-		c.output.AddError(err)
+	if instanceID == "" {
+		// A safety brake for runaway automation, launching instances faster than they're cleaned up
+		if err := c.verifyInstanceCap(ctx, opts.MaxInstancesOrDefault()); err != nil {
+			return c.output.AddError(err) // fatal
+		}
+
+		instanceID, err = c.launchProbe(ctx, launchInput, subnetId, opts)
+		if launchInput.spot && (isSpotCapacityError(err) || errors.Is(err, errSpotInterrupted)) {
+			c.fallBackToOnDemand(ctx, err.Error())
+			launchInput.spot = false
+			instanceID, err = c.launchProbe(ctx, launchInput, subnetId, opts)
+		}
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
 	}
 
-	if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
+	c.classifyEgressPath(ctx, routeTable, p)
+	helpers.EmitEndpointResults(opts, subnetId, &c.output)
+
+	if opts.PoolWindow > 0 {
+		c.logger.Info(ctx, "Keeping probe instance %s in the pool for later verifications of subnet %s", instanceID, subnetId)
+		c.releasePooledInstance(ctx, instanceID)
+	} else if err := c.terminateEC2Instance(ctx, instanceID); err != nil {
 		c.output.AddError(err)
+	} else {
+		opts.Emit(events.Event{Type: events.TypeInstanceTerminated, Subnet: subnetId, InstanceID: instanceID})
 	}
 
+	remediation.Apply(&c.output, p)
+
 	return &c.output
 }
 
@@ -477,7 +1028,10 @@ func (c *Client) validateEgress(ctx context.Context, subnetId, amiId, kmsKeyId,
 // Basic workflow is:
 // - ask AWS API for VPC attributes
 // - ensure they're set correctly
-func (c *Client) verifyDns(ctx context.Context, vpcID string) *output.Output {
+//
+// It returns whether both attributes are enabled, regardless of what the output holds from other checks, and the
+// error of the AWS API if they couldn't be looked up.
+func (c *Client) verifyDns(ctx context.Context, vpcID string) (bool, error) {
 	c.logger.Info(ctx, "Verifying DNS config for VPC %s", vpcID)
 	// Request boolean values from AWS API
 	dnsSprtResult, err := c.ec2Client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{
@@ -489,7 +1043,7 @@ func (c *Client) verifyDns(ctx context.Context, vpcID string) *output.Output {
 		c.output.AddException(handledErrors.NewGenericError(
 			fmt.Errorf("failed to validate the %s attribute on VPC: %s is true", ec2Types.VpcAttributeNameEnableDnsSupport, vpcID)),
 		)
-		return &c.output
+		return false, err
 	}
 
 	dnsHostResult, err := c.ec2Client.DescribeVpcAttribute(ctx, &ec2.DescribeVpcAttributeInput{
@@ -501,23 +1055,41 @@ func (c *Client) verifyDns(ctx context.Context, vpcID string) *output.Output {
 		c.output.AddException(handledErrors.NewGenericError(
 			fmt.Errorf("failed to validate the %s attribute on VPC: %s is true", ec2Types.VpcAttributeNameEnableDnsHostnames, vpcID),
 		))
-		return &c.output
+		return false, err
 	}
 
 	// Verify results
 	c.logger.Info(ctx, "DNS Support for VPC %s: %t", vpcID, *dnsSprtResult.EnableDnsSupport.Value)
 	c.logger.Info(ctx, "DNS Hostnames for VPC %s: %t", vpcID, *dnsHostResult.EnableDnsHostnames.Value)
+	ok := true
 	if !(*dnsSprtResult.EnableDnsSupport.Value) {
 		c.output.AddException(handledErrors.NewGenericError(
 			fmt.Errorf("the %s attribute on VPC: %s is %t, must be true", ec2Types.VpcAttributeNameEnableDnsSupport, vpcID, *dnsSprtResult.EnableDnsSupport.Value),
 		))
+		ok = false
 	}
 
 	if !(*dnsHostResult.EnableDnsHostnames.Value) {
 		c.output.AddException(handledErrors.NewGenericError(
 			fmt.Errorf("the %s attribute on VPC: %s is %t, must be true", ec2Types.VpcAttributeNameEnableDnsHostnames, vpcID, *dnsHostResult.EnableDnsHostnames.Value),
 		))
+		ok = false
 	}
 
-	return &c.output
+	return ok, nil
+}
+
+// recordVpcCidr records the VPC's primary CIDR, whose base + 2 address is the Amazon provided resolver, so that
+// nameservers set by the DHCP options can be told apart from it
+func (c *Client) recordVpcCidr(ctx context.Context, vpcID string) {
+	out, err := c.ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if err == nil && len(out.Vpcs) == 0 {
+		err = fmt.Errorf("VPC %s not found", vpcID)
+	}
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up the CIDR of VPC %s, custom nameservers can't be told apart from the Amazon provided resolver: %v", vpcID, err))
+		return
+	}
+
+	c.output.SetVPCCIDR(aws.ToString(out.Vpcs[0].CidrBlock))
 }
@@ -0,0 +1,70 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+// profileCredentials are the credentials of the shared config profiles loaded, shared by every client of the run, so
+// an MFA-protected profile prompts for its token once, rather than once per client, e.g. per subnet verified
+var profileCredentials = struct {
+	sync.Mutex
+	byProfile map[string]aws.CredentialsProvider
+}{byProfile: map[string]aws.CredentialsProvider{}}
+
+// mfaTokenProvider prompts for the MFA token of a profile assuming a role with an mfa_serial, replaced in tests
+var mfaTokenProvider = func(profile string) func() (string, error) {
+	return func() (string, error) {
+		// Prompted on stderr, stdout carrying the results
+		fmt.Fprintf(os.Stderr, "MFA token code for AWS profile %s: ", profile)
+		var token string
+		if _, err := fmt.Scanln(&token); err != nil {
+			return "", fmt.Errorf("unable to read the MFA token code: %w", err)
+		}
+		return token, nil
+	}
+}
+
+// loadProfileConfig loads the shared config profile like the AWS CLI does, honoring profiles signing in through SSO
+// and profiles assuming a role with MFA, whose token is prompted for. The profile's credentials are resolved right
+// away, so a profile needing an SSO login fails the client's creation with a hint rather than its first call.
+func loadProfileConfig(ctx context.Context, profile, region string) (aws.Config, error) {
+	profileCredentials.Lock()
+	defer profileCredentials.Unlock()
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithSharedConfigProfile(profile),
+		config.WithRegion(region),
+	}
+	creds, loaded := profileCredentials.byProfile[profile]
+	if loaded {
+		opts = append(opts, config.WithCredentialsProvider(creds))
+	} else {
+		opts = append(opts, config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = mfaTokenProvider(profile)
+		}))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil || loaded {
+		return cfg, err
+	}
+
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		var ssoErr *ssocreds.InvalidTokenError
+		if errors.As(err, &ssoErr) {
+			return aws.Config{}, fmt.Errorf("the SSO session of AWS profile %s has expired or was never started, sign in with `aws sso login --profile %s`: %w", profile, profile, err)
+		}
+		return aws.Config{}, fmt.Errorf("unable to get the credentials of AWS profile %s: %w", profile, err)
+	}
+	profileCredentials.byProfile[profile] = cfg.Credentials
+
+	return cfg, nil
+}
@@ -0,0 +1,190 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// newSessionV1 builds an aws-sdk-go session for the services the verifier reaches through it rather than
+// aws-sdk-go-v2, e.g. CloudWatch Logs. It shares the credentials already resolved for aws-sdk-go-v2, so profiles
+// needing an SSO login or an MFA token, and assumed roles, are resolved once.
+func newSessionV1(region, profile string, creds aws.CredentialsProvider) (*session.Session, error) {
+	return session.NewSessionWithOptions(session.Options{
+		Config: awsv1.Config{
+			Region:      awsv1.String(region),
+			Credentials: awscredsv1.NewCredentials(&credentialsV1{ctx: context.Background(), creds: creds}),
+		},
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+// credentialsV1 provides aws-sdk-go-v2 credentials to aws-sdk-go
+type credentialsV1 struct {
+	ctx     context.Context
+	creds   aws.CredentialsProvider
+	expires time.Time
+	canExp  bool
+}
+
+func (c *credentialsV1) Retrieve() (awscredsv1.Value, error) {
+	value, err := c.creds.Retrieve(c.ctx)
+	if err != nil {
+		return awscredsv1.Value{}, err
+	}
+	c.expires, c.canExp = value.Expires, value.CanExpire
+
+	return awscredsv1.Value{
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+		ProviderName:    value.Source,
+	}, nil
+}
+
+func (c *credentialsV1) IsExpired() bool {
+	return c.canExp && !time.Now().Before(c.expires)
+}
+
+// newRegionalLogsClient builds CloudWatch Logs clients from the session, in the region of the probe's subnet
+func newRegionalLogsClient(sess *session.Session) func(region string) CloudWatchLogsClient {
+	return func(region string) CloudWatchLogsClient {
+		return cloudwatchlogs.New(sess, awsv1.NewConfig().WithRegion(region))
+	}
+}
+
+// probeOutput returns the probe's base64-encoded output so far from the requested result channel. When the channel
+// can't be queried, or the console shows the probe finished without its results reaching the channel, the console
+// output is used instead for the rest of the run.
+func (c *Client) probeOutput(ctx context.Context, instanceID string, opts probe.Options) (string, error) {
+	if c.resultsViaConsole {
+		return c.consoleOutput(ctx, instanceID)
+	}
+
+	var (
+		reported string
+		channel  string
+		err      error
+	)
+	switch opts.ResultChannel {
+	case probe.ResultChannelCloudWatch:
+		channel = fmt.Sprintf("CloudWatch Logs group %s", opts.ResultLogGroupOrDefault())
+		reported, err = c.cloudWatchOutput(ctx, opts.ResultLogGroupOrDefault())
+	case probe.ResultChannelCallback:
+		channel = "the results callback"
+		if opts.ResultReceiver == nil {
+			err = errors.New("no results callback configured")
+		} else if output, ok := opts.ResultReceiver.Result(c.runID); ok {
+			reported = base64.StdEncoding.EncodeToString([]byte(output))
+		}
+	default:
+		return c.consoleOutput(ctx, instanceID)
+	}
+	if err != nil {
+		c.logger.Debug(ctx, "Unable to query %s for the results: %v", channel, err)
+		c.output.AddWarning(fmt.Sprintf("the probe's results could not be read from %s, using the console output instead: %v", channel, err))
+		c.resultsViaConsole = true
+		return c.consoleOutput(ctx, instanceID)
+	}
+	if reported != "" {
+		return reported, nil
+	}
+
+	// Nothing reported yet, which is also what a probe unable to reach the channel looks like
+	consoleOutput, err := c.consoleOutput(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	if consoleLogs, err := base64.StdEncoding.DecodeString(consoleOutput); err == nil && helpers.CheckConsoleCompletion(string(consoleLogs), c.markers).Done {
+		c.output.AddWarning(fmt.Sprintf("the probe's results did not reach %s, using the console output instead", channel))
+		c.resultsViaConsole = true
+		return consoleOutput, nil
+	}
+
+	return "", nil
+}
+
+// consoleBuffer is the console output of an instance pieced together from the reads so far
+type consoleBuffer struct {
+	instanceID string
+	logs       string
+	// gap is set once the reads missed part of the output
+	gap bool
+}
+
+// consoleOutput returns the probe instance's base64-encoded console output so far, or the output of the command
+// re-running the probe on a pooled instance
+func (c *Client) consoleOutput(ctx context.Context, instanceID string) (string, error) {
+	if c.pooledCommandID != "" {
+		return c.commandOutput(ctx, instanceID)
+	}
+
+	consoleOutput, err := c.ec2Client.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+		Latest:     aws.Bool(true),
+	})
+	if err != nil {
+		return "", handledErrors.NewGenericError(err)
+	}
+
+	// Only the latest 64KB are returned, so the reads are pieced together, chatty images otherwise pushing the start
+	// of the probe's output out before it's done
+	latest, err := base64.StdEncoding.DecodeString(aws.ToString(consoleOutput.Output))
+	if err != nil || len(latest) == 0 {
+		return aws.ToString(consoleOutput.Output), nil
+	}
+	if c.console.instanceID != instanceID {
+		c.console = consoleBuffer{instanceID: instanceID}
+	}
+	merged, gap := helpers.MergeConsoleOutput(c.console.logs, string(latest))
+	if gap && !c.console.gap {
+		c.logger.Debug(ctx, "The console output of %s outgrew its buffer between two reads, part of it is missing", instanceID)
+		c.output.AddWarning("the probe instance's console output outgrew the console buffer between two reads, part of the probe's output may be missing")
+	}
+	c.console.logs, c.console.gap = merged, c.console.gap || gap
+
+	return base64.StdEncoding.EncodeToString([]byte(merged)), nil
+}
+
+// cloudWatchOutput returns the base64-encoded results the probe wrote to CloudWatch Logs for this run, or an empty
+// string if it hasn't written them yet
+func (c *Client) cloudWatchOutput(ctx context.Context, logGroup string) (string, error) {
+	if c.regionalLogsClient == nil {
+		return "", errors.New("no CloudWatch Logs client configured")
+	}
+
+	resp, err := c.regionalLogsClient(c.region).GetLogEventsWithContext(ctx, &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  awsv1.String(logGroup),
+		LogStreamName: awsv1.String(c.runID),
+		StartFromHead: awsv1.Bool(false),
+		Limit:         awsv1.Int64(1),
+	})
+	if err != nil {
+		// The probe creates the run's log stream once it has its results
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == cloudwatchlogs.ErrCodeResourceNotFoundException {
+			c.logger.Debug(ctx, "No results in CloudWatch Logs for run %s yet", c.runID)
+			return "", nil
+		}
+		return "", err
+	}
+	if len(resp.Events) == 0 {
+		return "", nil
+	}
+
+	return awsv1.StringValue(resp.Events[0].Message), nil
+}
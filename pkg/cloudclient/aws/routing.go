@@ -0,0 +1,188 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+const defaultRouteCidr = "0.0.0.0/0"
+
+// routePath records the hops followed from the subnet towards the internet, so a failure can name the hop
+// where routing stops
+type routePath []string
+
+func (p routePath) failure(reason string) error {
+	return handledErrors.NewRoutingError(fmt.Sprintf("the path to the internet stops at %s: %s (path: %s)", p[len(p)-1], reason, strings.Join(p, " -> ")))
+}
+
+// subnetRouteTable returns the route table explicitly associated with the subnet, or the VPC's main route table
+func (c *Client) subnetRouteTable(ctx context.Context, vpcID, subnetID string) (*ec2Types.RouteTable, error) {
+	for _, filter := range []ec2Types.Filter{
+		{Name: aws.String("association.subnet-id"), Values: []string{subnetID}},
+		{Name: aws.String("association.main"), Values: []string{"true"}},
+	} {
+		out, err := c.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}, filter},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(out.RouteTables) > 0 {
+			return &out.RouteTables[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find the route table of subnet %s", subnetID)
+}
+
+// defaultRoute returns the route table's route for 0.0.0.0/0, or nil if there isn't one
+func defaultRoute(routeTable *ec2Types.RouteTable) *ec2Types.Route {
+	for i, route := range routeTable.Routes {
+		if aws.ToString(route.DestinationCidrBlock) == defaultRouteCidr {
+			return &routeTable.Routes[i]
+		}
+	}
+
+	return nil
+}
+
+// internetGateway returns the internet gateway the route table's default route goes to, which makes its subnets
+// public, or an empty string if there's none
+func internetGateway(routeTable *ec2Types.RouteTable) string {
+	if route := defaultRoute(routeTable); route != nil && strings.HasPrefix(aws.ToString(route.GatewayId), "igw-") {
+		return aws.ToString(route.GatewayId)
+	}
+
+	return ""
+}
+
+// isInternetRoute reports whether the route sends traffic out of the VPC towards the internet directly, through
+// an internet gateway, a NAT gateway, or an appliance (e.g. a firewall endpoint or NAT instance)
+func isInternetRoute(route *ec2Types.Route) bool {
+	return strings.HasPrefix(aws.ToString(route.GatewayId), "igw-") ||
+		strings.HasPrefix(aws.ToString(route.GatewayId), "vpce-") ||
+		route.NatGatewayId != nil ||
+		route.NetworkInterfaceId != nil ||
+		route.InstanceId != nil
+}
+
+// verifyEgressRoute follows the default route of the subnet's route table through a transit gateway or a VPC
+// peering connection, if that's where it leads, reporting the hop where the path to the internet stops. Other
+// default routes are left to the probe, as is the path when the API calls following it fail, e.g. without the
+// permissions to describe the transit gateway, which is warned about rather than failing the verification.
+func (c *Client) verifyEgressRoute(ctx context.Context, subnet *ec2Types.Subnet, routeTable *ec2Types.RouteTable, p proxy.ProxyConfig) {
+	path := routePath{aws.ToString(subnet.SubnetId), aws.ToString(routeTable.RouteTableId)}
+	route := defaultRoute(routeTable)
+	switch {
+	case route == nil:
+		c.logger.Debug(ctx, "Route table %s has no default route", aws.ToString(routeTable.RouteTableId))
+	case route.State == ec2Types.RouteStateBlackhole:
+		c.output.AddFailure(path.failure("the default route is a blackhole"))
+	case route.TransitGatewayId != nil:
+		if err := c.verifyTransitGatewayRoute(ctx, aws.ToString(subnet.VpcId), aws.ToString(route.TransitGatewayId), path); err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to follow the default route through transit gateway %s, leaving it to the probe: %v", aws.ToString(route.TransitGatewayId), err))
+		}
+	case route.VpcPeeringConnectionId != nil:
+		if err := c.verifyPeeringRoute(ctx, subnet, aws.ToString(route.VpcPeeringConnectionId), p, path); err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to follow the default route through peering connection %s, leaving it to the probe: %v", aws.ToString(route.VpcPeeringConnectionId), err))
+		}
+	}
+}
+
+// verifyTransitGatewayRoute walks the VPC's transit gateway attachment, the transit gateway route table
+// associated with it and the attachment its default route leads to
+func (c *Client) verifyTransitGatewayRoute(ctx context.Context, vpcID, transitGatewayID string, path routePath) error {
+	path = append(path, transitGatewayID)
+
+	// Attachments being deleted, or deleted ones still listed, don't carry traffic
+	attachments, err := c.ec2Client.DescribeTransitGatewayAttachments(ctx, &ec2.DescribeTransitGatewayAttachmentsInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("transit-gateway-id"), Values: []string{transitGatewayID}},
+			{Name: aws.String("resource-id"), Values: []string{vpcID}},
+			{Name: aws.String("state"), Values: []string{string(ec2Types.TransitGatewayAttachmentStateAvailable)}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(attachments.TransitGatewayAttachments) == 0 {
+		c.output.AddFailure(path.failure(fmt.Sprintf("VPC %s has no available attachment to the transit gateway", vpcID)))
+		return nil
+	}
+
+	attachment := attachments.TransitGatewayAttachments[0]
+	path = append(path, aws.ToString(attachment.TransitGatewayAttachmentId))
+	if attachment.Association == nil || attachment.Association.TransitGatewayRouteTableId == nil {
+		c.output.AddFailure(path.failure("the attachment is not associated with a transit gateway route table"))
+		return nil
+	}
+
+	tgwRouteTableID := aws.ToString(attachment.Association.TransitGatewayRouteTableId)
+	path = append(path, tgwRouteTableID)
+	routes, err := c.ec2Client.SearchTransitGatewayRoutes(ctx, &ec2.SearchTransitGatewayRoutesInput{
+		TransitGatewayRouteTableId: aws.String(tgwRouteTableID),
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("route-search.exact-match"), Values: []string{defaultRouteCidr}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(routes.Routes) == 0 {
+		c.output.AddFailure(path.failure("there is no default route"))
+		return nil
+	}
+
+	route := routes.Routes[0]
+	if route.State == ec2Types.TransitGatewayRouteStateBlackhole || len(route.TransitGatewayAttachments) == 0 {
+		c.output.AddFailure(path.failure("the default route is a blackhole"))
+		return nil
+	}
+
+	egress := route.TransitGatewayAttachments[0]
+	path = append(path, aws.ToString(egress.TransitGatewayAttachmentId))
+	if egress.ResourceType != ec2Types.TransitGatewayAttachmentResourceTypeVpc {
+		c.logger.Info(ctx, "Egress leaves the transit gateway through %s attachment %s, which is not verified further (path: %s)",
+			egress.ResourceType, aws.ToString(egress.TransitGatewayAttachmentId), strings.Join(path, " -> "))
+		return nil
+	}
+
+	return c.verifyEgressVpc(ctx, aws.ToString(egress.TransitGatewayAttachmentId), path)
+}
+
+// verifyEgressVpc checks that the subnets of the egress VPC's transit gateway attachment route to the internet
+func (c *Client) verifyEgressVpc(ctx context.Context, attachmentID string, path routePath) error {
+	vpcAttachments, err := c.ec2Client.DescribeTransitGatewayVpcAttachments(ctx, &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		TransitGatewayAttachmentIds: []string{attachmentID},
+	})
+	if err != nil {
+		return err
+	}
+	if len(vpcAttachments.TransitGatewayVpcAttachments) == 0 {
+		return fmt.Errorf("unable to find transit gateway VPC attachment %s", attachmentID)
+	}
+
+	egressVpc := vpcAttachments.TransitGatewayVpcAttachments[0]
+	path = append(path, aws.ToString(egressVpc.VpcId))
+	for _, subnetID := range egressVpc.SubnetIds {
+		routeTable, err := c.subnetRouteTable(ctx, aws.ToString(egressVpc.VpcId), subnetID)
+		if err != nil {
+			return err
+		}
+		if route := defaultRoute(routeTable); route != nil && route.State != ec2Types.RouteStateBlackhole && isInternetRoute(route) {
+			c.logger.Info(ctx, "Found a path to the internet through the transit gateway (path: %s)",
+				strings.Join(append(path, subnetID, aws.ToString(routeTable.RouteTableId)), " -> "))
+			return nil
+		}
+	}
+
+	c.output.AddFailure(path.failure("none of the attachment's subnets has a default route to an internet or NAT gateway"))
+	return nil
+}
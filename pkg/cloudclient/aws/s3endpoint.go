@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+)
+
+// requiredS3Buckets are the buckets cluster nodes pull from over S3 during install and upgrades, e.g. the
+// storage backing quay.io image layers
+var requiredS3Buckets = []string{
+	"quayio-production-s3",
+	"quay-registry-s3",
+}
+
+// verifyS3GatewayEndpoint checks that, when the VPC has an S3 gateway endpoint, the subnet's route table is
+// associated with it and the endpoint policy allows the buckets OpenShift needs. A VPC without an S3 gateway
+// endpoint reaches S3 like any other egress traffic and is not a failure.
+func (c *Client) verifyS3GatewayEndpoint(ctx context.Context, vpcID, subnetID, routeTableID string) {
+	endpointsOut, err := c.ec2Client.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2Types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("service-name"), Values: []string{fmt.Sprintf("com.amazonaws.%s.s3", c.region)}},
+			{Name: aws.String("vpc-endpoint-type"), Values: []string{string(ec2Types.VpcEndpointTypeGateway)}},
+		},
+	})
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up the S3 gateway endpoints of VPC %s, skipping their checks: %v", vpcID, err))
+		return
+	}
+	if len(endpointsOut.VpcEndpoints) == 0 {
+		c.logger.Debug(ctx, "No S3 gateway endpoint in VPC %s", vpcID)
+		return
+	}
+
+	for _, endpoint := range endpointsOut.VpcEndpoints {
+		if !helpers.Contains(endpoint.RouteTableIds, routeTableID) {
+			continue
+		}
+
+		endpointID := aws.ToString(endpoint.VpcEndpointId)
+		c.logger.Info(ctx, "Subnet %s routes S3 traffic through gateway endpoint %s", subnetID, endpointID)
+		if endpoint.PolicyDocument == nil {
+			// The default policy allows full access
+			return
+		}
+
+		policy, err := parsePolicyDocument(aws.ToString(endpoint.PolicyDocument))
+		if err != nil {
+			c.output.AddFailure(handledErrors.NewS3EndpointError(fmt.Sprintf("%s: %s", endpointID, err)))
+			return
+		}
+		for _, bucket := range requiredS3Buckets {
+			if !policy.allows("s3:GetObject", fmt.Sprintf("arn:aws:s3:::%s/*", bucket)) {
+				c.output.AddFailure(handledErrors.NewS3EndpointError(
+					fmt.Sprintf("the policy of %s does not allow s3:GetObject on bucket %s", endpointID, bucket),
+				))
+			}
+		}
+		return
+	}
+
+	c.logger.Info(ctx, "VPC %s has an S3 gateway endpoint, but route table %s of subnet %s is not associated with it", vpcID, routeTableID, subnetID)
+}
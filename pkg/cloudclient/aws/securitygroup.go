@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+// egressDestination is a destination the security groups must allow TCP traffic to
+type egressDestination struct {
+	cidr string
+	port int32
+}
+
+func (d egressDestination) String() string {
+	return fmt.Sprintf("TCP port %d to %s", d.port, d.cidr)
+}
+
+// verifySecurityGroupEgress checks the egress rules of the security groups, e.g. the probe's, allow HTTPS and HTTP to
+// the internet, or to the proxy if one is configured, recording a failure for each destination they don't allow.
+// The groups are evaluated together, as the rules of all security groups of a network interface apply. Without any
+// group, the VPC's default security group is checked, as that's what the probe instance gets. It returns false if
+// traffic to a destination is blocked.
+func (c *Client) verifySecurityGroupEgress(ctx context.Context, vpcID string, groupIDs []string, p proxy.ProxyConfig) (bool, error) {
+	input := &ec2.DescribeSecurityGroupsInput{GroupIds: groupIDs}
+	if len(groupIDs) == 0 {
+		input = &ec2.DescribeSecurityGroupsInput{
+			Filters: []ec2Types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+				{Name: aws.String("group-name"), Values: []string{"default"}},
+			},
+		}
+	}
+	groups, err := c.ec2Client.DescribeSecurityGroups(ctx, input)
+	if err != nil {
+		return false, err
+	}
+	if len(groups.SecurityGroups) == 0 {
+		return false, fmt.Errorf("unable to find the security groups %v of VPC %s", groupIDs, vpcID)
+	}
+
+	var names []string
+	var permissions []ec2Types.IpPermission
+	for _, group := range groups.SecurityGroups {
+		names = append(names, aws.ToString(group.GroupId))
+		permissions = append(permissions, group.IpPermissionsEgress...)
+	}
+
+	ok := true
+	for _, destination := range c.egressDestinations(ctx, p) {
+		if !securityGroupsAllow(permissions, destination.cidr, destination.port) {
+			c.output.AddFailure(handledErrors.NewSecurityGroupError(fmt.Sprintf("security groups %s have no egress rule allowing %s, the traffic is dropped before it leaves the instance",
+				strings.Join(names, ", "), destination)))
+			ok = false
+		}
+	}
+
+	return ok, nil
+}
+
+// egressDestinations returns where egress traffic is sent: the proxy if one is configured, or else the internet on
+// the HTTPS and HTTP ports. A proxy whose hostname can't be resolved here is skipped.
+func (c *Client) egressDestinations(ctx context.Context, p proxy.ProxyConfig) []egressDestination {
+	if !p.Configured() {
+		return []egressDestination{{cidr: defaultRouteCidr, port: 443}, {cidr: defaultRouteCidr, port: 80}}
+	}
+
+	addresses, unresolved := p.Addresses(ctx)
+	for _, host := range unresolved {
+		c.logger.Debug(ctx, "Unable to resolve proxy %s, not checking the security groups allow it", host)
+	}
+	destinations := make([]egressDestination, 0, len(addresses))
+	for _, address := range addresses {
+		destinations = append(destinations, egressDestination{cidr: address.IP + "/32", port: address.Port})
+	}
+
+	return destinations
+}
+
+// securityGroupsAllow evaluates security group egress rules like AWS does, allowing TCP traffic to port and cidr if
+// any rule does. Rules referencing prefix lists or other security groups can't be evaluated here and are ignored.
+func securityGroupsAllow(permissions []ec2Types.IpPermission, cidr string, port int32) bool {
+	for _, permission := range permissions {
+		if protocol := aws.ToString(permission.IpProtocol); protocol != "-1" && protocol != "tcp" && protocol != "6" {
+			continue
+		}
+		// All ports are -1, or unset, for rules of all protocols
+		if from, to := aws.ToInt32(permission.FromPort), aws.ToInt32(permission.ToPort); aws.ToString(permission.IpProtocol) != "-1" &&
+			from != -1 && (port < from || port > to) {
+			continue
+		}
+		for _, ipRange := range permission.IpRanges {
+			if cidrContains(aws.ToString(ipRange.CidrIp), cidr) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
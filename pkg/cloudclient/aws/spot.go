@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// spotCapacityErrors identify RunInstances failures caused by a lack of spot capacity, which on-demand capacity
+// usually doesn't suffer from
+var spotCapacityErrors = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientCapacity",
+	"SpotMaxPriceTooLow",
+	"MaxSpotInstanceCountExceeded",
+	"capacity-not-available",
+}
+
+// errSpotInterrupted is returned when the spot probe instance was reclaimed before the probe finished
+var errSpotInterrupted = errors.New("the spot probe instance was interrupted")
+
+// isSpotCapacityError reports whether err is a lack of spot capacity rather than a problem with the request
+func isSpotCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, code := range spotCapacityErrors {
+		if strings.Contains(err.Error(), code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// spotMarketOptions requests a one-time spot instance, which is terminated when interrupted
+func spotMarketOptions() *ec2Types.InstanceMarketOptionsRequest {
+	return &ec2Types.InstanceMarketOptionsRequest{
+		MarketType: ec2Types.MarketTypeSpot,
+		SpotOptions: &ec2Types.SpotMarketOptions{
+			SpotInstanceType:             ec2Types.SpotInstanceTypeOneTime,
+			InstanceInterruptionBehavior: ec2Types.InstanceInterruptionBehaviorTerminate,
+		},
+	}
+}
+
+// spotInterrupted reports whether the probe instance was terminated without being asked to, i.e. reclaimed by EC2
+func (c *Client) spotInterrupted(ctx context.Context, instanceID string) bool {
+	result, err := c.ec2Client.DescribeInstanceStatus(ctx, &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         []string{instanceID},
+		IncludeAllInstances: aws.Bool(true),
+	})
+	if err != nil || len(result.InstanceStatuses) != 1 {
+		c.WriteDebugLogs(ctx, fmt.Sprintf("Unable to determine whether spot instance %s was interrupted: %v", instanceID, err))
+		return false
+	}
+
+	switch result.InstanceStatuses[0].InstanceState.Name {
+	case ec2Types.InstanceStateNameShuttingDown, ec2Types.InstanceStateNameTerminated:
+		return true
+	default:
+		return false
+	}
+}
+
+// fallBackToOnDemand records why the run switched from spot to on-demand capacity
+func (c *Client) fallBackToOnDemand(ctx context.Context, reason string) {
+	c.logger.Info(ctx, "Retrying the verification on on-demand capacity: %s", reason)
+	metadata := c.output.Metadata()
+	metadata.CapacityType = output.CapacityOnDemand
+	metadata.CapacityFallback = reason
+}
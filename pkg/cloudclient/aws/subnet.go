@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// reservedSubnetAddresses are the addresses AWS reserves in every subnet: the network address, the VPC router, the
+// DNS server, one for future use and the broadcast address
+const reservedSubnetAddresses = 5
+
+// lowFreeSubnetAddresses is how few free addresses a subnet has left before it's reported as nearly exhausted, as
+// the cluster's nodes need addresses of their own
+const lowFreeSubnetAddresses = 16
+
+// verifySubnet checks the subnet is in the expected VPC, if any, and is large enough for the probe instance with an
+// address free for it, recording a failure for each problem found. It returns false if the probe instance can't be
+// launched into it.
+func (c *Client) verifySubnet(subnet *ec2Types.Subnet, opts probe.Options) bool {
+	subnetID := aws.ToString(subnet.SubnetId)
+	ok := true
+	if opts.SubnetVPC != "" && aws.ToString(subnet.VpcId) != opts.SubnetVPC {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnet %s is in VPC %s, not the expected VPC %s", subnetID, aws.ToString(subnet.VpcId), opts.SubnetVPC)))
+		ok = false
+	}
+
+	// IPv6-only subnets have no IPv4 CIDR block to size up
+	if _, cidr, err := net.ParseCIDR(aws.ToString(subnet.CidrBlock)); err == nil {
+		if ones, bits := cidr.Mask.Size(); 1<<(bits-ones) <= reservedSubnetAddresses {
+			c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnet %s's CIDR block %s is too small for an instance, as AWS reserves %d addresses of every subnet", subnetID, cidr, reservedSubnetAddresses)))
+			ok = false
+		}
+	}
+
+	if opts.VerifiesIPv6(false) && !subnetHasIPv6(subnet) {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnet %s has no IPv6 CIDR block, but egress over IPv6 was requested", subnetID)))
+		ok = false
+	}
+
+	// Launching into a full subnet fails with a confusing InsufficientFreeAddressesInSubnet error
+	if free := aws.ToInt32(subnet.AvailableIpAddressCount); subnet.AvailableIpAddressCount != nil && free == 0 {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnet %s has no free IP addresses left for the probe instance", subnetID)))
+		ok = false
+	} else if free > 0 && free < lowFreeSubnetAddresses {
+		c.output.AddWarning(fmt.Sprintf("Subnet %s is nearly out of IP addresses, only %d are free, which may not leave enough for the cluster's nodes", subnetID, free))
+	}
+
+	return ok
+}
+
+// verifySubnetPrivate records a failure if the subnet is public, as its route table sends the default route to an
+// internet gateway, returning false in that case
+func (c *Client) verifySubnetPrivate(subnet *ec2Types.Subnet, routeTable *ec2Types.RouteTable) bool {
+	if igw := internetGateway(routeTable); igw != "" {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnet %s is public, its route table %s routes %s to internet gateway %s, but a private subnet was requested", aws.ToString(subnet.SubnetId), aws.ToString(routeTable.RouteTableId), defaultRouteCidr, igw)))
+		return false
+	}
+
+	return true
+}
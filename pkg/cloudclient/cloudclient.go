@@ -1,3 +1,5 @@
+// Package cloudclient selects the cloud client verifying a cloud account's network. The CloudClient interface and its
+// methods' long positional parameters are internal and may change; pkg/verifier wraps them in a stable API.
 package cloudclient
 
 import (
@@ -7,10 +9,11 @@ import (
 
 	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
 	awscredsv1 "github.com/aws/aws-sdk-go/aws/credentials"
-	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
 	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
 	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
 	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	proxy "github.com/openshift/osd-network-verifier/pkg/proxy"
 
 	"golang.org/x/oauth2/google"
@@ -25,20 +28,39 @@ type CloudClient interface {
 	// ValidateEgress validates that all required targets are reachable from the vpcsubnet
 	// target URLs: https://docs.openshift.com/rosa/rosa_getting_started/rosa-aws-prereqs.html#osd-aws-privatelink-firewall-prerequisites
 	// Expected return value is *output.Output that's storing failures, exceptions and errors
-	ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig) *output.Output
+	ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig, opts probe.Options) *output.Output
 
 	// VerifyDns verifies that a given VPC meets the DNS requirements specified in:
 	// https://docs.openshift.com/container-platform/4.10/installing/installing_aws/installing-aws-vpc.html
 	// Expected return value is *output.Output that's storing failures, exceptions and errors
 	VerifyDns(ctx context.Context, vpcID string) *output.Output
+
+	// DescribeSubnetZones returns the availability zone of each of the given subnets
+	// Regional subnets, which span every zone in their region, map to an empty string
+	DescribeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error)
+
+	// DescribePrivateSubnets returns the availability zone of each private subnet of the VPC, i.e. those whose
+	// default route doesn't lead straight to an internet gateway
+	DescribePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error)
+}
+
+// ImageBuilder is implemented by the clients able to bake a cloud image for the probe instance, so verifications in
+// bandwidth-constrained or registry-restricted environments don't depend on pulling the validator image at boot
+type ImageBuilder interface {
+	// BuildImage launches a build instance from baseImage, or the default image, into vpcSubnetID, pulls the validator
+	// image of opts on it and bakes an image named name from it, returning the image's ID, to pass as the cloud image
+	// of verifications. The build instance is deleted whichever way the build ends.
+	BuildImage(ctx context.Context, vpcSubnetID, baseImage, name string, opts probe.Options) (string, error)
 }
 
-func NewClient(ctx context.Context, logger ocmlog.Logger, creds interface{}, region, instanceType string, tags map[string]string) (CloudClient, error) {
+func NewClient(ctx context.Context, logger logging.Logger, creds interface{}, region, instanceType string, tags map[string]string) (CloudClient, error) {
 	switch c := creds.(type) {
-	case awscredsv1.Credentials, awscredsv2.StaticCredentialsProvider, string:
+	case awscredsv1.Credentials, awscredsv2.StaticCredentialsProvider, awsCloudClient.AssumeRoleCredentials, string:
 		return awsCloudClient.NewClient(ctx, logger, c, region, instanceType, tags)
 	case *google.Credentials:
 		return gcpCloudClient.NewClient(ctx, logger, c, region, instanceType, tags)
+	case gcpCloudClient.ProjectCredentials:
+		return gcpCloudClient.NewCrossProjectClient(ctx, logger, c, region, instanceType, tags)
 	default:
 		return nil, fmt.Errorf("unsupported credentials type %T", c)
 	}
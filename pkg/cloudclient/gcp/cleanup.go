@@ -0,0 +1,88 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"google.golang.org/api/googleapi"
+)
+
+// computeInstance identifies a probe instance, and its boot disk, which is named after it
+type computeInstance struct {
+	name string
+	zone string
+}
+
+// newRunID returns the ID of a new run, unique enough to tell apart the runs of a project
+var newRunID = func() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// cleanupPollInterval is how often the run's resources are looked up after the teardown, until they're gone
+var cleanupPollInterval = 10 * time.Second
+
+// verifyCleanup waits for the run's probe instances and their boot disks to be gone after the teardown, recording
+// whether they are in the run metadata. Those still there after the teardown timeout are reported as an error, so
+// leaks don't go unnoticed.
+func (c *Client) verifyCleanup(ctx context.Context) {
+	c.logger.Info(ctx, "Verifying the resources of run %s were cleaned up", c.runID)
+	// Verify even when the run's context is done, as the teardown does
+	ctx, cancel := helpers.TeardownContext()
+	defer cancel()
+
+	var remaining []string
+	err := helpers.PollImmediateWithContext(ctx, cleanupPollInterval, helpers.TeardownTimeout, func() (bool, error) {
+		var err error
+		remaining, err = c.runResources(ctx)
+		return len(remaining) == 0, err
+	})
+
+	verified := err == nil
+	c.output.Metadata().CleanupVerified = &verified
+	switch {
+	case len(remaining) > 0:
+		c.output.AddError(fmt.Errorf("resources of run %s survived the teardown, delete them manually: %s", c.runID, strings.Join(remaining, ", ")))
+	case err != nil:
+		c.output.AddError(fmt.Errorf("unable to verify the resources of run %s were cleaned up: %w", c.runID, err))
+	}
+}
+
+// runResources returns the run's probe instances and boot disks that still exist, described as e.g.
+// "instance verifier-1 in us-east1-b"
+func (c *Client) runResources(ctx context.Context) ([]string, error) {
+	var resources []string
+	for _, i := range c.instances {
+		_, err := c.computeService.Instances.Get(c.projectID, i.zone, i.name).Context(ctx).Do()
+		if exists, err := resourceExists(err); err != nil {
+			return nil, err
+		} else if exists {
+			resources = append(resources, fmt.Sprintf("instance %s in %s", i.name, i.zone))
+		}
+
+		_, err = c.computeService.Disks.Get(c.projectID, i.zone, i.name).Context(ctx).Do()
+		if exists, err := resourceExists(err); err != nil {
+			return nil, err
+		} else if exists {
+			resources = append(resources, fmt.Sprintf("disk %s in %s", i.name, i.zone))
+		}
+	}
+
+	return resources, nil
+}
+
+// resourceExists tells from the error of looking a resource up whether it exists, returning any error other than
+// it not being found
+func resourceExists(err error) (bool, error) {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
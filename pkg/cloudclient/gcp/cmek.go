@@ -0,0 +1,169 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	cloudkmsv1 "google.golang.org/api/cloudkms/v1"
+	cloudresourcemanagerv1 "google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// cmekRole is the role the Compute Engine service agent needs on a key to encrypt and decrypt disks with it
+	cmekRole = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
+	// cmekEncrypterRole and cmekDecrypterRole grant the same as cmekRole when bound together
+	cmekEncrypterRole = "roles/cloudkms.cryptoKeyEncrypter"
+	cmekDecrypterRole = "roles/cloudkms.cryptoKeyDecrypter"
+)
+
+// cryptoKeyNameRe matches a Cloud KMS key's resource name, e.g.
+// projects/my-project/locations/us-east1/keyRings/my-ring/cryptoKeys/my-key
+var cryptoKeyNameRe = regexp.MustCompile(`^projects/([^/]+)/locations/([^/]+)/keyRings/([^/]+)/cryptoKeys/([^/]+)$`)
+
+// verifyKMSKey checks, before the launch, that the Cloud KMS key given to encrypt the probe instance's boot disk can
+// be: it must be an enabled encryption key in a location compatible with the instance's region, and the project's
+// Compute Engine service agent must have the cryptoKeyEncrypterDecrypter role on it, whether bound on the key, its
+// key ring or its project. Failures, including the exact binding missing, are recorded and false returned. Checks
+// whose lookups fail otherwise, e.g. without the cloudkms.cryptoKeys.getIamPolicy permission, are skipped with a
+// warning.
+func (c *Client) verifyKMSKey(ctx context.Context, keyName string) bool {
+	m := cryptoKeyNameRe.FindStringSubmatch(keyName)
+	if m == nil {
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("%s is not a key's resource name, e.g. projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY", keyName)))
+		return false
+	}
+	keyProject, location, keyRing, key := m[1], m[2], m[3], m[4]
+	if !keyLocationCompatible(location, c.region) {
+		c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is in location %s, disks in region %s can only be encrypted with keys in %s, a multi-region containing it or global", keyName, location, c.region, c.region)))
+		return false
+	}
+
+	if c.kmsService == nil {
+		kmsService, err := cloudkmsv1.NewService(ctx, c.clientOptions...)
+		if err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to check KMS key %s before launching: %v", keyName, err))
+			return true
+		}
+		c.kmsService = kmsService
+	}
+
+	cryptoKey, err := c.kmsService.Projects.Locations.KeyRings.CryptoKeys.Get(keyName).Context(ctx).Do()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s not found", keyName)))
+			return false
+		}
+		c.output.AddWarning(fmt.Sprintf("Unable to look up KMS key %s to check it before launching: %v", keyName, err))
+	} else {
+		if cryptoKey.Purpose != "ENCRYPT_DECRYPT" {
+			c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s is for %s, disks can only be encrypted with ENCRYPT_DECRYPT keys", keyName, cryptoKey.Purpose)))
+			return false
+		}
+		if cryptoKey.Primary == nil {
+			c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("key %s has no primary version, it must have an ENABLED one", keyName)))
+			return false
+		}
+		if cryptoKey.Primary.State != "ENABLED" {
+			c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf("the primary version of key %s is %s, it must be ENABLED", keyName, cryptoKey.Primary.State)))
+			return false
+		}
+	}
+
+	project, err := c.computeService.Projects.Get(c.projectID).Context(ctx).Do()
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up project %s to check the Compute Engine service agent's access to KMS key %s: %v", c.projectID, keyName, err))
+		return true
+	}
+	// The project's id in Compute Engine is its number
+	member := fmt.Sprintf("serviceAccount:service-%d@compute-system.iam.gserviceaccount.com", project.Id)
+
+	// The role is inherited from the key ring and the project, the key's IAM policy only holding its own bindings
+	keyRingName := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s", keyProject, location, keyRing)
+	lookups := []struct {
+		resource  string
+		getPolicy func() ([]*cloudkmsv1.Binding, error)
+	}{
+		{keyName, func() ([]*cloudkmsv1.Binding, error) {
+			policy, err := c.kmsService.Projects.Locations.KeyRings.CryptoKeys.GetIamPolicy(keyName).Context(ctx).Do()
+			if err != nil {
+				return nil, err
+			}
+			return policy.Bindings, nil
+		}},
+		{keyRingName, func() ([]*cloudkmsv1.Binding, error) {
+			policy, err := c.kmsService.Projects.Locations.KeyRings.GetIamPolicy(keyRingName).Context(ctx).Do()
+			if err != nil {
+				return nil, err
+			}
+			return policy.Bindings, nil
+		}},
+		{"projects/" + keyProject, func() ([]*cloudkmsv1.Binding, error) {
+			return c.projectBindings(ctx, keyProject)
+		}},
+	}
+	for _, lookup := range lookups {
+		bindings, err := lookup.getPolicy()
+		if err != nil {
+			c.output.AddWarning(fmt.Sprintf("Unable to read the IAM policy of %s to check the Compute Engine service agent's access to KMS key %s: %v", lookup.resource, keyName, err))
+			return true
+		}
+		if grantsCMEK(bindings, member) {
+			c.logger.Debug(ctx, "%s has %s on %s", member, cmekRole, lookup.resource)
+			return true
+		}
+	}
+
+	c.output.AddFailure(handledErrors.NewKMSKeyError(fmt.Sprintf(
+		"the Compute Engine service agent lacks %s on key %s, grant it with `gcloud kms keys add-iam-policy-binding %s --keyring %s --location %s --project %s --member %s --role %s`",
+		cmekRole, keyName, key, keyRing, location, keyProject, member, cmekRole)))
+	return false
+}
+
+// projectBindings returns the bindings of the project's IAM policy, as Cloud KMS bindings
+func (c *Client) projectBindings(ctx context.Context, project string) ([]*cloudkmsv1.Binding, error) {
+	if c.resourceManagerService == nil {
+		resourceManagerService, err := cloudresourcemanagerv1.NewService(ctx, c.clientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		c.resourceManagerService = resourceManagerService
+	}
+
+	policy, err := c.resourceManagerService.Projects.GetIamPolicy(project, &cloudresourcemanagerv1.GetIamPolicyRequest{}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]*cloudkmsv1.Binding, 0, len(policy.Bindings))
+	for _, b := range policy.Bindings {
+		bindings = append(bindings, &cloudkmsv1.Binding{Role: b.Role, Members: b.Members})
+	}
+
+	return bindings, nil
+}
+
+// grantsCMEK returns whether the bindings grant the member encrypting and decrypting with a key
+func grantsCMEK(bindings []*cloudkmsv1.Binding, member string) bool {
+	roles := map[string]bool{}
+	for _, b := range bindings {
+		for _, m := range b.Members {
+			if m == member {
+				roles[b.Role] = true
+			}
+		}
+	}
+
+	return roles[cmekRole] || (roles[cmekEncrypterRole] && roles[cmekDecrypterRole])
+}
+
+// keyLocationCompatible returns whether disks in the region can be encrypted with a key in the location: the region
+// itself, a multi-region containing it, e.g. us for us-east1, or global
+func keyLocationCompatible(location, region string) bool {
+	return location == region || location == "global" || strings.HasPrefix(region, location+"-")
+}
@@ -0,0 +1,293 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	computev1 "google.golang.org/api/compute/v1"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+const (
+	// internetCidr stands in for the internet as the destination of egress traffic
+	internetCidr = "0.0.0.0/0"
+
+	firewallPolicyHierarchy = "HIERARCHY"
+	firewallActionAllow     = "allow"
+	firewallActionDeny      = "deny"
+	firewallActionGotoNext  = "goto_next"
+)
+
+// egressDestination is a destination the firewall must allow TCP traffic to
+type egressDestination struct {
+	cidr string
+	port int
+}
+
+func (d egressDestination) String() string {
+	return fmt.Sprintf("TCP port %d to %s", d.port, d.cidr)
+}
+
+// verifyFirewallEgress evaluates the firewall rules applying to the probe instance in the network like GCP does,
+// recording a failure naming the rule that denies egress to each destination: the internet on the HTTPS and HTTP
+// ports, or the proxy if one is configured. Hierarchical firewall policies are evaluated first, from the organization
+// down, then the VPC firewall rules and network firewall policies, before the implied rule allowing all egress. It
+// returns false if egress to a destination is denied. The checks are skipped with a warning when the effective
+// firewalls can't be looked up, e.g. without the compute.networks.getEffectiveFirewalls permission.
+func (c *Client) verifyFirewallEgress(ctx context.Context, network string, p proxy.ProxyConfig, opts probe.Options) bool {
+	m := networkSelfLinkRe.FindStringSubmatch(network)
+	if m == nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to parse network %s to check its firewall rules before launching", network))
+		return true
+	}
+	effective, err := c.computeService.Networks.GetEffectiveFirewalls(m[1], m[2]).Context(ctx).Do()
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to look up the firewall rules of network %s to check them before launching: %v", m[2], err))
+		return true
+	}
+
+	ok := true
+	for _, destination := range c.egressDestinations(ctx, p) {
+		if rule := deniedBy(effective, m[0], opts.NetworkTags, destination); rule != "" {
+			c.output.AddFailure(handledErrors.NewFirewallError(fmt.Sprintf("%s denies %s", rule, destination)))
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// egressDestinations returns where egress traffic is sent: the proxy if one is configured, or else the internet on
+// the HTTPS and HTTP ports. A proxy whose hostname can't be resolved here is skipped.
+func (c *Client) egressDestinations(ctx context.Context, p proxy.ProxyConfig) []egressDestination {
+	if !p.Configured() {
+		return []egressDestination{{cidr: internetCidr, port: 443}, {cidr: internetCidr, port: 80}}
+	}
+
+	addresses, unresolved := p.Addresses(ctx)
+	for _, host := range unresolved {
+		c.logger.Debug(ctx, "Unable to resolve proxy %s, not checking the firewall allows it", host)
+	}
+	destinations := make([]egressDestination, 0, len(addresses))
+	for _, address := range addresses {
+		destinations = append(destinations, egressDestination{cidr: address.IP + "/32", port: int(address.Port)})
+	}
+
+	return destinations
+}
+
+// deniedBy returns a description of the firewall rule denying egress to the destination from an instance with the
+// network tags, or "" if it's allowed. Rules targeting service accounts or secure tags don't apply to the probe
+// instance, which has neither.
+func deniedBy(effective *computev1.NetworksGetEffectiveFirewallsResponse, network string, tags []string, d egressDestination) string {
+	var hierarchical, networkPolicies []*computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy
+	for _, policy := range effective.FirewallPolicys {
+		if policy.Type == firewallPolicyHierarchy {
+			hierarchical = append(hierarchical, policy)
+		} else {
+			networkPolicies = append(networkPolicies, policy)
+		}
+	}
+
+	for _, policy := range hierarchical {
+		if action, rule := policyAction(policy, network, d); action != firewallActionGotoNext {
+			if action == firewallActionDeny {
+				return fmt.Sprintf("rule %s of hierarchical firewall policy %s", rule, policyName(policy))
+			}
+			return ""
+		}
+	}
+
+	if action, rule := vpcFirewallAction(effective.Firewalls, tags, d); action != firewallActionGotoNext {
+		if action == firewallActionDeny {
+			return fmt.Sprintf("VPC firewall rule %s", rule)
+		}
+		return ""
+	}
+
+	for _, policy := range networkPolicies {
+		if action, rule := policyAction(policy, network, d); action != firewallActionGotoNext {
+			if action == firewallActionDeny {
+				return fmt.Sprintf("rule %s of network firewall policy %s", rule, policyName(policy))
+			}
+			return ""
+		}
+	}
+
+	// The implied egress rule allows all egress
+	return ""
+}
+
+// policyAction returns the action of the highest priority rule of the firewall policy matching the destination, and
+// the rule's description, or goto_next if no rule matches
+func policyAction(policy *computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy, network string, d egressDestination) (string, string) {
+	rules := make([]*computev1.FirewallPolicyRule, len(policy.Rules))
+	copy(rules, policy.Rules)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+
+	for _, rule := range rules {
+		if rule.Disabled || rule.Direction != "EGRESS" || rule.Match == nil ||
+			len(rule.TargetServiceAccounts) > 0 || len(rule.TargetSecureTags) > 0 || !targetsNetwork(rule.TargetResources, network) {
+			continue
+		}
+		if !rangesContain(rule.Match.DestIpRanges, d.cidr) {
+			continue
+		}
+		matched := false
+		for _, l4 := range rule.Match.Layer4Configs {
+			matched = matched || protocolPortsMatch(l4.IpProtocol, l4.Ports, d.port)
+		}
+		if !matched {
+			continue
+		}
+
+		description := fmt.Sprintf("at priority %d", rule.Priority)
+		if rule.RuleName != "" {
+			description = fmt.Sprintf("%s (priority %d)", rule.RuleName, rule.Priority)
+		}
+		// deny() may carry a status, e.g. deny(403)
+		action := rule.Action
+		if strings.HasPrefix(action, firewallActionDeny) {
+			action = firewallActionDeny
+		}
+		return action, description
+	}
+
+	return firewallActionGotoNext, ""
+}
+
+// vpcFirewallAction returns the action of the highest priority VPC firewall rule matching the destination, denies
+// winning ties, and the rule's description, or goto_next if no rule matches
+func vpcFirewallAction(firewalls []*computev1.Firewall, tags []string, d egressDestination) (string, string) {
+	rules := make([]*computev1.Firewall, len(firewalls))
+	copy(rules, firewalls)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return len(rules[i].Denied) > 0 && len(rules[j].Denied) == 0
+	})
+
+	for _, rule := range rules {
+		if rule.Disabled || rule.Direction != "EGRESS" || len(rule.TargetServiceAccounts) > 0 || !targetsTags(rule.TargetTags, tags) {
+			continue
+		}
+		// Egress rules without destination ranges apply to all destinations
+		ranges := rule.DestinationRanges
+		if len(ranges) == 0 {
+			ranges = []string{internetCidr}
+		}
+		if !rangesContain(ranges, d.cidr) {
+			continue
+		}
+		description := fmt.Sprintf("%s (priority %d)", rule.Name, rule.Priority)
+		for _, denied := range rule.Denied {
+			if protocolPortsMatch(denied.IPProtocol, denied.Ports, d.port) {
+				return firewallActionDeny, description
+			}
+		}
+		for _, allowed := range rule.Allowed {
+			if protocolPortsMatch(allowed.IPProtocol, allowed.Ports, d.port) {
+				return firewallActionAllow, description
+			}
+		}
+	}
+
+	return firewallActionGotoNext, ""
+}
+
+// policyName returns the name a firewall policy is known by
+func policyName(policy *computev1.NetworksGetEffectiveFirewallsResponseEffectiveFirewallPolicy) string {
+	if policy.ShortName != "" {
+		return fmt.Sprintf("%s (%s)", policy.ShortName, policy.Name)
+	}
+
+	return policy.Name
+}
+
+// targetsNetwork returns whether a firewall policy rule's target resources, if any, include the network
+func targetsNetwork(targetResources []string, network string) bool {
+	if len(targetResources) == 0 {
+		return true
+	}
+	for _, target := range targetResources {
+		if strings.HasSuffix(target, network) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targetsTags returns whether a VPC firewall rule's target tags, if any, include one of the instance's network tags
+func targetsTags(targetTags, tags []string) bool {
+	if len(targetTags) == 0 {
+		return true
+	}
+	for _, target := range targetTags {
+		for _, tag := range tags {
+			if target == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rangesContain returns whether one of the ranges contains the whole cidr
+func rangesContain(ranges []string, cidr string) bool {
+	for _, r := range ranges {
+		if cidrContains(r, cidr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// protocolPortsMatch returns whether a firewall rule's protocol and ports match TCP traffic to port
+func protocolPortsMatch(protocol string, ports []string, port int) bool {
+	if protocol != "all" && protocol != "tcp" && protocol != "6" {
+		return false
+	}
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		from, to := p, p
+		if i := strings.Index(p, "-"); i >= 0 {
+			from, to = p[:i], p[i+1:]
+		}
+		low, errLow := strconv.Atoi(from)
+		high, errHigh := strconv.Atoi(to)
+		if errLow == nil && errHigh == nil && port >= low && port <= high {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cidrContains reports whether the outer CIDR block contains the whole inner CIDR block
+func cidrContains(outer, inner string) bool {
+	_, outerNet, err := net.ParseCIDR(outer)
+	if err != nil {
+		return false
+	}
+	innerIP, innerNet, err := net.ParseCIDR(inner)
+	if err != nil {
+		return false
+	}
+
+	outerOnes, _ := outerNet.Mask.Size()
+	innerOnes, _ := innerNet.Mask.Size()
+	return outerNet.Contains(innerIP) && outerOnes <= innerOnes
+}
@@ -1,29 +1,82 @@
+// Package gcp verifies the network of a GCP project from a probe Compute Engine instance. Its types, ProjectCredentials
+// included, may change in any release; pkg/verifier's GCPCredentials is the stable way to configure them.
 package gcp
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 	"time"
 
-	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
 	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	"github.com/openshift/osd-network-verifier/pkg/proxy"
 	"golang.org/x/oauth2/google"
+	cloudkmsv1 "google.golang.org/api/cloudkms/v1"
+	cloudresourcemanagerv1 "google.golang.org/api/cloudresourcemanager/v1"
 	computev1 "google.golang.org/api/compute/v1"
+	loggingv2 "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
 )
 
 // ClientIdentifier is what kind of cloud this implement supports
 const ClientIdentifier string = "GCP"
 
+// The labels of every resource the verifier creates, named as the AWS tags are, which are valid label keys too
+const (
+	runIDLabelKey     = "osd-network-verifier-run-id"
+	batchIDLabelKey   = "osd-network-verifier-batch-id"
+	createdByLabelKey = "osd-network-verifier-created-by"
+	// expiresAtLabelKey's value is in seconds since the epoch
+	expiresAtLabelKey = "osd-network-verifier-expires-at"
+)
+
+// subnetworkSelfLinkRe matches both full (https://www.googleapis.com/compute/v1/...) and
+// partial (projects/...) subnetwork self-links
+var subnetworkSelfLinkRe = regexp.MustCompile(`^(?:https://www\.googleapis\.com/compute/v1/)?projects/([^/]+)/regions/([^/]+)/subnetworks/([^/]+)$`)
+
 // Client represents a GCP Client
 type Client struct {
-	projectID      string
-	region         string
-	zone           string
-	instanceType   string
-	computeService *computev1.Service
-	tags           map[string]string
-	logger         ocmlog.Logger
-	output         output.Output
+	projectID string
+	// networkProjectID is the project of bare subnetwork names, e.g. a Shared VPC host project, projectID's if empty
+	networkProjectID string
+	region           string
+	zone             string
+	instanceType     string
+	computeService   *computev1.Service
+	// clientOptions are passed to every Google API client, e.g. to authenticate with impersonated credentials
+	clientOptions []option.ClientOption
+	// loggingService reads the probe's results when they're reported through Cloud Logging
+	loggingService *loggingv2.Service
+	// kmsService and resourceManagerService check the KMS key encrypting the probe's boot disk is usable
+	kmsService             *cloudkmsv1.Service
+	resourceManagerService *cloudresourcemanagerv1.Service
+	// runID identifies the run's results in channels shared between runs, e.g. Cloud Logging
+	runID string
+	// batchID identifies the verifications the run was launched together with, see probe.Options.BatchID
+	batchID string
+	// markers tell the run's output apart in the console, see helpers.RunMarkers
+	markers helpers.Markers
+	// createdBy is the service account the credentials belong to, if known
+	createdBy string
+	// expiresAt is when the resources of the run can be deemed leaked
+	expiresAt time.Time
+	// console is the serial console output read so far
+	console serialConsole
+	// instances are the probe instances created by the run
+	instances []computeInstance
+	// resultsViaConsole is set once the requested result channel turned out to be unusable
+	resultsViaConsole bool
+	tags              map[string]string
+	logger            logging.Logger
+	output            output.Output
+}
+
+// DefaultValidatorImage returns the validator container image reference the probe instance runs by default
+func DefaultValidatorImage() string {
+	return defaultNetworkValidatorImage
 }
 
 func (c *Client) ByoVPCValidator(ctx context.Context) error {
@@ -31,15 +84,66 @@ func (c *Client) ByoVPCValidator(ctx context.Context) error {
 	return nil
 }
 
-func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig) *output.Output {
-	return c.validateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, timeout, proxy)
+func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig, opts probe.Options) *output.Output {
+	return c.validateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, timeout, proxy, opts)
+}
+
+// BuildImage bakes an image with the validator image pre-pulled, see cloudclient.ImageBuilder
+func (c *Client) BuildImage(ctx context.Context, vpcSubnetID, baseImage, name string, opts probe.Options) (string, error) {
+	return c.buildImage(ctx, vpcSubnetID, baseImage, name, opts)
 }
 
 func (c *Client) VerifyDns(ctx context.Context, vpcID string) *output.Output {
 	return &c.output
 }
 
-func NewClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, region, instanceType string, tags map[string]string) (*Client, error) {
+// DescribeSubnetZones maps every subnet to an empty zone, as GCP subnetworks are regional
+func (c *Client) DescribeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error) {
+	zones := make(map[string]string, len(subnetIDs))
+	for _, subnetID := range subnetIDs {
+		zones[subnetID] = ""
+	}
+
+	return zones, nil
+}
+
+// DescribePrivateSubnets is not supported, as GCP subnetworks don't route to the internet on their own
+func (c *Client) DescribePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error) {
+	return nil, fmt.Errorf("verifying every subnet of a VPC is only supported on AWS")
+}
+
+func NewClient(ctx context.Context, logger logging.Logger, credentials *google.Credentials, region, instanceType string, tags map[string]string) (*Client, error) {
 	// initialize actual client
+	return newClient(ctx, logger, ProjectCredentials{Credentials: credentials}, region, instanceType, tags)
+}
+
+// NewCrossProjectClient creates a client creating the probe instance, charging quota and finding the subnetwork in
+// the projects of the credentials, see ProjectCredentials
+func NewCrossProjectClient(ctx context.Context, logger logging.Logger, credentials ProjectCredentials, region, instanceType string, tags map[string]string) (*Client, error) {
 	return newClient(ctx, logger, credentials, region, instanceType, tags)
 }
+
+// ParseSubnetworkSelfLink extracts the project, region and subnetwork name from a subnetwork self-link,
+// e.g. projects/my-project/regions/us-east1/subnetworks/my-subnet. ok is false if subnet is not a self-link.
+func ParseSubnetworkSelfLink(subnet string) (project, region, name string, ok bool) {
+	m := subnetworkSelfLinkRe.FindStringSubmatch(subnet)
+	if m == nil {
+		return "", "", "", false
+	}
+
+	return m[1], m[2], m[3], true
+}
+
+// RegionFromSubnet returns the region encoded in a subnetwork self-link. If the subnet is not a self-link,
+// fallback is returned. An error is returned if fallback is set and disagrees with the self-link's region.
+func RegionFromSubnet(subnet, fallback string) (string, error) {
+	_, region, _, ok := ParseSubnetworkSelfLink(subnet)
+	if !ok {
+		return fallback, nil
+	}
+	if fallback != "" && fallback != region {
+		return "", fmt.Errorf("region %s does not match region %s of subnetwork %s", fallback, region, subnet)
+	}
+
+	return region, nil
+}
@@ -3,12 +3,31 @@ package gcp
 //tests for ValidateEgress, NewClient have been skipped because it calls gcp api
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/imagecache"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	"github.com/openshift/osd-network-verifier/pkg/proxy"
 	"golang.org/x/oauth2/google"
+	cloudkmsv1 "google.golang.org/api/cloudkms/v1"
+	cloudresourcemanagerv1 "google.golang.org/api/cloudresourcemanager/v1"
+	computev1 "google.golang.org/api/compute/v1"
+	loggingv2 "google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
 )
 
 func TestByoVPCValidator(t *testing.T) {
@@ -28,7 +47,7 @@ func TestValidateEgress(t *testing.T) {
 	cloudImageID := "image-id"
 	cli := Client{}
 	timeout := 1 * time.Second
-	if !cli.ValidateEgress(ctx, subnetID, cloudImageID, "", "", timeout, proxy.ProxyConfig{}).IsSuccessful() {
+	if !cli.ValidateEgress(ctx, subnetID, cloudImageID, "", "", timeout, proxy.ProxyConfig{}, probe.Options{}).IsSuccessful() {
 		t.Errorf("validation should have been successful")
 	}
 }
@@ -55,3 +74,797 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("unexpected tags: %v", client.tags)
 	}
 }
+
+func TestRegionFromSubnet(t *testing.T) {
+	tests := []struct {
+		name      string
+		subnet    string
+		fallback  string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "bare subnet name uses fallback",
+			subnet:   "my-subnet",
+			fallback: "us-east1",
+			expected: "us-east1",
+		},
+		{
+			name:     "partial self-link",
+			subnet:   "projects/my-project/regions/europe-west4/subnetworks/my-subnet",
+			expected: "europe-west4",
+		},
+		{
+			name:     "full self-link",
+			subnet:   "https://www.googleapis.com/compute/v1/projects/my-project/regions/europe-west4/subnetworks/my-subnet",
+			fallback: "europe-west4",
+			expected: "europe-west4",
+		},
+		{
+			name:      "mismatched region",
+			subnet:    "projects/my-project/regions/europe-west4/subnetworks/my-subnet",
+			fallback:  "us-east1",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		region, err := RegionFromSubnet(test.subnet, test.fallback)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if region != test.expected {
+			t.Errorf("%s: expected region %s, got %s", test.name, test.expected, region)
+		}
+	}
+}
+
+func TestMarkPSCEndpoints(t *testing.T) {
+	results := markPSCEndpoints([]output.PSCResult{
+		{Domain: "storage.googleapis.com", Address: "10.3.0.5", Reachable: true},
+		{Domain: "compute.googleapis.com", Address: "142.250.1.95", Reachable: true},
+		{Domain: "iam.googleapis.com"},
+	}, []string{"10.3.0.5"})
+
+	if !results[0].Success() {
+		t.Errorf("expected %s to be reached through the endpoint", results[0].Domain)
+	}
+	if results[1].ViaEndpoint {
+		t.Errorf("expected %s not to be reached through the endpoint", results[1].Domain)
+	}
+	if results[2].Success() {
+		t.Errorf("expected unresolved %s to fail", results[2].Domain)
+	}
+}
+
+func TestZoneFallback(t *testing.T) {
+	if !isZoneCapacityError(errors.New("unable to create instance: ZONE_RESOURCE_POOL_EXHAUSTED: The zone 'projects/p/zones/us-east1-b' does not have enough resources available to fulfill the request")) {
+		t.Errorf("expected a stockout to be a zone capacity error")
+	}
+	if isZoneCapacityError(errors.New("unable to create instance: QUOTA_EXCEEDED")) {
+		t.Errorf("expected a quota error not to be a zone capacity error")
+	}
+
+	zones := fallbackZones("us-east1-b", []string{
+		"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-d",
+		"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b",
+		"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-c",
+	})
+	if len(zones) != 2 || zones[0] != "us-east1-c" || zones[1] != "us-east1-d" {
+		t.Errorf("unexpected fallback zones: %v", zones)
+	}
+}
+
+func TestProbeOutputCloudLoggingFallback(t *testing.T) {
+	ctx := context.TODO()
+	logging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":403,"message":"Permission 'logging.logEntries.list' denied"}}`, http.StatusForbidden)
+	}))
+	defer logging.Close()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"contents":"USERDATA END"}`)
+	}))
+	defer compute.Close()
+
+	loggingService, err := loggingv2.NewService(ctx, option.WithEndpoint(logging.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &ocmlog.StdLogger{}
+	c := &Client{projectID: "p", zone: "us-east1-b", loggingService: loggingService, computeService: computeService, runID: "r", logger: logger}
+
+	scriptOutput, err := c.probeOutput(ctx, "verifier-1", probe.Options{ResultChannel: probe.ResultChannelCloudLogging})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(scriptOutput, "USERDATA END") {
+		t.Errorf("expected the console output, got %s", scriptOutput)
+	}
+	if !c.resultsViaConsole {
+		t.Errorf("expected later polls to use the console")
+	}
+	if len(c.output.Warnings()) != 1 {
+		t.Errorf("expected a warning about the fallback, got %v", c.output.Warnings())
+	}
+}
+
+func TestVerifySubnetwork(t *testing.T) {
+	ctx := context.TODO()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/subnetworks/missing") {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"name":"nodes","network":"https://www.googleapis.com/compute/v1/projects/p/global/networks/other","ipCidrRange":"10.0.0.0/30"}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{projectID: "p", region: "us-east1", computeService: computeService, logger: &ocmlog.StdLogger{}}
+	if _, ok := c.verifySubnetwork(ctx, "missing", probe.Options{}); ok {
+		t.Errorf("expected a missing subnetwork to fail the pre-flight")
+	}
+	if _, ok := c.verifySubnetwork(ctx, "nodes", probe.Options{SubnetVPC: "vpc"}); ok {
+		t.Errorf("expected a subnetwork of another network to fail the pre-flight")
+	}
+	failures, _, _ := c.output.Parse()
+	if len(failures) != 3 {
+		t.Fatalf("expected the missing subnetwork, the network and the range to be reported, got %v", failures)
+	}
+	if expected := "subnet error: subnetwork nodes is in VPC network other, not the expected network vpc"; failures[1].Error() != expected {
+		t.Errorf("expected %q, got %q", expected, failures[1])
+	}
+}
+
+func TestVerifyFirewallEgress(t *testing.T) {
+	ctx := context.TODO()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/projects/host/global/networks/vpc/getEffectiveFirewalls") {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{
+			"firewalls": [
+				{"name":"allow-https","direction":"EGRESS","priority":900,"destinationRanges":["0.0.0.0/0"],"allowed":[{"IPProtocol":"tcp","ports":["443"]}]},
+				{"name":"deny-all-egress","direction":"EGRESS","priority":1000,"destinationRanges":["0.0.0.0/0"],"denied":[{"IPProtocol":"all"}]},
+				{"name":"allow-workers","direction":"EGRESS","priority":100,"destinationRanges":["0.0.0.0/0"],"targetTags":["worker"],"allowed":[{"IPProtocol":"all"}]}
+			],
+			"firewallPolicys": [{
+				"name":"123","shortName":"org-egress","type":"HIERARCHY",
+				"rules": [
+					{"priority":10,"direction":"EGRESS","action":"goto_next","match":{"destIpRanges":["0.0.0.0/0"],"layer4Configs":[{"ipProtocol":"tcp","ports":["443"]}]}},
+					{"priority":20,"direction":"EGRESS","action":"deny","match":{"destIpRanges":["10.1.0.0/16"],"layer4Configs":[{"ipProtocol":"tcp","ports":["3000-4000"]}]}}
+				]
+			}]
+		}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := "https://www.googleapis.com/compute/v1/projects/host/global/networks/vpc"
+
+	tests := []struct {
+		name            string
+		proxy           proxy.ProxyConfig
+		opts            probe.Options
+		expectedFailure string
+	}{
+		{
+			name:            "VPC firewall rule denying HTTP",
+			expectedFailure: "firewall error: VPC firewall rule deny-all-egress (priority 1000) denies TCP port 80 to 0.0.0.0/0",
+		},
+		{
+			name: "network tags of an allowed target",
+			opts: probe.Options{NetworkTags: []string{"worker"}},
+		},
+		{
+			name:            "hierarchical policy denying the proxy",
+			proxy:           proxy.ProxyConfig{HttpsProxy: "http://10.1.0.10:3128"},
+			expectedFailure: "firewall error: rule at priority 20 of hierarchical firewall policy org-egress (123) denies TCP port 3128 to 10.1.0.10/32",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{projectID: "p", computeService: computeService, logger: &ocmlog.StdLogger{}}
+			ok := c.verifyFirewallEgress(ctx, network, test.proxy, test.opts)
+
+			failures, _, _ := c.output.Parse()
+			if test.expectedFailure == "" {
+				if !ok || len(failures) != 0 {
+					t.Errorf("expected egress to be allowed, got %v", failures)
+				}
+				return
+			}
+			if ok || len(failures) != 1 {
+				t.Fatalf("expected one failure, got %v", failures)
+			}
+			if failures[0].Error() != test.expectedFailure {
+				t.Errorf("expected %q, got %q", test.expectedFailure, failures[0])
+			}
+		})
+	}
+}
+
+func TestCreateComputeServiceInstanceLabels(t *testing.T) {
+	ctx := context.TODO()
+	var inserted computev1.Instance
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/instances"):
+			if err := json.NewDecoder(r.Body).Decode(&inserted); err != nil {
+				t.Error(err)
+			}
+			fmt.Fprint(w, `{"name":"op-1"}`)
+		case strings.HasSuffix(r.URL.Path, "/operations/op-1/wait"):
+			fmt.Fprint(w, `{"name":"op-1","status":"DONE"}`)
+		default:
+			fmt.Fprint(w, `{"labelFingerprint":"fp"}`)
+		}
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := map[string]string{"osd-network-verifier": "owned"}
+	c := &Client{projectID: "p", zone: "us-east1-b", computeService: computeService, tags: tags, logger: &ocmlog.StdLogger{}}
+	if _, err := c.createComputeServiceInstance(ctx, createComputeServiceInstanceInput{instanceName: "verifier-1", machineType: "e2-micro"}); err != nil {
+		t.Fatal(err)
+	}
+	if labels := inserted.Disks[0].InitializeParams.Labels; labels["osd-network-verifier"] != "owned" {
+		t.Errorf("expected the boot disk to be labeled on creation, got %v", labels)
+	}
+	if key := inserted.Metadata.Items[0].Key; key != "user-data" {
+		t.Errorf("expected the userdata under user-data by default, got %s", key)
+	}
+
+	userdata := "#cloud-config\nruncmd:\n  - echo done >/dev/console\n"
+	if _, err := c.createComputeServiceInstance(ctx, createComputeServiceInstanceInput{instanceName: "verifier-2", machineType: "e2-micro",
+		userdata: userdata, scriptMetadataKey: probe.ScriptMetadataStartupScript}); err != nil {
+		t.Fatal(err)
+	}
+	if item := inserted.Metadata.Items[0]; item.Key != "startup-script" || !strings.HasPrefix(*item.Value, "#!/bin/bash\n") ||
+		!strings.HasSuffix(*item.Value, "\necho done >/dev/console\n") {
+		t.Errorf("expected the userdata as a shell script under startup-script, got %s: %q", item.Key, *item.Value)
+	}
+}
+
+func TestStartupScriptContainerRuntime(t *testing.T) {
+	userdata, err := generateUserData(map[string]string{"USERDATA_BEGIN": "USERDATA BEGIN", "VALIDATOR_IMAGE": "quay.io/app-sre/osd-network-verifier:v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := helpers.StartupScript(userdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Images without cloud-init don't ship docker: podman is installed and stands in for it before the probe runs
+	setup := strings.Index(script, `ln -sf "$(command -v podman)" /usr/bin/docker`)
+	probeRun := strings.Index(script, "\n/run-container.sh\n")
+	if setup < 0 || probeRun < 0 || setup > probeRun {
+		t.Errorf("expected podman to be set up as docker before the probe runs, got:\n%s", script)
+	}
+	for _, install := range []string{"dnf install -y podman", "yum install -y podman", "apt-get install -y podman"} {
+		if !strings.Contains(script, install) {
+			t.Errorf("expected the startup script to install podman with %q", install)
+		}
+	}
+	if !strings.Contains(script, "sudo docker run") {
+		t.Error("expected the probe to run the validator through docker")
+	}
+
+	// Without either, the probe reports it among its results rather than running nothing
+	begin := strings.Index(script, `echo "USERDATA BEGIN" >> /var/log/userdata-output`)
+	missing := strings.Index(script, "Failed to find docker, or podman standing in for it")
+	if begin < 0 || missing < begin {
+		t.Errorf("expected a missing container runtime to be reported after the results begin, got:\n%s", script)
+	}
+}
+
+func TestScriptMetadataKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        probe.Options
+		sourceImage string
+		expected    string
+	}{
+		{"container optimized", probe.Options{}, "projects/cos-cloud/global/images/family/cos-97-lts", probe.ScriptMetadataUserData},
+		{"rhel family", probe.Options{}, "projects/rhel-cloud/global/images/family/rhel-9", probe.ScriptMetadataStartupScript},
+		{"rocky image", probe.Options{}, "projects/rocky-linux-cloud/global/images/rocky-linux-9-v20240910", probe.ScriptMetadataStartupScript},
+		{"built image", probe.Options{}, "projects/p/global/images/osd-network-verifier-1", probe.ScriptMetadataUserData},
+		{"requested", probe.Options{ScriptMetadataKey: probe.ScriptMetadataStartupScript}, "projects/p/global/images/custom", probe.ScriptMetadataStartupScript},
+	}
+	for _, test := range tests {
+		if key := scriptMetadataKey(test.opts, test.sourceImage); key != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, key)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	c := &Client{tags: map[string]string{"owner": "sre", "osd-network-verifier-run-id": "mine"}}
+	if labels := c.labels(); !reflect.DeepEqual(labels, map[string]string{"owner": "sre", "osd-network-verifier-run-id": "mine"}) {
+		t.Errorf("expected only the tags given without a run, got %v", labels)
+	}
+
+	c.runID = "run1"
+	c.createdBy = "Verifier@my-project.iam.gserviceaccount.com"
+	c.expiresAt = time.Unix(1700000000, 0)
+	expected := map[string]string{
+		"owner":                           "sre",
+		"osd-network-verifier-run-id":     "run1",
+		"osd-network-verifier-created-by": "verifier_my-project_iam_gserviceaccount_com",
+		"osd-network-verifier-expires-at": "1700000000",
+	}
+	if labels := c.labels(); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected %v, got %v", expected, labels)
+	}
+	if len(c.tags) != 2 {
+		t.Errorf("expected the client's tags to be left as given, got %v", c.tags)
+	}
+
+	if value := labelValue(strings.Repeat("a", 70)); len(value) != 63 {
+		t.Errorf("expected the label value to be truncated to 63 characters, got %d", len(value))
+	}
+}
+
+func TestConsoleOutputOffsets(t *testing.T) {
+	ctx := context.TODO()
+	// The console returns its output in pieces, having dropped its first 10 bytes before they were read
+	pieces := map[string]string{
+		"0":  `{"start":"10","next":"30","contents":"USERDATA BEGIN\nnoise\n"}`,
+		"30": `{"start":"30","next":"43","contents":"USERDATA END\n"}`,
+		"43": `{"start":"43","next":"43","contents":""}`,
+	}
+	var starts []string
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := r.URL.Query().Get("start")
+		starts = append(starts, start)
+		fmt.Fprint(w, pieces[start])
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{projectID: "p", zone: "us-east1-b", computeService: computeService, logger: &ocmlog.StdLogger{}}
+	logs, err := c.consoleOutput(ctx, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs != "USERDATA BEGIN\nnoise\nUSERDATA END\n" {
+		t.Errorf("expected the pieces to be joined, got %q", logs)
+	}
+	if !reflect.DeepEqual(starts, []string{"0", "30", "43"}) {
+		t.Errorf("expected each read to start where the previous one ended, got %v", starts)
+	}
+	if len(c.output.Warnings()) != 1 {
+		t.Errorf("expected a warning for the dropped output, got %v", c.output.Warnings())
+	}
+
+	// The next poll reads on from the end
+	starts = nil
+	if _, err := c.consoleOutput(ctx, "verifier-1"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(starts, []string{"43"}) {
+		t.Errorf("expected the next poll to read on from the end, got %v", starts)
+	}
+}
+
+func TestRunResources(t *testing.T) {
+	ctx := context.TODO()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The instance is gone, but its disk survived
+		if strings.HasSuffix(r.URL.Path, "/instances/verifier-1") {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"name":"verifier-1"}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{projectID: "p", computeService: computeService, instances: []computeInstance{{name: "verifier-1", zone: "us-east1-c"}}, logger: &ocmlog.StdLogger{}}
+	resources, err := c.runResources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0] != "disk verifier-1 in us-east1-c" {
+		t.Errorf("expected the surviving disk, got %v", resources)
+	}
+}
+
+func TestVerifyInstanceCap(t *testing.T) {
+	ctx := context.TODO()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/projects/p/aggregated/instances") {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"items":{"zones/us-east1-b":{"instances":[
+			{"name":"verifier-1","zone":"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b"},
+			{"name":"verifier-web","zone":"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b"}
+		]}}}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{projectID: "p", computeService: computeService, logger: &ocmlog.StdLogger{}}
+	if err := c.verifyInstanceCap(ctx, 2); err != nil {
+		t.Errorf("expected only the probe instance to count, got %v", err)
+	}
+	err = c.verifyInstanceCap(ctx, 1)
+	if expected := "refusing to launch another probe instance, as 1 already exist in project p, at or beyond the cap of 1: " +
+		"delete those left behind with `gcloud compute instances delete verifier-1 --project p --zone us-east1-b`, or raise the cap with --max-instances"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestVerifyInstanceCapBatch(t *testing.T) {
+	ctx := context.TODO()
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items":{"zones/us-east1-b":{"instances":[
+			{"name":"verifier-1","zone":"https://www.googleapis.com/compute/v1/projects/p/zones/us-east1-b","labels":{"osd-network-verifier-batch-id":"batch1"}}
+		]}}}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{projectID: "p", computeService: computeService, batchID: "batch1", logger: &ocmlog.StdLogger{}}
+	err = c.verifyInstanceCap(ctx, 1)
+	if expected := "refusing to launch another probe instance, as 1 already exist in project p (1 of them this batch's, verifying other subnets), at or beyond the cap of 1: " +
+		"raise the cap with --max-instances"; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestSourceImage(t *testing.T) {
+	ctx := context.TODO()
+	lookups := 0
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/projects/cos-cloud/global/images/family/cos-97-lts") {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		lookups++
+		fmt.Fprint(w, `{"selfLink":"https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-97-16919-103-16"}`)
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{region: "us-east1", computeService: computeService, logger: &ocmlog.StdLogger{}}
+	cache := imagecache.New(filepath.Join(t.TempDir(), "images.json"), time.Hour)
+
+	// The family is resolved once, then taken from the cache
+	for i := 0; i < 2; i++ {
+		if image := c.sourceImage(ctx, "cos-97-lts", cache); image != "https://www.googleapis.com/compute/v1/projects/cos-cloud/global/images/cos-97-16919-103-16" {
+			t.Errorf("expected the family to be resolved to its image, got %s", image)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected a single lookup, got %d", lookups)
+	}
+
+	// Families that can't be resolved, and those without a cache, are left to Compute Engine
+	if image := c.sourceImage(ctx, "cos-stable", cache); image != "projects/cos-cloud/global/images/family/cos-stable" {
+		t.Errorf("expected the unresolved family, got %s", image)
+	}
+	if image := c.sourceImage(ctx, "cos-97-lts", nil); image != "projects/cos-cloud/global/images/family/cos-97-lts" {
+		t.Errorf("expected the family without a cache, got %s", image)
+	}
+}
+
+func TestWaitForOperation(t *testing.T) {
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = time.Millisecond
+	ctx := context.TODO()
+	polls := 0
+	compute := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/zones/us-east1-b/operations/stop") && polls < 2:
+			fmt.Fprint(w, `{"name":"stop","zone":"us-east1-b","status":"RUNNING"}`)
+		case strings.HasSuffix(r.URL.Path, "/zones/us-east1-b/operations/stop"):
+			fmt.Fprint(w, `{"name":"stop","zone":"us-east1-b","status":"DONE"}`)
+		case strings.HasSuffix(r.URL.Path, "/global/operations/insert"):
+			fmt.Fprint(w, `{"name":"insert","status":"DONE","error":{"errors":[{"code":"QUOTA_EXCEEDED","message":"Quota 'IMAGES' exceeded"}]}}`)
+		default:
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+		}
+	}))
+	defer compute.Close()
+	computeService, err := computev1.NewService(ctx, option.WithEndpoint(compute.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{projectID: "project", zone: "us-east1-b", computeService: computeService, logger: &ocmlog.StdLogger{}}
+
+	// Zonal operations are polled in their zone until done
+	if err := c.waitForOperation(ctx, &computev1.Operation{Name: "stop", Zone: "us-east1-b"}); err != nil {
+		t.Errorf("expected the operation to finish, got %v", err)
+	}
+	if polls != 2 {
+		t.Errorf("expected the operation to be polled twice, got %d", polls)
+	}
+
+	// Global operations are polled globally, their errors returned
+	err = c.waitForOperation(ctx, &computev1.Operation{Name: "insert"})
+	if err == nil || err.Error() != "QUOTA_EXCEEDED: Quota 'IMAGES' exceeded" {
+		t.Errorf("expected the operation's error, got %v", err)
+	}
+}
+
+func TestVerifyKMSKey(t *testing.T) {
+	ctx := context.TODO()
+	const (
+		keyName = "projects/kms-project/locations/us-east1/keyRings/ring/cryptoKeys/key"
+		member  = "serviceAccount:service-123@compute-system.iam.gserviceaccount.com"
+	)
+	var keyState, keyBindings, projectBindings string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/projects/project"):
+			fmt.Fprint(w, `{"name":"project","id":"123"}`)
+		case strings.HasSuffix(r.URL.Path, "/cryptoKeys/key"):
+			fmt.Fprintf(w, `{"name":%q,"purpose":"ENCRYPT_DECRYPT","primary":{"state":%q}}`, keyName, keyState)
+		case strings.HasSuffix(r.URL.Path, "/cryptoKeys/key:getIamPolicy"):
+			fmt.Fprint(w, keyBindings)
+		case strings.HasSuffix(r.URL.Path, "/keyRings/ring:getIamPolicy"):
+			fmt.Fprint(w, `{}`)
+		case strings.HasSuffix(r.URL.Path, "/projects/kms-project:getIamPolicy"):
+			fmt.Fprint(w, projectBindings)
+		default:
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+	opts := []option.ClientOption{option.WithEndpoint(api.URL), option.WithoutAuthentication()}
+	computeService, err := computev1.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmsService, err := cloudkmsv1.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourceManagerService, err := cloudresourcemanagerv1.NewService(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newClient := func() *Client {
+		return &Client{projectID: "project", region: "us-east1", computeService: computeService, kmsService: kmsService,
+			resourceManagerService: resourceManagerService, logger: &ocmlog.StdLogger{}}
+	}
+
+	tests := []struct {
+		name            string
+		keyName         string
+		keyState        string
+		keyBindings     string
+		projectBindings string
+		usable          bool
+	}{
+		{
+			name:        "granted on the key",
+			keyName:     keyName,
+			keyState:    "ENABLED",
+			keyBindings: fmt.Sprintf(`{"bindings":[{"role":"roles/cloudkms.cryptoKeyEncrypterDecrypter","members":[%q]}]}`, member),
+			usable:      true,
+		},
+		{
+			name:            "granted on the key's project",
+			keyName:         keyName,
+			keyState:        "ENABLED",
+			keyBindings:     `{}`,
+			projectBindings: fmt.Sprintf(`{"bindings":[{"role":"roles/cloudkms.cryptoKeyEncrypter","members":[%q]},{"role":"roles/cloudkms.cryptoKeyDecrypter","members":[%q]}]}`, member, member),
+			usable:          true,
+		},
+		{
+			name:            "not granted",
+			keyName:         keyName,
+			keyState:        "ENABLED",
+			keyBindings:     fmt.Sprintf(`{"bindings":[{"role":"roles/cloudkms.cryptoKeyDecrypter","members":[%q]}]}`, member),
+			projectBindings: `{}`,
+		},
+		{
+			name:     "disabled",
+			keyName:  keyName,
+			keyState: "DISABLED",
+		},
+		{
+			name:    "in another region",
+			keyName: "projects/kms-project/locations/europe-west1/keyRings/ring/cryptoKeys/key",
+		},
+		{
+			name:    "not found",
+			keyName: "projects/kms-project/locations/us-east1/keyRings/ring/cryptoKeys/missing",
+		},
+		{
+			name:    "not a key name",
+			keyName: "key",
+		},
+	}
+	for _, test := range tests {
+		keyState, keyBindings, projectBindings = test.keyState, test.keyBindings, test.projectBindings
+		c := newClient()
+		if usable := c.verifyKMSKey(ctx, test.keyName); usable != test.usable {
+			t.Errorf("%s: expected usable %t, got %t", test.name, test.usable, usable)
+		}
+		failures, _, _ := c.output.Parse()
+		if test.usable == (len(failures) > 0) {
+			t.Errorf("%s: unexpected failures %v", test.name, failures)
+		}
+		if len(c.output.Warnings()) > 0 {
+			t.Errorf("%s: unexpected warnings %v", test.name, c.output.Warnings())
+		}
+	}
+}
+
+func TestKeyLocationCompatible(t *testing.T) {
+	for location, compatible := range map[string]bool{
+		"us-east1":    true,
+		"us":          true,
+		"global":      true,
+		"us-central1": false,
+		"europe":      false,
+		"us-east":     false,
+	} {
+		if keyLocationCompatible(location, "us-east1") != compatible {
+			t.Errorf("expected location %s to be compatible with us-east1: %t", location, compatible)
+		}
+	}
+}
+
+// redirectTransport sends every request to the test server
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	r.URL.Scheme, r.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestImpersonatedCredentials(t *testing.T) {
+	ctx := context.TODO()
+	var paths []string
+	var delegates []string
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var req struct {
+			Delegates []string `json:"delegates"`
+			Lifetime  string   `json:"lifetime"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		delegates = req.Delegates
+		if strings.Contains(r.URL.Path, "denied@") {
+			http.Error(w, `{"error":{"code":403,"message":"Permission 'iam.serviceAccounts.getAccessToken' denied"}}`, http.StatusForbidden)
+			return
+		}
+		// Tokens expiring right away are requested again on every use
+		fmt.Fprintf(w, `{"accessToken":"token-%d","expireTime":%q}`, len(paths), time.Now().Add(5*time.Second).UTC().Format(time.RFC3339))
+	}))
+	defer iam.Close()
+	client := &http.Client{Transport: redirectTransport{iam}}
+
+	// The last service account is impersonated through the ones before it
+	creds, err := impersonatedCredentials(ctx, "project", []string{"a@p.iam.gserviceaccount.com", "b@p.iam.gserviceaccount.com"}, option.WithHTTPClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.ProjectID != "project" {
+		t.Errorf("expected the project to be kept, got %s", creds.ProjectID)
+	}
+	if len(paths) != 1 || paths[0] != "/v1/projects/-/serviceAccounts/b@p.iam.gserviceaccount.com:generateAccessToken" {
+		t.Errorf("expected a token to be requested for the last service account right away, got %v", paths)
+	}
+	if len(delegates) != 1 || delegates[0] != "projects/-/serviceAccounts/a@p.iam.gserviceaccount.com" {
+		t.Errorf("expected the first service account as delegate, got %v", delegates)
+	}
+
+	// Expired tokens are refreshed
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token-2" {
+		t.Errorf("expected the expiring token to be refreshed, got %s", token.AccessToken)
+	}
+
+	// A broken chain fails right away
+	if _, err := impersonatedCredentials(ctx, "project", []string{"a@p.iam.gserviceaccount.com", "denied@p.iam.gserviceaccount.com"}, option.WithHTTPClient(client)); err == nil {
+		t.Error("expected impersonating a service account without permission to fail")
+	}
+	if _, err := impersonatedCredentials(ctx, "project", nil); err == nil {
+		t.Error("expected an empty chain to fail")
+	}
+}
+
+func TestProjectCredentials(t *testing.T) {
+	credentials := &google.Credentials{ProjectID: "service"}
+	if _, err := (ProjectCredentials{}).instanceProject(); err == nil {
+		t.Errorf("expected an error without any project")
+	}
+	for _, tc := range []struct {
+		projects        ProjectCredentials
+		instanceProject string
+		networkProject  string
+	}{
+		{ProjectCredentials{Credentials: credentials}, "service", "service"},
+		{ProjectCredentials{Credentials: credentials, NetworkProject: "host"}, "service", "host"},
+		{ProjectCredentials{InstanceProject: "instances", QuotaProject: "billing"}, "instances", "instances"},
+	} {
+		instanceProject, err := tc.projects.instanceProject()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if instanceProject != tc.instanceProject {
+			t.Errorf("expected instance project %s, got %s", tc.instanceProject, instanceProject)
+		}
+		if networkProject := tc.projects.networkProject(instanceProject); networkProject != tc.networkProject {
+			t.Errorf("expected network project %s, got %s", tc.networkProject, networkProject)
+		}
+	}
+}
+
+func TestProjectCredentialsPrincipal(t *testing.T) {
+	creds := ProjectCredentials{Credentials: &google.Credentials{JSON: []byte(`{"type":"service_account","client_email":"verifier@p.iam.gserviceaccount.com"}`)}}
+	if principal := creds.principal(); principal != "verifier@p.iam.gserviceaccount.com" {
+		t.Errorf("expected the key's service account, got %q", principal)
+	}
+	if principal := (ProjectCredentials{Credentials: &google.Credentials{ProjectID: "p"}}).principal(); principal != "" {
+		t.Errorf("expected no principal without the credentials' JSON, got %q", principal)
+	}
+}
+
+func TestSubnetworkSelfLinkNetworkProject(t *testing.T) {
+	defer func(vpc string) { os.Setenv("GCP_VPC_NAME", vpc) }(os.Getenv("GCP_VPC_NAME"))
+	os.Setenv("GCP_VPC_NAME", "shared")
+	c := &Client{projectID: "service", networkProjectID: "host", region: "us-east1"}
+	if expected, selfLink := "projects/host/regions/us-east1/subnetworks/nodes", c.subnetworkSelfLink("nodes"); selfLink != expected {
+		t.Errorf("expected %s, got %s", expected, selfLink)
+	}
+	if expected, selfLink := "projects/other/regions/us-east1/subnetworks/nodes", c.subnetworkSelfLink("projects/other/regions/us-east1/subnetworks/nodes"); selfLink != expected {
+		t.Errorf("expected a self-link to be kept, got %s", selfLink)
+	}
+	if expected, network := "projects/host/global/networks/shared", c.networkName(); network != expected {
+		t.Errorf("expected %s, got %s", expected, network)
+	}
+}
@@ -0,0 +1,159 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	computev1 "google.golang.org/api/compute/v1"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// imageBuildTimeout bounds each step of baking the image: stopping the build instance, and creating the image
+const imageBuildTimeout = 30 * time.Minute
+
+// operationPollInterval is how often a long-running operation is checked for having finished
+var operationPollInterval = 5 * time.Second
+
+// buildImage creates a build instance from baseImage, or the default image family, in the subnetwork, pulls the
+// validator image on it and creates an image named name in the project from its boot disk, returning the image's
+// partial self-link. The build instance is deleted whichever way the build ends.
+func (c *Client) buildImage(ctx context.Context, vpcSubnetID, baseImage, name string, opts probe.Options) (string, error) {
+	if c.instanceType == "" {
+		machineType, err := c.selectDefaultMachineType(ctx, opts.Architecture())
+		if err != nil {
+			return "", err
+		}
+		c.instanceType = machineType
+	}
+	if baseImage == "" && opts.Architecture() == probe.ArchitectureArm64 {
+		baseImage = defaultArm64ImageFamily
+	}
+	baseImage, err := c.setCloudImage(baseImage)
+	if err != nil {
+		return "", err
+	}
+	sourceImage := c.sourceImage(ctx, baseImage, opts.ImageCache)
+	instanceName, err := c.newInstanceName(opts.ResourcePolicy)
+	if err != nil {
+		return "", err
+	}
+	if err := c.verifyInstanceCap(ctx, opts.MaxInstancesOrDefault()); err != nil {
+		return "", err
+	}
+
+	// The build instance is deleted only once it's stopped and the image created from its disk
+	c.expiresAt = helpers.ResourceExpiry(opts, time.Now()).Add(2 * imageBuildTimeout)
+	validatorImage := opts.ValidatorImageOrDefault(defaultNetworkValidatorImage)
+	c.logger.Info(ctx, "Building an image from %s with %s pre-pulled", sourceImage, validatorImage)
+	instance, err := c.createComputeServiceInstance(ctx, createComputeServiceInstanceInput{
+		vpcSubnetID:  c.subnetworkSelfLink(vpcSubnetID),
+		subnetID:     vpcSubnetID,
+		userdata:     helpers.ImageBuildUserdata(validatorImage),
+		zone:         c.zone,
+		machineType:  c.instanceType,
+		instanceName: instanceName,
+		sourceImage:  sourceImage,
+		networkName:  c.networkName(),
+		// Base images without cloud-init run the pull as a startup script
+		scriptMetadataKey: scriptMetadataKey(opts, sourceImage),
+	})
+	defer func() {
+		c.terminateComputeServiceInstance(ctx, instance.instanceName)
+		if _, _, errs := c.output.Parse(); len(errs) > 0 {
+			c.logger.Error(ctx, "Unable to delete build instance %s, delete it manually: %v", instance.instanceName, errs)
+		}
+	}()
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.waitForComputeServiceInstanceCompletion(ctx, instance.instanceName, opts.LaunchTimeoutOrDefault()); err != nil {
+		return "", err
+	}
+	if err := c.waitForValidatorPull(ctx, instance.instanceName, opts); err != nil {
+		return "", err
+	}
+
+	// The boot disk is imaged once the instance is stopped, so its filesystem is consistent
+	c.logger.Info(ctx, "Stopping build instance %s", instance.instanceName)
+	op, err := c.computeService.Instances.Stop(c.projectID, c.zone, instance.instanceName).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if err := c.waitForOperation(ctx, op); err != nil {
+		return "", fmt.Errorf("unable to stop build instance %s: %w", instance.instanceName, err)
+	}
+
+	c.logger.Info(ctx, "Creating image %s from the boot disk of build instance %s", name, instance.instanceName)
+	op, err = c.computeService.Images.Insert(c.projectID, &computev1.Image{
+		Name:        name,
+		Description: fmt.Sprintf("%s with %s pre-pulled, built by osd-network-verifier", sourceImage, validatorImage),
+		// The boot disk is named after the instance
+		SourceDisk: fmt.Sprintf("projects/%s/zones/%s/disks/%s", c.projectID, c.zone, instance.instanceName),
+		Labels:     c.tags,
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if err := c.waitForOperation(ctx, op); err != nil {
+		return "", fmt.Errorf("unable to create image %s: %w", name, err)
+	}
+
+	return fmt.Sprintf("projects/%s/global/images/%s", c.projectID, name), nil
+}
+
+// waitForValidatorPull waits, up to the console timeout, for the build instance to report it pulled the validator
+// image in its serial console output
+func (c *Client) waitForValidatorPull(ctx context.Context, instanceName string, opts probe.Options) error {
+	var pullErr error
+	err := helpers.PollImmediateWithContext(ctx, opts.ConsolePollIntervalOrDefault(), opts.ConsoleTimeoutOrDefault(), func() (bool, error) {
+		consoleLogs, err := c.consoleOutput(ctx, instanceName)
+		if err != nil {
+			return false, err
+		}
+		done, err := helpers.ParseImageBuild(consoleLogs)
+		pullErr = err
+		return done, nil
+	})
+	if errors.Is(err, helpers.ErrPollTimeout) {
+		return fmt.Errorf("the build instance did not pull the validator image within the console timeout of %s: %w", opts.ConsoleTimeoutOrDefault(), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return pullErr
+}
+
+// waitForOperation waits for the zonal or global operation to finish, returning its errors
+func (c *Client) waitForOperation(ctx context.Context, op *computev1.Operation) error {
+	err := helpers.PollImmediateWithContext(ctx, operationPollInterval, imageBuildTimeout, func() (bool, error) {
+		var err error
+		if op.Zone != "" {
+			op, err = c.computeService.ZoneOperations.Get(c.projectID, c.zone, op.Name).Context(ctx).Do()
+		} else {
+			op, err = c.computeService.GlobalOperations.Get(c.projectID, op.Name).Context(ctx).Do()
+		}
+		if err != nil {
+			return false, err
+		}
+		return op.Status == "DONE", nil
+	})
+	if err != nil {
+		return err
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		var messages []string
+		for _, e := range op.Error.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	return nil
+}
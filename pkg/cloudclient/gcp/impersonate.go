@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// cloudPlatformScope is the scope of the impersonated service account's tokens, covering every API the verifier calls
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// ImpersonatedCredentials returns credentials for the project impersonating the last service account of the chain,
+// e.g. one with access to the customer's project, through the service accounts before it, in order, each allowed to
+// create tokens for the next, as gcloud's --impersonate-service-account does. The first is impersonated with the
+// application default credentials. A token is requested right away, so a broken chain fails before anything is
+// created.
+func ImpersonatedCredentials(ctx context.Context, projectID string, chain []string) (*google.Credentials, error) {
+	return impersonatedCredentials(ctx, projectID, chain)
+}
+
+func impersonatedCredentials(ctx context.Context, projectID string, chain []string, opts ...option.ClientOption) (*google.Credentials, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no service account to impersonate")
+	}
+
+	// No lifetime is set, as tokens with one aren't refreshed: tokens last the default hour, and a new one is
+	// requested through the whole chain once one expires, so long runs outlive them
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: chain[len(chain)-1],
+		Delegates:       chain[:len(chain)-1],
+		Scopes:          []string{cloudPlatformScope},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ts.Token(); err != nil {
+		return nil, fmt.Errorf("unable to impersonate service account %s: %w", strings.Join(chain, " -> "), err)
+	}
+
+	return &google.Credentials{ProjectID: projectID, TokenSource: ts}, nil
+}
@@ -0,0 +1,78 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+// probeInstanceNamePattern matches the names of the verifier's probe instances, allowing for a resource policy's
+// name prefix
+var probeInstanceNamePattern = regexp.MustCompile(`verifier-[0-9]+$`)
+
+// existingProbeInstances returns the names of the verifier's probe instances in the project, as zone/name, told apart
+// by their names, and how many more are labeled as the run's batch's. Stopped instances count too, as their disks
+// remain.
+func (c *Client) existingProbeInstances(ctx context.Context) ([]string, int, error) {
+	var instances []string
+	batch := 0
+	err := c.computeService.Instances.AggregatedList(c.projectID).Filter(`name eq ".*verifier-[0-9]+"`).Pages(ctx, func(list *computev1.InstanceAggregatedList) error {
+		for _, scoped := range list.Items {
+			for _, i := range scoped.Instances {
+				switch {
+				case !probeInstanceNamePattern.MatchString(i.Name):
+				case c.batchID != "" && i.Labels[batchIDLabelKey] == c.batchID:
+					batch++
+				default:
+					instances = append(instances, path.Base(i.Zone)+"/"+i.Name)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return instances, batch, nil
+}
+
+// verifyInstanceCap refuses to launch another probe instance when as many as the cap already exist, e.g. left behind
+// by a buggy automation loop, returning an error listing them along with the command to delete them. The check is
+// skipped with a warning when the instances can't be listed, e.g. without the compute.instances.list permission.
+// Instances of the run's batch count towards the cap without being offered for deletion, as they're still in use.
+func (c *Client) verifyInstanceCap(ctx context.Context, maxInstances int) error {
+	if maxInstances < 0 {
+		return nil
+	}
+
+	instances, batch, err := c.existingProbeInstances(ctx)
+	if err != nil {
+		c.output.AddWarning(fmt.Sprintf("Unable to count the existing probe instances to enforce the cap of %d: %v", maxInstances, err))
+		return nil
+	}
+	if len(instances)+batch < maxInstances {
+		return nil
+	}
+
+	commands := make([]string, 0, len(instances))
+	for _, i := range instances {
+		zone, name := path.Split(i)
+		commands = append(commands, fmt.Sprintf("`gcloud compute instances delete %s --project %s --zone %s`", name, c.projectID, strings.TrimSuffix(zone, "/")))
+	}
+	remedy := "raise the cap with --max-instances"
+	if len(commands) > 0 {
+		remedy = fmt.Sprintf("delete those left behind with %s, or %s", strings.Join(commands, ", "), remedy)
+	}
+	var batchNote string
+	if batch > 0 {
+		batchNote = fmt.Sprintf(" (%d of them this batch's, verifying other subnets)", batch)
+	}
+
+	return fmt.Errorf("refusing to launch another probe instance, as %d already exist in project %s%s, at or beyond the cap of %d: %s",
+		len(instances)+batch, c.projectID, batchNote, maxInstances, remedy)
+}
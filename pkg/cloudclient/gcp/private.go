@@ -1,24 +1,34 @@
 package gcp
 
-//Features to add - image-id, kms-key-id
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
-	"golang.org/x/oauth2/google"
 	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
 
-	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
 	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/events"
 	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/imagecache"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
 	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/remediation"
+	"github.com/openshift/osd-network-verifier/pkg/resourcepolicy"
+	"github.com/openshift/osd-network-verifier/pkg/version"
 )
 
 type createComputeServiceInstanceInput struct {
@@ -30,59 +40,114 @@ type createComputeServiceInstanceInput struct {
 	instanceName   string
 	sourceImage    string
 	networkName    string
+	// subnetID is the subnet as given by the caller, vpcSubnetID its self-link
+	subnetID      string
+	preemptible   bool
+	resultChannel string
+	networkTags   []string
+	// stackType is the IP stack of the probe's network interface, dual-stack in a dual-stack subnetwork
+	stackType string
+	// externalIPv6 gives the probe an external IPv6 address, the only way out to the internet over IPv6
+	externalIPv6 bool
+	// kmsKeyName is the Cloud KMS key encrypting the boot disk, Google-managed encryption being used without one
+	kmsKeyName string
+	// scriptMetadataKey is the metadata key the userdata is passed under, one of probe.ScriptMetadataOptions, as a
+	// shell script under probe.ScriptMetadataStartupScript. Defaults to probe.ScriptMetadataUserData.
+	scriptMetadataKey string
 }
 
 var (
-	// TODO find a location for future docker images
-	networkValidatorImage string = "quay.io/app-sre/osd-network-verifier:v0.1.159-9a6e0eb"
-	userdataEndVerifier   string = "USERDATA END"
+	// defaultNetworkValidatorImage is run by the probe unless overridden, e.g. with --validator-image
+	defaultNetworkValidatorImage string = "quay.io/app-sre/osd-network-verifier:v0.1.159-9a6e0eb"
+	// validatorRegion is the AWS region the validator builds its AWS endpoints for, which has no GCP equivalent
+	validatorRegion = "us-east-2"
+
+	// defaultMachineTypes are tried in order when no machine type was requested, by CPU architecture
+	defaultMachineTypes = map[string][]string{
+		probe.ArchitectureX86_64: {"e2-standard-2", "n2-standard-2", "n1-standard-2"},
+		probe.ArchitectureArm64:  {"t2a-standard-1"},
+	}
+
+	// cosImageProject is the project of the container optimized images the probe instance boots from
+	cosImageProject = "cos-cloud"
+
+	// defaultArm64ImageFamily replaces the default container optimized image, which is x86_64 only, for arm64
+	defaultArm64ImageFamily = "cos-arm64-stable"
+
+	// startupScriptImages prefix the families and projects of the images without cloud-init, which run the probe's
+	// script as a startup script rather than as user-data
+	startupScriptImages = []string{"rhel", "rocky-linux", "centos", "debian"}
 )
 
-func newClient(ctx context.Context, logger ocmlog.Logger, credentials *google.Credentials, region, instanceType string, tags map[string]string) (*Client, error) {
+func newClient(ctx context.Context, logger logging.Logger, projects ProjectCredentials, region, instanceType string, tags map[string]string) (*Client, error) {
 	//use oauth2 token in credentials struct to create a client,
 	// https://pkg.go.dev/golang.org/x/oauth2/google#Credentials
 
 	// https://cloud.google.com/docs/authentication/production
 	//service account credentials order/priority - env variable, service account attached to resource, error
 
-	computeService, err := computev1.NewService(ctx)
+	// Credentials with a token source, e.g. impersonated ones, authenticate every call, the application default
+	// credentials being used otherwise
+	var clientOptions []option.ClientOption
+	if projects.Credentials != nil && projects.Credentials.TokenSource != nil {
+		clientOptions = append(clientOptions, option.WithTokenSource(projects.Credentials.TokenSource))
+	}
+	if projects.QuotaProject != "" {
+		clientOptions = append(clientOptions, option.WithQuotaProject(projects.QuotaProject))
+	}
+	projectID, err := projects.instanceProject()
+	if err != nil {
+		return nil, err
+	}
+	computeService, err := computev1.NewService(ctx, clientOptions...)
 	if err != nil {
 		return nil, err
 	}
 
 	c := &Client{
-		projectID: credentials.ProjectID,
-		region:    region,
+		projectID:        projectID,
+		networkProjectID: projects.networkProject(projectID),
+		region:           region,
 		//Zone b is supported by all regions and has the most machine types compared to zone a and c
 		//https://cloud.google.com/compute/docs/regions-zones#available
 		zone:           fmt.Sprintf("%s-b", region),
-		instanceType:   instanceType,
 		computeService: computeService,
+		clientOptions:  clientOptions,
+		createdBy:      projects.principal(),
 		tags:           tags,
-		logger:         logger,
+		logger:         redact.Logger(logger),
 		output:         output.Output{},
 	}
 
-	if err := c.validateMachineType(ctx); err != nil {
-		return nil, fmt.Errorf("Instance type %s is invalid: %v", c.instanceType, err)
+	// The first supported machine type of the preference list is used. Without one, a default is selected for the
+	// CPU architecture when validating egress
+	if preferred := helpers.SplitInstanceTypes(instanceType); len(preferred) > 0 {
+		selected, err := c.selectMachineType(ctx, preferred)
+		if err != nil {
+			return nil, err
+		}
+		if selected != preferred[0] {
+			c.logger.Info(ctx, "Instance type %s is unavailable in %s, falling back to %s", preferred[0], c.zone, selected)
+		}
+		c.instanceType = selected
 	}
 
 	return c, nil
 }
 
-func (c *Client) validateMachineType(ctx context.Context) error {
+func (c *Client) validateMachineType(ctx context.Context, instanceType string) error {
 	//  machineTypes List https://cloud.google.com/compute/docs/reference/rest/v1/machineTypes/list
 
-	c.logger.Debug(ctx, "Gathering description of instance type %s from ComputeService API", c.instanceType)
+	c.logger.Debug(ctx, "Gathering description of instance type %s from ComputeService API", instanceType)
 
 	descOut := c.computeService.MachineTypes.List(c.projectID, c.zone)
 
 	found := false
 	if err := descOut.Pages(ctx, func(page *computev1.MachineTypeList) error {
 		for _, machineType := range page.Items {
-			if string(machineType.Name) == c.instanceType {
+			if string(machineType.Name) == instanceType {
 				found = true
-				c.logger.Debug(ctx, "Instance type %s supported", c.instanceType)
+				c.logger.Debug(ctx, "Instance type %s supported", instanceType)
 				break
 			}
 		}
@@ -93,23 +158,131 @@ func (c *Client) validateMachineType(ctx context.Context) error {
 	}
 
 	if !found {
-		return fmt.Errorf("Instance type %s not found in ComputeService API", c.instanceType)
+		return fmt.Errorf("Instance type %s not found in ComputeService API", instanceType)
 	}
 
 	return nil
 }
 
+// selectMachineType returns the first machine type of the preference list that's supported in the client's zone
+func (c *Client) selectMachineType(ctx context.Context, preferred []string) (string, error) {
+	var reasons []string
+	for _, machineType := range preferred {
+		if err := c.validateMachineType(ctx, machineType); err != nil {
+			c.logger.Debug(ctx, "Machine type %s is not usable in %s: %v", machineType, c.zone, err)
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		return machineType, nil
+	}
+
+	return "", fmt.Errorf("none of the instance types %v are usable in %s: %s", preferred, c.zone, strings.Join(reasons, "; "))
+}
+
+// selectDefaultMachineType returns the first of the default machine types for the CPU architecture that's
+// offered in the client's zone
+func (c *Client) selectDefaultMachineType(ctx context.Context, architecture string) (string, error) {
+	candidates, ok := defaultMachineTypes[architecture]
+	if !ok {
+		return "", fmt.Errorf("unsupported CPU architecture %s", architecture)
+	}
+
+	machineType, err := c.selectMachineType(ctx, candidates)
+	if err != nil {
+		return "", fmt.Errorf("%v, please specify one with `--instance-type`", err)
+	}
+	c.logger.Debug(ctx, "Selected default machine type %s, the first of %v offered for %s in %s", machineType, candidates, architecture, c.zone)
+
+	return machineType, nil
+}
+
+// newInstanceName returns a random name for the probe instance, named and labeled as the policy requires. Its boot
+// disk is labeled along with it.
+func (c *Client) newInstanceName(policy *resourcepolicy.Policy) (string, error) {
+	r, err := policy.Apply(resourcepolicy.Resource{Kind: resourcepolicy.KindInstance, Name: fmt.Sprintf("verifier-%v", rand.Intn(10000)), Tags: c.tags})
+	if err != nil {
+		return "", err
+	}
+	c.tags = r.Tags
+
+	return r.Name, nil
+}
+
+// labels returns the labels of the run's resources: the client's tags, over which the run's ID, who created them and
+// when they can be deemed leaked take precedence, so they can be audited, cleaned up and attributed
+func (c *Client) labels() map[string]string {
+	labels := make(map[string]string, len(c.tags)+4)
+	for k, v := range c.tags {
+		labels[k] = v
+	}
+	if c.runID != "" {
+		labels[runIDLabelKey] = c.runID
+	}
+	if c.batchID != "" {
+		labels[batchIDLabelKey] = c.batchID
+	}
+	if c.createdBy != "" {
+		labels[createdByLabelKey] = labelValue(c.createdBy)
+	}
+	if !c.expiresAt.IsZero() {
+		labels[expiresAtLabelKey] = strconv.FormatInt(c.expiresAt.Unix(), 10)
+	}
+
+	return labels
+}
+
+// labelValue turns s into a valid label value: lowercase letters, digits, dashes and underscores, up to 63 characters
+func labelValue(s string) string {
+	value := []rune(strings.ToLower(s))
+	for i, r := range value {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			value[i] = '_'
+		}
+	}
+	if len(value) > 63 {
+		value = value[:63]
+	}
+
+	return string(value)
+}
+
+// scriptMetadataKey returns the metadata key the probe's script is passed under to an instance booting sourceImage: the
+// requested one, or the startup script for images of the families or projects without cloud-init
+func scriptMetadataKey(opts probe.Options, sourceImage string) string {
+	if opts.ScriptMetadataKey != "" {
+		return opts.ScriptMetadataKey
+	}
+	for _, segment := range strings.Split(sourceImage, "/") {
+		for _, prefix := range startupScriptImages {
+			if strings.HasPrefix(segment, prefix) {
+				return probe.ScriptMetadataStartupScript
+			}
+		}
+	}
+
+	return probe.ScriptMetadataUserData
+}
+
 func (c *Client) createComputeServiceInstance(ctx context.Context, input createComputeServiceInstanceInput) (createComputeServiceInstanceInput, error) {
+	metadataKey, script := probe.ScriptMetadataUserData, input.userdata
+	if input.scriptMetadataKey == probe.ScriptMetadataStartupScript {
+		var err error
+		if script, err = helpers.StartupScript(input.userdata); err != nil {
+			return input, err
+		}
+		metadataKey = probe.ScriptMetadataStartupScript
+	}
 
 	req := &computev1.Instance{
-		Name:        input.instanceName,
-		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s", c.zone, input.machineType),
+		Name: input.instanceName,
 
 		Disks: []*computev1.AttachedDisk{
 			{
 				InitializeParams: &computev1.AttachedDiskInitializeParams{
 					DiskSizeGb:  10,
 					SourceImage: input.sourceImage,
+					// Label the boot disk as it's created, as the instance's labels set below don't carry over to it
+					Labels: c.labels(),
 				},
 				AutoDelete: true,
 				Boot:       true,
@@ -121,24 +294,43 @@ func (c *Client) createComputeServiceInstance(ctx context.Context, input createC
 			{
 				Name:       input.networkName,
 				Subnetwork: input.vpcSubnetID,
+				StackType:  input.stackType,
 			},
 		},
 
 		Metadata: &computev1.Metadata{
 			Items: []*computev1.MetadataItems{
 				{
-					Key:   "user-data",
-					Value: &input.userdata,
+					Key:   metadataKey,
+					Value: &script,
 				},
 			},
 		},
 	}
+	if input.externalIPv6 {
+		req.NetworkInterfaces[0].Ipv6AccessConfigs = []*computev1.AccessConfig{{Name: "external-ipv6", Type: "DIRECT_IPV6", NetworkTier: "PREMIUM"}}
+	}
+	if len(input.networkTags) > 0 {
+		req.Tags = &computev1.Tags{Items: input.networkTags}
+	}
+	if input.preemptible {
+		req.Scheduling = preemptibleScheduling()
+	}
+	if input.resultChannel == probe.ResultChannelCloudLogging {
+		req.ServiceAccounts = loggingServiceAccounts()
+	}
+	if input.kmsKeyName != "" {
+		req.Disks[0].DiskEncryptionKey = &computev1.CustomerEncryptionKey{KmsKeyName: input.kmsKeyName}
+	}
 
-	//send request to computeService
-	instanceResp, err := c.computeService.Instances.Insert(c.projectID, c.zone, req).Context(ctx).Do()
+	//send request to computeService, falling back to the region's other zones on stockouts
+	err := c.insertInstance(ctx, req, input.machineType)
+	// The instance may exist even if its creation failed, e.g. when waiting for it timed out
+	c.instances = append(c.instances, computeInstance{name: input.instanceName, zone: c.zone})
 	if err != nil {
-		return input, fmt.Errorf("unable to create instance: %v %v", err, instanceResp)
+		return input, err
 	}
+	input.zone = c.zone
 
 	c.logger.Info(ctx, "Created instance with ID: %s", input.instanceName)
 
@@ -153,7 +345,7 @@ func (c *Client) createComputeServiceInstance(ctx context.Context, input createC
 
 	reqbody := &computev1.InstancesSetLabelsRequest{
 		LabelFingerprint: inst.LabelFingerprint,
-		Labels:           c.tags,
+		Labels:           c.labels(),
 	}
 
 	//send request to apply tags, return error if tags are invalid
@@ -198,9 +390,9 @@ func (c *Client) describeComputeServiceInstances(ctx context.Context, instanceNa
 	return status, nil
 }
 
-func (c *Client) waitForComputeServiceInstanceCompletion(ctx context.Context, instanceName string) error {
+func (c *Client) waitForComputeServiceInstanceCompletion(ctx context.Context, instanceName string, timeout time.Duration) error {
 	//wait for the instance to run
-	err := helpers.PollImmediate(5*time.Second, 2*time.Minute, func() (bool, error) {
+	err := helpers.PollImmediateWithContext(ctx, 5*time.Second, timeout, func() (bool, error) {
 		code, descError := c.describeComputeServiceInstances(ctx, instanceName)
 		switch code {
 		case "RUNNING":
@@ -227,6 +419,10 @@ func (c *Client) waitForComputeServiceInstanceCompletion(ctx context.Context, in
 
 func generateUserData(variables map[string]string) (string, error) {
 	variableMapper := func(varName string) string {
+		// "$$" escapes a literal "$", keeping the probe's own shell variables out of the expansion
+		if varName == "$" {
+			return "$"
+		}
 		return variables[varName]
 	}
 	data := os.Expand(helpers.UserdataTemplate, variableMapper)
@@ -234,69 +430,91 @@ func generateUserData(variables map[string]string) (string, error) {
 	return data, nil
 }
 
-func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceName string) error {
+func (c *Client) findUnreachableEndpoints(ctx context.Context, instanceName, vpcSubnetID string, opts probe.Options) error {
 	// Compile the regular expressions once
-	reVerify := regexp.MustCompile(userdataEndVerifier)
-	reUnreachableErrors := regexp.MustCompile(`Unable to reach (\S+)`)
+	reValidatorImage := regexp.MustCompile(`VALIDATOR_IMAGE_DIGEST (\S+@\S+)`)
+	failurePatterns := opts.FailurePatternsOrDefault(defaultNetworkValidatorImage)
+	soakRoundsLogged := 0
 
 	// getConsoleOutput then parse, use c.output to store result of the execution
-	err := helpers.PollImmediate(30*time.Second, 4*time.Minute, func() (bool, error) {
-		output, err := c.computeService.Instances.GetSerialPortOutput(c.projectID, c.zone, instanceName).Context(ctx).Do()
+	err := helpers.PollImmediateWithContext(ctx, opts.ConsolePollIntervalOrDefault(), opts.ConsoleTimeoutOrDefault(), func() (bool, error) {
+		// First, gather the probe's output, from the ComputeService console unless another result channel was requested
+		scriptOutput, err := c.probeOutput(ctx, instanceName, opts)
 		if err != nil {
 			return false, err
 		}
 
-		if output != nil {
-			// First, gather the ComputeService console output
-			scriptOutput := fmt.Sprintf("%#v", output)
-
-			if err != nil {
-				// unable to decode output. we will try again
-				c.logger.Debug(ctx, "Error while collecting console output, will retry on next check interval: %s", err)
-				return false, nil
+		// In the early stages, an ComputeService instance may be running but the console is not populated with any data, retry if that is the case
+		if scriptOutput != "" {
+			c.output.SetConsoleLogs(scriptOutput)
+			if match := reValidatorImage.FindStringSubmatch(scriptOutput); match != nil {
+				c.output.Metadata().ValidatorImageDigest = match[1]
 			}
-
-			// In the early stages, an ComputeService instance may be running but the console is not populated with any data, retry if that is the case
-			if len(scriptOutput) < 1 {
-				c.logger.Debug(ctx, "ComputeService console output not yet populated with data, continuing to wait...")
-				return false, nil
+			// Stream the soak rounds as they're reported, rather than once the probe is done
+			for _, round := range helpers.ParseSoakRounds(scriptOutput) {
+				if round.Round > soakRoundsLogged {
+					c.logger.Info(ctx, "%s", round)
+					soakRoundsLogged = round.Round
+				}
 			}
 
 			// Check for the specific string we output in the generated userdata file at the end to verify the userdata script has run
 			// It is possible we get EC2 console output, but the userdata script has not yet completed.
-			verifyMatch := reVerify.FindString(string(scriptOutput))
-			if len(verifyMatch) < 1 {
+			// The done marker printed after the output stands in for it when the console cut it off.
+			completion := helpers.CheckConsoleCompletion(scriptOutput, c.markers)
+			if !completion.Done {
 				c.logger.Debug(ctx, "ComputeService console output contains data, but end of userdata script not seen, continuing to wait...")
 				return false, nil
 			}
+			if completion.Truncated {
+				c.output.AddWarning("the probe's console output does not match its checksum, part of it was cut off or interleaved with other console output, so some results may be missing")
+			}
 
 			// check output failures, report as exception if they occurred
-			var rgx = regexp.MustCompile(`(?m)^(.*Cannot.*)|(.*Could not.*)|(.*Failed.*)|(.*command not found.*)`)
-			notFoundMatch := rgx.FindAllStringSubmatch(string(scriptOutput), -1)
+			notFoundMatch := failurePatterns.Failures(scriptOutput)
 
-			if len(notFoundMatch) > 0 { //&& len(success) < 1
+			if len(notFoundMatch) > 0 {
+				c.logger.Debug(ctx, "Failures in the console output: %v", notFoundMatch)
 				c.output.AddException(handledErrors.NewEgressURLError("internet connectivity problem: please ensure there's internet access in given vpc subnets"))
+			} else if !failurePatterns.Succeeded(scriptOutput) {
+				c.output.AddException(handledErrors.NewGenericError(errors.New("egress tests were not run: the probe's output matches none of the success patterns. Further investigation needed")))
 			}
 
 			// If debug logging is enabled, output the full console log that appears to include the full userdata run
-			c.logger.Debug(ctx, "Full ComputeService console output:\n---\n%s\n---", output)
+			c.logger.Debug(ctx, "Full ComputeService console output:\n---\n%s\n---", scriptOutput)
 
-			c.output.SetEgressFailures(reUnreachableErrors.FindAllString(string(scriptOutput), -1))
+			helpers.ParseProbeResults(&c.output, scriptOutput, vpcSubnetID)
 			return true, nil
 		}
 		c.logger.Debug(ctx, "Waiting for UserData script to complete...")
 		return false, nil
 	})
 
+	if errors.Is(err, helpers.ErrPollTimeout) {
+		return fmt.Errorf("the probe did not report its results within the console timeout of %s, slow proxies or large endpoint lists may need a larger --console-timeout: %w", opts.ConsoleTimeoutOrDefault(), err)
+	}
+
 	return err
 }
 
-// terminateComputeServiceInstance terminates target ComputeService instance
+// recordPartialResults reports whatever the probe got through before the console timeout, marking the run incomplete
+func (c *Client) recordPartialResults(subnetID string, opts probe.Options) {
+	if logs := c.output.ConsoleLogs(); logs != "" {
+		helpers.ParseProbeResults(&c.output, logs, subnetID)
+	}
+	c.output.SetIncomplete(fmt.Sprintf("the probe did not finish within the console timeout of %s", opts.ConsoleTimeoutOrDefault()))
+}
+
+// terminateComputeServiceInstance deletes target ComputeService instance, along with its boot disk, which is deleted
+// with it
 // uses c.output to store result of the execution
 func (c *Client) terminateComputeServiceInstance(ctx context.Context, instanceName string) {
 	c.logger.Info(ctx, "Terminating ComputeService instance with id %s", instanceName)
+	// Terminate even when the run's context is done, e.g. --run-timeout passed
+	ctx, cancel := helpers.TeardownContext()
+	defer cancel()
 
-	_, err := c.computeService.Instances.Stop(c.projectID, c.zone, instanceName).Context(ctx).Do()
+	_, err := c.computeService.Instances.Delete(c.projectID, c.zone, instanceName).Context(ctx).Do()
 
 	c.output.AddError(err)
 
@@ -312,27 +530,220 @@ func (c *Client) setCloudImage(cloudImageID string) (string, error) {
 	return cloudImageID, nil
 }
 
+// sourceImage returns the image family in the container optimized image project the probe instance boots from, or
+// the image given as a path, e.g. projects/PROJECT/global/images/NAME as built with `image build`. When images are
+// cached, the family is resolved to the image it currently points to, a cached one being reused, so bulk runs boot the
+// same image and skip the lookup; an image that can't be resolved is left to Compute Engine to resolve on creation.
+func (c *Client) sourceImage(ctx context.Context, family string, cache *imagecache.Cache) string {
+	if strings.Contains(family, "/") {
+		return family
+	}
+	familyImage := fmt.Sprintf("projects/%s/global/images/family/%s", cosImageProject, family)
+	if cache == nil {
+		return familyImage
+	}
+
+	image, err := cache.Resolve(imagecache.Key(strings.ToLower(ClientIdentifier), c.region, familyImage), func() (string, error) {
+		image, err := c.computeService.Images.GetFromFamily(cosImageProject, family).Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		return image.SelfLink, nil
+	})
+	if err != nil {
+		c.logger.Debug(ctx, "Unable to resolve image family %s, leaving it to Compute Engine: %v", familyImage, err)
+		return familyImage
+	}
+
+	return image
+}
+
+// subnetworkSelfLink returns vpcSubnetID as a partial self-link, expanding bare subnetwork names using the
+// client's network project and region
+func (c *Client) subnetworkSelfLink(vpcSubnetID string) string {
+	if project, region, name, ok := ParseSubnetworkSelfLink(vpcSubnetID); ok {
+		return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", project, region, name)
+	}
+
+	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", c.networkProject(), c.region, vpcSubnetID)
+}
+
+// networkProject returns the project of the VPC network
+func (c *Client) networkProject() string {
+	if c.networkProjectID != "" {
+		return c.networkProjectID
+	}
+
+	return c.projectID
+}
+
+// networkName returns the VPC network of GCP_VPC_NAME, in the client's network project
+func (c *Client) networkName() string {
+	return fmt.Sprintf("projects/%s/global/networks/%s", c.networkProject(), os.Getenv("GCP_VPC_NAME"))
+}
+
 // validateEgress performs validation process for egress
 // Basic workflow is:
 // - prepare for ComputeService instance creation
 // - create instance and wait till it gets ready, wait for gcpUserData script execution
 // - find unreachable endpoints & parse output, then terminate instance
 // - return `c.output` which stores the execution results
-func (c *Client) validateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration, p proxy.ProxyConfig) *output.Output {
+func (c *Client) validateEgress(ctx context.Context, vpcSubnetID, cloudImageID string, kmsKeyID string, timeout time.Duration, p proxy.ProxyConfig, opts probe.Options) *output.Output {
+	c.output.SetConsoleSanitizer(opts.ConsoleSanitizer)
+	opts.Emit(events.Event{Type: events.TypeStarted, Subnet: vpcSubnetID})
+	defer func() { helpers.EmitFinished(opts, vpcSubnetID, &c.output) }()
+	metadata := c.output.Metadata()
+	metadata.Provider = ClientIdentifier
+	metadata.Region = c.region
+	metadata.Zone = c.zone
+	metadata.Subnet = vpcSubnetID
+	metadata.ValidatorImage = opts.ValidatorImageOrDefault(defaultNetworkValidatorImage)
+	metadata.VerifierVersion = version.String()
+	metadata.EgressListVersion = endpoints.CatalogVersion
+	metadata.StartTime = time.Now()
+	defer func() { metadata.EndTime = time.Now() }()
+
+	ocpVersion, err := endpoints.OCPVersion(opts.OCPVersion)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	metadata.OCPVersion = ocpVersion
+	c.output.SetCategories(opts.LogForwarding.Categories())
+
 	c.logger.Debug(ctx, "Using configured timeout of %s for each egress request", timeout.String())
 
+	// Pre-flight: the probe trusts the CA bundle given, which fails every TLS probe if unusable
+	caFailures, caWarnings := p.VerifyCACert(time.Now())
+	for _, warning := range caWarnings {
+		c.output.AddWarning(warning)
+	}
+	if len(caFailures) > 0 {
+		for _, failure := range caFailures {
+			c.output.AddFailure(failure)
+		}
+		c.logger.Error(ctx, "The CA bundle is unusable, not launching the probe instance")
+		return &c.output
+	}
+
+	// Pre-flight: the subnetwork must be the one meant, and have room for the probe instance
+	subnet, ok := c.verifySubnetwork(ctx, vpcSubnetID, opts)
+	if !ok {
+		c.logger.Error(ctx, "Subnetwork %s is unsuitable, not launching the probe instance", vpcSubnetID)
+		return &c.output
+	}
+
+	// Pre-flight: firewall rules denying egress pinpoint what the probe can only observe as timeouts
+	if subnet != nil && !c.verifyFirewallEgress(ctx, subnet.Network, p, opts) {
+		c.logger.Error(ctx, "The firewall denies egress from subnetwork %s, not launching the probe instance", vpcSubnetID)
+		return &c.output
+	}
+
+	// Pre-flight: an unusable KMS key only surfaces as a cryptic instance creation error
+	if kmsKeyID != "" && !c.verifyKMSKey(ctx, kmsKeyID) {
+		c.logger.Error(ctx, "KMS key %s is unusable, not launching the probe instance", kmsKeyID)
+		return &c.output
+	}
+
+	// Dual-stack subnetworks are verified over IPv6 as well, unless pinned, the probe given an IPv6 address
+	var stackType string
+	var externalIPv6 bool
+	dualStack := subnet != nil && subnet.StackType == subnetStackDual
+	metadata.IPVersions = opts.IPVersions(dualStack)
+	metadata.IPVersionPinned = opts.IPVersion != ""
+	if opts.VerifiesIPv6(dualStack) {
+		// A subnetwork that couldn't be looked up is taken to have external IPv6 addresses, as pinned
+		stackType, externalIPv6 = subnetStackDual, subnet == nil || subnet.Ipv6AccessType == ipv6AccessExternal
+		if !externalIPv6 {
+			c.output.AddWarning(fmt.Sprintf("Subnetwork %s has internal IPv6 addresses only, which can't reach the internet, egress over IPv6 is bound to fail", vpcSubnetID))
+		}
+	}
+
+	if c.instanceType == "" {
+		machineType, err := c.selectDefaultMachineType(ctx, opts.Architecture())
+		if err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		c.instanceType = machineType
+	}
+	metadata.InstanceType = c.instanceType
+
+	var pscAddresses, pscCheckDomains []string
+	if opts.PrivateServiceConnect {
+		addresses, err := c.pscEndpointAddresses(ctx, vpcSubnetID)
+		switch {
+		case err != nil:
+			c.output.AddError(err)
+		case len(addresses) == 0:
+			c.output.AddFailure(handledErrors.NewPSCError("no Private Service Connect endpoint for Google APIs found in the subnetwork's network"))
+		default:
+			pscAddresses, pscCheckDomains = addresses, pscDomains
+		}
+	}
+
+	// Identifies the results of this run when they're reported through a shared channel
+	c.runID = newRunID()
+	c.batchID = opts.BatchID
+	c.markers = helpers.RunMarkers(opts, c.runID)
+	c.expiresAt = helpers.ResourceExpiry(opts, time.Now())
+	if opts.ResultChannel != "" && opts.ResultChannel != probe.ResultChannelConsole {
+		metadata.RunID = c.runID
+	}
+	if opts.VerifyCleanup {
+		// Deferred to run after the probe instance is terminated, whichever way the run ends
+		defer c.verifyCleanup(ctx)
+	}
+	var callbackURL, callbackToken, callbackCA string
+	if opts.ResultReceiver != nil {
+		callbackURL, callbackToken = opts.ResultReceiver.URL(), opts.ResultReceiver.Token()
+		// The certificate is passed base64-encoded, on a single line of the script
+		callbackCA = base64.StdEncoding.EncodeToString([]byte(opts.ResultReceiver.CACertificate()))
+	}
+
+	if opts.Preset != "" {
+		c.logger.Info(ctx, "Probing the %s endpoints as well", opts.Preset)
+	}
+
 	userDataVariables := map[string]string{
-		"AWS_REGION":               "us-east-2",
-		"USERDATA_BEGIN":           "USERDATA BEGIN",
-		"USERDATA_END":             userdataEndVerifier,
+		// The validator's own endpoint list is AWS-centric and requires an AWS region, the GCP endpoints are probed
+		// through the osd-gcp preset instead
+		"AWS_REGION":               validatorRegion,
+		"USERDATA_BEGIN":           c.markers.Begin,
+		"USERDATA_END":             c.markers.End,
+		"USERDATA_DONE":            c.markers.Done,
 		"VALIDATOR_START_VERIFIER": "VALIDATOR START",
 		"VALIDATOR_END_VERIFIER":   "VALIDATOR END",
-		"VALIDATOR_IMAGE":          networkValidatorImage,
+		"RESULTS_SCHEMA_VERSIONS":  helpers.SupportedSchemaVersions,
+		"VALIDATOR_IMAGE":          metadata.ValidatorImage,
 		"TIMEOUT":                  timeout.String(),
+		"ENDPOINT_TIMEOUTS":        strings.Join(endpoints.Timeouts(timeout), " "),
+		"RETRY_FAILED":             strconv.FormatBool(opts.RetryFailedEndpoints),
+		"RETRY_DELAY_SECONDS":      strconv.Itoa(int(helpers.RetryDelay.Seconds())),
+		"RETRY_TIMEOUT_SECONDS":    strconv.Itoa(int(math.Ceil(timeout.Seconds()))),
 		"HTTP_PROXY":               p.HttpProxy,
 		"HTTPS_PROXY":              p.HttpsProxy,
 		"CACERT":                   base64.StdEncoding.EncodeToString([]byte(p.Cacert)),
 		"NOTLS":                    strconv.FormatBool(p.NoTls),
+		"TRACEROUTE_MAX_ENDPOINTS": strconv.Itoa(helpers.TracerouteMaxEndpoints),
+		"TLS_DIAG_MAX_ENDPOINTS":   strconv.Itoa(helpers.TLSDiagnosticsMaxEndpoints),
+		"PCAP":                     strconv.FormatBool(opts.CapturePackets),
+		"PCAP_MAX_ENDPOINTS":       strconv.Itoa(helpers.PcapMaxEndpoints),
+		"PCAP_MAX_PACKETS":         strconv.Itoa(helpers.PcapMaxPackets),
+		"DNS_SERVERS":              strings.Join(opts.DNSServers, " "),
+		"NAMESERVERS":              strings.Join(opts.Nameservers, " "),
+		"DNS_DOMAINS":              strings.Join(endpoints.Hostnames(), " "),
+		"PSC_DOMAINS":              strings.Join(pscCheckDomains, " "),
+		"RESULT_CHANNEL":           opts.ResultChannel,
+		"RESULT_LOG_NAME":          c.resultLogName(),
+		"RUN_ID":                   c.runID,
+		"CALLBACK_URL":             callbackURL,
+		"CALLBACK_TOKEN":           callbackToken,
+		"CALLBACK_CA":              callbackCA,
+		"EXTRA_ENDPOINTS":          strings.Join(append(endpoints.Extra(opts.Preset, ocpVersion, c.region), opts.LogForwarding.Endpoints()...), " "),
+		"EXTRA_TIMEOUT_SECONDS":    strconv.Itoa(int(math.Ceil(timeout.Seconds()))),
+		"IP_VERSIONS":              strings.Join(metadata.IPVersions, " "),
+		"SOAK_SECONDS":             strconv.Itoa(int(opts.SoakDuration.Seconds())),
+		"SOAK_INTERVAL_SECONDS":    strconv.Itoa(int(opts.SoakIntervalOrDefault().Seconds())),
+		"ATTEMPTS":                 strconv.Itoa(opts.Attempts),
 	}
 
 	userData, err := generateUserData(userDataVariables)
@@ -340,46 +751,89 @@ func (c *Client) validateEgress(ctx context.Context, vpcSubnetID, cloudImageID s
 		return c.output.AddError(err)
 	}
 
-	c.logger.Debug(ctx, "Generated userdata script:\n---\n%s\n---", userData)
+	if c.logger.DebugEnabled() {
+		// The proxy's credentials, the callback token and any private key in the CA bundle are masked
+		redactedUserData, _ := generateUserData(redact.Variables(userDataVariables))
+		c.logger.Debug(ctx, "Generated userdata script:\n---\n%s\n---", redactedUserData)
+	}
 
+	if cloudImageID == "" && opts.Architecture() == probe.ArchitectureArm64 {
+		cloudImageID = defaultArm64ImageFamily
+	}
 	cloudImageID, err = c.setCloudImage(cloudImageID)
 	if err != nil {
 		return c.output.AddError(err) // fatal
 	}
 
+	sourceImage := c.sourceImage(ctx, cloudImageID, opts.ImageCache)
+	metadata.Image = sourceImage
+
 	//for random name
 	rand.Seed(time.Now().UnixNano())
 
 	//image list https://cloud.google.com/compute/docs/images/os-details#red_hat_enterprise_linux_rhel
 
-	instance, err := c.createComputeServiceInstance(ctx, createComputeServiceInstanceInput{
-		vpcSubnetID:  fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", c.projectID, c.region, vpcSubnetID),
-		userdata:     userData,
-		zone:         c.zone,
-		machineType:  c.instanceType,
-		instanceName: fmt.Sprintf("verifier-%v", rand.Intn(10000)),
-		sourceImage:  fmt.Sprintf("projects/cos-cloud/global/images/family/%s", cloudImageID),
-		networkName:  fmt.Sprintf("projects/%s/global/networks/%s", c.projectID, os.Getenv("GCP_VPC_NAME")),
-	})
+	instanceName, err := c.newInstanceName(opts.ResourcePolicy)
 	if err != nil {
-		c.terminateComputeServiceInstance(ctx, instance.instanceName)
 		return c.output.AddError(err) // fatal
 	}
-
-	c.logger.Debug(ctx, "Waiting for ComputeService instance %s to be running", instance.instanceName)
-	if instanceReadyErr := c.waitForComputeServiceInstanceCompletion(ctx, instance.instanceName); instanceReadyErr != nil {
-		c.terminateComputeServiceInstance(ctx, instance.instanceName) // try to terminate the created instance
-		return c.output.AddError(instanceReadyErr)                    // fatal
+	launchInput := createComputeServiceInstanceInput{
+		vpcSubnetID:   c.subnetworkSelfLink(vpcSubnetID),
+		subnetID:      vpcSubnetID,
+		userdata:      userData,
+		zone:          c.zone,
+		machineType:   c.instanceType,
+		instanceName:  instanceName,
+		sourceImage:   sourceImage,
+		networkName:   c.networkName(),
+		preemptible:   opts.Spot,
+		resultChannel: opts.ResultChannel,
+		networkTags:   opts.NetworkTags,
+		stackType:     stackType,
+		externalIPv6:  externalIPv6,
+		kmsKeyName:    kmsKeyID,
+		// The image's family or project tells whether it runs cloud-init
+		scriptMetadataKey: scriptMetadataKey(opts, sourceImage),
+	}
+	metadata.CapacityType = output.CapacityOnDemand
+	if opts.Spot {
+		metadata.CapacityType = output.CapacitySpot
 	}
 
-	c.logger.Info(ctx, "Gathering and parsing console log output...")
+	// A safety brake for runaway automation, launching instances faster than they're cleaned up
+	if err := c.verifyInstanceCap(ctx, opts.MaxInstancesOrDefault()); err != nil {
+		return c.output.AddError(err) // fatal
+	}
 
-	err = c.findUnreachableEndpoints(ctx, instance.instanceName)
+	instance, err := c.launchProbe(ctx, launchInput, opts)
+	if launchInput.preemptible && (isZoneCapacityError(err) || errors.Is(err, errPreempted)) {
+		c.fallBackToOnDemand(ctx, err.Error())
+		launchInput.preemptible = false
+		if launchInput.instanceName, err = c.newInstanceName(opts.ResourcePolicy); err != nil {
+			return c.output.AddError(err) // fatal
+		}
+		instance, err = c.launchProbe(ctx, launchInput, opts)
+	}
 	if err != nil {
-		c.output.AddError(err)
+		return c.output.AddError(err) // fatal
+	}
+	if len(pscCheckDomains) > 0 {
+		c.output.SetPSCResults(markPSCEndpoints(helpers.ParsePSCResults(c.output.ConsoleLogs()), pscAddresses))
 	}
 
+	// The probe has no external IP, so without a proxy its traffic can only reach the internet through Cloud NAT
+	if p.HttpProxy != "" || p.HttpsProxy != "" {
+		metadata.EgressPath = output.EgressPathProxy
+	} else if metadata.EgressIP != "" {
+		metadata.EgressPath = output.EgressPathCloudNAT
+	}
+
+	helpers.EmitEndpointResults(opts, vpcSubnetID, &c.output)
+
 	c.terminateComputeServiceInstance(ctx, instance.instanceName)
+	opts.Emit(events.Event{Type: events.TypeInstanceTerminated, Subnet: vpcSubnetID, InstanceID: instance.instanceName})
+
+	remediation.Apply(&c.output, p)
 
 	return &c.output
 }
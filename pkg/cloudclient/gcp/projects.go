@@ -0,0 +1,56 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2/google"
+)
+
+// ProjectCredentials are credentials verifying across projects: the probe instance is created in InstanceProject,
+// API quota and billing are charged to QuotaProject, and bare subnetwork names are taken to be in NetworkProject,
+// e.g. a Shared VPC host project. Projects left empty default to the credentials' project, and quota to the
+// credentials' own quota project.
+type ProjectCredentials struct {
+	Credentials     *google.Credentials
+	InstanceProject string
+	QuotaProject    string
+	NetworkProject  string
+}
+
+// instanceProject returns the project the probe instance is created in
+func (p ProjectCredentials) instanceProject() (string, error) {
+	if p.InstanceProject != "" {
+		return p.InstanceProject, nil
+	}
+	if p.Credentials == nil || p.Credentials.ProjectID == "" {
+		return "", fmt.Errorf("no project to create the probe instance in")
+	}
+
+	return p.Credentials.ProjectID, nil
+}
+
+// networkProject returns the project of the subnetwork, the instance's unless set
+func (p ProjectCredentials) networkProject(instanceProject string) string {
+	if p.NetworkProject != "" {
+		return p.NetworkProject
+	}
+
+	return instanceProject
+}
+
+// principal returns the service account of the credentials, empty when it isn't known from their JSON, e.g. for
+// impersonated or metadata server credentials
+func (p ProjectCredentials) principal() string {
+	if p.Credentials == nil || len(p.Credentials.JSON) == 0 {
+		return ""
+	}
+	var key struct {
+		ClientEmail string `json:"client_email"`
+	}
+	if err := json.Unmarshal(p.Credentials.JSON, &key); err != nil {
+		return ""
+	}
+
+	return key.ClientEmail
+}
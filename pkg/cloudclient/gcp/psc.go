@@ -0,0 +1,76 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	computev1 "google.golang.org/api/compute/v1"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// pscDomains are the Google APIs cluster nodes call, which must be reached through the Private Service
+// Connect endpoint on PSC-enabled clusters
+var pscDomains = []string{
+	"compute.googleapis.com",
+	"storage.googleapis.com",
+	"iam.googleapis.com",
+	"iamcredentials.googleapis.com",
+	"cloudresourcemanager.googleapis.com",
+	"dns.googleapis.com",
+	"oauth2.googleapis.com",
+	"serviceusage.googleapis.com",
+}
+
+// pscTargets are the forwarding rule targets of Private Service Connect endpoints for Google APIs
+var pscTargets = map[string]bool{
+	"all-apis": true,
+	"vpc-sc":   true,
+}
+
+var networkSelfLinkRe = regexp.MustCompile(`projects/([^/]+)/global/networks/([^/]+)$`)
+
+// pscEndpointAddresses returns the addresses of the Private Service Connect endpoints for Google APIs in the
+// network of the subnet. The endpoints are global forwarding rules, which live in the network's (host) project.
+func (c *Client) pscEndpointAddresses(ctx context.Context, vpcSubnetID string) ([]string, error) {
+	project, region, name, _ := ParseSubnetworkSelfLink(c.subnetworkSelfLink(vpcSubnetID))
+	subnet, err := c.computeService.Subnetworks.Get(project, region, name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get subnetwork %s: %v", name, err)
+	}
+
+	m := networkSelfLinkRe.FindStringSubmatch(subnet.Network)
+	if m == nil {
+		return nil, fmt.Errorf("unable to parse network %s of subnetwork %s", subnet.Network, name)
+	}
+
+	var addresses []string
+	if err := c.computeService.GlobalForwardingRules.List(m[1]).Pages(ctx, func(page *computev1.ForwardingRuleList) error {
+		for _, rule := range page.Items {
+			if pscTargets[rule.Target] && strings.HasSuffix(rule.Network, m[0]) {
+				c.logger.Debug(ctx, "Found Private Service Connect endpoint %s (%s) for %s", rule.Name, rule.IPAddress, rule.Target)
+				addresses = append(addresses, rule.IPAddress)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to list global forwarding rules of project %s: %v", m[1], err)
+	}
+
+	return addresses, nil
+}
+
+// markPSCEndpoints sets ViaEndpoint on the results that resolved to one of the endpoint addresses
+func markPSCEndpoints(results []output.PSCResult, addresses []string) []output.PSCResult {
+	for i, r := range results {
+		for _, address := range addresses {
+			if r.Address == address {
+				results[i].ViaEndpoint = true
+			}
+		}
+	}
+
+	return results
+}
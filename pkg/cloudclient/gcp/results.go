@@ -0,0 +1,162 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	computev1 "google.golang.org/api/compute/v1"
+	loggingv2 "google.golang.org/api/logging/v2"
+
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+const (
+	// resultLogID is the Cloud Logging log the probe writes its results to
+	resultLogID = "osd-network-verifier"
+	// loggingWriteScope lets the probe write its results to Cloud Logging with the default service account
+	loggingWriteScope = "https://www.googleapis.com/auth/logging.write"
+)
+
+// resultLogName is the full name of the Cloud Logging log the probe writes its results to
+func (c *Client) resultLogName() string {
+	return fmt.Sprintf("projects/%s/logs/%s", c.projectID, resultLogID)
+}
+
+// loggingServiceAccounts lets the probe authenticate to Cloud Logging as the project's default service account
+func loggingServiceAccounts() []*computev1.ServiceAccount {
+	return []*computev1.ServiceAccount{{
+		Email:  "default",
+		Scopes: []string{loggingWriteScope},
+	}}
+}
+
+// probeOutput returns the probe's output so far from the requested result channel. When the channel can't be
+// queried, or the console shows the probe finished without its results reaching the channel, the console output is
+// used instead for the rest of the run.
+func (c *Client) probeOutput(ctx context.Context, instanceName string, opts probe.Options) (string, error) {
+	if c.resultsViaConsole {
+		return c.consoleOutput(ctx, instanceName)
+	}
+
+	var (
+		reported string
+		channel  string
+		err      error
+	)
+	switch opts.ResultChannel {
+	case probe.ResultChannelCloudLogging:
+		channel = "Cloud Logging"
+		reported, err = c.cloudLoggingOutput(ctx)
+	case probe.ResultChannelCallback:
+		channel = "the results callback"
+		if opts.ResultReceiver == nil {
+			err = errors.New("no results callback configured")
+		} else {
+			reported, _ = opts.ResultReceiver.Result(c.runID)
+		}
+	default:
+		return c.consoleOutput(ctx, instanceName)
+	}
+	if err != nil {
+		c.logger.Debug(ctx, "Unable to query %s for the results: %v", channel, err)
+		c.output.AddWarning(fmt.Sprintf("the probe's results could not be read from %s, using the console output instead: %v", channel, err))
+		c.resultsViaConsole = true
+		return c.consoleOutput(ctx, instanceName)
+	}
+	if reported != "" {
+		return reported, nil
+	}
+
+	// Nothing reported yet, which is also what a probe unable to reach the channel looks like
+	consoleOutput, err := c.consoleOutput(ctx, instanceName)
+	if err != nil {
+		return "", err
+	}
+	if helpers.CheckConsoleCompletion(consoleOutput, c.markers).Done {
+		c.output.AddWarning(fmt.Sprintf("the probe's results did not reach %s, using the console output instead", channel))
+		c.resultsViaConsole = true
+		return consoleOutput, nil
+	}
+
+	return "", nil
+}
+
+// serialConsoleReads bounds the reads of the serial console output each poll, each returning up to 1MB
+const serialConsoleReads = 8
+
+// serialConsole is the serial console output of an instance read so far
+type serialConsole struct {
+	instanceName string
+	contents     string
+	// next is the offset of the next read
+	next int64
+	// gap is set once the console dropped output before it was read
+	gap bool
+}
+
+// consoleOutput returns the probe instance's serial console output so far. It's read on from where the previous read
+// ended, as each read returns up to 1MB, chatty images otherwise keeping the end of the probe's output out of reach.
+func (c *Client) consoleOutput(ctx context.Context, instanceName string) (string, error) {
+	if c.console.instanceName != instanceName {
+		c.console = serialConsole{instanceName: instanceName}
+	}
+
+	for i := 0; i < serialConsoleReads; i++ {
+		serialOutput, err := c.computeService.Instances.GetSerialPortOutput(c.projectID, c.zone, instanceName).Start(c.console.next).Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		if serialOutput == nil {
+			break
+		}
+		// The console only keeps its latest output, what was printed before it being dropped
+		if serialOutput.Start > c.console.next && !c.console.gap {
+			c.logger.Debug(ctx, "The serial console of %s dropped %d bytes before they were read", instanceName, serialOutput.Start-c.console.next)
+			c.output.AddWarning("the probe instance's serial console dropped part of its output before it was read, part of the probe's output may be missing")
+			c.console.gap = true
+		}
+		c.console.contents += serialOutput.Contents
+		if serialOutput.Next <= c.console.next || serialOutput.Contents == "" {
+			break
+		}
+		c.console.next = serialOutput.Next
+	}
+
+	return c.console.contents, nil
+}
+
+// cloudLoggingOutput returns the results the probe wrote to Cloud Logging for this run, or an empty string if it
+// hasn't written them yet
+func (c *Client) cloudLoggingOutput(ctx context.Context) (string, error) {
+	if c.loggingService == nil {
+		loggingService, err := loggingv2.NewService(ctx, c.clientOptions...)
+		if err != nil {
+			return "", err
+		}
+		c.loggingService = loggingService
+	}
+
+	resp, err := c.loggingService.Entries.List(&loggingv2.ListLogEntriesRequest{
+		ResourceNames: []string{fmt.Sprintf("projects/%s", c.projectID)},
+		Filter:        fmt.Sprintf(`logName=%q AND labels.run_id=%q`, c.resultLogName(), c.runID),
+		OrderBy:       "timestamp desc",
+		PageSize:      1,
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Entries) == 0 {
+		c.logger.Debug(ctx, "No results in Cloud Logging for run %s yet", c.runID)
+		return "", nil
+	}
+
+	logged, err := base64.StdEncoding.DecodeString(resp.Entries[0].TextPayload)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode the results logged for run %s: %v", c.runID, err)
+	}
+
+	return string(logged), nil
+}
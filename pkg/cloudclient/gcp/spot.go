@@ -0,0 +1,93 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	computev1 "google.golang.org/api/compute/v1"
+
+	"github.com/openshift/osd-network-verifier/pkg/events"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// errPreempted is returned when the preemptible probe instance was reclaimed before the probe finished
+var errPreempted = errors.New("the preemptible probe instance was preempted")
+
+// preemptibleScheduling is the scheduling preemptible instances require, they can't restart or live migrate
+func preemptibleScheduling() *computev1.Scheduling {
+	automaticRestart := false
+	return &computev1.Scheduling{
+		Preemptible:       true,
+		AutomaticRestart:  &automaticRestart,
+		OnHostMaintenance: "TERMINATE",
+	}
+}
+
+// preempted reports whether the probe instance stopped without being asked to, i.e. was reclaimed by Compute Engine
+func (c *Client) preempted(ctx context.Context, instanceName string) bool {
+	instance, err := c.computeService.Instances.Get(c.projectID, c.zone, instanceName).Context(ctx).Do()
+	if err != nil {
+		c.logger.Debug(ctx, "Unable to determine whether instance %s was preempted: %v", instanceName, err)
+		return false
+	}
+
+	switch instance.Status {
+	case "STOPPING", "STOPPED", "TERMINATED":
+		return true
+	default:
+		return false
+	}
+}
+
+// fallBackToOnDemand records why the run switched from preemptible to on-demand capacity
+func (c *Client) fallBackToOnDemand(ctx context.Context, reason string) {
+	c.logger.Info(ctx, "Retrying the verification on on-demand capacity: %s", reason)
+	metadata := c.output.Metadata()
+	metadata.CapacityType = output.CapacityOnDemand
+	metadata.CapacityFallback = reason
+}
+
+// launchProbe creates the probe instance, waits for it to run and collects the probe's results. A preemptible instance
+// preempted along the way is stopped and errPreempted returned, so the run can be retried on on-demand capacity.
+// Otherwise, the returned instance is left running for the caller to terminate.
+func (c *Client) launchProbe(ctx context.Context, input createComputeServiceInstanceInput, opts probe.Options) (createComputeServiceInstanceInput, error) {
+	instance, err := c.createComputeServiceInstance(ctx, input)
+	if err != nil {
+		c.terminateComputeServiceInstance(ctx, instance.instanceName)
+		return instance, err
+	}
+
+	metadata := c.output.Metadata()
+	metadata.InstanceID = instance.instanceName
+	metadata.Zone = instance.zone
+	opts.Emit(events.Event{Type: events.TypeInstanceLaunched, Subnet: input.subnetID, InstanceID: instance.instanceName})
+
+	c.logger.Debug(ctx, "Waiting for ComputeService instance %s to be running", instance.instanceName)
+	if instanceReadyErr := c.waitForComputeServiceInstanceCompletion(ctx, instance.instanceName, opts.LaunchTimeoutOrDefault()); instanceReadyErr != nil {
+		preempted := input.preemptible && c.preempted(ctx, instance.instanceName)
+		c.terminateComputeServiceInstance(ctx, instance.instanceName) // try to terminate the created instance
+		if preempted {
+			return instance, fmt.Errorf("%w while starting: %v", errPreempted, instanceReadyErr)
+		}
+		return instance, instanceReadyErr
+	}
+	opts.Emit(events.Event{Type: events.TypeInstanceRunning, Subnet: input.subnetID, InstanceID: instance.instanceName})
+
+	c.logger.Info(ctx, "Gathering and parsing console log output...")
+
+	if err := c.findUnreachableEndpoints(ctx, instance.instanceName, input.subnetID, opts); err != nil {
+		if input.preemptible && c.preempted(ctx, instance.instanceName) {
+			c.terminateComputeServiceInstance(ctx, instance.instanceName)
+			return instance, fmt.Errorf("%w before the probe finished: %v", errPreempted, err)
+		}
+		if errors.Is(err, helpers.ErrPollTimeout) {
+			c.recordPartialResults(input.subnetID, opts)
+		}
+		c.output.AddError(err)
+	}
+
+	return instance, nil
+}
@@ -0,0 +1,68 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	computev1 "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// Stack and IPv6 access types of subnetworks
+const (
+	subnetStackDual    = "IPV4_IPV6"
+	ipv6AccessExternal = "EXTERNAL"
+)
+
+// reservedSubnetworkAddresses are the addresses GCP reserves in every subnetwork's primary range: the network
+// address, the default gateway, the second-to-last and the broadcast address
+const reservedSubnetworkAddresses = 4
+
+// verifySubnetwork checks the subnetwork exists, is in the expected VPC network and is large enough for the probe
+// instance, recording a failure for each problem found. It returns the subnetwork, and false if the probe instance
+// can't be launched into it. The checks are skipped with a warning when the subnetwork can't be looked up otherwise,
+// e.g. without the compute.subnetworks.get permission, in which case no subnetwork is returned.
+func (c *Client) verifySubnetwork(ctx context.Context, vpcSubnetID string, opts probe.Options) (*computev1.Subnetwork, bool) {
+	project, region, name, _ := ParseSubnetworkSelfLink(c.subnetworkSelfLink(vpcSubnetID))
+	subnet, err := c.computeService.Subnetworks.Get(project, region, name).Context(ctx).Do()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnetwork %s not found in region %s of project %s, check its name and region", name, region, project)))
+			return nil, false
+		}
+		c.output.AddWarning(fmt.Sprintf("Unable to look up subnetwork %s to check it before launching: %v", name, err))
+		return nil, true
+	}
+
+	ok := true
+	network := opts.SubnetVPC
+	if network == "" {
+		network = os.Getenv("GCP_VPC_NAME")
+	}
+	if network != "" && path.Base(subnet.Network) != network {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnetwork %s is in VPC network %s, not the expected network %s", name, path.Base(subnet.Network), network)))
+		ok = false
+	}
+
+	if _, cidr, err := net.ParseCIDR(subnet.IpCidrRange); err == nil {
+		if ones, bits := cidr.Mask.Size(); 1<<(bits-ones) <= reservedSubnetworkAddresses {
+			c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnetwork %s's range %s is too small for an instance, as GCP reserves %d addresses of every subnetwork", name, cidr, reservedSubnetworkAddresses)))
+			ok = false
+		}
+	}
+
+	if opts.VerifiesIPv6(false) && subnet.StackType != subnetStackDual {
+		c.output.AddFailure(handledErrors.NewSubnetError(fmt.Sprintf("subnetwork %s is not dual-stack, but egress over IPv6 was requested", name)))
+		ok = false
+	}
+
+	return subnet, ok
+}
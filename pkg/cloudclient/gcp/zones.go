@@ -0,0 +1,103 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	computev1 "google.golang.org/api/compute/v1"
+)
+
+// zoneCapacityErrors identify instance insert failures caused by a zone running out of capacity, which another zone
+// of the region may well have
+var zoneCapacityErrors = []string{
+	"ZONE_RESOURCE_POOL_EXHAUSTED",
+	"does not have enough resources available",
+}
+
+// isZoneCapacityError reports whether err is a stockout of the zone rather than a problem with the request
+func isZoneCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, code := range zoneCapacityErrors {
+		if strings.Contains(err.Error(), code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fallbackZones returns the zones of the region other than current, in name order
+func fallbackZones(current string, regionZones []string) []string {
+	var zones []string
+	for _, zone := range regionZones {
+		if zone = path.Base(zone); zone != current {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+
+	return zones
+}
+
+// insertInstance creates the instance in the client's zone, retrying in the region's other zones when a zone is out of
+// capacity. c.zone is left set to the zone the instance was created in.
+func (c *Client) insertInstance(ctx context.Context, req *computev1.Instance, machineType string) error {
+	err := c.insertInstanceInZone(ctx, req, machineType)
+	if !isZoneCapacityError(err) {
+		return err
+	}
+
+	region, regionErr := c.computeService.Regions.Get(c.projectID, c.region).Context(ctx).Do()
+	if regionErr != nil {
+		c.logger.Debug(ctx, "Unable to list the zones of region %s to retry in: %v", c.region, regionErr)
+		return err
+	}
+
+	originalZone := c.zone
+	for _, zone := range fallbackZones(originalZone, region.Zones) {
+		c.logger.Info(ctx, "Zone %s is out of capacity for %s, retrying in zone %s", c.zone, machineType, zone)
+		c.zone = zone
+		if validateErr := c.validateMachineType(ctx, machineType); validateErr != nil {
+			c.logger.Debug(ctx, "Skipping zone %s: %v", zone, validateErr)
+			continue
+		}
+
+		err = c.insertInstanceInZone(ctx, req, machineType)
+		if !isZoneCapacityError(err) {
+			return err
+		}
+	}
+	c.zone = originalZone
+
+	return err
+}
+
+// insertInstanceInZone creates the instance in c.zone and waits for the insert operation, whose errors include
+// stockouts, to finish
+func (c *Client) insertInstanceInZone(ctx context.Context, req *computev1.Instance, machineType string) error {
+	req.MachineType = fmt.Sprintf("zones/%s/machineTypes/%s", c.zone, machineType)
+
+	op, err := c.computeService.Instances.Insert(c.projectID, c.zone, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to create instance: %v %v", err, op)
+	}
+
+	op, err = c.computeService.ZoneOperations.Wait(c.projectID, c.zone, op.Name).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to create instance: %v", err)
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		var messages []string
+		for _, e := range op.Error.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("unable to create instance: %s", strings.Join(messages, "; "))
+	}
+
+	return nil
+}
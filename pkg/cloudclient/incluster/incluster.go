@@ -0,0 +1,428 @@
+// Package incluster verifies egress from the pod network the verifier runs in. Use pkg/verifier's NewInCluster rather
+// than this package, whose API isn't kept stable.
+package incluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"github.com/openshift/osd-network-verifier/pkg/events"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/remediation"
+	"github.com/openshift/osd-network-verifier/pkg/version"
+)
+
+// ClientIdentifier is what kind of cloud this implement supports
+const ClientIdentifier = "in-cluster"
+
+const (
+	// serviceAccountTokenPath is mounted into every pod running with a service account
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// maxConcurrentProbes bounds how many endpoints are probed at once
+	maxConcurrentProbes = 8
+)
+
+// checkIPURL reports the public IP a request came from
+var checkIPURL = "http://checkip.amazonaws.com"
+
+var errUnsupported = errors.New("not supported when verifying egress from within a cluster")
+
+// Client verifies egress from the network of the pod it runs in, probing the endpoints directly rather than from a
+// probe instance
+type Client struct {
+	logger logging.Logger
+	output output.Output
+	// endpoints are probed as host:port, defaulting to the catalog's hostnames on 443
+	endpoints []string
+}
+
+// Detect returns whether the verifier is running in a cluster pod, i.e. it has a service account token mounted
+func Detect() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return false
+	}
+	_, err := os.Stat(serviceAccountTokenPath)
+
+	return err == nil
+}
+
+// NewClient creates a client probing the egress endpoints from the pod network
+func NewClient(logger logging.Logger) *Client {
+	var eps []string
+	for _, host := range endpoints.Hostnames() {
+		eps = append(eps, net.JoinHostPort(host, "443"))
+	}
+
+	return &Client{logger: redact.Logger(logger), endpoints: eps}
+}
+
+// ByoVPCValidator is not supported from within a cluster, as there are no cloud credentials to inspect the VPC with
+func (c *Client) ByoVPCValidator(ctx context.Context) error {
+	return errUnsupported
+}
+
+// ValidateEgress probes each endpoint from the pod, ignoring the subnet and instance settings as no instance is
+// launched
+func (c *Client) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, p proxy.ProxyConfig, opts probe.Options) *output.Output {
+	c.output.SetConsoleSanitizer(opts.ConsoleSanitizer)
+	opts.Emit(events.Event{Type: events.TypeStarted})
+	defer func() { helpers.EmitFinished(opts, "", &c.output) }()
+	metadata := c.output.Metadata()
+	metadata.Provider = ClientIdentifier
+	metadata.VerifierVersion = version.String()
+	metadata.EgressListVersion = endpoints.CatalogVersion
+	metadata.StartTime = time.Now()
+	defer func() { metadata.EndTime = time.Now() }()
+
+	caFailures, caWarnings := p.VerifyCACert(time.Now())
+	for _, warning := range caWarnings {
+		c.output.AddWarning(warning)
+	}
+	if len(caFailures) > 0 {
+		for _, failure := range caFailures {
+			c.output.AddFailure(failure)
+		}
+		c.logger.Error(ctx, "The CA bundle is unusable, not probing")
+		return &c.output
+	}
+	transport, err := newTransport(p, opts.Nameservers)
+	if err != nil {
+		return c.output.AddError(err) // fatal
+	}
+	c.logger.Info(ctx, "Probing %d endpoints from the pod network", len(c.endpoints))
+
+	results := c.probeAll(ctx, transport, c.endpoints, timeout)
+	var logs strings.Builder
+	var unreachable []string
+	for _, endpoint := range c.endpoints {
+		probed := results[endpoint]
+		result := output.EndpointResult{
+			Endpoint:     endpoint,
+			Success:      probed.err == nil,
+			Latency:      probed.response.ResponseTime,
+			HTTPStatus:   probed.response.Status,
+			RedirectURL:  probed.response.RedirectURL,
+			FailureStage: probed.response.FailureStage,
+			TLSIssuer:    probed.response.TLSIssuer,
+		}
+		if probed.response.FailureStage == output.FailureStageTLS {
+			result.TLS = tlsDiagnostics(ctx, transport, endpoint, endpoints.Timeout(endpoint, timeout), probed.err)
+		}
+		if !result.Success {
+			// The same format as the validator's, so the results are parsed and remediated alike
+			fmt.Fprintf(&logs, "Unable to reach %s: %s\n", endpoint, probed.err)
+			unreachable = append(unreachable, "Unable to reach "+endpoint)
+			result.Note = probed.err.Error()
+		}
+		c.output.AddEndpointResult(result)
+	}
+	c.output.SetEgressFailures(unreachable)
+
+	// Re-probe the unreachable endpoints after a pause, to tell transient blips from blocked egress
+	if opts.RetryFailedEndpoints && len(unreachable) > 0 {
+		var retry []string
+		for endpoint, probed := range results {
+			if probed.err != nil {
+				retry = append(retry, endpoint)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(helpers.RetryDelay):
+			var recovered []string
+			for endpoint, probed := range c.probeAll(ctx, transport, retry, timeout) {
+				if probed.err == nil {
+					recovered = append(recovered, endpoint)
+				}
+			}
+			c.output.MarkRecovered(recovered)
+		}
+	}
+	helpers.EmitEndpointResults(opts, "", &c.output)
+
+	metadata.EgressIP = egressIP(ctx, transport)
+	if p.HttpProxy != "" || p.HttpsProxy != "" {
+		metadata.EgressPath = output.EgressPathProxy
+	}
+	c.output.SetConsoleLogs(logs.String())
+	remediation.Apply(&c.output, p)
+
+	return &c.output
+}
+
+// VerifyDns is not supported from within a cluster, as there are no cloud credentials to inspect the VPC with
+func (c *Client) VerifyDns(ctx context.Context, vpcID string) *output.Output {
+	return c.output.AddError(fmt.Errorf("verifying the DNS of VPC %s is %w", vpcID, errUnsupported))
+}
+
+// DescribeSubnetZones is not supported from within a cluster
+func (c *Client) DescribeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error) {
+	return nil, errUnsupported
+}
+
+// DescribePrivateSubnets is not supported from within a cluster
+func (c *Client) DescribePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error) {
+	return nil, errUnsupported
+}
+
+// probeResult is the outcome of probing an endpoint
+type probeResult struct {
+	response output.HTTPResponse
+	// err is nil if the endpoint was reached
+	err error
+}
+
+// probeAll probes the endpoints concurrently, returning the result of each
+func (c *Client) probeAll(ctx context.Context, transport *http.Transport, eps []string, timeout time.Duration) map[string]probeResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]probeResult, len(eps))
+		slots   = make(chan struct{}, maxConcurrentProbes)
+	)
+	for _, endpoint := range eps {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(endpoint string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			response, err := probeEndpoint(ctx, transport, endpoint, endpoints.Timeout(endpoint, timeout))
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[endpoint] = probeResult{response: response, err: err}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// probeEndpoint makes an HTTPS request to the endpoint, returning its response. Any HTTP response counts as
+// reachable, as it's egress rather than the service being verified.
+func probeEndpoint(ctx context.Context, transport *http.Transport, endpoint string, timeout time.Duration) (output.HTTPResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The stages the request got through, to tell which one it failed at. Through a proxy, these are the stages of
+	// the connection to the proxy, and a proxy refusing the tunnel fails before the TLS handshake starts.
+	var resolved, connected, handshakeStarted, handshaken bool
+	trace := &httptrace.ClientTrace{
+		DNSDone:           func(info httptrace.DNSDoneInfo) { resolved = info.Err == nil },
+		ConnectDone:       func(network, addr string, err error) { connected = connected || err == nil },
+		TLSHandshakeStart: func() { handshakeStarted = true },
+		TLSHandshakeDone:  func(state tls.ConnectionState, err error) { handshaken = err == nil },
+	}
+
+	response := output.HTTPResponse{Endpoint: endpoint}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, "https://"+endpoint, nil)
+	if err != nil {
+		return response, err
+	}
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	response.ResponseTime = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(err, &dnsErr) || (!resolved && !connected && isHostname(endpoint)):
+			response.FailureStage = output.FailureStageDNS
+		case !connected:
+			response.FailureStage = output.FailureStageTCP
+		case handshakeStarted && !handshaken:
+			response.FailureStage = output.FailureStageTLS
+			var unknownAuthority x509.UnknownAuthorityError
+			if errors.As(err, &unknownAuthority) && unknownAuthority.Cert != nil {
+				response.TLSIssuer = unknownAuthority.Cert.Issuer.String()
+			}
+		default:
+			response.FailureStage = output.FailureStageHTTP
+		}
+		return response, err
+	}
+	resp.Body.Close()
+	response.Status = resp.StatusCode
+	response.RedirectURL = resp.Header.Get("Location")
+
+	return response, nil
+}
+
+// tlsVersions are the TLS versions the handshake is re-attempted pinned to, by name
+var tlsVersions = []struct {
+	name    string
+	version uint16
+}{
+	{"1.2", tls.VersionTLS12},
+	{"1.3", tls.VersionTLS13},
+}
+
+// tlsDiagnostics details the endpoint's failed TLS handshake: its error, and the handshakes re-attempted pinned to
+// each TLS version, without verifying the certificate, with the cipher suite and ALPN protocol negotiated
+func tlsDiagnostics(ctx context.Context, transport *http.Transport, endpoint string, timeout time.Duration, err error) *output.TLSDiagnostics {
+	d := &output.TLSDiagnostics{Error: err.Error()}
+	for _, v := range tlsVersions {
+		pinned := transport.Clone()
+		pinned.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, // #nosec G402 -- only to tell protocol problems from certificate ones
+			MinVersion:         v.version,
+			MaxVersion:         v.version,
+			NextProtos:         []string{"h2", "http/1.1"},
+		}
+
+		attempt := output.TLSAttempt{Version: v.name}
+		var handshakeErr error
+		handshaken := false
+		trace := &httptrace.ClientTrace{
+			TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+				handshaken, handshakeErr = err == nil, err
+				if err == nil {
+					attempt.Cipher = tls.CipherSuiteName(state.CipherSuite)
+					attempt.ALPN = state.NegotiatedProtocol
+				}
+			},
+		}
+		attemptCtx, cancel := context.WithTimeout(httptrace.WithClientTrace(ctx, trace), timeout)
+		req, reqErr := http.NewRequestWithContext(attemptCtx, http.MethodHead, "https://"+endpoint, nil)
+		if reqErr == nil {
+			// Only the handshake matters, the request fails anyway when h2 is negotiated
+			if resp, err := pinned.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			} else if handshakeErr == nil && !handshaken {
+				handshakeErr = err
+			}
+		}
+		cancel()
+		pinned.CloseIdleConnections()
+
+		attempt.Success = handshaken
+		if handshakeErr != nil {
+			attempt.Error = handshakeErr.Error()
+		}
+		d.Attempts = append(d.Attempts, attempt)
+	}
+
+	return d
+}
+
+// isHostname returns whether the endpoint's host is a name to resolve rather than an IP address
+func isHostname(endpoint string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+
+	return net.ParseIP(host) == nil
+}
+
+// egressIP returns the public IP the pod's traffic reaches the internet from, or an empty string if it can't be
+// looked up
+func egressIP(ctx context.Context, transport *http.Transport) string {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkIPURL, nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	return strings.TrimSpace(string(body))
+}
+
+// newTransport builds a transport going through the proxy, if any, and trusting its CA certificate. Lookups go through
+// the nameservers given, rather than the pod's, when there are any.
+func newTransport(p proxy.ProxyConfig, nameservers []string) (*http.Transport, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.NoTls} // #nosec G402 -- only when asked to with --no-tls
+	if p.Cacert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(p.Cacert)) {
+			return nil, fmt.Errorf("no certificates found in the CA certificate given")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}
+	if len(nameservers) > 0 {
+		resolver, err := newResolver(nameservers)
+		if err != nil {
+			return nil, err
+		}
+		transport.DialContext = (&net.Dialer{Resolver: resolver}).DialContext
+	}
+	httpProxy, httpsProxy := p.HttpProxy, p.HttpsProxy
+	if httpsProxy == "" {
+		httpsProxy = httpProxy
+	}
+	if httpProxy != "" || httpsProxy != "" {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			proxyURL := httpProxy
+			if req.URL.Scheme == "https" {
+				proxyURL = httpsProxy
+			}
+			if proxyURL == "" {
+				return nil, nil
+			}
+			return url.Parse(proxyURL)
+		}
+	}
+
+	return transport, nil
+}
+
+// newResolver returns a resolver querying the nameservers given, each an IP with an optional port defaulting to 53.
+// Every query is sent to the next nameserver in turn, so the resolver's retries reach the others when one is down.
+func newResolver(nameservers []string) (*net.Resolver, error) {
+	addresses := make([]string, 0, len(nameservers))
+	for _, nameserver := range nameservers {
+		host, port, err := net.SplitHostPort(nameserver)
+		if err != nil {
+			host, port = strings.Trim(nameserver, "[]"), "53"
+		}
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("nameserver %s is not an IP address", nameserver)
+		}
+		addresses = append(addresses, net.JoinHostPort(host, port))
+	}
+
+	var next uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			address := addresses[(atomic.AddUint32(&next, 1)-1)%uint32(len(addresses))]
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}, nil
+}
@@ -0,0 +1,134 @@
+package incluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/events"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEgress(t *testing.T) {
+	reachable := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer reachable.Close()
+	checkIP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "203.0.113.7")
+	}))
+	defer checkIP.Close()
+	defer func(url string) { checkIPURL = url }(checkIPURL)
+	checkIPURL = checkIP.URL
+
+	// Nothing listens on a port just closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := l.Addr().String()
+	l.Close()
+
+	c := NewClient(&ocmlog.StdLogger{})
+	c.endpoints = []string{strings.TrimPrefix(reachable.URL, "https://"), unreachable}
+	var stream bytes.Buffer
+	out := c.ValidateEgress(context.TODO(), "", "", "", "", time.Second, proxy.ProxyConfig{NoTls: true}, probe.Options{Events: events.NewNDJSON(&stream)})
+
+	assert.False(t, out.IsSuccessful())
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(stream.String()), "\n") {
+		var e events.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{events.TypeStarted, events.TypeEndpoint, events.TypeEndpoint, events.TypeFinished}, types)
+	results := out.EndpointResults()
+	assert.Len(t, results, 2)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, http.StatusForbidden, results[0].HTTPStatus)
+	assert.False(t, results[1].Success)
+	assert.Zero(t, results[1].HTTPStatus)
+	assert.Equal(t, output.FailureStageTCP, results[1].FailureStage)
+	assert.Equal(t, unreachable, results[1].Endpoint)
+	assert.Contains(t, out.ConsoleLogs(), "Unable to reach "+unreachable)
+	assert.Equal(t, ClientIdentifier, out.Metadata().Provider)
+	assert.Equal(t, "203.0.113.7", out.Metadata().EgressIP)
+
+	// Without --no-tls, the test server's self-signed certificate isn't trusted
+	c = NewClient(&ocmlog.StdLogger{})
+	c.endpoints = []string{strings.TrimPrefix(reachable.URL, "https://")}
+	out = c.ValidateEgress(context.TODO(), "", "", "", "", time.Second, proxy.ProxyConfig{}, probe.Options{})
+	assert.False(t, out.IsSuccessful())
+	assert.Equal(t, output.FailureStageTLS, out.EndpointResults()[0].FailureStage)
+}
+
+func TestTLSDiagnostics(t *testing.T) {
+	// A server only speaking TLS 1.2, with a certificate that isn't trusted
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	c := NewClient(&ocmlog.StdLogger{})
+	c.endpoints = []string{strings.TrimPrefix(server.URL, "https://")}
+	out := c.ValidateEgress(context.TODO(), "", "", "", "", time.Second, proxy.ProxyConfig{}, probe.Options{})
+
+	result := out.EndpointResults()[0]
+	assert.Equal(t, "O=Acme Co", result.TLSIssuer, "the issuer of the certificate that isn't trusted is reported")
+	if assert.NotNil(t, result.TLS) {
+		assert.Contains(t, result.TLS.Error, "certificate")
+		if assert.Len(t, result.TLS.Attempts, 2) {
+			assert.Equal(t, "1.2", result.TLS.Attempts[0].Version)
+			assert.True(t, result.TLS.Attempts[0].Success, "the certificate isn't verified re-attempting the handshake")
+			assert.NotEmpty(t, result.TLS.Attempts[0].Cipher)
+			assert.Equal(t, "1.3", result.TLS.Attempts[1].Version)
+			assert.False(t, result.TLS.Attempts[1].Success)
+			assert.NotEmpty(t, result.TLS.Attempts[1].Error)
+		}
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	_, err := newResolver([]string{"dns.example.com"})
+	assert.Error(t, err, "nameservers must be IP addresses")
+
+	// A nameserver that never answers, only noting it was queried
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	queried := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 512)
+		if _, _, err := conn.ReadFrom(buf); err == nil {
+			queried <- struct{}{}
+		}
+	}()
+
+	resolver, err := newResolver([]string{conn.LocalAddr().String()})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 500*time.Millisecond)
+	defer cancel()
+	_, _ = resolver.LookupHost(ctx, "quay.io")
+
+	select {
+	case <-queried:
+	case <-time.After(time.Second):
+		t.Error("the lookup wasn't sent to the nameserver given")
+	}
+}
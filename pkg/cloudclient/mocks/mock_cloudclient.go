@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	output "github.com/openshift/osd-network-verifier/pkg/output"
+	probe "github.com/openshift/osd-network-verifier/pkg/probe"
 	proxy "github.com/openshift/osd-network-verifier/pkg/proxy"
 )
 
@@ -51,18 +52,48 @@ func (mr *MockCloudClientMockRecorder) ByoVPCValidator(ctx interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ByoVPCValidator", reflect.TypeOf((*MockCloudClient)(nil).ByoVPCValidator), ctx)
 }
 
+// DescribePrivateSubnets mocks base method.
+func (m *MockCloudClient) DescribePrivateSubnets(ctx context.Context, vpcID string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribePrivateSubnets", ctx, vpcID)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribePrivateSubnets indicates an expected call of DescribePrivateSubnets.
+func (mr *MockCloudClientMockRecorder) DescribePrivateSubnets(ctx, vpcID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribePrivateSubnets", reflect.TypeOf((*MockCloudClient)(nil).DescribePrivateSubnets), ctx, vpcID)
+}
+
+// DescribeSubnetZones mocks base method.
+func (m *MockCloudClient) DescribeSubnetZones(ctx context.Context, subnetIDs []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeSubnetZones", ctx, subnetIDs)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSubnetZones indicates an expected call of DescribeSubnetZones.
+func (mr *MockCloudClientMockRecorder) DescribeSubnetZones(ctx, subnetIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSubnetZones", reflect.TypeOf((*MockCloudClient)(nil).DescribeSubnetZones), ctx, subnetIDs)
+}
+
 // ValidateEgress mocks base method.
-func (m *MockCloudClient) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig) *output.Output {
+func (m *MockCloudClient) ValidateEgress(ctx context.Context, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId string, timeout time.Duration, proxy proxy.ProxyConfig, opts probe.Options) *output.Output {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ValidateEgress", ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy)
+	ret := m.ctrl.Call(m, "ValidateEgress", ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy, opts)
 	ret0, _ := ret[0].(*output.Output)
 	return ret0
 }
 
 // ValidateEgress indicates an expected call of ValidateEgress.
-func (mr *MockCloudClientMockRecorder) ValidateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy interface{}) *gomock.Call {
+func (mr *MockCloudClientMockRecorder) ValidateEgress(ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy, opts interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateEgress", reflect.TypeOf((*MockCloudClient)(nil).ValidateEgress), ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateEgress", reflect.TypeOf((*MockCloudClient)(nil).ValidateEgress), ctx, vpcSubnetID, cloudImageID, kmsKeyID, securityGroupId, timeout, proxy, opts)
 }
 
 // VerifyDns mocks base method.
@@ -0,0 +1,99 @@
+package cloudclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platforms NewClient can build a client for, as given to --platform
+const (
+	PlatformAWS = "aws"
+	PlatformGCP = "gcp"
+	// PlatformROSAHCP is ROSA with hosted control planes, on AWS
+	PlatformROSAHCP = "rosa-hcp"
+	// PlatformOSDGCP is OpenShift Dedicated on GCP
+	PlatformOSDGCP = "osd-gcp"
+)
+
+// SupportedPlatforms lists the valid --platform values
+var SupportedPlatforms = []string{PlatformAWS, PlatformGCP, PlatformROSAHCP, PlatformOSDGCP}
+
+// platformProviders maps the platforms that are a flavour of cluster, with endpoints of their own, to the cloud
+// provider they run on
+var platformProviders = map[string]string{
+	PlatformROSAHCP: PlatformAWS,
+	PlatformOSDGCP:  PlatformGCP,
+}
+
+// Provider returns the cloud provider a platform runs on, which is the platform itself unless it's a flavour of
+// cluster
+func Provider(platform string) string {
+	if provider, ok := platformProviders[platform]; ok {
+		return provider
+	}
+
+	return platform
+}
+
+// credentialSources are the environment variables that indicate credentials for each platform
+var credentialSources = []struct {
+	platform string
+	name     string
+	envVars  []string
+}{
+	{platform: PlatformAWS, name: "AWS", envVars: []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE"}},
+	{platform: PlatformGCP, name: "GCP", envVars: []string{"GOOGLE_APPLICATION_CREDENTIALS"}},
+	{name: "Azure", envVars: []string{"AZURE_CLIENT_ID", "AZURE_TENANT_ID", "AZURE_SUBSCRIPTION_ID"}},
+}
+
+// DetectPlatform picks the platform whose credentials are present, based on the environment (looked up with
+// getenv) and the AWS profile given on the command line, if any. With no credentials found AWS is assumed, as it
+// can also pick up e.g. instance roles. When credentials are found for several platforms, the subnet ID tells them
+// apart, AWS subnet IDs starting with "subnet-" unlike GCP subnet names and self-links, AWS being kept when it
+// doesn't, and a warning describing the ambiguity is returned alongside. The error lists the credentials found when
+// they're only for an unsupported platform.
+func DetectPlatform(getenv func(string) string, awsProfile, subnetID string) (string, string, error) {
+	var found, unsupported []string
+	platforms := map[string]bool{}
+	for _, source := range credentialSources {
+		var present []string
+		if source.platform == PlatformAWS && awsProfile != "" {
+			present = append(present, "--profile")
+		}
+		for _, envVar := range source.envVars {
+			if getenv(envVar) != "" {
+				present = append(present, envVar)
+			}
+		}
+		if len(present) == 0 {
+			continue
+		}
+
+		description := fmt.Sprintf("%s (%s)", source.name, strings.Join(present, ", "))
+		if source.platform == "" {
+			unsupported = append(unsupported, description)
+			continue
+		}
+		found = append(found, description)
+		platforms[source.platform] = true
+	}
+
+	switch {
+	case len(platforms) > 1:
+		platform, reason := PlatformAWS, "AWS"
+		if subnetID != "" && !strings.HasPrefix(subnetID, "subnet-") {
+			platform, reason = PlatformGCP, fmt.Sprintf("GCP, as subnet %s isn't an AWS subnet ID", subnetID)
+		} else if subnetID != "" {
+			reason = fmt.Sprintf("AWS, as subnet %s is an AWS subnet ID", subnetID)
+		}
+		return platform, fmt.Sprintf("found credentials for several platforms: %s, using %s; pass --platform to choose one", strings.Join(found, "; "), reason), nil
+	case len(platforms) == 1:
+		for platform := range platforms {
+			return platform, "", nil
+		}
+	case len(unsupported) > 0:
+		return "", "", fmt.Errorf("found credentials only for unsupported platforms: %s, supported platforms are %v", strings.Join(unsupported, "; "), SupportedPlatforms)
+	}
+
+	return PlatformAWS, "", nil
+}
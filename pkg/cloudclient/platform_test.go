@@ -0,0 +1,108 @@
+package cloudclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		awsProfile  string
+		subnetID    string
+		expected    string
+		expectedErr string
+		ambiguous   bool
+	}{
+		{
+			name:     "no credentials defaults to AWS",
+			expected: PlatformAWS,
+		},
+		{
+			name:     "AWS access key",
+			env:      map[string]string{"AWS_ACCESS_KEY_ID": "AKIA"},
+			expected: PlatformAWS,
+		},
+		{
+			name:       "AWS profile flag",
+			awsProfile: "default",
+			expected:   PlatformAWS,
+		},
+		{
+			name:     "GCP service account",
+			env:      map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			expected: PlatformGCP,
+		},
+		{
+			name:      "ambiguous keeps AWS",
+			env:       map[string]string{"AWS_PROFILE": "default", "GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			expected:  PlatformAWS,
+			ambiguous: true,
+		},
+		{
+			name:      "ambiguous with an AWS subnet ID",
+			env:       map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			subnetID:  "subnet-0123456789abcdef0",
+			expected:  PlatformAWS,
+			ambiguous: true,
+		},
+		{
+			name:      "ambiguous with a GCP subnet name",
+			env:       map[string]string{"AWS_PROFILE": "default", "GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			subnetID:  "master-subnet",
+			expected:  PlatformGCP,
+			ambiguous: true,
+		},
+		{
+			name:      "ambiguous with a GCP subnet self-link",
+			env:       map[string]string{"AWS_PROFILE": "default", "GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			subnetID:  "projects/p/regions/us-east1/subnetworks/worker",
+			expected:  PlatformGCP,
+			ambiguous: true,
+		},
+		{
+			name:        "only Azure",
+			env:         map[string]string{"AZURE_CLIENT_ID": "id"},
+			expectedErr: "Azure (AZURE_CLIENT_ID)",
+		},
+		{
+			name:     "Azure alongside a supported platform",
+			env:      map[string]string{"AZURE_CLIENT_ID": "id", "GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"},
+			expected: PlatformGCP,
+		},
+	}
+
+	for _, test := range tests {
+		getenv := func(key string) string { return test.env[key] }
+		platform, warning, err := DetectPlatform(getenv, test.awsProfile, test.subnetID)
+		if test.expectedErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("%s: expected error containing %q, got %v", test.name, test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if platform != test.expected {
+			t.Errorf("%s: expected platform %s, got %s", test.name, test.expected, platform)
+		}
+		if test.ambiguous != strings.Contains(warning, "AWS (") || test.ambiguous != strings.Contains(warning, "GCP (GOOGLE_APPLICATION_CREDENTIALS)") {
+			t.Errorf("%s: expected a warning listing the credentials found only when ambiguous, got %q", test.name, warning)
+		}
+	}
+}
+
+func TestProvider(t *testing.T) {
+	for platform, expected := range map[string]string{
+		PlatformAWS:     PlatformAWS,
+		PlatformGCP:     PlatformGCP,
+		PlatformROSAHCP: PlatformAWS,
+		PlatformOSDGCP:  PlatformGCP,
+	} {
+		if provider := Provider(platform); provider != expected {
+			t.Errorf("%s: expected provider %s, got %s", platform, expected, provider)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package clusterproxy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// proxyName is the name of the cluster-wide Proxy object
+	proxyName = "cluster"
+	// trustedCANamespace holds the configmap the Proxy's trustedCA names
+	trustedCANamespace = "openshift-config"
+	// trustedCAKey is the configmap key holding the PEM-encoded CA bundle
+	trustedCAKey = "ca-bundle.crt"
+)
+
+var proxyGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Proxy"}
+
+// Config is the cluster-wide proxy configuration
+type Config struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// CACert is the PEM-encoded CA bundle of the Proxy's trustedCA configmap
+	CACert string
+}
+
+// NewClient builds a client able to read the proxy configuration of the cluster
+func NewClient(cfg *rest.Config) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// Get reads the cluster's Proxy object and its trusted CA bundle. The Proxy's spec is used rather than its status, so
+// changes not yet rolled out to nodes are verified.
+func Get(ctx context.Context, c client.Client) (*Config, error) {
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(proxyGVK)
+	if err := c.Get(ctx, client.ObjectKey{Name: proxyName}, proxy); err != nil {
+		return nil, fmt.Errorf("unable to get the cluster proxy configuration: %w", err)
+	}
+
+	spec := func(field ...string) string {
+		value, _, _ := unstructured.NestedString(proxy.Object, append([]string{"spec"}, field...)...)
+		return value
+	}
+	config := &Config{
+		HTTPProxy:  spec("httpProxy"),
+		HTTPSProxy: spec("httpsProxy"),
+		NoProxy:    spec("noProxy"),
+	}
+
+	if name := spec("trustedCA", "name"); name != "" {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: trustedCANamespace, Name: name}, cm); err != nil {
+			return nil, fmt.Errorf("unable to get the proxy's trusted CA configmap %s/%s: %w", trustedCANamespace, name, err)
+		}
+		config.CACert = cm.Data[trustedCAKey]
+		if config.CACert == "" {
+			return nil, fmt.Errorf("the proxy's trusted CA configmap %s/%s has no %s", trustedCANamespace, name, trustedCAKey)
+		}
+	}
+
+	return config, nil
+}
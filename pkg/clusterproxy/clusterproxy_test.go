@@ -0,0 +1,49 @@
+package clusterproxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGet(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	proxy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "cluster"},
+		"spec": map[string]interface{}{
+			"httpProxy":  "http://proxy:3128",
+			"httpsProxy": "http://proxy:3128",
+			"noProxy":    ".example.com",
+			"trustedCA":  map[string]interface{}{"name": "user-ca-bundle"},
+		},
+	}}
+	proxy.SetGroupVersionKind(proxyGVK)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-config", Name: "user-ca-bundle"},
+		Data:       map[string]string{"ca-bundle.crt": "-----BEGIN CERTIFICATE-----"},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(proxy, cm).Build()
+	config, err := Get(context.TODO(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "http://proxy:3128",
+		NoProxy:    ".example.com",
+		CACert:     "-----BEGIN CERTIFICATE-----",
+	}, config)
+
+	// A trusted CA configmap that's gone is an error rather than silently verifying without it
+	c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(proxy).Build()
+	_, err = Get(context.TODO(), c)
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package egresslist
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// allowlistEntry is a rule of a customer's firewall allowlist
+type allowlistEntry struct {
+	// host is an exact hostname, or a domain suffix starting with "."
+	host string
+	// port is 0 when the rule allows any port
+	port int
+}
+
+// Allowlist is a customer's firewall allowlist, to compare the required endpoints against before launching anything
+type Allowlist []allowlistEntry
+
+// LoadAllowlist reads an allowlist file
+func LoadAllowlist(file string) (Allowlist, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read allowlist %s: %w", file, err)
+	}
+
+	allowlist, err := ParseAllowlist(data)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist %s: %w", file, err)
+	}
+
+	return allowlist, nil
+}
+
+// ParseAllowlist parses an allowlist of one rule per line, ignoring blank lines and # comments. A rule is a hostname,
+// or a domain suffix written as *.example.com or .example.com, optionally followed by :port. Only the first
+// comma-separated field of a line is used, so a CSV export of a list can be compared as is.
+func ParseAllowlist(data []byte) (Allowlist, error) {
+	var allowlist Allowlist
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.Index(line, ","); i >= 0 {
+			// host,port,... as exported
+			fields := strings.Split(line, ",")
+			line = fields[0]
+			if port := strings.TrimSpace(fields[1]); port != "" && port != "port" {
+				line = net.JoinHostPort(strings.TrimSpace(line), port)
+			}
+		}
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || line == "host" {
+			continue
+		}
+
+		entry := allowlistEntry{host: line}
+		if host, port, err := net.SplitHostPort(line); err == nil {
+			entry.host = host
+			if entry.port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("line %d: invalid port %s", n, port)
+			}
+		}
+		entry.host = strings.TrimPrefix(entry.host, "*")
+		if entry.host == "" || entry.host == "." {
+			return nil, fmt.Errorf("line %d: no hostname", n)
+		}
+		allowlist = append(allowlist, entry)
+	}
+
+	return allowlist, scanner.Err()
+}
+
+// Allows returns whether a rule of the allowlist covers the endpoint. A wildcard endpoint is only covered by a rule
+// for the same or a broader domain suffix.
+func (a Allowlist) Allows(e Entry) bool {
+	host := strings.ToLower(e.Host)
+	if strings.HasPrefix(host, "*.") {
+		host = host[1:]
+	}
+	for _, rule := range a {
+		if rule.port != 0 && rule.port != e.Port {
+			continue
+		}
+		// .example.com allows example.com itself as well as its subdomains
+		if rule.host == host || rule.host == "."+host || (strings.HasPrefix(rule.host, ".") && strings.HasSuffix(host, rule.host)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Missing returns the endpoints of the list the allowlist doesn't cover
+func (l *List) Missing(a Allowlist) []Entry {
+	var missing []Entry
+	for _, e := range l.Endpoints {
+		if !a.Allows(e) {
+			missing = append(missing, e)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,150 @@
+package egresslist
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"sigs.k8s.io/yaml"
+)
+
+// Protocols an endpoint can be probed with
+const (
+	ProtocolTCP   = "tcp"
+	ProtocolHTTP  = "http"
+	ProtocolHTTPS = "https"
+)
+
+// labelRe matches a DNS label, see RFC 1123
+var labelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// List is an egress list file, describing the endpoints a cluster needs to reach
+type List struct {
+	// Version identifies the revision of the list
+	Version   string  `json:"version"`
+	Endpoints []Entry `json:"endpoints"`
+}
+
+// Entry is an endpoint of an egress list
+type Entry struct {
+	// Host is either an exact hostname or, if prefixed with "*.", a domain suffix
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Protocol is one of the Protocol constants, defaulting to tcp
+	Protocol string `json:"protocol,omitempty"`
+	// Category is the cluster service that breaks if the endpoint is unreachable, one of endpoints.Services
+	Category string `json:"category"`
+}
+
+// Load reads an egress list file
+func Load(file string) (*List, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read egress list %s: %w", file, err)
+	}
+
+	list, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("egress list %s: %w", file, err)
+	}
+
+	return list, nil
+}
+
+// Parse parses an egress list, rejecting unknown fields so misspelt ones don't go unnoticed
+func Parse(data []byte) (*List, error) {
+	list := &List{}
+	if err := yaml.UnmarshalStrict(data, list); err != nil {
+		return nil, fmt.Errorf("unable to parse egress list: %w", err)
+	}
+
+	return list, nil
+}
+
+// Validate describes every problem of the list: missing fields, malformed hostnames, duplicate endpoints, ports
+// out of range or at odds with the protocol, unknown categories, and categories without any endpoint. An empty
+// result means the list is valid.
+func (l *List) Validate() []string {
+	var problems []string
+	if l.Version == "" {
+		problems = append(problems, "version is required")
+	}
+	if len(l.Endpoints) == 0 {
+		return append(problems, "no endpoints listed")
+	}
+
+	knownCategories := map[string]bool{}
+	for _, category := range endpoints.Services() {
+		knownCategories[category] = true
+	}
+	covered := map[string]bool{}
+	seen := map[string]int{}
+	for i, e := range l.Endpoints {
+		name := fmt.Sprintf("endpoint %d (%s)", i+1, e.Host)
+		if e.Host == "" {
+			problems = append(problems, fmt.Sprintf("endpoint %d: host is required", i+1))
+		} else if err := validHost(e.Host); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, err))
+		}
+
+		if e.Port < 1 || e.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s: port %d is out of range", name, e.Port))
+		}
+		switch e.Protocol {
+		case "", ProtocolTCP:
+		case ProtocolHTTP:
+			if e.Port == 443 {
+				problems = append(problems, fmt.Sprintf("%s: protocol http on port 443, expected https", name))
+			}
+		case ProtocolHTTPS:
+			if e.Port == 80 {
+				problems = append(problems, fmt.Sprintf("%s: protocol https on port 80, expected http", name))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown protocol %q, must be one of %s, %s or %s", name, e.Protocol, ProtocolTCP, ProtocolHTTP, ProtocolHTTPS))
+		}
+
+		if !knownCategories[e.Category] {
+			problems = append(problems, fmt.Sprintf("%s: unknown category %q, must be one of %q", name, e.Category, endpoints.Services()))
+		}
+		covered[e.Category] = true
+
+		key := e.Host + ":" + strconv.Itoa(e.Port)
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: duplicates endpoint %d, %s", name, first, key))
+		} else {
+			seen[key] = i + 1
+		}
+	}
+
+	for _, category := range endpoints.Services() {
+		if !covered[category] {
+			problems = append(problems, fmt.Sprintf("no endpoint for the %s category", category))
+		}
+	}
+
+	return problems
+}
+
+// validHost checks that host is a hostname, optionally prefixed with "*." to match a domain suffix
+func validHost(host string) error {
+	name := strings.TrimPrefix(host, "*.")
+	if len(name) > 253 {
+		return fmt.Errorf("hostname is longer than 253 characters")
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("hostname %q is not fully qualified", host)
+	}
+	for _, label := range labels {
+		if !labelRe.MatchString(label) {
+			return fmt.Errorf("hostname %q has an invalid label %q", host, label)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package egresslist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	list, err := Parse([]byte(`version: "1"
+endpoints:
+- {host: observatorium-mst.api.openshift.com, port: 443, protocol: https, category: telemetry}
+- {host: console.redhat.com, port: 443, category: insights}
+- {host: "*.quay.io", port: 443, category: image registry}
+- {host: sso.redhat.com, port: 443, category: OIDC}
+- {host: api.openshift.com, port: 443, category: update service}
+- {host: api.pagerduty.com, port: 443, category: support}
+- {host: ec2.us-east-1.amazonaws.com, port: 443, category: cloud provider API}
+`))
+	assert.NoError(t, err)
+	assert.Empty(t, list.Validate())
+
+	list, err = Parse([]byte(`endpoints:
+- {host: quay.io, port: 443, protocol: http, category: image registry}
+- {host: quay.io, port: 443, category: image registry}
+- {host: bad_host.example.com, port: 0, category: telemetry}
+- {host: localhost, port: 80, protocol: udp, category: other}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"version is required",
+		"endpoint 1 (quay.io): protocol http on port 443, expected https",
+		"endpoint 2 (quay.io): duplicates endpoint 1, quay.io:443",
+		`endpoint 3 (bad_host.example.com): hostname "bad_host.example.com" has an invalid label "bad_host"`,
+		"endpoint 3 (bad_host.example.com): port 0 is out of range",
+		`endpoint 4 (localhost): hostname "localhost" is not fully qualified`,
+		`endpoint 4 (localhost): unknown protocol "udp", must be one of tcp, http or https`,
+		`endpoint 4 (localhost): unknown category "other", must be one of ["telemetry" "insights" "image registry" "OIDC" "update service" "support" "cloud provider API"]`,
+		"no endpoint for the insights category",
+		"no endpoint for the OIDC category",
+		"no endpoint for the update service category",
+		"no endpoint for the support category",
+		"no endpoint for the cloud provider API category",
+	}, list.Validate())
+
+	_, err = Parse([]byte(`version: "1"
+endpoint: []
+`))
+	assert.Error(t, err)
+}
+
+func TestEffective(t *testing.T) {
+	list, err := Effective("rosa-hcp", "4.12", "us-west-2")
+	assert.NoError(t, err)
+	assert.Contains(t, list.Endpoints, Entry{Host: "sts.us-west-2.amazonaws.com", Port: 443, Protocol: ProtocolHTTPS, Category: "cloud provider API"})
+	for _, e := range list.Endpoints {
+		assert.NotEmpty(t, e.Category, e.Host)
+	}
+
+	var csv strings.Builder
+	assert.NoError(t, (&List{Endpoints: list.Endpoints[:1]}).Write(&csv, FormatCSV))
+	assert.Equal(t, "host,port,protocol,category\nregistry.redhat.io,443,https,image registry\n", csv.String())
+	assert.Error(t, list.Write(&csv, "xml"))
+}
+
+func TestMissing(t *testing.T) {
+	allowlist, err := ParseAllowlist([]byte(`# the proxy's allowlist
+.redhat.io
+*.quay.io
+quay.io:443
+api.openshift.com:80
+host,port,protocol,category
+sso.redhat.com,443,https,OIDC
+`))
+	assert.NoError(t, err)
+
+	list := &List{Endpoints: []Entry{
+		{Host: "registry.redhat.io", Port: 443},
+		{Host: "redhat.io", Port: 443},
+		{Host: "quay.io", Port: 443},
+		{Host: "*.quay.io", Port: 443},
+		{Host: "api.openshift.com", Port: 443},
+		{Host: "sso.redhat.com", Port: 443},
+		{Host: "*.openshiftapps.com", Port: 443},
+	}}
+	assert.Equal(t, []Entry{
+		{Host: "api.openshift.com", Port: 443},
+		{Host: "*.openshiftapps.com", Port: 443},
+	}, list.Missing(allowlist))
+
+	_, err = ParseAllowlist([]byte("quay.io:https\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package egresslist
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	"sigs.k8s.io/yaml"
+)
+
+// Formats a list can be exported in
+const (
+	FormatYAML = "yaml"
+	FormatCSV  = "csv"
+)
+
+// csvHeader names the columns of a CSV export
+var csvHeader = []string{"host", "port", "protocol", "category"}
+
+// Effective returns the endpoints the verifier probes beyond the validator's own list for the OpenShift version and
+// the preset, if any, in the region, categorized by the catalog
+func Effective(preset, ocpVersion, region string) (*List, error) {
+	list := &List{Version: endpoints.CatalogVersion}
+	for _, endpoint := range endpoints.Extra(preset, ocpVersion, region) {
+		host, portValue, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %s: %w", endpoint, err)
+		}
+		port, err := strconv.Atoi(portValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port of endpoint %s: %w", endpoint, err)
+		}
+
+		protocol := ProtocolTCP
+		if port == 443 {
+			protocol = ProtocolHTTPS
+		}
+		list.Endpoints = append(list.Endpoints, Entry{
+			Host:     host,
+			Port:     port,
+			Protocol: protocol,
+			Category: endpoints.Lookup(host).RequiredBy,
+		})
+	}
+
+	return list, nil
+}
+
+// Write writes the list in the given format, one of the Format constants
+func (l *List) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatYAML:
+		data, err := yaml.Marshal(l)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, e := range l.Endpoints {
+			if err := cw.Write([]string{e.Host, strconv.Itoa(e.Port), e.Protocol, e.Category}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %s, must be %s or %s", format, FormatYAML, FormatCSV)
+	}
+}
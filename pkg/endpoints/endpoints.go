@@ -0,0 +1,160 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// firewallPrerequisitesURL lists every endpoint a ROSA/OSD cluster needs to reach
+	firewallPrerequisitesURL = "https://docs.openshift.com/rosa/rosa_install_access_delete_clusters/rosa_getting_started_iam/rosa-aws-prereqs.html#osd-aws-privatelink-firewall-prerequisites_rosa-aws-prereqs"
+	// configuringFirewallURL explains the endpoints OpenShift itself depends on
+	configuringFirewallURL = "https://docs.openshift.com/container-platform/4.10/installing/install_config/configuring-firewall.html"
+	// remoteHealthURL explains telemetry and insights
+	remoteHealthURL = "https://docs.openshift.com/container-platform/4.10/support/remote_health_monitoring/about-remote-health-monitoring.html"
+	// updateServiceURL explains the update service
+	updateServiceURL = "https://docs.openshift.com/container-platform/4.10/updating/understanding-openshift-updates.html"
+)
+
+// CatalogVersion identifies the revision of the endpoint catalog embedded in the verifier, bump it whenever the
+// catalog changes
+const CatalogVersion = "2022.09.1"
+
+// Services of an OpenShift cluster that depend on egress
+const (
+	ServiceTelemetry     = "telemetry"
+	ServiceInsights      = "insights"
+	ServiceImageRegistry = "image registry"
+	ServiceOIDC          = "OIDC"
+	ServiceUpdate        = "update service"
+	ServiceSupport       = "support"
+	ServiceCloudAPI      = "cloud provider API"
+)
+
+// Services returns every service of the catalog
+func Services() []string {
+	return []string{ServiceTelemetry, ServiceInsights, ServiceImageRegistry, ServiceOIDC, ServiceUpdate, ServiceSupport, ServiceCloudAPI}
+}
+
+// serviceTimeouts override the run's per-request timeout for the endpoints of a service, e.g. telemetry answers
+// quickly while large registry HEAD requests can take much longer
+var serviceTimeouts = map[string]time.Duration{
+	ServiceTelemetry:     5 * time.Second,
+	ServiceImageRegistry: 30 * time.Second,
+}
+
+// Endpoint describes why an OpenShift cluster needs to reach a host
+type Endpoint struct {
+	// Host is either an exact hostname or, if prefixed with "*.", a domain suffix
+	Host string
+	// DocsURL points to the documentation explaining why the host is required
+	DocsURL string
+	// RequiredBy is the cluster service that breaks if the host is unreachable
+	RequiredBy string
+}
+
+// catalog is ordered from most to least specific, the first matching entry wins
+var catalog = []Endpoint{
+	{Host: "api.openshift.com", DocsURL: updateServiceURL, RequiredBy: ServiceUpdate},
+	{Host: "mirror.openshift.com", DocsURL: updateServiceURL, RequiredBy: ServiceUpdate},
+	{Host: "infogw.api.openshift.com", DocsURL: remoteHealthURL, RequiredBy: ServiceInsights},
+	{Host: "observatorium.api.openshift.com", DocsURL: remoteHealthURL, RequiredBy: ServiceTelemetry},
+	{Host: "observatorium-mst.api.openshift.com", DocsURL: remoteHealthURL, RequiredBy: ServiceTelemetry},
+	{Host: "cert-api.access.redhat.com", DocsURL: remoteHealthURL, RequiredBy: ServiceInsights},
+	{Host: "api.access.redhat.com", DocsURL: remoteHealthURL, RequiredBy: ServiceInsights},
+	{Host: "console.redhat.com", DocsURL: remoteHealthURL, RequiredBy: ServiceInsights},
+	{Host: "cloud.redhat.com", DocsURL: remoteHealthURL, RequiredBy: ServiceInsights},
+	{Host: "sso.redhat.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceOIDC},
+	{Host: "registry.redhat.io", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "registry.access.redhat.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "registry.connect.redhat.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "quay.io", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "*.quay.io", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "quay-registry.s3.amazonaws.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "quayio-production-s3.s3.amazonaws.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "storage.googleapis.com", DocsURL: configuringFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "oidc.op1.openshiftapps.com", DocsURL: rosaHCPFirewallURL, RequiredBy: ServiceOIDC},
+	{Host: "*.openshiftapps.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+	{Host: "rh-oidc.s3.us-east-1.amazonaws.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceOIDC},
+	{Host: "*.amazonaws.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceCloudAPI},
+	{Host: "*.googleapis.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceCloudAPI},
+	{Host: "accounts.google.com", DocsURL: gcpFirewallURL, RequiredBy: ServiceCloudAPI},
+	{Host: "gcr.io", DocsURL: gcpFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "*.pkg.dev", DocsURL: gcpFirewallURL, RequiredBy: ServiceImageRegistry},
+	{Host: "*.pagerduty.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+	{Host: "api.deadmanssnitch.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+	{Host: "nosnch.in", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+	{Host: "*.splunkcloud.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+	{Host: "sftp.access.redhat.com", DocsURL: firewallPrerequisitesURL, RequiredBy: ServiceSupport},
+}
+
+// Lookup returns the catalog entry for an endpoint given as host or host:port.
+// Unknown endpoints fall back to the general firewall prerequisites.
+func Lookup(endpoint string) Endpoint {
+	host := hostname(endpoint)
+	for _, e := range catalog {
+		if matches(e.Host, host) {
+			return e
+		}
+	}
+
+	return Endpoint{Host: host, DocsURL: firewallPrerequisitesURL}
+}
+
+// Timeouts returns the per-request timeout overrides of the catalog hosts as host=duration pairs, for the validator
+// to use instead of defaultTimeout. Hosts without an override, or whose override equals defaultTimeout, are omitted.
+func Timeouts(defaultTimeout time.Duration) []string {
+	var timeouts []string
+	for _, e := range catalog {
+		if timeout, ok := serviceTimeouts[e.RequiredBy]; ok && timeout != defaultTimeout {
+			timeouts = append(timeouts, fmt.Sprintf("%s=%s", e.Host, timeout))
+		}
+	}
+
+	return timeouts
+}
+
+// Timeout returns the per-request timeout of an endpoint given as host or host:port, which is defaultTimeout unless
+// the service it's required by overrides it
+func Timeout(endpoint string, defaultTimeout time.Duration) time.Duration {
+	if timeout, ok := serviceTimeouts[Lookup(endpoint).RequiredBy]; ok {
+		return timeout
+	}
+
+	return defaultTimeout
+}
+
+// Hostnames returns every exact (non-wildcard) hostname in the catalog
+func Hostnames() []string {
+	var hosts []string
+	for _, e := range catalog {
+		if !strings.HasPrefix(e.Host, "*.") {
+			hosts = append(hosts, e.Host)
+		}
+	}
+
+	return hosts
+}
+
+func matches(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+
+	return pattern == host
+}
+
+// hostname strips any scheme, port and path from an endpoint
+func hostname(endpoint string) string {
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	if i := strings.Index(endpoint, "/"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+
+	return endpoint
+}
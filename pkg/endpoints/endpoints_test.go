@@ -0,0 +1,135 @@
+package endpoints
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		endpoint   string
+		expected   string
+		requiredBy string
+	}{
+		{endpoint: "quay.io:443", expected: configuringFirewallURL, requiredBy: ServiceImageRegistry},
+		{endpoint: "cdn02.quay.io:443", expected: configuringFirewallURL, requiredBy: ServiceImageRegistry},
+		{endpoint: "https://api.openshift.com/api/upgrades_info", expected: updateServiceURL, requiredBy: ServiceUpdate},
+		{endpoint: "infogw.api.openshift.com", expected: remoteHealthURL, requiredBy: ServiceInsights},
+		{endpoint: "rh-oidc.s3.us-east-1.amazonaws.com:443", expected: firewallPrerequisitesURL, requiredBy: ServiceOIDC},
+		{endpoint: "unknown.example.com:80", expected: firewallPrerequisitesURL},
+	}
+
+	for _, test := range tests {
+		e := Lookup(test.endpoint)
+		if e.DocsURL != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.endpoint, test.expected, e.DocsURL)
+		}
+		if e.RequiredBy != test.requiredBy {
+			t.Errorf("%s: expected to be required by %q, got %q", test.endpoint, test.requiredBy, e.RequiredBy)
+		}
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	timeouts := Timeouts(2 * time.Second)
+	expected := map[string]bool{"observatorium.api.openshift.com=5s": true, "quay.io=30s": true, "*.quay.io=30s": true}
+	found := 0
+	for _, timeout := range timeouts {
+		if expected[timeout] {
+			found++
+		}
+		if timeout == "api.openshift.com=2s" {
+			t.Errorf("unexpected override for a service without one: %s", timeout)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %v among %v", expected, timeouts)
+	}
+
+	for _, timeout := range Timeouts(5 * time.Second) {
+		if timeout == "observatorium.api.openshift.com=5s" {
+			t.Errorf("unexpected override equal to the default timeout: %s", timeout)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	if timeout := Timeout("cdn01.quay.io:443", 2*time.Second); timeout != 30*time.Second {
+		t.Errorf("expected the image registry timeout, got %s", timeout)
+	}
+	if timeout := Timeout("api.openshift.com:443", 2*time.Second); timeout != 2*time.Second {
+		t.Errorf("expected the default timeout, got %s", timeout)
+	}
+}
+
+func TestPresetEndpoints(t *testing.T) {
+	eps := PresetEndpoints(PresetROSAHCP, "us-west-2")
+	if len(eps) == 0 || eps[0] != "sts.us-west-2.amazonaws.com:443" {
+		t.Errorf("expected the regional STS endpoint first, got %v", eps)
+	}
+	if eps := PresetEndpoints(PresetOSDGCP, "us-east1"); !contains(eps, "us-east1-docker.pkg.dev:443") {
+		t.Errorf("expected the regional Artifact Registry endpoint, got %v", eps)
+	}
+	for _, preset := range Presets() {
+		for _, endpoint := range PresetEndpoints(preset, "us-west-2") {
+			if Lookup(endpoint).RequiredBy == "" {
+				t.Errorf("%s: expected a catalog entry for %s", preset, endpoint)
+			}
+		}
+	}
+	if eps := PresetEndpoints("", "us-west-2"); len(eps) != 0 {
+		t.Errorf("expected no endpoints without a preset, got %v", eps)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestOCPVersion(t *testing.T) {
+	versions := OCPVersions()
+	if len(versions) < 2 || versions[0] != "4.10" {
+		t.Fatalf("expected the versions oldest first, got %v", versions)
+	}
+	for input, expected := range map[string]string{
+		"":        DefaultOCPVersion(),
+		"4.11":    "4.11",
+		"4.11.3":  "4.11",
+		"v4.10.0": "4.10",
+	} {
+		if version, err := OCPVersion(input); err != nil || version != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", input, expected, version, err)
+		}
+	}
+	if _, err := OCPVersion("3.11"); err == nil {
+		t.Error("expected an error for a version without an egress list")
+	}
+
+	for _, version := range versions {
+		for _, endpoint := range OCPVersionEndpoints(version) {
+			if Lookup(endpoint).RequiredBy == "" {
+				t.Errorf("%s: expected a catalog entry for %s", version, endpoint)
+			}
+		}
+	}
+}
+
+func TestExtra(t *testing.T) {
+	eps := Extra(PresetOSDGCP, "4.12", "us-east1")
+	if !contains(eps, "registry.redhat.io:443") || !contains(eps, "us-east1-docker.pkg.dev:443") {
+		t.Errorf("expected the version's and the preset's endpoints, got %v", eps)
+	}
+	seen := map[string]bool{}
+	for _, endpoint := range eps {
+		if seen[endpoint] {
+			t.Errorf("%s listed twice", endpoint)
+		}
+		seen[endpoint] = true
+	}
+}
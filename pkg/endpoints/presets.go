@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"sort"
+	"strings"
+)
+
+// rosaHCPFirewallURL lists the endpoints a ROSA with hosted control planes cluster needs to reach
+const rosaHCPFirewallURL = "https://docs.openshift.com/rosa/rosa_hcp/rosa-hcp-aws-prereqs.html#rosa-hcp-firewall-prerequisites_rosa-hcp-aws-prereqs"
+
+// gcpFirewallURL lists the endpoints an OpenShift Dedicated cluster on GCP needs to reach
+const gcpFirewallURL = "https://docs.openshift.com/dedicated/osd_planning/gcp-ccs.html#osd-gcp-firewall-prerequisites_gcp-ccs"
+
+// Presets select the endpoints of a flavour of cluster the validator's own list doesn't cover, as given to --platform
+const (
+	PresetROSAHCP = "rosa-hcp"
+	PresetOSDGCP  = "osd-gcp"
+)
+
+// presets are the endpoints of each preset as host:port, where {region} is replaced with the region
+var presets = map[string][]string{
+	// Worker nodes of HCP clusters exchange their web identity tokens with the regional STS endpoint, and the
+	// managed OIDC configuration is served from Red Hat's OIDC bucket
+	PresetROSAHCP: {
+		"sts.{region}.amazonaws.com:443",
+		"oidc.op1.openshiftapps.com:443",
+	},
+	// The validator's list is AWS-centric, OSD clusters on GCP rather depend on the Google APIs, pull images from
+	// gcr.io and Artifact Registry, and report telemetry like any other cluster
+	PresetOSDGCP: {
+		"accounts.google.com:443",
+		"oauth2.googleapis.com:443",
+		"compute.googleapis.com:443",
+		"iam.googleapis.com:443",
+		"iamcredentials.googleapis.com:443",
+		"cloudresourcemanager.googleapis.com:443",
+		"serviceusage.googleapis.com:443",
+		"dns.googleapis.com:443",
+		"storage.googleapis.com:443",
+		"monitoring.googleapis.com:443",
+		"logging.googleapis.com:443",
+		"gcr.io:443",
+		"artifactregistry.googleapis.com:443",
+		"{region}-docker.pkg.dev:443",
+		"infogw.api.openshift.com:443",
+		"observatorium-mst.api.openshift.com:443",
+	},
+}
+
+// Presets returns the names of the known presets
+func Presets() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// PresetEndpoints returns the endpoints of the preset in the region as host:port, none for an unknown or empty
+// preset
+func PresetEndpoints(preset, region string) []string {
+	var eps []string
+	for _, endpoint := range presets[preset] {
+		eps = append(eps, strings.ReplaceAll(endpoint, "{region}", region))
+	}
+
+	return eps
+}
@@ -0,0 +1,130 @@
+package endpoints
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ocpVersions are the OpenShift endpoints each minor version of OpenShift requires, as host:port. Keep the catalog's
+// entries in sync when adding a version.
+var ocpVersions = map[string][]string{
+	"4.10": {
+		"registry.redhat.io:443",
+		"registry.access.redhat.com:443",
+		"quay.io:443",
+		"cdn.quay.io:443",
+		"sso.redhat.com:443",
+		"api.openshift.com:443",
+		"mirror.openshift.com:443",
+		"storage.googleapis.com:443",
+		"cert-api.access.redhat.com:443",
+		"api.access.redhat.com:443",
+		"infogw.api.openshift.com:443",
+		"cloud.redhat.com:443",
+		"observatorium.api.openshift.com:443",
+	},
+	// Insights uploads moved to console.redhat.com, and telemetry to the multi-tenant observatorium
+	"4.11": {
+		"registry.redhat.io:443",
+		"registry.access.redhat.com:443",
+		"quay.io:443",
+		"cdn.quay.io:443",
+		"sso.redhat.com:443",
+		"api.openshift.com:443",
+		"mirror.openshift.com:443",
+		"storage.googleapis.com:443",
+		"cert-api.access.redhat.com:443",
+		"api.access.redhat.com:443",
+		"infogw.api.openshift.com:443",
+		"console.redhat.com:443",
+		"observatorium-mst.api.openshift.com:443",
+	},
+	// Quay serves image layers from its S3 bucket directly
+	"4.12": {
+		"registry.redhat.io:443",
+		"registry.access.redhat.com:443",
+		"quay.io:443",
+		"cdn.quay.io:443",
+		"quayio-production-s3.s3.amazonaws.com:443",
+		"sso.redhat.com:443",
+		"api.openshift.com:443",
+		"mirror.openshift.com:443",
+		"storage.googleapis.com:443",
+		"cert-api.access.redhat.com:443",
+		"api.access.redhat.com:443",
+		"infogw.api.openshift.com:443",
+		"console.redhat.com:443",
+		"observatorium-mst.api.openshift.com:443",
+	},
+}
+
+// OCPVersions returns the OpenShift minor versions with an egress list, oldest first
+func OCPVersions() []string {
+	var versions []string
+	for version := range ocpVersions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return minor(versions[i]) < minor(versions[j])
+	})
+
+	return versions
+}
+
+// DefaultOCPVersion returns the newest OpenShift minor version with an egress list
+func DefaultOCPVersion() string {
+	versions := OCPVersions()
+
+	return versions[len(versions)-1]
+}
+
+// OCPVersion returns the minor version with an egress list matching version, which is either a minor version like
+// 4.11 or a full one like 4.11.3. It's the newest one if version is empty.
+func OCPVersion(version string) (string, error) {
+	if version == "" {
+		return DefaultOCPVersion(), nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) >= 2 {
+		if _, ok := ocpVersions[parts[0]+"."+parts[1]]; ok {
+			return parts[0] + "." + parts[1], nil
+		}
+	}
+
+	return "", fmt.Errorf("no egress list for OpenShift version %s, must be one of %v", version, OCPVersions())
+}
+
+// OCPVersionEndpoints returns the endpoints the OpenShift minor version requires as host:port, none for an unknown
+// version
+func OCPVersionEndpoints(version string) []string {
+	return append([]string(nil), ocpVersions[version]...)
+}
+
+// Extra returns the endpoints to probe beyond the validator's own list: those of the OpenShift version followed by
+// those of the preset in the region, without duplicates
+func Extra(preset, ocpVersion, region string) []string {
+	var eps []string
+	seen := map[string]bool{}
+	for _, endpoint := range append(OCPVersionEndpoints(ocpVersion), PresetEndpoints(preset, region)...) {
+		if !seen[endpoint] {
+			seen[endpoint] = true
+			eps = append(eps, endpoint)
+		}
+	}
+
+	return eps
+}
+
+// minor returns the minor number of a 4.y version
+func minor(version string) int {
+	parts := strings.SplitN(version, ".", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[1])
+
+	return n
+}
@@ -11,10 +11,15 @@ import (
 // GenericError implements the error interface
 type GenericError struct {
 	message string
+	// cause is the error a generic error was made from, if any
+	cause error
 }
 
 func (e *GenericError) Error() string { return e.message }
 
+// Unwrap returns the error the generic error was made from, so errors.Is and errors.As see through it
+func (e *GenericError) Unwrap() error { return e.cause }
+
 // Ensure GenericError implements the error interface
 var _ error = &GenericError{}
 
@@ -33,10 +38,12 @@ func NewGenericError(err error) *GenericError {
 			case ae.ErrorCode() == "UnauthorizedOperation":
 				return &GenericError{
 					message: fmt.Sprintf("missing required permission %s:%s", strings.ToLower(oe.Service()), oe.Operation()),
+					cause:   err,
 				}
 			default:
 				return &GenericError{
 					message: fmt.Sprintf("error performing %s:%s: %s", strings.ToLower(oe.Service()), oe.Operation(), ae.ErrorMessage()),
+					cause:   err,
 				}
 			}
 		}
@@ -45,6 +52,7 @@ func NewGenericError(err error) *GenericError {
 	// Just feed forward other generic errors
 	return &GenericError{
 		message: fmt.Sprintf("network verifier error: %s", err.Error()),
+		cause:   err,
 	}
 }
 
@@ -54,3 +62,73 @@ func NewEgressURLError(message string) error {
 		message: fmt.Sprintf("egressURL error: %s", message),
 	}
 }
+
+// NewDNSError prepends the provided message with `dns error: `
+func NewDNSError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("dns error: %s", message),
+	}
+}
+
+// NewS3EndpointError prepends the provided message with `s3 gateway endpoint error: `
+func NewS3EndpointError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("s3 gateway endpoint error: %s", message),
+	}
+}
+
+// NewPSCError prepends the provided message with `private service connect error: `
+func NewPSCError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("private service connect error: %s", message),
+	}
+}
+
+// NewRoutingError prepends the provided message with `routing error: `
+func NewRoutingError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("routing error: %s", message),
+	}
+}
+
+// NewSubnetError prepends the provided message with `subnet error: `
+func NewSubnetError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("subnet error: %s", message),
+	}
+}
+
+// NewFirewallError prepends the provided message with `firewall error: `
+func NewFirewallError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("firewall error: %s", message),
+	}
+}
+
+// NewSecurityGroupError prepends the provided message with `security group error: `
+func NewSecurityGroupError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("security group error: %s", message),
+	}
+}
+
+// NewKMSKeyError prepends the provided message with `kms key error: `
+func NewKMSKeyError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("kms key error: %s", message),
+	}
+}
+
+// NewCACertError prepends the provided message with `ca certificate error: `
+func NewCACertError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("ca certificate error: %s", message),
+	}
+}
+
+// NewVPCEndpointError prepends the provided message with `vpc endpoint error: `
+func NewVPCEndpointError(message string) error {
+	return &GenericError{
+		message: fmt.Sprintf("vpc endpoint error: %s", message),
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Types of the events emitted over a verification, in the order they happen
+const (
+	// TypeStarted is emitted when the verification of a subnet, or the pod network, starts
+	TypeStarted = "started"
+	// TypeInstanceLaunched is emitted once the probe instance is created
+	TypeInstanceLaunched = "instance_launched"
+	// TypeInstanceRunning is emitted once the probe instance is running, the probe starting on it
+	TypeInstanceRunning = "instance_running"
+	// TypeEndpoint is emitted for each endpoint's result, as soon as the probe reported it
+	TypeEndpoint = "endpoint"
+	// TypeInstanceTerminated is emitted once the probe instance is terminated
+	TypeInstanceTerminated = "instance_terminated"
+	// TypeFinished is emitted when the verification of a subnet, or the pod network, is done
+	TypeFinished = "finished"
+	// TypeDone is emitted last, once every verification of the run is done
+	TypeDone = "done"
+)
+
+// Event is something that happened over a verification
+type Event struct {
+	Time time.Time `json:"time"`
+	// Type is one of the Type constants
+	Type string `json:"type"`
+	// Subnet is the subnet verified, empty for the pod network and TypeDone
+	Subnet string `json:"subnet,omitempty"`
+	// InstanceID is the probe instance, once there's one
+	InstanceID string `json:"instance_id,omitempty"`
+	// Endpoint is the endpoint's result of TypeEndpoint, an output.EndpointResult. The output package depends on the
+	// probe options, which depend on this one, so it can't be typed as such.
+	Endpoint interface{} `json:"endpoint,omitempty"`
+	// Success is whether the verification succeeded, of TypeFinished and TypeDone
+	Success *bool `json:"success,omitempty"`
+	// Message details the event, e.g. the errors of an unsuccessful verification
+	Message string `json:"message,omitempty"`
+}
+
+// Sink receives the events of the verifications as they happen. It must be safe for concurrent use, as subnets are
+// verified in parallel.
+type Sink interface {
+	Emit(Event)
+}
+
+// NDJSON writes each event as a JSON object on a line of its own, for wrappers to follow the verification live
+type NDJSON struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewNDJSON returns a sink writing the events to w
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{w: w}
+}
+
+// Emit writes the event as a line of JSON. Events that can't be written are dropped, as the verification goes on
+// regardless.
+func (n *NDJSON) Emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, _ = n.w.Write(append(line, '\n'))
+}
+
+// Bool returns a pointer to b, for Event.Success
+func Bool(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewNDJSON(&buf)
+	at := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	sink.Emit(Event{Time: at, Type: TypeInstanceLaunched, Subnet: "subnet-1", InstanceID: "i-1"})
+	sink.Emit(Event{Time: at, Type: TypeEndpoint, Subnet: "subnet-1", Endpoint: map[string]interface{}{"endpoint": "quay.io:443", "success": false}})
+	sink.Emit(Event{Type: TypeDone, Success: Bool(false)})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !assert.Len(t, lines, 3, "one line per event") {
+		return
+	}
+	assert.Equal(t, `{"time":"2022-06-01T12:00:00Z","type":"instance_launched","subnet":"subnet-1","instance_id":"i-1"}`, lines[0])
+	assert.Equal(t, `{"time":"2022-06-01T12:00:00Z","type":"endpoint","subnet":"subnet-1","endpoint":{"endpoint":"quay.io:443","success":false}}`, lines[1])
+
+	var done Event
+	assert.NoError(t, json.Unmarshal([]byte(lines[2]), &done))
+	assert.Equal(t, TypeDone, done.Type)
+	assert.False(t, done.Time.IsZero(), "events are timestamped when emitted")
+	if assert.NotNil(t, done.Success) {
+		assert.False(t, *done.Success)
+	}
+}
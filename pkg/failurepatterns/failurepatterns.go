@@ -0,0 +1,224 @@
+// Package failurepatterns holds the patterns the probe's console output is scanned with to tell whether its setup or
+// execution failed. They're defined by validator version in patterns.yaml, and can be overridden from a file, e.g. for
+// custom images whose console output differs.
+package failurepatterns
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed patterns.yaml
+var builtinDefinitions []byte
+
+// Definition lists the patterns, as regular expressions, console output lines are matched against
+type Definition struct {
+	// Failure matches the lines reporting the probe's setup or execution failed
+	Failure []string `json:"failure,omitempty"`
+	// Benign matches the lines Failure matches that don't concern the probe, e.g. kernel or cloud-init messages
+	Benign []string `json:"benign,omitempty"`
+	// Success, if set, has to match for the probe to have run its egress tests at all, e.g. a marker the validator
+	// prints once done
+	Success []string `json:"success,omitempty"`
+}
+
+// versionedDefinition is a built-in definition, applying from a validator version on
+type versionedDefinition struct {
+	Since string `json:"since"`
+	Definition
+}
+
+// Patterns are the compiled patterns of a definition
+type Patterns struct {
+	failure []*regexp.Regexp
+	benign  []*regexp.Regexp
+	success []*regexp.Regexp
+}
+
+var builtins []versionedDefinition
+
+func init() {
+	if err := yaml.UnmarshalStrict(builtinDefinitions, &builtins); err != nil {
+		panic(fmt.Sprintf("invalid built-in failure patterns: %v", err))
+	}
+	sort.Slice(builtins, func(i, j int) bool {
+		return compareVersions(parseVersion(builtins[i].Since), parseVersion(builtins[j].Since)) < 0
+	})
+}
+
+// ForValidator returns the built-in patterns of the validator image, by the version of its tag, e.g.
+// quay.io/app-sre/osd-network-verifier:v0.1.212-5f88b83. Images without a version tag get the newest patterns.
+func ForValidator(image string) *Patterns {
+	definition := builtins[len(builtins)-1].Definition
+	if version := parseVersion(imageTag(image)); version != nil {
+		for _, builtin := range builtins {
+			if compareVersions(parseVersion(builtin.Since), version) <= 0 {
+				definition = builtin.Definition
+			}
+		}
+	}
+
+	patterns, err := definition.Compile()
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in failure patterns: %v", err))
+	}
+
+	return patterns
+}
+
+// Load reads a definition overriding the validator image's built-in one: the patterns it lists replace the built-in
+// ones of the same kind, those it leaves out being kept
+func Load(file, validatorImage string) (*Patterns, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read failure patterns %s: %w", file, err)
+	}
+
+	patterns, err := Parse(data, validatorImage)
+	if err != nil {
+		return nil, fmt.Errorf("failure patterns %s: %w", file, err)
+	}
+
+	return patterns, nil
+}
+
+// Parse parses a definition overriding the validator image's built-in one, see Load
+func Parse(data []byte, validatorImage string) (*Patterns, error) {
+	var override Definition
+	if err := yaml.UnmarshalStrict(data, &override); err != nil {
+		return nil, fmt.Errorf("unable to parse failure patterns: %w", err)
+	}
+	patterns, err := override.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	builtin := ForValidator(validatorImage)
+	if override.Failure == nil {
+		patterns.failure = builtin.failure
+	}
+	if override.Benign == nil {
+		patterns.benign = builtin.benign
+	}
+	if override.Success == nil {
+		patterns.success = builtin.success
+	}
+
+	return patterns, nil
+}
+
+// Compile compiles the definition's patterns, rejecting invalid ones
+func (d Definition) Compile() (*Patterns, error) {
+	var (
+		patterns Patterns
+		err      error
+	)
+	if patterns.failure, err = compile("failure", d.Failure); err != nil {
+		return nil, err
+	}
+	if patterns.benign, err = compile("benign", d.Benign); err != nil {
+		return nil, err
+	}
+	if patterns.success, err = compile("success", d.Success); err != nil {
+		return nil, err
+	}
+
+	return &patterns, nil
+}
+
+func compile(kind string, expressions []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(expressions))
+	for _, expression := range expressions {
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", kind, expression, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// Failures returns the lines of the console output reporting a failure, leaving out the benign ones
+func (p *Patterns) Failures(consoleLogs string) []string {
+	var failures []string
+	for _, line := range strings.Split(consoleLogs, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if matchAny(p.failure, line) && !matchAny(p.benign, line) {
+			failures = append(failures, line)
+		}
+	}
+
+	return failures
+}
+
+// Succeeded returns whether the console output shows the probe ran its egress tests, always the case without success
+// patterns
+func (p *Patterns) Succeeded(consoleLogs string) bool {
+	if len(p.success) == 0 {
+		return true
+	}
+
+	return matchAny(p.success, consoleLogs)
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// imageTag returns the tag of an image reference, or an empty string if it has none
+func imageTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+
+	return ""
+}
+
+// parseVersion parses the major, minor and patch numbers of a version like v0.1.212-5f88b83, nil if it isn't one
+func parseVersion(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	numbers := make([]int, 0, 3)
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, n)
+	}
+
+	return numbers
+}
+
+func compareVersions(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+
+	return 0
+}
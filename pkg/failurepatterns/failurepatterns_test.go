@@ -0,0 +1,50 @@
+package failurepatterns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForValidator(t *testing.T) {
+	patterns := ForValidator("quay.io/app-sre/osd-network-verifier:v0.1.212-5f88b83")
+	logs := "[   12.345678] Failed to find cpu0 device node\r\n" +
+		"[FAILED] Failed to start Apply the settings specified in cloud-config.\n" +
+		"ci-info: Could not open route table\n" +
+		"USERDATA BEGIN\n" +
+		"Failed to successfully run the docker container\n" +
+		"/run-container.sh: line 3: docker: command not found\n"
+	assert.Equal(t, []string{
+		"Failed to successfully run the docker container",
+		"/run-container.sh: line 3: docker: command not found",
+	}, patterns.Failures(logs), "kernel, systemd and cloud-init messages are benign")
+	assert.True(t, patterns.Succeeded(logs), "without success patterns the probe is taken to have run")
+
+	// Images without a version get the newest patterns
+	assert.Equal(t, patterns, ForValidator("registry.example.com:5000/validator@sha256:abc"))
+	assert.Equal(t, patterns, ForValidator(""))
+}
+
+func TestParse(t *testing.T) {
+	patterns, err := Parse([]byte("failure:\n  - ^ERROR\nsuccess:\n  - VALIDATOR END\n"), "registry.example.com/validator:custom")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ERROR: no route"}, patterns.Failures("Failed to start\nERROR: no route\n[    1.0] ERROR: kernel"), "the built-in benign patterns are kept")
+	assert.False(t, patterns.Succeeded("USERDATA END"))
+	assert.True(t, patterns.Succeeded("VALIDATOR END\nUSERDATA END"))
+
+	patterns, err = Parse([]byte("benign: []\n"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"[    1.0] Failed"}, patterns.Failures("[    1.0] Failed"), "an empty list replaces the built-in one")
+
+	_, err = Parse([]byte("failure:\n  - \"[\"\n"), "")
+	assert.Error(t, err)
+	_, err = Parse([]byte("failures: []\n"), "")
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestParseVersion(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 212}, parseVersion(imageTag("quay.io/app-sre/osd-network-verifier:v0.1.212-5f88b83")))
+	assert.Nil(t, parseVersion(imageTag("registry.example.com:5000/validator")))
+	assert.Nil(t, parseVersion("latest"))
+	assert.True(t, compareVersions([]int{0, 1, 9}, []int{0, 1, 10}) < 0)
+}
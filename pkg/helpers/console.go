@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// ConsoleDoneMarker is printed to the console once the probe's output was, followed by the run's ID and the output's
+// MD5 checksum and size, so a finished run is told apart from one whose output the console buffer cut off
+const ConsoleDoneMarker = "USERDATA DONE"
+
+// Markers are the lines the userdata script prints before and after the probe's output, and once it printed it
+type Markers struct {
+	Begin string
+	End   string
+	Done  string
+}
+
+// RunMarkers returns the markers of a run, the run's ID appended to them, so neither the output of an earlier run on
+// a reused instance nor other scripts printing the same lines are taken for the run's
+func RunMarkers(opts probe.Options, runID string) Markers {
+	markers := Markers{Begin: opts.BeginMarkerOrDefault(), End: opts.EndMarkerOrDefault(), Done: ConsoleDoneMarker}
+	if runID != "" {
+		markers.Begin += " " + runID
+		markers.End += " " + runID
+		markers.Done += " " + runID
+	}
+
+	return markers
+}
+
+// orDefault returns the markers, the default ones of a run without an ID if unset
+func (m Markers) orDefault() Markers {
+	if m == (Markers{}) {
+		return RunMarkers(probe.Options{}, "")
+	}
+
+	return m
+}
+
+// markerPattern matches a marker as a whole, rather than as the start of a longer one, e.g. another run's
+func markerPattern(marker string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(marker) + `(?:\r|\n|$)`)
+}
+
+// Ended returns whether the console logs show the end marker. Unset markers are the default ones of a run without an
+// ID, e.g. an image build's.
+func (m Markers) Ended(consoleLogs string) bool {
+	return markerPattern(m.orDefault().End).MatchString(consoleLogs)
+}
+
+// consoleOverlap is how much of the console output read before is looked up in a newer read, to tell where they meet
+const consoleOverlap = 512
+
+// MergeConsoleOutput returns the console output read before, extended with what a newer read of the console's latest
+// output adds to it, as consoles only keep their latest output, e.g. 64KB on EC2. gap is set when the reads don't
+// overlap, so whatever was printed between them is missing.
+func MergeConsoleOutput(previous, latest string) (merged string, gap bool) {
+	if previous == "" || strings.HasPrefix(latest, previous) {
+		return latest, false
+	}
+
+	anchor := previous
+	if len(anchor) > consoleOverlap {
+		anchor = anchor[len(anchor)-consoleOverlap:]
+	}
+	// The latest read may repeat the anchor, the last occurrence being the one nearest to the previous read's end
+	for end := len(latest); end > 0; {
+		i := strings.LastIndex(latest[:end], anchor)
+		if i < 0 {
+			break
+		}
+		if overlap := latest[:i+len(anchor)]; strings.HasSuffix(previous, overlap) || strings.HasSuffix(overlap, previous) {
+			if len(overlap) > len(previous) {
+				return latest, false
+			}
+			return previous + latest[i+len(anchor):], false
+		}
+		end = i + len(anchor) - 1
+	}
+
+	return previous + latest, true
+}
+
+// ConsoleCompletion is what the console output tells of the probe's completion
+type ConsoleCompletion struct {
+	// Done is set once the end of the userdata script or the done marker was seen
+	Done bool
+	// Truncated is set when the probe's output doesn't match the checksum of the done marker, part of it having been
+	// cut off or interleaved with other console output
+	Truncated bool
+}
+
+// reConsoleNoise matches the lines other writers interleave with the probe's output on the console, kernel messages,
+// e.g. "[   12.345678] random: crng init done", and cloud-init's own, which the probe's checksum doesn't cover
+var reConsoleNoise = regexp.MustCompile(`^(\[\s*\d+\.\d+\] |cloud-init\[\d+\]: |ci-info: )`)
+
+// probeSection returns the probe's output as the probe checksummed it: the lines from the begin marker through the
+// end marker, without the console's line endings or the lines of other console writers. ok is unset when either
+// marker is missing.
+func probeSection(consoleLogs string, markers Markers) (section string, ok bool) {
+	begins := markerPattern(markers.Begin).FindAllStringIndex(consoleLogs, -1)
+	if len(begins) == 0 {
+		return "", false
+	}
+	consoleLogs = consoleLogs[begins[len(begins)-1][0]:]
+	end := markerPattern(markers.End).FindStringIndex(consoleLogs)
+	if end == nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(consoleLogs[:end[0]+len(markers.End)], "\n") {
+		if line = strings.TrimSuffix(line, "\r"); !reConsoleNoise.MatchString(line) {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return b.String(), true
+}
+
+// CheckConsoleCompletion returns whether the probe is done, as told by the end marker of the userdata script or, when
+// the console cut that off, the done marker printed after it, and whether its output, from the begin marker through
+// the end marker, is all there
+func CheckConsoleCompletion(consoleLogs string, markers Markers) ConsoleCompletion {
+	markers = markers.orDefault()
+	completion := ConsoleCompletion{Done: markers.Ended(consoleLogs)}
+
+	reDone := regexp.MustCompile(regexp.QuoteMeta(markers.Done) + ` ([0-9a-f]{32}) (\d+)`)
+	loc := reDone.FindStringSubmatchIndex(consoleLogs)
+	if loc == nil {
+		return completion
+	}
+	completion.Done = true
+
+	printed, ok := probeSection(consoleLogs[:loc[0]], markers)
+	if !ok {
+		completion.Truncated = true
+		return completion
+	}
+	sum := md5.Sum([]byte(printed))
+	size, _ := strconv.Atoi(consoleLogs[loc[4]:loc[5]])
+	completion.Truncated = len(printed) != size || hex.EncodeToString(sum[:]) != consoleLogs[loc[2]:loc[3]]
+
+	return completion
+}
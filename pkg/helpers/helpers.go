@@ -1,17 +1,303 @@
+// Package helpers parses the probe's console output and holds the utilities the cloud clients share. It's internal to
+// the verifier and may change in any release; Go consumers should use pkg/verifier.
 package helpers
 
 import (
+	"context"
 	_ "embed"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/events"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
 )
 
 //go:embed config/userdata.yaml
 var UserdataTemplate string
 
+//go:embed config/imagebuild.yaml
+var imageBuildTemplate string
+
+var reImageBuild = regexp.MustCompile(`IMAGE BUILD (PULLED|FAILED) (\S+)`)
+
+// TracerouteMaxEndpoints bounds how many unreachable endpoints the probe traces, as each trace takes up to 15s
+const TracerouteMaxEndpoints = 5
+
+// RetryDelay is how long the probe waits before re-probing unreachable endpoints, so transient blips can clear
+const RetryDelay = 10 * time.Second
+
+// TLSDiagnosticsMaxEndpoints bounds how many endpoints failing the TLS handshake the probe re-attempts the handshake
+// with, as each takes a request per TLS version
+const TLSDiagnosticsMaxEndpoints = 10
+
+// PcapMaxEndpoints and PcapMaxPackets bound the size of packet captures, which have to fit in the console output
+const (
+	PcapMaxEndpoints = 3
+	PcapMaxPackets   = 20
+)
+
+var rePacketCapture = regexp.MustCompile(`PCAP BEGIN (\S+)\s+([A-Za-z0-9+/=]*)\s+PCAP END`)
+
+var reUnreachableEndpoint = regexp.MustCompile(`Unable to reach (\S+)`)
+
+var reRetryResult = regexp.MustCompile(`RETRY (\S+) (REACHABLE|UNREACHABLE)`)
+
+var reDNSResult = regexp.MustCompile(`DNS (\S+) (\S+) (RESOLVED|UNRESOLVED) (\S+)`)
+
+// reDNSSkipped matches the userdata script's notice that it couldn't resolve against the requested DNS servers
+var reDNSSkipped = regexp.MustCompile(`DNS_SKIPPED ([^\r\n]+)`)
+
+// ParseProbeResults records the probe's results found in the console logs of the probe instance on o, whether or
+// not the userdata script finished
+func ParseProbeResults(o *output.Output, consoleLogs, subnetID string) {
+	recordValidatorResults(o, consoleLogs, subnetID)
+	o.MarkRecovered(ParseRecoveredEndpoints(consoleLogs))
+	// Before the HTTP responses, as intercepted endpoints answer the requests not verifying certificates
+	o.SetTLSIssuers(ParseTLSIssuers(consoleLogs))
+	o.SetTLSDiagnostics(ParseTLSDiagnostics(consoleLogs))
+	o.SetHTTPResponses(ParseHTTPResponses(consoleLogs), subnetID)
+	o.SetAttempts(ParseAttempts(consoleLogs), subnetID)
+	o.SetLastHops(ParseTraceroutes(consoleLogs))
+	o.SetDNSResults(ParseDNSResults(consoleLogs))
+	if match := reDNSSkipped.FindStringSubmatch(consoleLogs); match != nil {
+		o.AddWarning("DNS check skipped: " + strings.TrimSpace(match[1]))
+	}
+	o.SetResolvConf(ParseResolvConf(consoleLogs))
+	o.SetSoakRounds(ParseSoakRounds(consoleLogs))
+	o.Metadata().EgressIP = ParseEgressIP(consoleLogs)
+	captures, err := ParsePacketCaptures(consoleLogs)
+	if err != nil {
+		o.AddError(err)
+	}
+	o.SetPacketCaptures(captures)
+}
+
+// ParseDNSResults returns the DNS resolution results reported by the userdata script
+func ParseDNSResults(consoleLogs string) []output.DNSResult {
+	var results []output.DNSResult
+	for _, match := range reDNSResult.FindAllStringSubmatch(consoleLogs, -1) {
+		result := output.DNSResult{Resolver: match[1], Domain: match[2], Resolved: match[3] == "RESOLVED"}
+		if result.Resolved {
+			result.Address = match[4]
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+var rePSCResult = regexp.MustCompile(`PSC (\S+) (\S+) (REACHABLE|UNREACHABLE)`)
+
+// ParsePSCResults returns the Private Service Connect results reported by the userdata script. ViaEndpoint is
+// left for the caller, which knows the network's endpoints.
+func ParsePSCResults(consoleLogs string) []output.PSCResult {
+	var results []output.PSCResult
+	for _, match := range rePSCResult.FindAllStringSubmatch(consoleLogs, -1) {
+		result := output.PSCResult{Domain: match[1], Reachable: match[3] == "REACHABLE"}
+		if match[2] != "-" {
+			result.Address = match[2]
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+var reHTTPResponse = regexp.MustCompile(`HTTP_RESPONSE(6?) (\S+) (\d{3}) ([0-9.]+) (dns|tcp|tls|http|-)(?:[ \t]+(\S+))?`)
+
+// ParseHTTPResponses returns the HTTP responses of the endpoints reported by the userdata script over each IP
+// version, with a zero status and the stage the request failed at for those that didn't answer
+func ParseHTTPResponses(consoleLogs string) []output.HTTPResponse {
+	var responses []output.HTTPResponse
+	for _, match := range reHTTPResponse.FindAllStringSubmatch(consoleLogs, -1) {
+		response := output.HTTPResponse{Endpoint: match[2], RedirectURL: match[6], IPVersion: probe.IPVersion4}
+		if match[1] == "6" {
+			response.IPVersion = probe.IPVersion6
+		}
+		if match[5] != "-" {
+			response.FailureStage = match[5]
+		}
+		response.Status, _ = strconv.Atoi(match[3])
+		if seconds, err := strconv.ParseFloat(match[4], 64); err == nil {
+			response.ResponseTime = time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+		}
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+var reTLSIntercepted = regexp.MustCompile(`TLS_INTERCEPTED (\S+) ([^\n]+)`)
+
+// ParseTLSIssuers returns the issuer of the untrusted certificate of each endpoint the userdata script found TLS
+// intercepted on, as curl prints it, e.g. "C=US; O=Example Corp; CN=Example Proxy CA"
+func ParseTLSIssuers(consoleLogs string) map[string]string {
+	issuers := map[string]string{}
+	for _, match := range reTLSIntercepted.FindAllStringSubmatch(consoleLogs, -1) {
+		issuers[match[1]] = strings.TrimSpace(match[2])
+	}
+
+	return issuers
+}
+
+var (
+	reTLSError   = regexp.MustCompile(`TLS_ERROR (\S+) ([^\n]+)`)
+	reTLSAttempt = regexp.MustCompile(`TLS_ATTEMPT (\S+) (\S+) (OK|FAIL) (\S+) (\S+)(?:[ \t]+([^\n]+))?`)
+)
+
+// ParseTLSDiagnostics returns the details of the failed TLS handshakes reported by the userdata script: the
+// handshake error of each endpoint, and the handshakes re-attempted pinned to each TLS version
+func ParseTLSDiagnostics(consoleLogs string) map[string]*output.TLSDiagnostics {
+	diagnostics := map[string]*output.TLSDiagnostics{}
+	endpoint := func(endpoint string) *output.TLSDiagnostics {
+		if diagnostics[endpoint] == nil {
+			diagnostics[endpoint] = &output.TLSDiagnostics{}
+		}
+		return diagnostics[endpoint]
+	}
+	for _, match := range reTLSError.FindAllStringSubmatch(consoleLogs, -1) {
+		endpoint(match[1]).Error = strings.TrimSpace(match[2])
+	}
+	for _, match := range reTLSAttempt.FindAllStringSubmatch(consoleLogs, -1) {
+		attempt := output.TLSAttempt{Version: match[2], Success: match[3] == "OK", Error: strings.TrimSpace(match[6])}
+		if match[4] != "-" {
+			attempt.Cipher = match[4]
+		}
+		if match[5] != "-" {
+			attempt.ALPN = match[5]
+		}
+		d := endpoint(match[1])
+		d.Attempts = append(d.Attempts, attempt)
+	}
+
+	return diagnostics
+}
+
+var reSoakRound = regexp.MustCompile(`SOAK_ROUND (\d+) (\d+) (\d+) (\S+)`)
+
+// ParseSoakRounds returns the rounds of re-testing the endpoints reported by the userdata script while soaking. Rounds
+// are streamed to the console as they're done and reported again with the rest of the output, so each round is only
+// returned once.
+func ParseSoakRounds(consoleLogs string) []output.SoakRound {
+	var rounds []output.SoakRound
+	seen := map[int]bool{}
+	for _, match := range reSoakRound.FindAllStringSubmatch(consoleLogs, -1) {
+		round, _ := strconv.Atoi(match[1])
+		if seen[round] {
+			continue
+		}
+		seen[round] = true
+		timestamp, _ := strconv.ParseInt(match[2], 10, 64)
+		endpoints, _ := strconv.Atoi(match[3])
+		r := output.SoakRound{Round: round, Time: time.Unix(timestamp, 0), Endpoints: endpoints}
+		if match[4] != "-" {
+			r.Unreachable = strings.Split(match[4], ",")
+		}
+		rounds = append(rounds, r)
+	}
+
+	return rounds
+}
+
+var reAttempts = regexp.MustCompile(`ATTEMPTS (\S+) (\d+) (\d+)`)
+
+// ParseAttempts returns how many of the attempts to reach each endpoint got through, reported by the userdata script
+// when probing each endpoint more than once
+func ParseAttempts(consoleLogs string) []output.Attempts {
+	var attempts []output.Attempts
+	for _, match := range reAttempts.FindAllStringSubmatch(consoleLogs, -1) {
+		a := output.Attempts{Endpoint: match[1]}
+		a.Successes, _ = strconv.Atoi(match[2])
+		a.Attempts, _ = strconv.Atoi(match[3])
+		attempts = append(attempts, a)
+	}
+
+	return attempts
+}
+
+var reEgressIP = regexp.MustCompile(`EGRESS_IP (\S+)`)
+
+// ParseRecoveredEndpoints returns the unreachable endpoints the userdata script reached when re-probing them
+func ParseRecoveredEndpoints(consoleLogs string) []string {
+	var recovered []string
+	for _, match := range reRetryResult.FindAllStringSubmatch(consoleLogs, -1) {
+		if match[2] == "REACHABLE" {
+			recovered = append(recovered, match[1])
+		}
+	}
+
+	return recovered
+}
+
+// ParseEgressIP returns the public IP the userdata script's traffic egressed from, or an empty string if it
+// couldn't be determined
+func ParseEgressIP(consoleLogs string) string {
+	match := reEgressIP.FindStringSubmatch(consoleLogs)
+	if match == nil || net.ParseIP(match[1]) == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+var reResolvConf = regexp.MustCompile(`RESOLV_CONF (nameserver|search) ([^\r\n]+)`)
+
+// ParseResolvConf returns the probe's resolver configuration reported by the userdata script
+func ParseResolvConf(consoleLogs string) output.ResolvConf {
+	rc := output.ResolvConf{}
+	for _, match := range reResolvConf.FindAllStringSubmatch(consoleLogs, -1) {
+		values := strings.Fields(match[2])
+		switch match[1] {
+		case "nameserver":
+			rc.Nameservers = append(rc.Nameservers, values...)
+		case "search":
+			// Only the last search line takes effect
+			rc.SearchDomains = values
+		}
+	}
+
+	return rc
+}
+
+var reTraceroute = regexp.MustCompile(`TRACEROUTE (\S+) LAST_HOP (\S+) (\d+)`)
+
+// ParseTraceroutes returns the last responsive hop of each endpoint traced by the userdata script,
+// formatted as "<ip> (hop <n>)", or "none" if no hop responded
+func ParseTraceroutes(consoleLogs string) map[string]string {
+	hops := map[string]string{}
+	for _, match := range reTraceroute.FindAllStringSubmatch(consoleLogs, -1) {
+		if match[2] == "none" {
+			hops[match[1]] = "none"
+			continue
+		}
+		hops[match[1]] = match[2] + " (hop " + match[3] + ")"
+	}
+
+	return hops
+}
+
+// ErrPollTimeout is returned by the poll functions when the condition isn't met within the timeout
+var ErrPollTimeout = errors.New("timed out waiting for the condition")
+
+// TeardownTimeout bounds the cleanup of cloud resources, which runs even when the run's context is done
+const TeardownTimeout = 2 * time.Minute
+
 // PollImmediate calls the condition function at the specified interval up to the specified timeout
 // until the condition function returns true or an error
 func PollImmediate(interval time.Duration, timeout time.Duration, condition func() (bool, error)) error {
+	return PollImmediateWithContext(context.Background(), interval, timeout, condition)
+}
+
+// PollImmediateWithContext is PollImmediate, which also stops when ctx is done, e.g. the run deadline passed
+func PollImmediateWithContext(ctx context.Context, interval time.Duration, timeout time.Duration, condition func() (bool, error)) error {
 	var totalTime time.Duration = 0
 
 	for totalTime < timeout {
@@ -24,9 +310,113 @@ func PollImmediate(interval time.Duration, timeout time.Duration, condition func
 			return nil
 		}
 
-		time.Sleep(interval)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for the condition: %w", ctx.Err())
+		case <-time.After(interval):
+		}
 		totalTime += interval
 	}
 
-	return errors.New("timed out waiting for the condition")
+	return ErrPollTimeout
+}
+
+// TeardownContext returns a context for cleaning up cloud resources that isn't done when the run's context is, so
+// probe instances are terminated even after the run deadline passed
+func TeardownContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), TeardownTimeout)
+}
+
+// ResourceExpiry returns when the resources of a run started at now can be deemed leaked: once the probe had all the
+// time it's given to launch, report and stay pooled, and its teardown had its own
+func ResourceExpiry(opts probe.Options, now time.Time) time.Time {
+	return now.Add(opts.LaunchTimeoutOrDefault() + opts.ConsoleTimeoutOrDefault() + opts.PoolWindow + TeardownTimeout)
+}
+
+// EmitEndpointResults emits an event for each endpoint result of the subnet's verification
+func EmitEndpointResults(opts probe.Options, subnetID string, o *output.Output) {
+	results := o.EndpointResults()
+	for i := range results {
+		opts.Emit(events.Event{Type: events.TypeEndpoint, Subnet: subnetID, InstanceID: o.Metadata().InstanceID, Endpoint: &results[i]})
+	}
+}
+
+// EmitFinished emits the end of the subnet's verification, with whether it succeeded and, if not, why
+func EmitFinished(opts probe.Options, subnetID string, o *output.Output) {
+	event := events.Event{Type: events.TypeFinished, Subnet: subnetID, InstanceID: o.Metadata().InstanceID, Success: events.Bool(o.IsSuccessful())}
+	if !o.IsSuccessful() {
+		failures, exceptions, errs := o.Parse()
+		var reasons []string
+		for _, list := range [][]error{failures, exceptions, errs} {
+			for _, err := range list {
+				reasons = append(reasons, err.Error())
+			}
+		}
+		event.Message = strings.Join(reasons, "; ")
+	}
+	opts.Emit(event)
+}
+
+// ParsePacketCaptures returns the pcap data, keyed by endpoint, captured by the userdata script
+func ParsePacketCaptures(consoleLogs string) (map[string][]byte, error) {
+	captures := map[string][]byte{}
+	for _, match := range rePacketCapture.FindAllStringSubmatch(consoleLogs, -1) {
+		data, err := base64.StdEncoding.DecodeString(match[2])
+		if err != nil {
+			return captures, fmt.Errorf("unable to decode packet capture for %s: %w", match[1], err)
+		}
+		if len(data) > 0 {
+			captures[match[1]] = data
+		}
+	}
+
+	return captures, nil
+}
+
+// ImageBuildUserdata returns the userdata of an image build instance, pulling the validator image so it's baked into
+// the image built from the instance
+func ImageBuildUserdata(validatorImage string) string {
+	return os.Expand(imageBuildTemplate, func(varName string) string {
+		if varName == "VALIDATOR_IMAGE" {
+			return validatorImage
+		}
+		return ""
+	})
+}
+
+// ParseImageBuild returns whether the image build instance is done pulling the validator image, as reported in its
+// console logs, and an error if the pull failed
+func ParseImageBuild(consoleLogs string) (bool, error) {
+	match := reImageBuild.FindStringSubmatch(consoleLogs)
+	if match == nil {
+		return false, nil
+	}
+	if match[1] == "FAILED" {
+		return true, fmt.Errorf("unable to pull the validator image %s on the build instance, check it exists and the build instance's subnet can reach its registry", match[2])
+	}
+
+	return true, nil
+}
+
+// SplitInstanceTypes splits a comma-separated instance type preference list, e.g. "e2-micro,e2-small"
+func SplitInstanceTypes(instanceTypes string) []string {
+	var result []string
+	for _, instanceType := range strings.Split(instanceTypes, ",") {
+		if instanceType = strings.TrimSpace(instanceType); instanceType != "" {
+			result = append(result, instanceType)
+		}
+	}
+
+	return result
+}
+
+// Contains reports whether value is among values
+func Contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
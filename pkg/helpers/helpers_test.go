@@ -0,0 +1,375 @@
+package helpers
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceroutes(t *testing.T) {
+	logs := `Unable to reach quay.io:443
+Unable to reach api.openshift.com:443
+TRACEROUTE quay.io:443 LAST_HOP 10.0.1.1 2
+TRACEROUTE api.openshift.com:443 LAST_HOP none 0`
+
+	assert.Equal(t, map[string]string{
+		"quay.io:443":           "10.0.1.1 (hop 2)",
+		"api.openshift.com:443": "none",
+	}, ParseTraceroutes(logs))
+}
+
+func TestParseTLSIssuers(t *testing.T) {
+	logs := `Unable to reach quay.io:443
+TLS_INTERCEPTED quay.io:443 C=US; O=Example Corp; CN=Example Proxy CA
+TLS_INTERCEPTED api.openshift.com:443 unknown`
+
+	assert.Equal(t, map[string]string{
+		"quay.io:443":           "C=US; O=Example Corp; CN=Example Proxy CA",
+		"api.openshift.com:443": "unknown",
+	}, ParseTLSIssuers(logs))
+
+	o := output.Output{}
+	ParseProbeResults(&o, logs+"\nHTTP_RESPONSE quay.io:443 200 0.1 - \n", "subnet-1")
+	results := o.EndpointResults()
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "C=US; O=Example Corp; CN=Example Proxy CA", results[0].TLSIssuer)
+		assert.Equal(t, "TLS intercepted, certificate issued by C=US; O=Example Corp; CN=Example Proxy CA", results[0].Note, "the interception is noted rather than the answer to the request not verifying certificates")
+	}
+}
+
+func TestParseTLSDiagnostics(t *testing.T) {
+	logs := `Unable to reach quay.io:443
+TLS_ERROR quay.io:443 OpenSSL SSL_connect: Connection reset by peer in connection to quay.io:443
+TLS_ATTEMPT quay.io:443 1.2 OK ECDHE-RSA-AES128-GCM-SHA256 h2
+TLS_ATTEMPT quay.io:443 1.3 FAIL - - OpenSSL SSL_connect: Connection reset by peer in connection to quay.io:443`
+
+	expected := &output.TLSDiagnostics{
+		Error: "OpenSSL SSL_connect: Connection reset by peer in connection to quay.io:443",
+		Attempts: []output.TLSAttempt{
+			{Version: "1.2", Success: true, Cipher: "ECDHE-RSA-AES128-GCM-SHA256", ALPN: "h2"},
+			{Version: "1.3", Error: "OpenSSL SSL_connect: Connection reset by peer in connection to quay.io:443"},
+		},
+	}
+	assert.Equal(t, map[string]*output.TLSDiagnostics{"quay.io:443": expected}, ParseTLSDiagnostics(logs))
+
+	o := output.Output{}
+	ParseProbeResults(&o, logs, "subnet-1")
+	assert.Equal(t, expected, o.EndpointResults()[0].TLS)
+	assert.Equal(t, "TLS handshake failed: OpenSSL SSL_connect: Connection reset by peer in connection to quay.io:443", o.EndpointResults()[0].Note)
+}
+
+func TestParsePacketCaptures(t *testing.T) {
+	logs := `PCAP BEGIN quay.io:443
+1MOyoQIABAA=
+PCAP END quay.io:443
+PCAP BEGIN api.openshift.com:443
+
+PCAP END api.openshift.com:443`
+
+	captures, err := ParsePacketCaptures(logs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"quay.io:443": {0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00},
+	}, captures)
+}
+
+func TestParseProbeResultsDNSSkipped(t *testing.T) {
+	o := output.Output{}
+	ParseProbeResults(&o, "USERDATA BEGIN\r\nDNS_SKIPPED dig is not installed on the probe image\r\nUSERDATA END", "subnet-1")
+
+	assert.Empty(t, o.DNSResults())
+	assert.Equal(t, []string{"DNS check skipped: dig is not installed on the probe image"}, o.Warnings())
+}
+
+func TestParseDNSResults(t *testing.T) {
+	logs := `DNS 10.0.0.2 quay.io RESOLVED 52.1.2.3
+DNS 10.0.0.2 registry.redhat.io UNRESOLVED -`
+
+	results := ParseDNSResults(logs)
+	assert.Len(t, results, 2)
+	assert.True(t, results[0].Resolved)
+	assert.Equal(t, "52.1.2.3", results[0].Address)
+	assert.False(t, results[1].Resolved)
+	assert.Equal(t, "registry.redhat.io", results[1].Domain)
+}
+
+func TestParsePSCResults(t *testing.T) {
+	logs := `PSC storage.googleapis.com 10.3.0.5 REACHABLE
+PSC compute.googleapis.com - UNREACHABLE`
+
+	results := ParsePSCResults(logs)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "10.3.0.5", results[0].Address)
+	assert.True(t, results[0].Reachable)
+	assert.Empty(t, results[1].Address)
+	assert.False(t, results[1].Reachable)
+}
+
+func TestParseHTTPResponses(t *testing.T) {
+	logs := "HTTP_RESPONSE quay.io:443 301 0.250123 - https://quay.io/\nHTTP_RESPONSE sso.redhat.com:443 000 2.001 tls \nHTTP_RESPONSE api.openshift.com:443 403 0.05 -\nHTTP_RESPONSE cert-api.access.redhat.com:443 000 0.001 dns\nHTTP_RESPONSE6 quay.io:443 000 0.002 dns \n"
+
+	responses := ParseHTTPResponses(logs)
+	assert.Len(t, responses, 5)
+	assert.Equal(t, output.HTTPResponse{Endpoint: "quay.io:443", Status: 301, RedirectURL: "https://quay.io/", ResponseTime: 250 * time.Millisecond, IPVersion: probe.IPVersion4}, responses[0])
+	assert.Zero(t, responses[1].Status)
+	assert.Empty(t, responses[1].RedirectURL)
+	assert.Equal(t, output.FailureStageTLS, responses[1].FailureStage)
+	assert.Equal(t, 403, responses[2].Status)
+	assert.Empty(t, responses[2].FailureStage)
+
+	o := &output.Output{}
+	o.AddEndpointResult(output.EndpointResult{Endpoint: "api.openshift.com:443", Subnet: "subnet-a"})
+	o.AddEndpointResult(output.EndpointResult{Endpoint: "cert-api.access.redhat.com:443", Subnet: "subnet-a"})
+	o.SetHTTPResponses(responses, "subnet-a")
+	results := o.EndpointResults()
+	assert.Len(t, results, 4, "the endpoint that didn't answer over IPv4 isn't known to have been probed")
+	assert.Equal(t, "answered HTTP 403", results[0].Note)
+	assert.Equal(t, output.FailureStageHTTP, results[0].FailureStage)
+	assert.Equal(t, "DNS resolution failed", results[1].Note)
+	assert.Equal(t, output.FailureStageDNS, results[1].FailureStage)
+	assert.True(t, results[2].Success)
+	assert.Empty(t, results[2].FailureStage)
+	assert.Equal(t, "https://quay.io/", results[2].RedirectURL)
+	assert.False(t, results[3].Success)
+	assert.Equal(t, probe.IPVersion6, results[3].IPVersion)
+	failures, _, _ := o.Parse()
+	assert.Empty(t, failures, "endpoints unreachable over IPv6 only warrant a warning")
+	assert.Len(t, o.Warnings(), 1)
+
+	pinned := &output.Output{}
+	pinned.Metadata().IPVersionPinned = true
+	pinned.SetHTTPResponses(ParseHTTPResponses(logs), "subnet-1")
+	failures, _, _ = pinned.Parse()
+	assert.Len(t, failures, 1, "endpoints unreachable over a pinned IP version fail the verification")
+	assert.Contains(t, failures[0].Error(), "Unable to reach quay.io:443 over IPv6")
+}
+
+func TestParseAttempts(t *testing.T) {
+	attempts := ParseAttempts("HTTP_RESPONSE quay.io:443 200 0.1 -\nATTEMPTS quay.io:443 2 3\nATTEMPTS sso.redhat.com:443 0 3\n")
+	assert.Equal(t, []output.Attempts{
+		{Endpoint: "quay.io:443", Attempts: 3, Successes: 2},
+		{Endpoint: "sso.redhat.com:443", Attempts: 3},
+	}, attempts)
+}
+
+func TestParseSoakRounds(t *testing.T) {
+	// Rounds are streamed to the console and reported again with the rest of the output
+	logs := "SOAK_ROUND 1 1661990400 3 -\nSOAK_ROUND 2 1661990460 3 quay.io:443,sso.redhat.com:443\nUSERDATA BEGIN\nSOAK_ROUND 1 1661990400 3 -\nSOAK_ROUND 2 1661990460 3 quay.io:443,sso.redhat.com:443\n"
+
+	rounds := ParseSoakRounds(logs)
+	assert.Len(t, rounds, 2)
+	assert.Empty(t, rounds[0].Unreachable)
+	assert.Equal(t, 3, rounds[1].Endpoints)
+	assert.Equal(t, int64(1661990460), rounds[1].Time.Unix())
+	assert.Equal(t, []string{"quay.io:443", "sso.redhat.com:443"}, rounds[1].Unreachable)
+}
+
+func TestParseEgressIP(t *testing.T) {
+	assert.Equal(t, "3.5.140.2", ParseEgressIP("USERDATA BEGIN\nEGRESS_IP 3.5.140.2\nUSERDATA END"))
+	assert.Empty(t, ParseEgressIP("EGRESS_IP -"))
+	assert.Empty(t, ParseEgressIP("USERDATA END"))
+}
+
+func TestParseResolvConf(t *testing.T) {
+	logs := `RESOLV_CONF search ec2.internal
+RESOLV_CONF nameserver 10.0.0.2
+RESOLV_CONF search a.example.com b.example.com c.example.com d.example.com
+RESOLV_CONF nameserver 192.168.1.53`
+
+	rc := ParseResolvConf(logs)
+	assert.Equal(t, []string{"10.0.0.2", "192.168.1.53"}, rc.Nameservers)
+	rc.VPCCIDR = "10.0.0.0/16"
+	assert.Equal(t, []string{"192.168.1.53"}, rc.CustomNameservers())
+	assert.Len(t, rc.SearchDomains, 4)
+	assert.Len(t, rc.Problems(), 1)
+}
+
+func TestSplitInstanceTypes(t *testing.T) {
+	assert.Equal(t, []string{"e2-micro", "e2-small", "n2-standard-2"}, SplitInstanceTypes("e2-micro, e2-small,,n2-standard-2"))
+	assert.Empty(t, SplitInstanceTypes(""))
+}
+
+func TestPollImmediateWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := PollImmediateWithContext(ctx, time.Hour, 2*time.Hour, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestResourceExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := probe.Options{LaunchTimeout: time.Minute, ConsoleTimeout: 2 * time.Minute, SoakDuration: time.Minute, PoolWindow: time.Hour}
+	assert.Equal(t, now.Add(time.Hour+4*time.Minute+TeardownTimeout), ResourceExpiry(opts, now))
+}
+
+func TestMergeConsoleOutput(t *testing.T) {
+	merged, gap := MergeConsoleOutput("", "boot\n")
+	assert.Equal(t, "boot\n", merged)
+	assert.False(t, gap)
+
+	// The console kept all of the previous read
+	merged, gap = MergeConsoleOutput("boot\n", "boot\nUSERDATA BEGIN\n")
+	assert.Equal(t, "boot\nUSERDATA BEGIN\n", merged)
+	assert.False(t, gap)
+
+	// The console dropped the start of the previous read, which the latest one overlaps
+	previous := "boot\n" + strings.Repeat("noise\n", 200) + "USERDATA BEGIN\n"
+	merged, gap = MergeConsoleOutput(previous, previous[600:]+"Unable to reach quay.io:443\nUSERDATA END\n")
+	assert.Equal(t, previous+"Unable to reach quay.io:443\nUSERDATA END\n", merged)
+	assert.False(t, gap)
+
+	// Nothing of the previous read is left in the latest one
+	merged, gap = MergeConsoleOutput("boot\n", "USERDATA END\n")
+	assert.Equal(t, "boot\nUSERDATA END\n", merged)
+	assert.True(t, gap)
+}
+
+func TestCheckConsoleCompletion(t *testing.T) {
+	markers := RunMarkers(probe.Options{}, "run1")
+	printed := "USERDATA BEGIN run1\nUnable to reach quay.io:443\nUSERDATA END run1\n"
+	sum := md5.Sum([]byte(printed))
+	done := fmt.Sprintf("%s run1 %s %d\n", ConsoleDoneMarker, hex.EncodeToString(sum[:]), len(printed))
+
+	assert.Equal(t, ConsoleCompletion{}, CheckConsoleCompletion("boot\nUSERDATA BEGIN run1\n", markers))
+	assert.Equal(t, ConsoleCompletion{Done: true}, CheckConsoleCompletion("boot\n"+printed, markers), "the end marker alone tells the probe is done")
+	assert.Equal(t, ConsoleCompletion{Done: true}, CheckConsoleCompletion("boot\r\n"+strings.ReplaceAll(printed, "\n", "\r\n")+done, markers), "the console's line endings don't count")
+
+	// The end of the output was cut off, the done marker still telling the probe is done
+	completion := CheckConsoleCompletion("boot\nUSERDATA BEGIN run1\nUnable to re\n"+done, markers)
+	assert.Equal(t, ConsoleCompletion{Done: true, Truncated: true}, completion)
+	completion = CheckConsoleCompletion("reach quay.io:443\nUSERDATA END run1\n"+done, markers)
+	assert.Equal(t, ConsoleCompletion{Done: true, Truncated: true}, completion, "the start of the output was cut off")
+
+	// The markers of other runs, e.g. an earlier one on the same instance, don't count
+	earlier := "USERDATA BEGIN run0\nUSERDATA END run0\nUSERDATA END run10\n" + strings.ReplaceAll(done, "run1", "run0")
+	assert.Equal(t, ConsoleCompletion{}, CheckConsoleCompletion(earlier+"USERDATA BEGIN run1\n", markers))
+	assert.Equal(t, ConsoleCompletion{Done: true}, CheckConsoleCompletion(earlier+printed, markers))
+
+	// Kernel and cloud-init lines printed amid and after the output aren't part of what the probe checksummed
+	interleaved := strings.Replace(printed, "Unable", "[   42.123456] random: crng init done\nUnable", 1) +
+		"cloud-init[1234]: Cloud-init v. 22.2 finished\n" + done
+	assert.Equal(t, ConsoleCompletion{Done: true}, CheckConsoleCompletion(interleaved, markers))
+}
+
+func TestRunMarkers(t *testing.T) {
+	assert.Equal(t, Markers{Begin: "USERDATA BEGIN", End: "USERDATA END", Done: "USERDATA DONE"}, RunMarkers(probe.Options{}, ""))
+	assert.Equal(t, Markers{Begin: "PROBE START abc", End: "PROBE STOP abc", Done: "USERDATA DONE abc"}, RunMarkers(probe.Options{BeginMarker: "PROBE START", EndMarker: "PROBE STOP"}, "abc"))
+}
+
+func TestParseRecoveredEndpoints(t *testing.T) {
+	logs := "Unable to reach quay.io:443\nUnable to reach sso.redhat.com:443\nRETRY quay.io:443 REACHABLE\nRETRY sso.redhat.com:443 UNREACHABLE"
+	assert.Equal(t, []string{"quay.io:443"}, ParseRecoveredEndpoints(logs))
+	assert.Empty(t, ParseRecoveredEndpoints("USERDATA END"))
+}
+
+func TestImageBuild(t *testing.T) {
+	userdata := ImageBuildUserdata("quay.io/app-sre/osd-network-verifier:v1")
+	assert.Contains(t, userdata, "sudo docker pull quay.io/app-sre/osd-network-verifier:v1")
+	assert.NotContains(t, userdata, "${")
+
+	done, err := ParseImageBuild("Starting docker")
+	assert.False(t, done)
+	assert.NoError(t, err)
+	done, err = ParseImageBuild("IMAGE BUILD PULLED quay.io/app-sre/osd-network-verifier:v1\n")
+	assert.True(t, done)
+	assert.NoError(t, err)
+	done, err = ParseImageBuild("IMAGE BUILD FAILED quay.io/app-sre/osd-network-verifier:v1\n")
+	assert.True(t, done)
+	assert.Error(t, err)
+}
+
+func TestValidatorResultsSchema(t *testing.T) {
+	assert.Equal(t, fmt.Sprintf("%d %d", SchemaVersionText, SchemaVersionJSON), SupportedSchemaVersions)
+	assert.Equal(t, SchemaVersionText, ParseSchemaVersion("Unable to reach quay.io:443"), "validators stating no version emit text")
+	assert.Equal(t, 7, ParseSchemaVersion("RESULTS_SCHEMA 7\nUnable to reach quay.io:443"))
+
+	o := output.Output{}
+	ParseProbeResults(&o, "USERDATA BEGIN\nUnable to reach quay.io:443\nUSERDATA END", "subnet-1")
+	assert.Equal(t, SchemaVersionText, o.Metadata().ResultsSchema)
+	assert.Empty(t, o.Warnings())
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 1)
+
+	// Results in a newer schema are decoded as the newest one known, with a warning
+	o = output.Output{}
+	ParseProbeResults(&o, "RESULTS_SCHEMA 99\nUnable to reach quay.io:443", "subnet-1")
+	assert.Equal(t, 99, o.Metadata().ResultsSchema)
+	assert.Len(t, o.Warnings(), 1)
+	failures, _, _ = o.Parse()
+	assert.Len(t, failures, 1)
+}
+
+func TestDualFormatResults(t *testing.T) {
+	results := `RESULTS_JSON {"schema_version":2,"results":[{"endpoint":"quay.io:443","reachable":false,"error":"i/o timeout"},` +
+		`{"endpoint":"api.openshift.com:443","reachable":true}]}`
+	assert.Equal(t, SchemaVersionJSON, ParseSchemaVersion(results+"\r\n"), "validators printing JSON results without a version")
+	assert.Equal(t, SchemaVersionJSON, ParseSchemaVersion("RESULTS_SCHEMA 2\n"+results))
+	assert.Equal(t, SchemaVersionText, ParseSchemaVersion("Unable to reach quay.io:443\nRESULTS_JSON_SIZE 1"))
+
+	// The JSON results are decoded along with the text lines the userdata script prints, each endpoint once, and the
+	// reachable endpoints are reported with the latency of their HTTP response
+	o := output.Output{}
+	ParseProbeResults(&o, "USERDATA BEGIN\r\n"+results+"\r\nUnable to reach quay.io:443\r\nUnable to reach example.com:443\r\n"+
+		"HTTP_RESPONSE api.openshift.com:443 200 0.25 -\r\nUSERDATA END", "subnet-1")
+	assert.Equal(t, SchemaVersionJSON, o.Metadata().ResultsSchema)
+	assert.Empty(t, o.Warnings())
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 2)
+	var endpoints, notes []string
+	for _, result := range o.EndpointResults() {
+		endpoints = append(endpoints, result.Endpoint)
+		notes = append(notes, result.Note)
+	}
+	assert.Equal(t, []string{"quay.io:443", "example.com:443", "api.openshift.com:443"}, endpoints)
+	assert.Equal(t, []string{"i/o timeout", "", ""}, notes, "the validator's error is kept, the text lines carry none")
+	reached := o.EndpointResults()[2]
+	assert.True(t, reached.Success)
+	assert.Equal(t, "subnet-1", reached.Subnet)
+	assert.Equal(t, 250*time.Millisecond, reached.Latency)
+
+	// JSON results cut off by the console fall back to the text lines, with a warning
+	o = output.Output{}
+	ParseProbeResults(&o, "RESULTS_SCHEMA 2\n"+results[:60]+"\nUnable to reach example.com:443\n", "subnet-1")
+	assert.Len(t, o.Warnings(), 1)
+	failures, _, _ = o.Parse()
+	assert.Len(t, failures, 1)
+}
+
+func TestStartupScript(t *testing.T) {
+	script, err := StartupScript("#cloud-config\nrepo_update: true\nwrite_files:\n  - path: /run-container.sh\n    permissions: 755\n" +
+		"    content: |\n      #!/bin/bash\n      echo \"$$HOME\"\nruncmd:\n  - /run-container.sh\n  - cat /var/log/userdata-output >/dev/console\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "#!/bin/bash\n"+containerRuntimeSetup+
+		"mkdir -p '/'\n"+
+		"cat > '/run-container.sh' <<'OSD_NETWORK_VERIFIER_EOF'\n#!/bin/bash\necho \"$$HOME\"\nOSD_NETWORK_VERIFIER_EOF\n"+
+		"chmod 755 '/run-container.sh'\n"+
+		"/run-container.sh\ncat /var/log/userdata-output >/dev/console\n", script)
+
+	// The probe's own userdata converts
+	script, err = StartupScript(ImageBuildUserdata("quay.io/app-sre/osd-network-verifier:v1"))
+	assert.NoError(t, err)
+	assert.Contains(t, script, "sudo docker pull quay.io/app-sre/osd-network-verifier:v1")
+
+	_, err = StartupScript("write_files:\n  - path: /a\n    content: |\n      OSD_NETWORK_VERIFIER_EOF\n")
+	assert.Error(t, err)
+}
+
+func TestContains(t *testing.T) {
+	assert.True(t, Contains([]string{"rtb-a", "rtb-b"}, "rtb-b"))
+	assert.False(t, Contains([]string{"rtb-a"}, "rtb-b"))
+	assert.False(t, Contains(nil, ""))
+}
@@ -0,0 +1,172 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// Schema versions of the validator's results. The validator is passed the versions the verifier decodes, and states
+// the one it emits its results in on a "RESULTS_SCHEMA <version>" line, so the verifier and the validator image can be
+// rolled out independently.
+const (
+	// SchemaVersionText is the text lines, e.g. "Unable to reach quay.io:443", of validators stating no version
+	SchemaVersionText = 1
+	// SchemaVersionJSON is a "RESULTS_JSON <json>" line of jsonResults, alongside the text lines of endpoints the
+	// userdata script probes itself, or that a validator migrating to it still prints
+	SchemaVersionJSON = 2
+)
+
+// SupportedSchemaVersions are the schema versions the verifier decodes, as passed to the validator in the
+// RESULTS_SCHEMA_VERSIONS environment variable. A validator only switches to JSON for verifiers listing it, older ones
+// keep getting the text lines they decode.
+const SupportedSchemaVersions = "1 2"
+
+// resultsJSONPrefix starts the line of a validator's results in SchemaVersionJSON
+const resultsJSONPrefix = "RESULTS_JSON "
+
+// jsonResults are the validator's results in SchemaVersionJSON, e.g.
+// {"schema_version":2,"results":[{"endpoint":"quay.io:443","reachable":false,"error":"i/o timeout"}]}
+type jsonResults struct {
+	SchemaVersion int `json:"schema_version"`
+	Results       []struct {
+		Endpoint  string `json:"endpoint"`
+		Reachable bool   `json:"reachable"`
+		Error     string `json:"error,omitempty"`
+	} `json:"results"`
+}
+
+var (
+	reSchemaVersion = regexp.MustCompile(`RESULTS_SCHEMA (\d+)`)
+	reResultsJSON   = regexp.MustCompile(`(?m)^` + resultsJSONPrefix + `(.*?)\r?$`)
+)
+
+// ParseSchemaVersion returns the schema version the validator stated its results are in. Validators that don't state
+// one are told apart by what they print: SchemaVersionJSON for a results line of JSON, SchemaVersionText otherwise.
+func ParseSchemaVersion(consoleLogs string) int {
+	fallback := SchemaVersionText
+	if reResultsJSON.MatchString(consoleLogs) {
+		fallback = SchemaVersionJSON
+	}
+	match := reSchemaVersion.FindStringSubmatch(consoleLogs)
+	if match == nil {
+		return fallback
+	}
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return fallback
+	}
+
+	return version
+}
+
+// decodeValidatorResults returns the validator's results, decoded by the schema version they're in. Results in a
+// version the verifier doesn't decode are decoded as JSON, the newest it does, and an error is returned, as some may
+// be missed. An error is also returned along with what could be decoded when some of the results couldn't.
+func decodeValidatorResults(consoleLogs string) (int, []output.EndpointResult, error) {
+	version := ParseSchemaVersion(consoleLogs)
+	switch version {
+	case SchemaVersionText:
+		return version, decodeTextResults(consoleLogs), nil
+	case SchemaVersionJSON:
+		results, err := decodeJSONResults(consoleLogs)
+		return version, results, err
+	}
+
+	err := fmt.Errorf("the validator's results are in schema version %d, which this verifier doesn't decode (it decodes %s), "+
+		"upgrade the verifier or pin an older --validator-image: decoded as version %d, some results may be missing", version, SupportedSchemaVersions, SchemaVersionJSON)
+	results, decodeErr := decodeJSONResults(consoleLogs)
+	if decodeErr != nil {
+		err = fmt.Errorf("%v; %w", err, decodeErr)
+	}
+
+	return version, results, err
+}
+
+// decodeTextResults decodes the text lines of SchemaVersionText, which only name the unreachable endpoints, not why
+func decodeTextResults(consoleLogs string) []output.EndpointResult {
+	var unreachable []output.EndpointResult
+	for _, match := range reUnreachableEndpoint.FindAllStringSubmatch(consoleLogs, -1) {
+		unreachable = append(unreachable, output.EndpointResult{Endpoint: match[1]})
+	}
+
+	return unreachable
+}
+
+// decodeJSONResults decodes the results lines of SchemaVersionJSON, together with the text lines printed alongside
+// them, an endpoint reported unreachable by both being counted once with the error of its results line. Endpoints
+// the validator reached are returned too, after the unreachable ones, unless a text line reports them unreachable.
+// Results lines that don't parse, e.g. cut off by the console, are skipped with an error, the text lines still being
+// decoded.
+func decodeJSONResults(consoleLogs string) ([]output.EndpointResult, error) {
+	var (
+		results   []output.EndpointResult
+		reachable []output.EndpointResult
+		invalid   int
+		seen      = map[string]bool{}
+	)
+	add := func(result output.EndpointResult) {
+		if result.Endpoint != "" && !seen[result.Endpoint] {
+			seen[result.Endpoint] = true
+			results = append(results, result)
+		}
+	}
+
+	for _, match := range reResultsJSON.FindAllStringSubmatch(consoleLogs, -1) {
+		var decoded jsonResults
+		if err := json.Unmarshal([]byte(match[1]), &decoded); err != nil {
+			invalid++
+			continue
+		}
+		for _, result := range decoded.Results {
+			if result.Reachable {
+				reachable = append(reachable, output.EndpointResult{Endpoint: result.Endpoint, Success: true})
+			} else {
+				add(output.EndpointResult{Endpoint: result.Endpoint, Note: result.Error})
+			}
+		}
+	}
+	for _, result := range decodeTextResults(consoleLogs) {
+		add(result)
+	}
+	for _, result := range reachable {
+		add(result)
+	}
+
+	if invalid > 0 {
+		return results, fmt.Errorf("unable to parse %d of the validator's %s lines, e.g. cut off by the console: "+
+			"only its text results were decoded for them, some results may be missing", invalid, strings.TrimSpace(resultsJSONPrefix))
+	}
+
+	return results, nil
+}
+
+// egressFailures returns the egress failures of the unreachable endpoints, as the text schema words them
+func egressFailures(results []output.EndpointResult) []string {
+	var failures []string
+	for _, result := range results {
+		if !result.Success {
+			failures = append(failures, "Unable to reach "+result.Endpoint)
+		}
+	}
+
+	return failures
+}
+
+// recordValidatorResults records the validator's results on o, along with the schema version they were in
+func recordValidatorResults(o *output.Output, consoleLogs, subnetID string) {
+	version, results, err := decodeValidatorResults(consoleLogs)
+	if err != nil {
+		o.AddWarning(err.Error())
+	}
+	o.Metadata().ResultsSchema = version
+	o.SetEgressFailures(egressFailures(results))
+	for _, result := range results {
+		result.Subnet = subnetID
+		o.AddEndpointResult(result)
+	}
+}
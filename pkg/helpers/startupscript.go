@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// startupScriptDelimiter ends the here-documents the files of the cloud-config are written with
+const startupScriptDelimiter = "OSD_NETWORK_VERIFIER_EOF"
+
+// containerRuntimeSetup starts the startup script. The images without cloud-init, e.g. RHEL, Rocky Linux, CentOS and
+// Debian, don't ship docker, so podman stands in for it, installed from the distribution's repositories if need be. It's
+// linked as /usr/bin/docker, on sudo's secure_path, for the probe's docker commands to run it.
+const containerRuntimeSetup = `if ! command -v docker >/dev/null 2>&1; then
+  if ! command -v podman >/dev/null 2>&1; then
+    if command -v dnf >/dev/null 2>&1; then dnf install -y podman >/dev/null 2>&1
+    elif command -v yum >/dev/null 2>&1; then yum install -y podman >/dev/null 2>&1
+    elif command -v apt-get >/dev/null 2>&1; then apt-get update >/dev/null 2>&1 && DEBIAN_FRONTEND=noninteractive apt-get install -y podman >/dev/null 2>&1
+    fi
+  fi
+  if command -v podman >/dev/null 2>&1; then ln -sf "$(command -v podman)" /usr/bin/docker; fi
+fi
+`
+
+// cloudConfig is the part of a cloud-config the probe's userdata uses
+type cloudConfig struct {
+	WriteFiles []struct {
+		Path        string      `json:"path"`
+		Permissions interface{} `json:"permissions"`
+		Content     string      `json:"content"`
+	} `json:"write_files"`
+	Runcmd []string `json:"runcmd"`
+}
+
+// StartupScript turns the cloud-config of the probe's userdata into a shell script doing the same, for images without
+// cloud-init that run a startup script instead, e.g. RHEL or Rocky Linux on GCP: its files are written, then its
+// commands run in order. The other cloud-config modules, e.g. repo_update, are left out. As those images don't ship
+// docker, podman is set up in its place first.
+func StartupScript(userdata string) (string, error) {
+	var config cloudConfig
+	if err := yaml.Unmarshal([]byte(userdata), &config); err != nil {
+		return "", fmt.Errorf("unable to parse the userdata's cloud-config: %w", err)
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/bin/bash\n")
+	script.WriteString(containerRuntimeSetup)
+	for _, file := range config.WriteFiles {
+		content := file.Content
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if strings.Contains("\n"+content, "\n"+startupScriptDelimiter+"\n") {
+			return "", fmt.Errorf("the content of %s contains the line %s, which ends the startup script's here-document", file.Path, startupScriptDelimiter)
+		}
+		fmt.Fprintf(&script, "mkdir -p '%s'\n", path.Dir(file.Path))
+		fmt.Fprintf(&script, "cat > '%s' <<'%s'\n%s%[2]s\n", file.Path, startupScriptDelimiter, content)
+		if file.Permissions != nil {
+			fmt.Fprintf(&script, "chmod %v '%s'\n", file.Permissions, file.Path)
+		}
+	}
+	for _, command := range config.Runcmd {
+		script.WriteString(command + "\n")
+	}
+
+	return script.String(), nil
+}
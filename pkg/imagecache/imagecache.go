@@ -0,0 +1,114 @@
+package imagecache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long a resolved image is reused before it's looked up again
+const DefaultTTL = 24 * time.Hour
+
+// Cache remembers resolved images, e.g. the image an image family currently points to, on disk for a TTL, so bulk
+// multi-subnet runs don't repeat identical lookups, and re-runs in the same region skip them entirely. A nil cache
+// caches nothing. A cache file that can't be read or written is treated as empty, the lookups being made as without
+// one.
+type Cache struct {
+	file string
+	ttl  time.Duration
+	// now is the current time, replaced in tests
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// entry is a resolved image, as stored in the cache file
+type entry struct {
+	Image    string    `json:"image"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// New returns a cache stored in file, whose entries expire after ttl
+func New(file string, ttl time.Duration) *Cache {
+	return &Cache{file: file, ttl: ttl, now: time.Now}
+}
+
+// DefaultFile returns the cache file in the user's cache directory, e.g. ~/.cache/osd-network-verifier/images.json
+func DefaultFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "osd-network-verifier", "images.json"), nil
+}
+
+// Key identifies what's resolved, by the provider and region it's resolved in, e.g. an image family's name
+func Key(provider, region, name string) string {
+	return provider + "/" + region + "/" + name
+}
+
+// Resolve returns the image cached for the key, unless it expired, and otherwise the one resolve returns, caching it
+func (c *Cache) Resolve(key string, resolve func() (string, error)) (string, error) {
+	if c == nil {
+		return resolve()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := c.load()
+	if e, ok := entries[key]; ok && e.Image != "" && c.now().Sub(e.Resolved) < c.ttl {
+		return e.Image, nil
+	}
+
+	image, err := resolve()
+	if err != nil {
+		return "", err
+	}
+	entries[key] = entry{Image: image, Resolved: c.now()}
+	c.store(entries)
+
+	return image, nil
+}
+
+// load reads the cache file, returning no entries if it can't be read
+func (c *Cache) load() map[string]entry {
+	entries := map[string]entry{}
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return map[string]entry{}
+	}
+
+	return entries
+}
+
+// store writes the cache file, replacing it whole so concurrent verifiers never read a partial one. Entries that
+// can't be stored are looked up again next time.
+func (c *Cache) store(entries map[string]entry) {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.file); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,70 @@
+package imagecache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache", "images.json")
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	c := New(file, time.Hour)
+	c.now = func() time.Time { return now }
+	lookups := 0
+	resolve := func() (string, error) {
+		lookups++
+		return "image-1", nil
+	}
+
+	image, err := c.Resolve(Key("gcp", "us-east1", "cos-97-lts"), resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, "image-1", image)
+
+	// Cached on disk, so another cache on the same file, e.g. of a re-run, skips the lookup
+	rerun := New(file, time.Hour)
+	rerun.now = c.now
+	image, err = rerun.Resolve(Key("gcp", "us-east1", "cos-97-lts"), resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, "image-1", image)
+	assert.Equal(t, 1, lookups)
+
+	// Other regions are resolved of their own
+	_, err = c.Resolve(Key("gcp", "europe-west1", "cos-97-lts"), resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lookups)
+
+	// Expired entries are looked up again
+	now = now.Add(2 * time.Hour)
+	_, err = c.Resolve(Key("gcp", "us-east1", "cos-97-lts"), resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, lookups)
+
+	// Failed lookups aren't cached
+	_, err = c.Resolve("failing", func() (string, error) { return "", errors.New("forbidden") })
+	assert.EqualError(t, err, "forbidden")
+	_, err = c.Resolve("failing", resolve)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, lookups)
+}
+
+func TestResolveWithoutCache(t *testing.T) {
+	var none *Cache
+	image, err := none.Resolve("key", func() (string, error) { return "image-1", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "image-1", image)
+
+	// A corrupt cache file is treated as empty and replaced
+	file := filepath.Join(t.TempDir(), "images.json")
+	assert.NoError(t, os.WriteFile(file, []byte("{"), 0o600))
+	image, err = New(file, time.Hour).Resolve("key", func() (string, error) { return "image-2", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "image-2", image)
+	image, err = New(file, time.Hour).Resolve("key", func() (string, error) { return "", errors.New("not cached") })
+	assert.NoError(t, err)
+	assert.Equal(t, "image-2", image)
+}
@@ -0,0 +1,171 @@
+package installconfig
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// InstallConfig is the part of an OpenShift install-config.yaml relevant to verification
+type InstallConfig struct {
+	Platform              Platform   `json:"platform"`
+	Proxy                 *Proxy     `json:"proxy,omitempty"`
+	AdditionalTrustBundle string     `json:"additionalTrustBundle,omitempty"`
+	Networking            Networking `json:"networking"`
+}
+
+// Platform holds the cloud platform's settings, only one of which is set
+type Platform struct {
+	AWS *AWSPlatform `json:"aws,omitempty"`
+	GCP *GCPPlatform `json:"gcp,omitempty"`
+}
+
+// AWSPlatform holds the installer's AWS settings. Subnets are listed directly by older installers, and under vpc by
+// newer ones.
+type AWSPlatform struct {
+	Region  string   `json:"region"`
+	Subnets []string `json:"subnets,omitempty"`
+	VPC     *struct {
+		Subnets []struct {
+			ID string `json:"id"`
+		} `json:"subnets,omitempty"`
+	} `json:"vpc,omitempty"`
+}
+
+// GCPPlatform holds the installer's GCP settings
+type GCPPlatform struct {
+	ProjectID          string `json:"projectID"`
+	Region             string `json:"region"`
+	Network            string `json:"network,omitempty"`
+	ComputeSubnet      string `json:"computeSubnet,omitempty"`
+	ControlPlaneSubnet string `json:"controlPlaneSubnet,omitempty"`
+}
+
+// Proxy is the cluster-wide proxy
+type Proxy struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
+
+// Networking holds the cluster's address ranges
+type Networking struct {
+	NetworkType    string `json:"networkType,omitempty"`
+	MachineNetwork []struct {
+		CIDR string `json:"cidr"`
+	} `json:"machineNetwork,omitempty"`
+	ClusterNetwork []struct {
+		CIDR string `json:"cidr"`
+	} `json:"clusterNetwork,omitempty"`
+	ServiceNetwork []string `json:"serviceNetwork,omitempty"`
+}
+
+// Load reads an install-config.yaml
+func Load(file string) (*InstallConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read install config %s: %w", file, err)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("install config %s: %w", file, err)
+	}
+
+	return config, nil
+}
+
+// Parse parses an install-config.yaml, which must be for a cluster installed into existing subnets
+func Parse(data []byte) (*InstallConfig, error) {
+	config := &InstallConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse install config: %w", err)
+	}
+
+	switch {
+	case config.Platform.AWS == nil && config.Platform.GCP == nil:
+		return nil, fmt.Errorf("only the aws and gcp platforms are supported")
+	case len(config.SubnetIDs()) == 0:
+		return nil, fmt.Errorf("the cluster is not installed into existing subnets, there are no subnets to verify")
+	}
+
+	return config, nil
+}
+
+// PlatformName returns aws or gcp
+func (c *InstallConfig) PlatformName() string {
+	if c.Platform.GCP != nil {
+		return "gcp"
+	}
+
+	return "aws"
+}
+
+// Region returns the cluster's region
+func (c *InstallConfig) Region() string {
+	if c.Platform.GCP != nil {
+		return c.Platform.GCP.Region
+	}
+
+	return c.Platform.AWS.Region
+}
+
+// SubnetIDs returns the existing subnets the cluster is installed into: every listed subnet on AWS, and the compute
+// and control plane subnets on GCP
+func (c *InstallConfig) SubnetIDs() []string {
+	var subnetIDs []string
+	if gcp := c.Platform.GCP; gcp != nil {
+		for _, subnet := range []string{gcp.ComputeSubnet, gcp.ControlPlaneSubnet} {
+			if subnet != "" && (len(subnetIDs) == 0 || subnetIDs[0] != subnet) {
+				subnetIDs = append(subnetIDs, subnet)
+			}
+		}
+		return subnetIDs
+	}
+
+	subnetIDs = append(subnetIDs, c.Platform.AWS.Subnets...)
+	if c.Platform.AWS.VPC != nil {
+		for _, subnet := range c.Platform.AWS.VPC.Subnets {
+			subnetIDs = append(subnetIDs, subnet.ID)
+		}
+	}
+
+	return subnetIDs
+}
+
+// Overlaps describes the machine, cluster and service networks that overlap one another, which keeps traffic from
+// being routed to the right one
+func (n Networking) Overlaps() []string {
+	type cidr struct {
+		kind    string
+		network *net.IPNet
+	}
+	var cidrs []cidr
+	add := func(kind, value string) {
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			cidrs = append(cidrs, cidr{kind: kind, network: network})
+		}
+	}
+	for _, m := range n.MachineNetwork {
+		add("machine network", m.CIDR)
+	}
+	for _, c := range n.ClusterNetwork {
+		add("cluster network", c.CIDR)
+	}
+	for _, s := range n.ServiceNetwork {
+		add("service network", s)
+	}
+
+	var overlaps []string
+	for i, a := range cidrs {
+		for _, b := range cidrs[i+1:] {
+			if a.network.Contains(b.network.IP) || b.network.Contains(a.network.IP) {
+				overlaps = append(overlaps, fmt.Sprintf("%s %s overlaps %s %s", a.kind, a.network, b.kind, b.network))
+			}
+		}
+	}
+
+	return overlaps
+}
@@ -0,0 +1,85 @@
+package installconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(`apiVersion: v1
+baseDomain: example.com
+metadata:
+  name: mycluster
+platform:
+  aws:
+    region: us-east-1
+    subnets:
+    - subnet-a
+    vpc:
+      subnets:
+      - id: subnet-b
+        roles:
+        - type: ClusterNode
+proxy:
+  httpsProxy: http://proxy:3128
+  noProxy: .example.com
+additionalTrustBundle: |
+  -----BEGIN CERTIFICATE-----
+networking:
+  networkType: OVNKubernetes
+  machineNetwork:
+  - cidr: 10.0.0.0/16
+  clusterNetwork:
+  - cidr: 10.128.0.0/14
+    hostPrefix: 23
+  serviceNetwork:
+  - 172.30.0.0/16
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "aws", config.PlatformName())
+	assert.Equal(t, "us-east-1", config.Region())
+	assert.Equal(t, []string{"subnet-a", "subnet-b"}, config.SubnetIDs())
+	assert.Equal(t, &Proxy{HTTPSProxy: "http://proxy:3128", NoProxy: ".example.com"}, config.Proxy)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\n", config.AdditionalTrustBundle)
+	assert.Empty(t, config.Networking.Overlaps())
+
+	config, err = Parse([]byte(`platform:
+  gcp:
+    projectID: project
+    region: us-east1
+    network: vpc
+    computeSubnet: nodes
+    controlPlaneSubnet: nodes
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "gcp", config.PlatformName())
+	assert.Equal(t, "us-east1", config.Region())
+	assert.Equal(t, []string{"nodes"}, config.SubnetIDs())
+
+	_, err = Parse([]byte(`platform:
+  aws:
+    region: us-east-1
+`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`platform:
+  none: {}
+`))
+	assert.Error(t, err)
+}
+
+func TestOverlaps(t *testing.T) {
+	config, err := Parse([]byte(`platform:
+  aws:
+    subnets: [subnet-a]
+networking:
+  machineNetwork:
+  - cidr: 10.128.0.0/16
+  clusterNetwork:
+  - cidr: 10.128.0.0/14
+  serviceNetwork:
+  - 172.30.0.0/16
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"machine network 10.128.0.0/16 overlaps cluster network 10.128.0.0/14"}, config.Networking.Overlaps())
+}
@@ -0,0 +1,145 @@
+package logforwarding
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Types of log and metric forwarding destinations
+const (
+	TypeSplunkHEC = "splunk-hec"
+	TypeSyslogTLS = "syslog-tls"
+	TypeSumo      = "sumo"
+)
+
+// Types returns every type of destination
+func Types() []string {
+	return []string{TypeSplunkHEC, TypeSyslogTLS, TypeSumo}
+}
+
+// Category is the category the results of the destinations are reported under, so a failure isn't mistaken for one of
+// the endpoints the cluster itself depends on
+const Category = "log forwarding"
+
+// defaultPorts are the ports of each type of destination given without one: Splunk's HTTP Event Collector listens on
+// 8088 unless behind Splunk Cloud's 443, syslog over TLS on 6514, and Sumo Logic's HTTP sources on 443
+var defaultPorts = map[string]int{
+	TypeSplunkHEC: 8088,
+	TypeSyslogTLS: 6514,
+	TypeSumo:      443,
+}
+
+// Config is a log forwarding configuration file, listing the customer's destinations
+type Config struct {
+	Destinations []Destination `json:"destinations"`
+}
+
+// Destination is a log or metric forwarding destination
+type Destination struct {
+	// Name identifies the destination in the results, if given
+	Name string `json:"name,omitempty"`
+	// Type is one of the Type constants
+	Type string `json:"type"`
+	// URL is the destination's URL, e.g. a Splunk HEC or Sumo Logic HTTP source URL, or its host[:port]
+	URL string `json:"url"`
+}
+
+// Load reads a log forwarding configuration file
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read log forwarding configuration %s: %w", file, err)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("log forwarding configuration %s: %w", file, err)
+	}
+
+	return config, nil
+}
+
+// Parse parses a log forwarding configuration, rejecting unknown fields, types and destinations without a host
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse log forwarding configuration: %w", err)
+	}
+	for i, d := range config.Destinations {
+		if _, ok := defaultPorts[d.Type]; !ok {
+			return nil, fmt.Errorf("destination %d: unknown type %q, must be one of %q", i+1, d.Type, Types())
+		}
+		if _, err := d.Endpoint(); err != nil {
+			return nil, fmt.Errorf("destination %d: %w", i+1, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Endpoint returns the host:port the destination is reached at, the port defaulting to the type's
+func (d Destination) Endpoint() (string, error) {
+	address := d.URL
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL %s: %w", d.URL, err)
+		}
+		address = u.Host
+		if u.Port() == "" && u.Scheme == "https" {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, strconv.Itoa(defaultPorts[d.Type])
+	}
+	if host == "" {
+		return "", fmt.Errorf("no host in %q", d.URL)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port %s in %s", port, d.URL)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// Categories returns the category of each destination's endpoint, e.g. "log forwarding (splunk-hec audit)", keyed by
+// the endpoint as host:port. A nil configuration has none.
+func (c *Config) Categories() map[string]string {
+	if c == nil {
+		return nil
+	}
+	categories := map[string]string{}
+	for _, d := range c.Destinations {
+		endpoint, err := d.Endpoint()
+		if err != nil {
+			continue
+		}
+		category := fmt.Sprintf("%s (%s)", Category, d.Type)
+		if d.Name != "" {
+			category = fmt.Sprintf("%s (%s %s)", Category, d.Type, d.Name)
+		}
+		categories[endpoint] = category
+	}
+
+	return categories
+}
+
+// Endpoints returns the endpoints of the destinations as host:port, sorted
+func (c *Config) Endpoints() []string {
+	var eps []string
+	for endpoint := range c.Categories() {
+		eps = append(eps, endpoint)
+	}
+	sort.Strings(eps)
+
+	return eps
+}
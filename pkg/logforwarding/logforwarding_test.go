@@ -0,0 +1,39 @@
+package logforwarding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(`destinations:
+- {name: audit, type: splunk-hec, url: "https://http-inputs-acme.splunkcloud.com/services/collector"}
+- {type: splunk-hec, url: splunk.example.com}
+- {type: syslog-tls, url: "syslog.example.com"}
+- {type: syslog-tls, url: "tls://10.0.0.5:1514"}
+- {type: sumo, url: "https://endpoint4.collection.us2.sumologic.com/receiver/v1/http/abc"}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"10.0.0.5:1514",
+		"endpoint4.collection.us2.sumologic.com:443",
+		"http-inputs-acme.splunkcloud.com:443",
+		"splunk.example.com:8088",
+		"syslog.example.com:6514",
+	}, config.Endpoints())
+	assert.Equal(t, "log forwarding (splunk-hec audit)", config.Categories()["http-inputs-acme.splunkcloud.com:443"])
+	assert.Equal(t, "log forwarding (syslog-tls)", config.Categories()["syslog.example.com:6514"])
+
+	_, err = Parse([]byte(`destinations: [{type: fluentd, url: fluentd.example.com}]`))
+	assert.EqualError(t, err, `destination 1: unknown type "fluentd", must be one of ["splunk-hec" "syslog-tls" "sumo"]`)
+	_, err = Parse([]byte(`destinations: [{type: sumo, url: "https:///receiver"}]`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`destinations: [{type: syslog-tls, url: "syslog.example.com:70000"}]`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`destinations: [{type: sumo, uri: "https://collectors.sumologic.com"}]`))
+	assert.Error(t, err, "unknown fields are rejected")
+
+	var none *Config
+	assert.Empty(t, none.Endpoints())
+}
@@ -0,0 +1,77 @@
+// Package logging is the logging interface of the verifier's library packages, so embedding them doesn't depend on
+// ocm-sdk-go. Its Logger has the method set of ocm-sdk-go's logging.Logger, whose loggers are used as they are;
+// FromSlog adapts a log/slog logger, and NewStdLogger logs through the standard library's log package.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Logger is what the verifier logs its progress through. Messages are formatted with fmt.Sprintf.
+type Logger interface {
+	DebugEnabled() bool
+	InfoEnabled() bool
+	WarnEnabled() bool
+	ErrorEnabled() bool
+
+	Debug(ctx context.Context, format string, args ...interface{})
+	Info(ctx context.Context, format string, args ...interface{})
+	Warn(ctx context.Context, format string, args ...interface{})
+	Error(ctx context.Context, format string, args ...interface{})
+	// Fatal logs an error and exits the process
+	Fatal(ctx context.Context, format string, args ...interface{})
+}
+
+// StdLogger logs through the standard library's log package, debug messages only when enabled
+type StdLogger struct {
+	logger *log.Logger
+	debug  bool
+}
+
+var _ Logger = &StdLogger{}
+
+// NewStdLogger returns a logger writing to w, or stderr if it's nil, with debug messages if debug is set
+func NewStdLogger(w io.Writer, debug bool) *StdLogger {
+	if w == nil {
+		w = os.Stderr
+	}
+
+	return &StdLogger{logger: log.New(w, "", log.LstdFlags), debug: debug}
+}
+
+func (l *StdLogger) DebugEnabled() bool { return l.debug }
+func (l *StdLogger) InfoEnabled() bool  { return true }
+func (l *StdLogger) WarnEnabled() bool  { return true }
+func (l *StdLogger) ErrorEnabled() bool { return true }
+
+func (l *StdLogger) Debug(ctx context.Context, format string, args ...interface{}) {
+	if l.debug {
+		l.log("D", format, args...)
+	}
+}
+
+func (l *StdLogger) Info(ctx context.Context, format string, args ...interface{}) {
+	l.log("I", format, args...)
+}
+
+func (l *StdLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+	l.log("W", format, args...)
+}
+
+func (l *StdLogger) Error(ctx context.Context, format string, args ...interface{}) {
+	l.log("E", format, args...)
+}
+
+func (l *StdLogger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	l.log("F", format, args...)
+	os.Exit(1)
+}
+
+// log writes the message with its level, as ocm-sdk-go's standard logger does
+func (l *StdLogger) log(level, format string, args ...interface{}) {
+	l.logger.Printf("%s: %s", level, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// ocm-sdk-go's loggers are used as they are
+var _ Logger = &ocmlog.StdLogger{}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(&buf, false)
+	logger.Debug(context.TODO(), "hidden %d", 1)
+	logger.Info(context.TODO(), "Probing %d endpoints", 3)
+	logger.Error(context.TODO(), "Failure!")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2, "debug messages are only logged when enabled") {
+		assert.True(t, strings.HasSuffix(lines[0], "I: Probing 3 endpoints"), lines[0])
+		assert.True(t, strings.HasSuffix(lines[1], "E: Failure!"), lines[1])
+	}
+
+	buf.Reset()
+	logger = NewStdLogger(&buf, true)
+	assert.True(t, logger.DebugEnabled())
+	logger.Debug(context.TODO(), "shown")
+	assert.Contains(t, buf.String(), "D: shown")
+}
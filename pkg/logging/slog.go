@@ -0,0 +1,58 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// slogLogger logs through a log/slog logger, at its levels
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// FromSlog adapts a log/slog logger, the messages formatted before they're logged at the matching slog level, and
+// fatal ones at the error level
+func FromSlog(l *slog.Logger) Logger {
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) enabled(level slog.Level) bool {
+	return l.logger.Enabled(context.Background(), level)
+}
+
+func (l *slogLogger) DebugEnabled() bool { return l.enabled(slog.LevelDebug) }
+func (l *slogLogger) InfoEnabled() bool  { return l.enabled(slog.LevelInfo) }
+func (l *slogLogger) WarnEnabled() bool  { return l.enabled(slog.LevelWarn) }
+func (l *slogLogger) ErrorEnabled() bool { return l.enabled(slog.LevelError) }
+
+func (l *slogLogger) Debug(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelDebug, format, args...)
+}
+
+func (l *slogLogger) Info(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, format, args...)
+}
+
+func (l *slogLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, format, args...)
+}
+
+func (l *slogLogger) Error(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, format, args...)
+}
+
+func (l *slogLogger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, format, args...)
+	os.Exit(1)
+}
+
+func (l *slogLogger) log(ctx context.Context, level slog.Level, format string, args ...interface{}) {
+	if l.logger.Enabled(ctx, level) {
+		l.logger.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
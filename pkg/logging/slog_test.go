@@ -0,0 +1,26 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := FromSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	assert.False(t, logger.DebugEnabled())
+	assert.True(t, logger.InfoEnabled())
+	logger.Debug(context.TODO(), "hidden")
+	logger.Warn(context.TODO(), "Instance type %s is unavailable", "t3.micro")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), `level=WARN msg="Instance type t3.micro is unavailable"`)
+}
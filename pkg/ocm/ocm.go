@@ -0,0 +1,162 @@
+package ocm
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/helpers"
+)
+
+const (
+	// DefaultURL is the production OCM API
+	DefaultURL = "https://api.openshift.com"
+
+	// defaultMachinePoolID is used for the cluster's default compute nodes, which aren't listed as a machine pool
+	defaultMachinePoolID = "worker"
+
+	// redacted replaces the additional trust bundle of clusters in OCM's responses
+	redacted = "REDACTED"
+)
+
+// MachinePool is a cluster machine pool and the availability zones its nodes are placed in
+type MachinePool struct {
+	ID                string
+	AvailabilityZones []string
+}
+
+// ClusterNetwork describes the parts of a cluster's network configuration relevant to verification
+type ClusterNetwork struct {
+	CloudProvider string
+	Region        string
+	// SubnetIDs are the BYOVPC subnets (AWS) or the compute subnet (GCP) of the cluster
+	SubnetIDs    []string
+	MachinePools []MachinePool
+	// Proxy is the cluster-wide proxy, nil if the cluster has none
+	Proxy *ClusterProxy
+}
+
+// ClusterProxy is a cluster's cluster-wide proxy
+type ClusterProxy struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	// CACert is the cluster's additional trust bundle, PEM-encoded
+	CACert string
+	// CACertRedacted is set when OCM withholds the additional trust bundle, as it does unless the caller may read it
+	CACertRedacted bool
+}
+
+// NewConnection builds an OCM API connection authenticated with the given offline or access token
+func NewConnection(ctx context.Context, logger ocmlog.Logger, url, token string) (*sdk.Connection, error) {
+	if token == "" {
+		return nil, fmt.Errorf("an OCM token is required to look up cluster details")
+	}
+	if url == "" {
+		url = DefaultURL
+	}
+
+	return sdk.NewConnectionBuilder().
+		Logger(logger).
+		URL(url).
+		Tokens(token).
+		BuildContext(ctx)
+}
+
+// GetClusterNetwork fetches the subnets and machine pools of a cluster from OCM
+func GetClusterNetwork(ctx context.Context, conn *sdk.Connection, clusterID string) (*ClusterNetwork, error) {
+	clusterClient := conn.ClustersMgmt().V1().Clusters().Cluster(clusterID)
+
+	clusterResp, err := clusterClient.Get().SendContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cluster %s from OCM: %w", clusterID, err)
+	}
+	cluster := clusterResp.Body()
+
+	network := &ClusterNetwork{
+		CloudProvider: cluster.CloudProvider().ID(),
+		Region:        cluster.Region().ID(),
+		MachinePools: []MachinePool{{
+			ID:                defaultMachinePoolID,
+			AvailabilityZones: cluster.Nodes().AvailabilityZones(),
+		}},
+		Proxy: clusterProxy(cluster),
+	}
+
+	switch network.CloudProvider {
+	case "gcp":
+		if subnet := cluster.GCPNetwork().ComputeSubnet(); subnet != "" {
+			network.SubnetIDs = []string{subnet}
+		}
+	default:
+		network.SubnetIDs = cluster.AWS().SubnetIDs()
+	}
+
+	if len(network.SubnetIDs) == 0 {
+		return nil, fmt.Errorf("cluster %s does not use a customer-provided VPC, there are no subnets to verify", clusterID)
+	}
+
+	page, fetched := 1, 0
+	for {
+		poolsResp, err := clusterClient.MachinePools().List().Page(page).SendContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list machine pools of cluster %s from OCM: %w", clusterID, err)
+		}
+
+		poolsResp.Items().Each(func(pool *cmv1.MachinePool) bool {
+			if pool.ID() == defaultMachinePoolID {
+				// Already covered by the cluster's default compute nodes
+				return true
+			}
+			network.MachinePools = append(network.MachinePools, MachinePool{
+				ID:                pool.ID(),
+				AvailabilityZones: pool.AvailabilityZones(),
+			})
+			return true
+		})
+
+		fetched += poolsResp.Size()
+		if poolsResp.Size() == 0 || fetched >= poolsResp.Total() {
+			break
+		}
+		page++
+	}
+
+	return network, nil
+}
+
+// clusterProxy returns the cluster's proxy, nil if it has none
+func clusterProxy(cluster *cmv1.Cluster) *ClusterProxy {
+	p := &ClusterProxy{
+		HTTPProxy:  cluster.Proxy().HTTPProxy(),
+		HTTPSProxy: cluster.Proxy().HTTPSProxy(),
+	}
+	if p.HTTPProxy == "" && p.HTTPSProxy == "" {
+		return nil
+	}
+	if bundle := cluster.AdditionalTrustBundle(); bundle == redacted {
+		p.CACertRedacted = true
+	} else {
+		p.CACert = bundle
+	}
+
+	return p
+}
+
+// SubnetsByMachinePool assigns each subnet to the machine pools with nodes in the subnet's availability zone.
+// subnetZones maps subnet IDs to availability zones; subnets with an empty zone (e.g. regional GCP subnets)
+// belong to every pool.
+func (n *ClusterNetwork) SubnetsByMachinePool(subnetZones map[string]string) map[string][]string {
+	result := make(map[string][]string, len(n.MachinePools))
+	for _, pool := range n.MachinePools {
+		for _, subnetID := range n.SubnetIDs {
+			zone := subnetZones[subnetID]
+			if zone == "" || len(pool.AvailabilityZones) == 0 || helpers.Contains(pool.AvailabilityZones, zone) {
+				result[pool.ID] = append(result[pool.ID], subnetID)
+			}
+		}
+	}
+
+	return result
+}
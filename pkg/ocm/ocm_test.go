@@ -0,0 +1,53 @@
+package ocm
+
+import (
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnetsByMachinePool(t *testing.T) {
+	network := &ClusterNetwork{
+		SubnetIDs: []string{"subnet-a", "subnet-b", "subnet-c"},
+		MachinePools: []MachinePool{
+			{ID: "worker", AvailabilityZones: []string{"us-east-1a"}},
+			{ID: "infra", AvailabilityZones: []string{"us-east-1b", "us-east-1c"}},
+			{ID: "empty", AvailabilityZones: []string{"us-east-1d"}},
+		},
+	}
+	zones := map[string]string{
+		"subnet-a": "us-east-1a",
+		"subnet-b": "us-east-1b",
+		"subnet-c": "us-east-1c",
+	}
+
+	pools := network.SubnetsByMachinePool(zones)
+	assert.Equal(t, []string{"subnet-a"}, pools["worker"])
+	assert.Equal(t, []string{"subnet-b", "subnet-c"}, pools["infra"])
+	assert.Empty(t, pools["empty"])
+
+	// Regional subnets belong to every pool
+	regional := network.SubnetsByMachinePool(map[string]string{})
+	assert.Len(t, regional["empty"], 3)
+}
+
+func TestClusterProxy(t *testing.T) {
+	cluster, err := cmv1.NewCluster().Build()
+	assert.NoError(t, err)
+	assert.Nil(t, clusterProxy(cluster), "clusters without a proxy have none")
+
+	cluster, err = cmv1.NewCluster().
+		Proxy(cmv1.NewProxy().HTTPSProxy("https://proxy.example.com:3129")).
+		AdditionalTrustBundle("-----BEGIN CERTIFICATE-----\n").
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, &ClusterProxy{HTTPSProxy: "https://proxy.example.com:3129", CACert: "-----BEGIN CERTIFICATE-----\n"}, clusterProxy(cluster))
+
+	cluster, err = cmv1.NewCluster().
+		Proxy(cmv1.NewProxy().HTTPProxy("http://proxy.example.com:3128")).
+		AdditionalTrustBundle(redacted).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, &ClusterProxy{HTTPProxy: "http://proxy.example.com:3128", CACertRedacted: true}, clusterProxy(cluster))
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/openshift/osd-network-verifier/api/v1alpha1"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/service"
+)
+
+// pollInterval is how often running verifications are checked on
+const pollInterval = 30 * time.Second
+
+// NetworkVerificationReconciler runs a verification for each generation of a NetworkVerification's spec, reporting
+// its progress and results in the status. Verifications run in the background on the service.Manager, the status
+// records their ID so a verification is picked back up rather than started again.
+type NetworkVerificationReconciler struct {
+	Client  client.Client
+	Manager *service.Manager
+}
+
+// SetupWithManager registers the reconciler with mgr, ignoring changes that leave the spec as is
+func (r *NetworkVerificationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.NetworkVerification{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
+
+// Reconcile starts the verification of a new spec, or updates the status of the running one
+func (r *NetworkVerificationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	nv := &v1alpha1.NetworkVerification{}
+	if err := r.Client.Get(ctx, req.NamespacedName, nv); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	status := nv.Status
+	if status.ObservedGeneration == nv.Generation && (status.Phase == service.StatePassed || status.Phase == service.StateFailed) {
+		return ctrl.Result{}, nil
+	}
+
+	// A new spec, or a verification lost to an operator restart, is (re)started
+	st, err := r.Manager.Status(status.VerificationID)
+	if status.ObservedGeneration != nv.Generation || errors.Is(err, service.ErrNotFound) {
+		st, err = r.Manager.Start(requestFor(nv.Spec))
+		if err != nil {
+			// An invalid spec won't get any better until it's changed
+			nv.Status = v1alpha1.NetworkVerificationStatus{}
+			r.finish(nv, service.Status{State: service.StateFailed, Verdict: err.Error()}, (&output.Output{}).AddError(err).Result())
+			return ctrl.Result{}, r.Client.Status().Update(ctx, nv)
+		}
+		nv.Status = v1alpha1.NetworkVerificationStatus{
+			ObservedGeneration: nv.Generation,
+			VerificationID:     st.ID,
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !st.Done() {
+		nv.Status.Phase = st.State
+		if !st.Started.IsZero() {
+			nv.Status.StartTime = &metav1.Time{Time: st.Started}
+		}
+		meta.SetStatusCondition(&nv.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionComplete,
+			Status:             metav1.ConditionFalse,
+			Reason:             st.State,
+			Message:            "the verification has not finished",
+			ObservedGeneration: nv.Generation,
+		})
+		return ctrl.Result{RequeueAfter: pollInterval}, r.Client.Status().Update(ctx, nv)
+	}
+
+	st, result, err := r.Manager.Result(st.ID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	r.finish(nv, st, result)
+
+	return ctrl.Result{}, r.Client.Status().Update(ctx, nv)
+}
+
+// finish records the outcome of a verification in the status
+func (r *NetworkVerificationReconciler) finish(nv *v1alpha1.NetworkVerification, st service.Status, result output.Result) {
+	nv.Status.ObservedGeneration = nv.Generation
+	nv.Status.Phase = st.State
+	nv.Status.Verdict = st.Verdict
+	if !st.Started.IsZero() {
+		nv.Status.StartTime = &metav1.Time{Time: st.Started}
+	}
+	nv.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	if !st.Finished.IsZero() {
+		nv.Status.CompletionTime = &metav1.Time{Time: st.Finished}
+	}
+	nv.Status.Failures = result.Failures
+	nv.Status.Exceptions = result.Exceptions
+	nv.Status.Errors = result.Errors
+	nv.Status.Warnings = result.Warnings
+	nv.Status.Endpoints = nil
+	for _, e := range result.Endpoints {
+		nv.Status.Endpoints = append(nv.Status.Endpoints, v1alpha1.EndpointStatus{
+			Endpoint:   e.Endpoint,
+			Success:    e.Success,
+			Note:       e.Note,
+			RequiredBy: e.RequiredBy,
+			LastHop:    e.LastHop,
+			TLSIssuer:  e.TLSIssuer,
+		})
+	}
+
+	succeeded := metav1.ConditionFalse
+	if st.State == service.StatePassed {
+		succeeded = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&nv.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionComplete,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Finished",
+		Message:            "the verification has finished",
+		ObservedGeneration: nv.Generation,
+	})
+	meta.SetStatusCondition(&nv.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionSucceeded,
+		Status:             succeeded,
+		Reason:             st.State,
+		Message:            st.Verdict,
+		ObservedGeneration: nv.Generation,
+	})
+}
+
+// requestFor translates a NetworkVerification's spec to a verification request
+func requestFor(spec v1alpha1.NetworkVerificationSpec) service.Request {
+	req := service.Request{
+		Platform:        spec.Platform,
+		SubnetID:        spec.SubnetID,
+		Region:          spec.Region,
+		ImageID:         spec.ImageID,
+		InstanceType:    spec.InstanceType,
+		SecurityGroupID: spec.SecurityGroupID,
+		KMSKeyID:        spec.KMSKeyID,
+		CloudTags:       spec.CloudTags,
+	}
+	if spec.Timeout != nil {
+		req.Timeout = spec.Timeout.Duration.String()
+	}
+	if spec.Proxy != nil {
+		req.HTTPProxy = spec.Proxy.HTTPProxy
+		req.HTTPSProxy = spec.Proxy.HTTPSProxy
+		req.CACert = spec.Proxy.CACert
+		req.NoTLS = spec.Proxy.NoTLS
+	}
+
+	return req
+}
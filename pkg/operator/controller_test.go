@@ -0,0 +1,100 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/osd-network-verifier/api/v1alpha1"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/service"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	nv := &v1alpha1.NetworkVerification{
+		ObjectMeta: metav1.ObjectMeta{Name: "nv", Namespace: "default", Generation: 1},
+		Spec:       v1alpha1.NetworkVerificationSpec{Platform: "aws", SubnetID: "subnet-a"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nv).Build()
+
+	release := make(chan struct{})
+	m := service.NewManager(context.TODO(), &ocmlog.StdLogger{}, func(ctx context.Context, logger logging.Logger, req service.Request) *output.Output {
+		<-release
+		out := &output.Output{}
+		out.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+		out.AddEndpointResult(output.EndpointResult{Endpoint: "quay.io:443"})
+		return out
+	}, 1, 0)
+	r := &NetworkVerificationReconciler{Client: c, Manager: m}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "nv", Namespace: "default"}}
+	get := func() *v1alpha1.NetworkVerification {
+		got := &v1alpha1.NetworkVerification{}
+		if err := c.Get(context.TODO(), req.NamespacedName, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// The verification is started and checked on until it finishes
+	res, err := r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := get()
+	if res.RequeueAfter == 0 || got.Status.VerificationID == "" || got.Status.ObservedGeneration != 1 {
+		t.Errorf("expected a running verification to be requeued, got %+v", got.Status)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionComplete) {
+		t.Errorf("expected the verification not to be complete")
+	}
+
+	close(release)
+	for i := 0; i < 100; i++ {
+		if st, _ := m.Status(got.Status.VerificationID); st.Done() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	res, err = r.Reconcile(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = get()
+	if res.RequeueAfter != 0 || got.Status.Phase != service.StateFailed {
+		t.Errorf("expected the verification to have failed, got %+v", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionComplete) || !meta.IsStatusConditionFalse(got.Status.Conditions, v1alpha1.ConditionSucceeded) {
+		t.Errorf("unexpected conditions: %+v", got.Status.Conditions)
+	}
+	if len(got.Status.Endpoints) != 1 || got.Status.Endpoints[0].Endpoint != "quay.io:443" || len(got.Status.Failures) != 1 {
+		t.Errorf("expected the per-endpoint results in the status, got %+v", got.Status)
+	}
+
+	// An invalid spec fails without a verification
+	got.Spec.Platform = "azure"
+	got.Generation = 2
+	if err := c.Update(context.TODO(), got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	got = get()
+	if got.Status.Phase != service.StateFailed || got.Status.ObservedGeneration != 2 || got.Status.VerificationID != "" {
+		t.Errorf("expected the invalid spec to fail, got %+v", got.Status)
+	}
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// Attempts counts the requests the probe made to an endpoint, when probing each endpoint more than once
+type Attempts struct {
+	Endpoint  string
+	Attempts  int
+	Successes int
+}
+
+// SetAttempts records the attempts on the endpoint results over IPv4, counting the validator's failed request to an
+// endpoint it reported unreachable as one more. Endpoints only some attempts got through to are marked flaky rather
+// than unreachable, their failures called out as warnings instead, as intermittent failures, e.g. of a proxy, aren't a
+// hard block. Flaky endpoints without a result of their own are recorded as results of the subnet.
+func (o *Output) SetAttempts(attempts []Attempts, subnetID string) {
+	for _, a := range attempts {
+		matched := false
+		for i, r := range o.endpointResults {
+			if r.Endpoint != a.Endpoint || r.IPVersion == probe.IPVersion6 {
+				continue
+			}
+			matched = true
+			o.endpointResults[i].setAttempts(a)
+		}
+		if !matched && a.Successes < a.Attempts {
+			result := EndpointResult{Endpoint: a.Endpoint, Subnet: subnetID, Success: a.Successes > 0}
+			result.setAttempts(a)
+			o.AddEndpointResult(result)
+		}
+	}
+
+	for _, r := range o.endpointResults {
+		if r.Flaky && r.IPVersion != probe.IPVersion6 {
+			o.clearEgressFailure(r.Endpoint)
+			o.AddWarning(fmt.Sprintf("%s is flaky, reached in %d of %d attempts, e.g. an overloaded proxy or a flapping route", r.Endpoint, r.Successes, r.Attempts))
+		}
+	}
+}
+
+// setAttempts records the attempts on the result, marking it flaky if only some got through
+func (r *EndpointResult) setAttempts(a Attempts) {
+	r.Attempts, r.Successes = a.Attempts, a.Successes
+	if !r.Success {
+		// The validator's own attempt failed
+		r.Attempts++
+	}
+	r.Flaky = r.Successes > 0 && r.Successes < r.Attempts
+	if r.Flaky {
+		r.Success = false
+		r.DocsURL = ""
+		r.Note = fmt.Sprintf("reached in %d of %d attempts", r.Successes, r.Attempts)
+	}
+}
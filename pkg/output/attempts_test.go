@@ -0,0 +1,38 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAttempts(t *testing.T) {
+	o := Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443", "Unable to reach sso.redhat.com:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Subnet: "subnet-1"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:443", Subnet: "subnet-1"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "api.openshift.com:443", Subnet: "subnet-1", Success: true})
+
+	o.SetAttempts([]Attempts{
+		{Endpoint: "quay.io:443", Attempts: 3, Successes: 2},
+		{Endpoint: "sso.redhat.com:443", Attempts: 3},
+		{Endpoint: "api.openshift.com:443", Attempts: 3, Successes: 3},
+		{Endpoint: "registry.redhat.io:443", Attempts: 3, Successes: 1},
+	}, "subnet-1")
+
+	results := o.EndpointResults()
+	assert.True(t, results[0].Flaky, "an endpoint the validator failed to reach but the attempts did is flaky")
+	assert.Equal(t, 4, results[0].Attempts)
+	assert.Equal(t, "reached in 2 of 4 attempts", results[0].Note)
+	assert.False(t, results[1].Flaky)
+	assert.Equal(t, 4, results[1].Attempts)
+	assert.False(t, results[2].Flaky)
+	assert.True(t, results[2].Success)
+	assert.True(t, results[3].Flaky, "a flaky endpoint without a result of its own is recorded")
+	assert.Equal(t, "flaky", endpointResultString(results[3]))
+
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 1, "only the hard block fails the verification")
+	assert.EqualError(t, failures[0], "egressURL error: Unable to reach sso.redhat.com:443")
+	assert.Len(t, o.Warnings(), 2)
+}
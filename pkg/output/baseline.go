@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// Baseline lists known, accepted failures, e.g. a customer's documented deviations, so only new failures fail the
+// verification, and endpoints excluded from the verdict altogether, e.g. waived requirements
+type Baseline struct {
+	Failures []BaselineEntry `json:"failures,omitempty"`
+	// IgnoreEndpoints are the endpoints, as host[:port], to only report informationally
+	IgnoreEndpoints []string `json:"ignore_endpoints,omitempty"`
+}
+
+// BaselineEntry is an accepted failure, matching either every failure to reach an endpoint or one exact failure
+type BaselineEntry struct {
+	// Endpoint accepts every failure to reach the endpoint, given as host:port, e.g. being unreachable over IPv6 or
+	// in soak rounds
+	Endpoint string `json:"endpoint,omitempty"`
+	// Failure accepts the failure with exactly this message, as printed in the summary
+	Failure string `json:"failure,omitempty"`
+	// Reason is why the failure is accepted, shown alongside it
+	Reason string `json:"reason,omitempty"`
+}
+
+// LoadBaseline reads a baseline file
+func LoadBaseline(file string) (*Baseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read baseline %s: %w", file, err)
+	}
+
+	baseline, err := ParseBaseline(data)
+	if err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", file, err)
+	}
+
+	return baseline, nil
+}
+
+// ParseBaseline parses a baseline, rejecting unknown fields and entries that match nothing or are ambiguous
+func ParseBaseline(data []byte) (*Baseline, error) {
+	baseline := &Baseline{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(baseline); err != nil {
+		return nil, fmt.Errorf("unable to parse baseline: %w", err)
+	}
+	for i, e := range baseline.Failures {
+		if (e.Endpoint == "") == (e.Failure == "") {
+			return nil, fmt.Errorf("failure %d: exactly one of endpoint and failure must be given", i+1)
+		}
+	}
+	for _, endpoint := range baseline.IgnoreEndpoints {
+		if err := ValidateIgnoredEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	return baseline, nil
+}
+
+// matches returns whether the entry accepts the failure
+func (e BaselineEntry) matches(failure string) bool {
+	if e.Failure != "" {
+		return failure == e.Failure
+	}
+	prefix := handledErrors.NewEgressURLError("Unable to reach " + e.Endpoint).Error()
+
+	return failure == prefix || strings.HasPrefix(failure, prefix+" ")
+}
+
+// Apply sets aside the failures of the ignored endpoints, turns the failures of the output the baseline accepts into
+// warnings, so the output only fails on new ones, and notes the accepted failures on the endpoint results. A nil
+// baseline accepts nothing. The output is returned for chaining.
+func (b *Baseline) Apply(o *Output) *Output {
+	if b == nil || o == nil {
+		return o
+	}
+	o.IgnoreEndpoints(b.IgnoreEndpoints)
+
+	failures := o.failures[:0]
+	for _, f := range o.failures {
+		entry, ok := b.accepts(f.Error())
+		if !ok {
+			failures = append(failures, f)
+			continue
+		}
+		warning := fmt.Sprintf("Accepted by baseline: %s", f.Error())
+		if entry.Reason != "" {
+			warning = fmt.Sprintf("%s (%s)", warning, entry.Reason)
+		}
+		o.AddWarning(warning)
+	}
+	o.failures = failures
+
+	for i, r := range o.endpointResults {
+		if _, ok := b.accepts(handledErrors.NewEgressURLError("Unable to reach " + r.Endpoint).Error()); !ok || r.Success {
+			continue
+		}
+		if r.Note == "" {
+			o.endpointResults[i].Note = "accepted by baseline"
+		} else {
+			o.endpointResults[i].Note = r.Note + "; accepted by baseline"
+		}
+	}
+
+	return o
+}
+
+// accepts returns the first entry accepting the failure, and whether there is one
+func (b *Baseline) accepts(failure string) (BaselineEntry, bool) {
+	for _, e := range b.Failures {
+		if e.matches(failure) {
+			return e, true
+		}
+	}
+
+	return BaselineEntry{}, false
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBaseline(t *testing.T) {
+	b, err := ParseBaseline([]byte(`{"failures": [{"endpoint": "quay.io:443", "reason": "mirrored registry"}, {"failure": "dns error: custom resolver"}]}`))
+	assert.NoError(t, err)
+	assert.Len(t, b.Failures, 2)
+
+	_, err = ParseBaseline([]byte(`{"failures": [{"endpoint": "quay.io:443", "failure": "dns error: custom resolver"}]}`))
+	assert.Error(t, err, "an entry must match either an endpoint or a failure")
+	_, err = ParseBaseline([]byte(`{"failures": [{"reason": "nothing"}]}`))
+	assert.Error(t, err)
+	_, err = ParseBaseline([]byte(`{"failure": [{"endpoint": "quay.io:443"}]}`))
+	assert.Error(t, err, "unknown fields are rejected")
+	_, err = ParseBaseline([]byte(`{"ignore_endpoints": ["quay.io:https"]}`))
+	assert.Error(t, err)
+}
+
+func TestBaselineApply(t *testing.T) {
+	b := &Baseline{Failures: []BaselineEntry{
+		{Endpoint: "quay.io:443", Reason: "mirrored registry"},
+		{Failure: "dns error: custom resolver"},
+	}}
+	o := &Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443", "Unable to reach quay.io:443 over IPv6", "Unable to reach quay.io:4433", "Unable to reach sso.redhat.com:443"})
+	o.AddFailure(errors.New("dns error: custom resolver"))
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Note: "last hop: 10.0.0.1"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:443"})
+
+	assert.Same(t, o, b.Apply(o))
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 2, "only new failures remain")
+	assert.EqualError(t, failures[0], "egressURL error: Unable to reach quay.io:4433")
+	assert.EqualError(t, failures[1], "egressURL error: Unable to reach sso.redhat.com:443")
+	assert.Equal(t, []string{
+		"Accepted by baseline: egressURL error: Unable to reach quay.io:443 (mirrored registry)",
+		"Accepted by baseline: egressURL error: Unable to reach quay.io:443 over IPv6 (mirrored registry)",
+		"Accepted by baseline: dns error: custom resolver",
+	}, o.Warnings())
+	assert.Equal(t, "last hop: 10.0.0.1; accepted by baseline", o.EndpointResults()[0].Note)
+	assert.Empty(t, o.EndpointResults()[1].Note)
+
+	o = &Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+	b.Apply(o)
+	assert.True(t, o.IsSuccessful(), "a run with only accepted failures succeeds")
+
+	o = &Output{}
+	o.SetEgressFailures([]string{"Unable to reach telemetry.example.com:443"})
+	(&Baseline{IgnoreEndpoints: []string{"telemetry.example.com"}}).Apply(o)
+	assert.True(t, o.IsSuccessful(), "ignored endpoints don't count towards the verdict")
+	assert.Empty(t, o.Warnings())
+	assert.Len(t, o.Ignored(), 1)
+
+	var none *Baseline
+	o = &Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+	none.Apply(o)
+	assert.False(t, o.IsSuccessful())
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// EndpointStats summarizes the results of an endpoint over repeated runs of the verification
+type EndpointStats struct {
+	// Endpoint is the host:port that was probed
+	Endpoint string
+	// IPVersion is the IP version the endpoint was probed over, empty if unknown
+	IPVersion string
+	// Runs is the number of runs
+	Runs int
+	// Failures is the number of runs the endpoint was unreachable in
+	Failures int
+	// P50, P90 and P99 are percentiles of the latencies recorded, zero if none was
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// SuccessRate returns the share of runs the endpoint was reachable in, between 0 and 1
+func (s EndpointStats) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+
+	return float64(s.Runs-s.Failures) / float64(s.Runs)
+}
+
+// Benchmark summarizes the results of each endpoint over the runs of a repeated verification. Runs only report the
+// endpoints they reached a verdict on, so an endpoint missing from a run was reachable in it, as the validator only
+// reports unreachable ones. Runs that didn't finish, errored or probed nothing, e.g. failing the pre-flight checks, are
+// left out, as their endpoints weren't all probed.
+func Benchmark(runs []*Output) []EndpointStats {
+	type key struct{ endpoint, ipVersion string }
+	var order []key
+	failures := map[key]int{}
+	latencies := map[key][]time.Duration{}
+	completed := 0
+	for _, run := range runs {
+		if run.incomplete != "" || len(run.errors) > 0 || len(run.endpointResults) == 0 {
+			continue
+		}
+		completed++
+		for _, r := range run.endpointResults {
+			k := key{r.Endpoint, r.IPVersion}
+			if _, ok := failures[k]; !ok {
+				order = append(order, k)
+				failures[k] = 0
+			}
+			if !r.Success {
+				failures[k]++
+			}
+			if r.Latency > 0 {
+				latencies[k] = append(latencies[k], r.Latency)
+			}
+		}
+	}
+
+	stats := make([]EndpointStats, 0, len(order))
+	for _, k := range order {
+		s := EndpointStats{Endpoint: k.endpoint, IPVersion: k.ipVersion, Runs: completed, Failures: failures[k]}
+		sorted := latencies[k]
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		s.P50, s.P90, s.P99 = percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 99)
+		stats = append(stats, s)
+	}
+	// Least reachable first, as those are what a flaky network is about
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Failures > stats[j].Failures })
+
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of the sorted durations, zero if there are none
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// PrintBenchmark writes a human-readable table of each endpoint's success rate and latency percentiles over the runs
+func PrintBenchmark(w io.Writer, stats []EndpointStats) {
+	if len(stats) == 0 {
+		fmt.Fprintln(w, "No endpoint results to benchmark")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tSUCCESS RATE\tRUNS\tP50\tP90\tP99")
+	for _, s := range stats {
+		endpoint := s.Endpoint
+		if s.IPVersion == probe.IPVersion6 {
+			endpoint += " (IPv6)"
+		}
+		fmt.Fprintf(tw, "%s\t%.0f%%\t%d/%d\t%s\t%s\t%s\n",
+			endpoint, s.SuccessRate()*100, s.Runs-s.Failures, s.Runs, latencyString(s.P50), latencyString(s.P90), latencyString(s.P99))
+	}
+	tw.Flush()
+}
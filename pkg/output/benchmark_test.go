@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBenchmark(t *testing.T) {
+	var runs []*Output
+	for i := 1; i <= 10; i++ {
+		run := &Output{}
+		run.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Success: true, Latency: time.Duration(i) * time.Millisecond})
+		if i%5 == 0 {
+			run.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:443"})
+		}
+		runs = append(runs, run)
+	}
+	incomplete := &Output{}
+	incomplete.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443"})
+	incomplete.SetIncomplete("timed out")
+	runs = append(runs, incomplete, (&Output{}).AddError(errors.New("launch failed")))
+
+	stats := Benchmark(runs)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "sso.redhat.com:443", stats[0].Endpoint, "the least reachable endpoint comes first")
+	assert.Equal(t, 10, stats[0].Runs, "unfinished and errored runs are left out")
+	assert.Equal(t, 0.8, stats[0].SuccessRate())
+	assert.Zero(t, stats[0].P50)
+	assert.Equal(t, 1.0, stats[1].SuccessRate())
+	assert.Equal(t, 5*time.Millisecond, stats[1].P50)
+	assert.Equal(t, 9*time.Millisecond, stats[1].P90)
+	assert.Equal(t, 10*time.Millisecond, stats[1].P99)
+
+	var buf bytes.Buffer
+	PrintBenchmark(&buf, stats)
+	assert.Contains(t, buf.String(), "sso.redhat.com:443  80%           8/10   -    -    -")
+}
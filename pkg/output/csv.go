@@ -0,0 +1,43 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"endpoint", "category", "subnet", "result", "latency_ms", "note", "last_hop", "required_by", "docs_url", "provider", "region", "zone", "instance_id", "validator_image_digest", "start_time", "http_status", "redirect_url", "failure_stage", "ip_version"}
+
+// WriteCSVReport writes one row per endpoint per subnet for the given verification results
+func WriteCSVReport(w io.Writer, outputs ...*Output) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, o := range outputs {
+		m := o.metadata
+		startTime := ""
+		if !m.StartTime.IsZero() {
+			startTime = m.StartTime.UTC().Format(time.RFC3339)
+		}
+		for _, r := range o.endpointResults {
+			latency := ""
+			if r.Latency > 0 {
+				latency = strconv.FormatInt(r.Latency.Milliseconds(), 10)
+			}
+			status := ""
+			if r.HTTPStatus != 0 {
+				status = strconv.Itoa(r.HTTPStatus)
+			}
+			if err := cw.Write([]string{r.Endpoint, r.Category, r.Subnet, endpointResultString(r), latency, r.Note, r.LastHop, r.RequiredBy, r.DocsURL,
+				m.Provider, m.Region, m.Zone, m.InstanceID, m.ValidatorImageDigest, startTime, status, r.RedirectURL, r.FailureStage, r.IPVersion}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
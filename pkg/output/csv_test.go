@@ -0,0 +1,29 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteCSVReport(t *testing.T) {
+	a := &Output{}
+	a.Metadata().Provider = "AWS"
+	a.Metadata().Region = "us-east-1"
+	a.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Subnet: "subnet-a", Note: "timed out, after 2s", FailureStage: FailureStageTCP})
+	b := &Output{}
+	b.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Subnet: "subnet-b", Success: true, Latency: 1500 * time.Microsecond, HTTPStatus: 301, RedirectURL: "https://quay.io/"})
+
+	var buf bytes.Buffer
+	if err := WriteCSVReport(&buf, a, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `endpoint,category,subnet,result,latency_ms,note,last_hop,required_by,docs_url,provider,region,zone,instance_id,validator_image_digest,start_time,http_status,redirect_url,failure_stage,ip_version
+quay.io:443,,subnet-a,unreachable,,"timed out, after 2s",,image registry,https://docs.openshift.com/container-platform/4.10/installing/install_config/configuring-firewall.html,AWS,us-east-1,,,,,,,tcp,
+quay.io:443,,subnet-b,reachable,1,,,image registry,,,,,,,,301,https://quay.io/,,
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected csv:\n%s", buf.String())
+	}
+}
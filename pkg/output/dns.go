@@ -0,0 +1,58 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// DNSResult is the outcome of resolving a domain against a specific DNS server
+type DNSResult struct {
+	Resolver string `json:"resolver"`
+	Domain   string `json:"domain"`
+	Resolved bool   `json:"resolved"`
+	Address  string `json:"address,omitempty"`
+}
+
+// SetDNSResults stores the per-resolver results, and records every unresolved domain as a failure
+func (o *Output) SetDNSResults(results []DNSResult) {
+	o.dnsResults = results
+	for _, r := range results {
+		if !r.Resolved {
+			o.failures = append(o.failures, handledErrors.NewDNSError(fmt.Sprintf("unable to resolve %s using DNS server %s", r.Domain, r.Resolver)))
+		}
+	}
+}
+
+// DNSResults returns the per-resolver DNS results
+func (o *Output) DNSResults() []DNSResult {
+	return o.dnsResults
+}
+
+// printDNSResults summarizes how many domains each DNS server resolved
+func (o *Output) printDNSResults(w io.Writer) {
+	if len(o.dnsResults) == 0 {
+		return
+	}
+
+	resolved, total := map[string]int{}, map[string]int{}
+	for _, r := range o.dnsResults {
+		total[r.Resolver]++
+		if r.Resolved {
+			resolved[r.Resolver]++
+		}
+	}
+
+	resolvers := make([]string, 0, len(total))
+	for resolver := range total {
+		resolvers = append(resolvers, resolver)
+	}
+	sort.Strings(resolvers)
+
+	fmt.Fprintln(w, "DNS resolution:")
+	for _, resolver := range resolvers {
+		fmt.Fprintf(w, " - %s: resolved %d of %d required domains\n", resolver, resolved[resolver], total[resolver])
+	}
+}
@@ -0,0 +1,138 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// Stages a request to an endpoint can fail at
+const (
+	FailureStageDNS  = "dns"
+	FailureStageTCP  = "tcp"
+	FailureStageTLS  = "tls"
+	FailureStageHTTP = "http"
+)
+
+// failureStageNotes describe the failure at each stage, for the notes of unreachable endpoints
+var failureStageNotes = map[string]string{
+	FailureStageDNS:  "DNS resolution failed",
+	FailureStageTCP:  "TCP connect failed",
+	FailureStageTLS:  "TLS handshake failed",
+	FailureStageHTTP: "HTTP request failed",
+}
+
+// TLSDiagnostics details a failed TLS handshake with an endpoint
+type TLSDiagnostics struct {
+	// Error is the handshake error, e.g. "OpenSSL SSL_connect: Connection reset by peer"
+	Error string `json:"error,omitempty"`
+	// Attempts are the handshakes re-attempted pinned to each TLS version, without verifying the certificate, to
+	// tell protocol problems, e.g. a middlebox only allowing older versions, from certificate ones
+	Attempts []TLSAttempt `json:"attempts,omitempty"`
+}
+
+// TLSAttempt is a handshake attempted pinned to a TLS version
+type TLSAttempt struct {
+	// Version is the TLS version, e.g. 1.2
+	Version string `json:"version"`
+	// Success is true if the handshake completed
+	Success bool `json:"success"`
+	// Cipher is the cipher suite negotiated, as named by the probe's TLS library
+	Cipher string `json:"cipher,omitempty"`
+	// ALPN is the application protocol the endpoint agreed to, e.g. h2, empty if none
+	ALPN string `json:"alpn,omitempty"`
+	// Error is why the handshake failed
+	Error string `json:"error,omitempty"`
+}
+
+// HTTPResponse is what an endpoint answered the probe's HTTPS request with
+type HTTPResponse struct {
+	// Endpoint is the host:port that was requested
+	Endpoint string
+	// Status is the HTTP status code, zero if there was no response, e.g. on a timeout
+	Status int
+	// RedirectURL is where a redirect response pointed to
+	RedirectURL string
+	// ResponseTime is the time taken for the whole request
+	ResponseTime time.Duration
+	// FailureStage is where the request stopped, one of the FailureStage constants, empty if it got a response
+	FailureStage string
+	// IPVersion is the IP version the request was made over, one of the probe.IPVersion constants
+	IPVersion string
+	// TLSIssuer is the issuer of the certificate the endpoint presented, when it isn't trusted
+	TLSIssuer string
+}
+
+// SetHTTPResponses records the HTTP responses on the matching endpoint results. Endpoints that answered without a
+// result of their own weren't reported unreachable, so they're recorded as reachable results of the subnet. The
+// responses over IPv6 are recorded as results of their own, as the validator's results are over IPv4, and endpoints
+// unreachable over IPv6 are called out with a warning rather than failing the verification, as the cluster can fall
+// back to IPv4, unless the IP versions were pinned.
+func (o *Output) SetHTTPResponses(responses []HTTPResponse, subnetID string) {
+	var unreachableIPv6 []string
+	for _, response := range responses {
+		if response.IPVersion == probe.IPVersion6 {
+			result := EndpointResult{Endpoint: response.Endpoint, Subnet: subnetID, Success: response.FailureStage == ""}
+			result.setHTTPResponse(response)
+			o.AddEndpointResult(result)
+			if !result.Success {
+				unreachableIPv6 = append(unreachableIPv6, response.Endpoint)
+			}
+			continue
+		}
+
+		matched := false
+		for i, r := range o.endpointResults {
+			if r.Endpoint != response.Endpoint {
+				continue
+			}
+			matched = true
+			o.endpointResults[i].setHTTPResponse(response)
+		}
+		if !matched && response.Status != 0 {
+			result := EndpointResult{Endpoint: response.Endpoint, Subnet: subnetID, Success: true}
+			result.setHTTPResponse(response)
+			o.AddEndpointResult(result)
+		}
+	}
+
+	if len(unreachableIPv6) > 0 && o.metadata.IPVersionPinned {
+		for _, endpoint := range unreachableIPv6 {
+			o.SetEgressFailures([]string{fmt.Sprintf("Unable to reach %s over IPv6", endpoint)})
+		}
+	} else if len(unreachableIPv6) > 0 {
+		o.AddWarning(fmt.Sprintf("%d endpoints are unreachable over IPv6, those failing at the dns stage have no IPv6 address: %s", len(unreachableIPv6), strings.Join(unreachableIPv6, ", ")))
+	}
+}
+
+// setHTTPResponse records the response on the result, the response time standing in for an unknown latency. An
+// unreachable endpoint is noted with the stage it failed at, unless it already has a note.
+func (r *EndpointResult) setHTTPResponse(response HTTPResponse) {
+	r.IPVersion = response.IPVersion
+	r.HTTPStatus = response.Status
+	r.RedirectURL = response.RedirectURL
+	if r.Latency == 0 {
+		r.Latency = response.ResponseTime
+	}
+	if r.Success {
+		return
+	}
+
+	// A response to an unreachable endpoint means something else answered, e.g. a transparent proxy
+	r.FailureStage = response.FailureStage
+	if response.TLSIssuer != "" {
+		r.TLSIssuer = response.TLSIssuer
+	}
+	if r.FailureStage == "" && response.Status != 0 {
+		r.FailureStage = FailureStageHTTP
+	}
+	if r.Note == "" {
+		if response.Status != 0 {
+			r.Note = fmt.Sprintf("answered HTTP %d", response.Status)
+		} else {
+			r.Note = failureStageNotes[r.FailureStage]
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// ValidateIgnoredEndpoint checks an endpoint to ignore is given as host[:port]
+func ValidateIgnoredEndpoint(endpoint string) error {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host, port = endpoint, ""
+	}
+	if host == "" || strings.Contains(host, "/") {
+		return fmt.Errorf("invalid endpoint %q to ignore, must be host[:port]", endpoint)
+	}
+	if n, err := strconv.Atoi(port); port != "" && (err != nil || n < 1 || n > 65535) {
+		return fmt.Errorf("invalid port %s in endpoint %q to ignore", port, endpoint)
+	}
+
+	return nil
+}
+
+// matchesIgnored returns whether the endpoint, as host:port, is one of those to ignore, given as host[:port], those
+// without a port matching every port of the host
+func matchesIgnored(endpoint string, ignored []string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+	for _, i := range ignored {
+		if i == endpoint || i == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IgnoreEndpoints excludes the endpoints, given as host[:port], from the verdict, e.g. for waived requirements: the
+// failures to reach them are set aside and only reported informationally, and their results are marked ignored
+func (o *Output) IgnoreEndpoints(endpoints []string) {
+	if len(endpoints) == 0 {
+		return
+	}
+
+	prefix := handledErrors.NewEgressURLError("Unable to reach ").Error()
+	failures := o.failures[:0]
+	for _, f := range o.failures {
+		message := f.Error()
+		if fields := strings.Fields(strings.TrimPrefix(message, prefix)); strings.HasPrefix(message, prefix) && len(fields) > 0 && matchesIgnored(fields[0], endpoints) {
+			o.ignored = append(o.ignored, message)
+			continue
+		}
+		failures = append(failures, f)
+	}
+	o.failures = failures
+
+	for i, r := range o.endpointResults {
+		if matchesIgnored(r.Endpoint, endpoints) {
+			o.endpointResults[i].Ignored = true
+			o.endpointResults[i].DocsURL = ""
+		}
+	}
+}
+
+// Ignored returns the failures set aside as their endpoints are ignored
+func (o *Output) Ignored() []string {
+	return o.ignored
+}
+
+// printIgnored lists the failures of ignored endpoints, which don't count towards the verdict
+func (o *Output) printIgnored(w io.Writer) {
+	if len(o.ignored) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "ignored, not counted in the verdict:")
+	for _, v := range o.ignored {
+		fmt.Fprintln(w, " - ", v)
+	}
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIgnoredEndpoint(t *testing.T) {
+	assert.NoError(t, ValidateIgnoredEndpoint("quay.io"))
+	assert.NoError(t, ValidateIgnoredEndpoint("quay.io:443"))
+	assert.Error(t, ValidateIgnoredEndpoint("quay.io:https"))
+	assert.Error(t, ValidateIgnoredEndpoint(":443"))
+	assert.Error(t, ValidateIgnoredEndpoint("https://quay.io"))
+}
+
+func TestIgnoreEndpoints(t *testing.T) {
+	o := Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443", "Unable to reach quay.io:80 over IPv6", "Unable to reach sso.redhat.com:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", DocsURL: "https://docs.example.com"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:80", Success: true})
+
+	o.IgnoreEndpoints([]string{"quay.io", "sso.redhat.com:80"})
+
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 1)
+	assert.EqualError(t, failures[0], "egressURL error: Unable to reach sso.redhat.com:443")
+	assert.Equal(t, []string{"egressURL error: Unable to reach quay.io:443", "egressURL error: Unable to reach quay.io:80 over IPv6"}, o.Ignored())
+
+	results := o.EndpointResults()
+	assert.True(t, results[0].Ignored, "a host without a port matches every port")
+	assert.Equal(t, "ignored", endpointResultString(results[0]))
+	assert.Empty(t, results[0].DocsURL)
+	assert.False(t, results[1].Ignored)
+	assert.True(t, results[2].Ignored)
+	assert.Equal(t, "reachable", endpointResultString(results[2]), "a reachable ignored endpoint is reported as is")
+
+	var buf bytes.Buffer
+	o.printIgnored(&buf)
+	assert.Equal(t, `ignored, not counted in the verdict:
+ -  egressURL error: Unable to reach quay.io:443
+ -  egressURL error: Unable to reach quay.io:80 over IPv6
+`, buf.String())
+}
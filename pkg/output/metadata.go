@@ -0,0 +1,157 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Egress paths the probe's traffic can take to the internet
+const (
+	EgressPathProxy          = "proxy"
+	EgressPathNATGateway     = "NAT gateway"
+	EgressPathCloudNAT       = "Cloud NAT"
+	EgressPathPublicIP       = "public IP via internet gateway"
+	EgressPathTransitGateway = "transit gateway"
+	EgressPathPeering        = "VPC peering"
+	EgressPathOther          = "other"
+)
+
+// Capacity types the probe instance can be launched on
+const (
+	CapacitySpot     = "spot"
+	CapacityOnDemand = "on-demand"
+)
+
+// Metadata describes the environment a verification ran in
+type Metadata struct {
+	Provider     string
+	Region       string
+	Zone         string
+	Subnet       string
+	InstanceType string
+	InstanceID   string
+	// RunID identifies the run's results in result channels shared between runs, e.g. Cloud Logging
+	RunID string
+	// CapacityType is the capacity the probe instance ran on, one of the Capacity constants
+	CapacityType string
+	// CapacityFallback explains why a requested spot run switched to on-demand capacity
+	CapacityFallback string
+	// Image is the cloud image the probe instance booted from
+	Image string
+	// ValidatorImage is the container image reference requested for the validator
+	ValidatorImage string
+	// VerifierVersion is the build version of the verifier that ran
+	VerifierVersion string
+	// EgressListVersion is the version of the endpoint catalog embedded in the verifier
+	EgressListVersion string
+	// OCPVersion is the OpenShift minor version whose egress list was probed
+	OCPVersion string
+	// ValidatorImageDigest is the repo digest of the validator image that actually ran on the probe, e.g.
+	// quay.io/app-sre/osd-network-verifier@sha256:..., empty if it has none, e.g. an image loaded rather than pulled
+	ValidatorImageDigest string
+	// ResultsSchema is the schema version the validator's results were in
+	ResultsSchema int
+	// EgressIP is the public IP the probe's traffic reached the internet from, as seen by a checkip service
+	EgressIP string
+	// EgressPath classifies how the probe's traffic reached the internet, one of the EgressPath constants
+	EgressPath string
+	// IPVersions are the IP versions egress was verified over, e.g. both 4 and 6 from a dual-stack subnet
+	IPVersions []string
+	// IPVersionPinned is set when the IP versions were requested rather than those the subnet has addresses of, so
+	// endpoints unreachable over IPv6 fail the verification
+	IPVersionPinned bool
+	// CleanupVerified records whether no resource of the run survived the teardown, nil if that wasn't checked
+	CleanupVerified *bool
+	// Pooled is set when the probe instance was kept in a pool for later verifications, or reused from one
+	Pooled bool
+	// ResolvConf is the probe's effective resolver configuration, as set by the DHCP options
+	ResolvConf ResolvConf
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// Duration returns the total duration of the run, or zero if it hasn't finished
+func (m *Metadata) Duration() time.Duration {
+	if m.StartTime.IsZero() || m.EndTime.IsZero() {
+		return 0
+	}
+
+	return m.EndTime.Sub(m.StartTime)
+}
+
+// Metadata returns the run metadata, which may be updated in place as the run progresses
+func (o *Output) Metadata() *Metadata {
+	return &o.metadata
+}
+
+// fields returns the metadata as ordered name/value pairs, omitting unset values
+func (m *Metadata) fields() [][2]string {
+	var fields [][2]string
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{name, value})
+		}
+	}
+
+	add("provider", m.Provider)
+	add("region", m.Region)
+	add("zone", m.Zone)
+	add("subnet", m.Subnet)
+	add("instance type", m.InstanceType)
+	add("instance ID", m.InstanceID)
+	add("run ID", m.RunID)
+	add("capacity", m.CapacityType)
+	add("capacity fallback", m.CapacityFallback)
+	add("image", m.Image)
+	add("validator image", m.ValidatorImage)
+	add("validator image digest", m.ValidatorImageDigest)
+	if m.ResultsSchema > 0 {
+		add("results schema", strconv.Itoa(m.ResultsSchema))
+	}
+	add("verifier version", m.VerifierVersion)
+	add("egress list version", m.EgressListVersion)
+	add("OCP version", m.OCPVersion)
+	add("egress IP", m.EgressIP)
+	add("egress path", m.EgressPath)
+	if len(m.IPVersions) > 0 {
+		versions := make([]string, 0, len(m.IPVersions))
+		for _, v := range m.IPVersions {
+			versions = append(versions, "IPv"+v)
+		}
+		add("IP versions", strings.Join(versions, ", "))
+	}
+	add("nameservers", strings.Join(m.ResolvConf.Nameservers, " "))
+	add("search domains", strings.Join(m.ResolvConf.SearchDomains, " "))
+	if m.CleanupVerified != nil {
+		add("cleanup verified", strconv.FormatBool(*m.CleanupVerified))
+	}
+	if m.Pooled {
+		add("pooled instance", "true")
+	}
+	if !m.StartTime.IsZero() {
+		add("start", m.StartTime.UTC().Format(time.RFC3339))
+	}
+	if !m.EndTime.IsZero() {
+		add("end", m.EndTime.UTC().Format(time.RFC3339))
+	}
+	if d := m.Duration(); d > 0 {
+		add("duration", d.Round(time.Second).String())
+	}
+
+	return fields
+}
+
+func (o *Output) printMetadata(w io.Writer) {
+	fields := o.metadata.fields()
+	if len(fields) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Run metadata:")
+	for _, f := range fields {
+		fmt.Fprintf(w, " - %s: %s\n", f[0], f[1])
+	}
+}
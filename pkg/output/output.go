@@ -2,8 +2,10 @@ package output
 
 import (
 	"fmt"
+	"io"
 
 	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
 )
 
 // Output can be used when showcasing validation results at the end of the execution.
@@ -16,10 +18,41 @@ type Output struct {
 	exceptions []error
 	// errors is collection of unhandled errors
 	errors []error
+	// endpointResults holds the outcome of each probed egress endpoint
+	endpointResults []EndpointResult
+	// consoleLogs is the decoded console output of the probe instance
+	consoleLogs string
+	// consoleSanitizer masks the secrets of the console output stored
+	consoleSanitizer *redact.Sanitizer
+	// metadata describes the environment the verification ran in
+	metadata Metadata
+	// suggestions are next steps recommended based on the failures seen
+	suggestions []string
+	// packetCaptures holds pcap data captured on the probe, keyed by endpoint
+	packetCaptures map[string][]byte
+	// dnsResults holds the outcome of resolving required domains against specific DNS servers
+	dnsResults []DNSResult
+	// warnings are risks found during verification that don't fail it, e.g. asymmetric routing
+	warnings []string
+	// targets holds the results of multi-target runs keyed by target, e.g. subnet, with targetOrder keeping the
+	// order they were added in
+	targets     map[string]*Output
+	targetOrder []string
+	// pscResults holds the outcome of reaching Google APIs through Private Service Connect
+	pscResults []PSCResult
+	// incomplete explains why the probe's results are partial, empty when the probe finished
+	incomplete string
+	// categories are the categories of endpoints beyond the catalog, e.g. log forwarding destinations, keyed by
+	// endpoint
+	categories map[string]string
+	// soakRounds holds the outcome of each round of re-testing the endpoints while the probe soaked
+	soakRounds []SoakRound
+	// ignored are the failures set aside as their endpoints were excluded from the verdict
+	ignored []string
 }
 
 func (o *Output) AddDebugLogs(log string) {
-	o.debugLogs = append(o.debugLogs, log)
+	o.debugLogs = append(o.debugLogs, redact.String(log))
 }
 
 // AddError adds error as generic to the list of errors
@@ -31,11 +64,46 @@ func (o *Output) AddError(err error) *Output {
 	return o
 }
 
+// AddFailure adds a failed validation test to the list of failures
+func (o *Output) AddFailure(failure error) {
+	o.failures = append(o.failures, failure)
+}
+
 // AddException adds an exception to the list of exceptions
 func (o *Output) AddException(message error) {
 	o.exceptions = append(o.exceptions, message)
 }
 
+// AddSuggestion adds a recommended next step for resolving the failures
+func (o *Output) AddSuggestion(suggestion string) {
+	o.suggestions = append(o.suggestions, suggestion)
+}
+
+// Suggestions returns the recommended next steps for resolving the failures
+func (o *Output) Suggestions() []string {
+	return o.suggestions
+}
+
+// AddWarning adds a risk that doesn't fail the verification, but should be called out
+func (o *Output) AddWarning(warning string) {
+	o.warnings = append(o.warnings, warning)
+}
+
+// Warnings returns the risks called out during verification
+func (o *Output) Warnings() []string {
+	return o.warnings
+}
+
+// SetPacketCaptures stores the pcap data captured on the probe, keyed by endpoint
+func (o *Output) SetPacketCaptures(captures map[string][]byte) {
+	o.packetCaptures = captures
+}
+
+// PacketCaptures returns the pcap data captured on the probe, keyed by endpoint
+func (o *Output) PacketCaptures() map[string][]byte {
+	return o.packetCaptures
+}
+
 // SetEgressFailures sets egress endpoint failures as a bulk update
 func (o *Output) SetEgressFailures(failures []string) {
 	for _, f := range failures {
@@ -43,65 +111,109 @@ func (o *Output) SetEgressFailures(failures []string) {
 	}
 }
 
+// SetIncomplete marks the results as partial, e.g. the probe didn't finish before the console timeout
+func (o *Output) SetIncomplete(reason string) {
+	o.incomplete = reason
+}
+
+// Incomplete returns why the results are partial, or an empty string if the probe finished
+func (o *Output) Incomplete() string {
+	return o.incomplete
+}
+
 // IsSuccessful checks whether the output contains any item, returns false if there's any
 func (o *Output) IsSuccessful() bool {
-	if len(o.errors) > 0 || len(o.exceptions) > 0 || len(o.failures) > 0 {
+	if len(o.errors) > 0 || len(o.exceptions) > 0 || len(o.failures) > 0 || o.incomplete != "" {
 		return false
 	}
 
 	return true
 }
 
-func (o *Output) printFailures() {
+func (o *Output) printFailures(w io.Writer) {
 	if o != nil && len(o.failures) > 0 {
-		fmt.Println("printing out failures:")
+		fmt.Fprintln(w, "printing out failures:")
 		for _, v := range o.failures {
-			fmt.Println(" - ", v)
+			fmt.Fprintln(w, " - ", v)
 		}
 	}
 }
 
-func (o *Output) printExceptions() {
+func (o *Output) printExceptions(w io.Writer) {
 	if o != nil && len(o.exceptions) > 0 {
-		fmt.Println("printing out exceptions preventing the verifier from running the specific test:")
+		fmt.Fprintln(w, "printing out exceptions preventing the verifier from running the specific test:")
 		for _, v := range o.exceptions {
-			fmt.Println(" - ", v)
+			fmt.Fprintln(w, " - ", v)
 		}
 	}
 }
 
-func (o *Output) printErrors() {
+func (o *Output) printErrors(w io.Writer) {
 	if o != nil && len(o.errors) > 0 {
-		fmt.Println("printing out errors faced during the execution:")
+		fmt.Fprintln(w, "printing out errors faced during the execution:")
 		for _, v := range o.errors {
-			fmt.Println(" - ", v.Error())
+			fmt.Fprintln(w, " - ", v.Error())
+		}
+	}
+}
+
+func (o *Output) printWarnings(w io.Writer) {
+	if o != nil && len(o.warnings) > 0 {
+		fmt.Fprintln(w, "warnings:")
+		for _, v := range o.warnings {
+			fmt.Fprintln(w, " - ", v)
 		}
 	}
 }
 
-func (o *Output) printDebugLogs() {
+func (o *Output) printSuggestions(w io.Writer) {
+	if o != nil && len(o.suggestions) > 0 {
+		fmt.Fprintln(w, "suggested next steps:")
+		for _, v := range o.suggestions {
+			fmt.Fprintln(w, " - ", v)
+		}
+	}
+}
+
+func (o *Output) printDebugLogs(w io.Writer) {
 	if o != nil && len(o.debugLogs) > 0 {
-		fmt.Println("printing out debug logs from the execution:")
+		fmt.Fprintln(w, "printing out debug logs from the execution:")
 		for _, v := range o.debugLogs {
-			fmt.Println(" - ", v)
+			fmt.Fprintln(w, " - ", v)
 		}
 	}
 }
 
-// Summary can be used for printing out output structure
-func (o *Output) Summary(debug bool) {
-	fmt.Println("Summary:")
+// Summary can be used for printing out output structure to w
+func (o *Output) Summary(w io.Writer, debug bool) {
+	fmt.Fprintln(w, "Summary:")
+	o.printMetadata(w)
 	if debug {
-		o.printDebugLogs()
+		o.printDebugLogs(w)
+	}
+
+	if o.incomplete != "" {
+		fmt.Fprintf(w, "Incomplete run, the results below are partial: %s\n", o.incomplete)
 	}
 
 	if o.IsSuccessful() {
-		fmt.Println("All tests pass!")
+		fmt.Fprintln(w, "All tests pass!")
 	} else {
-		o.printFailures()
-		o.printExceptions()
-		o.printErrors()
+		// Failed endpoints are listed in the table when per-endpoint results are available
+		if len(o.endpointResults) == 0 {
+			o.printFailures(w)
+		}
+		o.printExceptions(w)
+		o.printErrors(w)
+		o.printSuggestions(w)
 	}
+	o.printWarnings(w)
+	o.printIgnored(w)
+
+	o.printDNSResults(w)
+	o.printPSCResults(w)
+	o.printSoakRounds(w)
+	o.PrintTable(w)
 }
 
 // Parse returns the data being stored on output
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// PSCResult is the outcome of resolving and connecting to a Google API domain from the probe, for networks
+// reaching Google APIs through a Private Service Connect endpoint
+type PSCResult struct {
+	Domain string `json:"domain"`
+	// Address is what the domain resolved to, empty if it didn't resolve
+	Address string `json:"address,omitempty"`
+	// ViaEndpoint is true if Address belongs to one of the network's Private Service Connect endpoints
+	ViaEndpoint bool `json:"via_endpoint"`
+	Reachable   bool `json:"reachable"`
+}
+
+// Success reports whether the domain resolved to a Private Service Connect endpoint that was reachable
+func (r PSCResult) Success() bool {
+	return r.Address != "" && r.ViaEndpoint && r.Reachable
+}
+
+// SetPSCResults stores the Private Service Connect results, and records every unsuccessful domain as a failure
+func (o *Output) SetPSCResults(results []PSCResult) {
+	o.pscResults = results
+	for _, r := range results {
+		switch {
+		case r.Address == "":
+			o.failures = append(o.failures, handledErrors.NewPSCError(fmt.Sprintf("unable to resolve %s", r.Domain)))
+		case !r.ViaEndpoint:
+			o.failures = append(o.failures, handledErrors.NewPSCError(
+				fmt.Sprintf("%s resolves to %s, which is not a Private Service Connect endpoint of the network, check the private DNS zone for googleapis.com", r.Domain, r.Address),
+			))
+		case !r.Reachable:
+			o.failures = append(o.failures, handledErrors.NewPSCError(fmt.Sprintf("unable to reach %s at Private Service Connect endpoint %s", r.Domain, r.Address)))
+		}
+	}
+}
+
+// PSCResults returns the Private Service Connect results
+func (o *Output) PSCResults() []PSCResult {
+	return o.pscResults
+}
+
+// printPSCResults summarizes how many Google API domains were reached through Private Service Connect
+func (o *Output) printPSCResults(w io.Writer) {
+	if len(o.pscResults) == 0 {
+		return
+	}
+
+	succeeded := 0
+	for _, r := range o.pscResults {
+		if r.Success() {
+			succeeded++
+		}
+	}
+	fmt.Fprintf(w, "Private Service Connect: %d of %d Google API domains reached through the endpoint\n", succeeded, len(o.pscResults))
+}
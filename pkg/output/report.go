@@ -0,0 +1,156 @@
+package output
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+)
+
+const (
+	// maxConsoleExcerptLines bounds the console log included in reports
+	maxConsoleExcerptLines = 200
+
+	// validatorStartMarker and validatorEndMarker enclose the validator container's own output in the console log
+	validatorStartMarker = "VALIDATOR START"
+	validatorEndMarker   = "VALIDATOR END"
+
+	// egressRequirementsURL documents the endpoints a cluster needs to reach
+	egressRequirementsURL = "https://docs.openshift.com/rosa/rosa_install_access_delete_clusters/rosa_getting_started_iam/rosa-aws-prereqs.html#osd-aws-privatelink-firewall-prerequisites_rosa-aws-prereqs"
+)
+
+// SetConsoleSanitizer sets how the console output is sanitized when it's stored, defaulting to the zero redact.Sanitizer
+func (o *Output) SetConsoleSanitizer(s *redact.Sanitizer) {
+	o.consoleSanitizer = s
+}
+
+// SetConsoleLogs stores the decoded console output of the probe instance for inclusion in reports, sanitized so its
+// secrets, e.g. the proxy's credentials echoed by the validator, don't end up in artifacts attached to tickets
+func (o *Output) SetConsoleLogs(logs string) {
+	o.consoleLogs = o.consoleSanitizer.Sanitize(logs)
+}
+
+// ConsoleLogs returns the decoded console output of the probe instance
+func (o *Output) ConsoleLogs() string {
+	return o.consoleLogs
+}
+
+// ConsoleExcerpt returns the tail of the probe's console output, bounded to a reasonable size for reports
+func (o *Output) ConsoleExcerpt() string {
+	lines := strings.Split(strings.TrimRight(o.consoleLogs, "\n"), "\n")
+	if len(lines) > maxConsoleExcerptLines {
+		lines = lines[len(lines)-maxConsoleExcerptLines:]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ValidatorOutput returns the validator container's own output, between its start and end markers in the console
+// log. The output is cut short when the validator didn't finish.
+func (o *Output) ValidatorOutput() string {
+	start := strings.Index(o.consoleLogs, validatorStartMarker)
+	if start < 0 {
+		return ""
+	}
+	validatorOutput := o.consoleLogs[start+len(validatorStartMarker):]
+	if end := strings.Index(validatorOutput, validatorEndMarker); end >= 0 {
+		validatorOutput = validatorOutput[:end]
+	}
+
+	return strings.Trim(validatorOutput, "\n")
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"result":         resultString,
+	"endpointResult": endpointResultString,
+	"latency":        latencyString,
+	"dash":           valueOrDash,
+	"metadata": func(o *Output) [][2]string {
+		return o.metadata.fields()
+	},
+	"exceptions": func(o *Output) []error {
+		return o.exceptions
+	},
+	"errors": func(o *Output) []error {
+		return o.errors
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>osd-network-verifier report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.pass { color: #2e7d32; }
+.fail { color: #c62828; }
+.flaky { color: #ef6c00; }
+.ignored { color: #757575; }
+pre { background: #f5f5f5; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>osd-network-verifier report</h1>
+<p>Generated {{ .Generated }}</p>
+{{ range .Outputs }}
+<section>
+<h2 class="{{ if .IsSuccessful }}pass{{ else }}fail{{ end }}">{{ .Verdict }}</h2>
+{{ with metadata . }}
+<table>
+{{ range . }}<tr><th>{{ index . 0 }}</th><td>{{ index . 1 }}</td></tr>
+{{ end }}
+</table>
+{{ end }}
+{{ with .EndpointResults }}
+<table>
+<tr><th>Endpoint</th><th>Category</th><th>Subnet</th><th>Result</th><th>Latency</th><th>HTTP</th><th>Note</th><th>Required by</th><th>Reference</th></tr>
+{{ range . }}<tr><td>{{ .Endpoint }}</td><td>{{ dash .Category }}</td><td>{{ dash .Subnet }}</td><td class="{{ if .Success }}pass{{ else if .Ignored }}ignored{{ else if .Flaky }}flaky{{ else }}fail{{ end }}">{{ endpointResult . }}</td><td>{{ latency .Latency }}</td><td>{{ with .HTTPStatus }}{{ . }}{{ else }}-{{ end }}</td><td>{{ dash .Note }}</td><td>{{ dash .RequiredBy }}</td><td>{{ with .DocsURL }}<a href="{{ . }}">docs</a>{{ else }}-{{ end }}</td></tr>
+{{ end }}
+</table>
+{{ end }}
+{{ with .DNSResults }}
+<h3>DNS resolution</h3>
+<table>
+<tr><th>DNS server</th><th>Domain</th><th>Result</th></tr>
+{{ range . }}<tr><td>{{ .Resolver }}</td><td>{{ .Domain }}</td>{{ if .Resolved }}<td class="pass">{{ .Address }}</td>{{ else }}<td class="fail">unresolved</td>{{ end }}</tr>
+{{ end }}
+</table>
+{{ end }}
+{{ with .PSCResults }}
+<h3>Private Service Connect</h3>
+<table>
+<tr><th>Domain</th><th>Resolved to</th><th>Via endpoint</th><th>Result</th></tr>
+{{ range . }}<tr><td>{{ .Domain }}</td><td>{{ dash .Address }}</td><td>{{ if .ViaEndpoint }}yes{{ else }}no{{ end }}</td><td class="{{ if .Success }}pass{{ else }}fail{{ end }}">{{ result .Success }}</td></tr>
+{{ end }}
+</table>
+{{ end }}
+{{ with exceptions . }}<h3>Exceptions</h3><ul>{{ range . }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}
+{{ with errors . }}<h3>Errors</h3><ul>{{ range . }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}
+{{ with .Warnings }}<h3>Warnings</h3><ul>{{ range . }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}
+{{ with .Suggestions }}<h3>Suggested next steps</h3><ul>{{ range . }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}
+{{ with .ValidatorOutput }}<h3>Validator output</h3><pre>{{ . }}</pre>{{ end }}
+{{ with .ConsoleExcerpt }}<h3>Console log excerpt</h3><pre>{{ . }}</pre>{{ end }}
+</section>
+{{ end }}
+<h2>Remediation</h2>
+<p>Every endpoint marked unreachable must be allowed by the firewall or proxy on the subnet's egress path.
+See <a href="{{ .RequirementsURL }}">the network prerequisites</a> for the full list of required endpoints.</p>
+</body>
+</html>
+`))
+
+// WriteHTMLReport renders a self-contained HTML report of one or more verification results
+func WriteHTMLReport(w io.Writer, outputs ...*Output) error {
+	return htmlReportTemplate.Execute(w, struct {
+		Generated       string
+		RequirementsURL string
+		Outputs         []*Output
+	}{
+		Generated:       time.Now().UTC().Format(time.RFC3339),
+		RequirementsURL: egressRequirementsURL,
+		Outputs:         outputs,
+	})
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	o := &Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Subnet: "subnet-a"})
+	o.AddException(errors.New("<script>alert(1)</script>"))
+	o.SetConsoleLogs("USERDATA BEGIN\nUnable to reach quay.io:443\nUSERDATA END\n")
+
+	var buf bytes.Buffer
+	if err := WriteHTMLReport(&buf, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := buf.String()
+
+	for _, expected := range []string{o.Verdict(), "<td>quay.io:443</td>", "USERDATA END", egressRequirementsURL} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q", expected)
+		}
+	}
+	if strings.Contains(report, "<script>") {
+		t.Errorf("expected exceptions to be escaped")
+	}
+}
+
+func TestValidatorOutput(t *testing.T) {
+	o := &Output{}
+	o.SetConsoleLogs("USERDATA BEGIN\nVALIDATOR START\nUnable to reach quay.io:443\nVALIDATOR END\nEGRESS_IP -\nUSERDATA END\n")
+	if validatorOutput := o.ValidatorOutput(); validatorOutput != "Unable to reach quay.io:443" {
+		t.Errorf("unexpected validator output %q", validatorOutput)
+	}
+
+	o.SetConsoleLogs("USERDATA BEGIN\nVALIDATOR START\nUnable to reach quay.io:443\n")
+	if validatorOutput := o.ValidatorOutput(); validatorOutput != "Unable to reach quay.io:443" {
+		t.Errorf("expected the output of an unfinished validator, got %q", validatorOutput)
+	}
+
+	o.SetConsoleLogs("USERDATA BEGIN\n")
+	if validatorOutput := o.ValidatorOutput(); validatorOutput != "" {
+		t.Errorf("expected no validator output, got %q", validatorOutput)
+	}
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// maxResolvConfEntries is the number of nameservers the resolver uses, and the number of search domains
+// beyond which cluster DNS lookups are known to slow down or fail
+const maxResolvConfEntries = 3
+
+// cloudResolvers are the link-local addresses of the AWS and GCP provided resolvers, the AWS resolver is also
+// reachable at the VPC CIDR base + 2
+var cloudResolvers = map[string]bool{
+	"169.254.169.253": true,
+	"169.254.169.254": true,
+	"fd00:ec2::253":   true,
+}
+
+// ResolvConf is the effective DNS resolver configuration of the probe instance
+type ResolvConf struct {
+	Nameservers   []string
+	SearchDomains []string
+	// VPCCIDR is the primary IPv4 CIDR of the probe's VPC, empty where it isn't known or there's no resolver at its
+	// base + 2, e.g. on GCP
+	VPCCIDR string
+}
+
+// CustomNameservers returns the nameservers that aren't the cloud provider's resolver
+func (r ResolvConf) CustomNameservers() []string {
+	vpcResolver := vpcResolver(r.VPCCIDR)
+
+	var custom []string
+	for _, ns := range r.Nameservers {
+		if cloudResolvers[ns] || (vpcResolver != "" && ns == vpcResolver) {
+			continue
+		}
+		custom = append(custom, ns)
+	}
+
+	return custom
+}
+
+// vpcResolver returns the address of the AWS resolver in a VPC with the given primary CIDR, the CIDR base + 2
+func vpcResolver(cidr string) string {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil || network.IP.To4() == nil {
+		return ""
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(network.IP.To4())+2)
+
+	return ip.String()
+}
+
+// Problems returns the resolver configurations known to break cluster DNS
+func (r ResolvConf) Problems() []string {
+	var problems []string
+	if len(r.Nameservers) > maxResolvConfEntries {
+		problems = append(problems, fmt.Sprintf("%d nameservers are configured, only the first %d are used", len(r.Nameservers), maxResolvConfEntries))
+	}
+	if len(r.SearchDomains) > maxResolvConfEntries {
+		problems = append(problems, fmt.Sprintf("%d search domains are configured, more than %d break cluster DNS lookups", len(r.SearchDomains), maxResolvConfEntries))
+	}
+
+	return problems
+}
+
+// SetResolvConf records the probe's resolver configuration, keeping any VPC CIDR set before, and warns of any
+// problems with it. These break the cluster's DNS, not the egress the probe verifies.
+func (o *Output) SetResolvConf(rc ResolvConf) {
+	if rc.VPCCIDR == "" {
+		rc.VPCCIDR = o.metadata.ResolvConf.VPCCIDR
+	}
+	o.metadata.ResolvConf = rc
+	for _, problem := range rc.Problems() {
+		o.AddWarning("resolv.conf of the probe instance: " + problem)
+	}
+}
+
+// SetVPCCIDR records the primary IPv4 CIDR of the probe's VPC, to tell the AWS resolver from custom nameservers
+func (o *Output) SetVPCCIDR(cidr string) {
+	o.metadata.ResolvConf.VPCCIDR = cidr
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCustomNameservers(t *testing.T) {
+	tests := []struct {
+		name string
+		rc   ResolvConf
+		want []string
+	}{
+		{
+			name: "VPC resolver and link-local resolver",
+			rc:   ResolvConf{Nameservers: []string{"10.0.0.2", "169.254.169.253"}, VPCCIDR: "10.0.0.0/16"},
+		},
+		{
+			name: "VPC CIDR not on an octet boundary",
+			rc:   ResolvConf{Nameservers: []string{"10.0.0.66"}, VPCCIDR: "10.0.0.64/26"},
+		},
+		{
+			name: "a .2 address outside the VPC base is custom",
+			rc:   ResolvConf{Nameservers: []string{"10.0.0.2", "192.168.1.2"}, VPCCIDR: "10.0.0.0/16"},
+			want: []string{"192.168.1.2"},
+		},
+		{
+			name: "unknown VPC CIDR",
+			rc:   ResolvConf{Nameservers: []string{"10.0.0.2", "169.254.169.254"}},
+			want: []string{"10.0.0.2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.rc.CustomNameservers(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSetResolvConf(t *testing.T) {
+	o := &Output{}
+	o.SetVPCCIDR("10.0.0.0/16")
+	o.SetResolvConf(ResolvConf{
+		Nameservers:   []string{"10.0.0.2"},
+		SearchDomains: []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"},
+	})
+
+	if got := o.Metadata().ResolvConf.VPCCIDR; got != "10.0.0.0/16" {
+		t.Errorf("expected the VPC CIDR to be kept, got %q", got)
+	}
+	if len(o.failures) != 0 {
+		t.Errorf("expected no failures, got %v", o.failures)
+	}
+	if warnings := o.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "4 search domains") {
+		t.Errorf("expected a warning about the search domains, got %v", warnings)
+	}
+}
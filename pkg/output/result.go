@@ -0,0 +1,62 @@
+package output
+
+// Result is a JSON-friendly summary of an Output, e.g. for API clients of the verifier
+type Result struct {
+	Success     bool              `json:"success"`
+	Verdict     string            `json:"verdict"`
+	Incomplete  string            `json:"incomplete,omitempty"`
+	Failures    []string          `json:"failures,omitempty"`
+	Exceptions  []string          `json:"exceptions,omitempty"`
+	Errors      []string          `json:"errors,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+	Suggestions []string          `json:"suggestions,omitempty"`
+	Endpoints   []EndpointResult  `json:"endpoints,omitempty"`
+	DNS         []DNSResult       `json:"dns,omitempty"`
+	PSC         []PSCResult       `json:"psc,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Targets holds the results of multi-target runs keyed by target, e.g. subnet
+	Targets map[string]Result `json:"targets,omitempty"`
+}
+
+// Result summarizes the Output for serialization
+func (o *Output) Result() Result {
+	r := Result{
+		Success:     o.IsSuccessful(),
+		Verdict:     o.Verdict(),
+		Incomplete:  o.incomplete,
+		Failures:    errorStrings(o.failures),
+		Exceptions:  errorStrings(o.exceptions),
+		Errors:      errorStrings(o.errors),
+		Warnings:    o.warnings,
+		Suggestions: o.suggestions,
+		Endpoints:   o.endpointResults,
+		DNS:         o.dnsResults,
+		PSC:         o.pscResults,
+	}
+	if fields := o.metadata.fields(); len(fields) > 0 {
+		r.Metadata = make(map[string]string, len(fields))
+		for _, f := range fields {
+			r.Metadata[f[0]] = f[1]
+		}
+	}
+	if len(o.targets) > 0 {
+		r.Targets = make(map[string]Result, len(o.targets))
+		for _, target := range o.targetOrder {
+			r.Targets[target] = o.targets[target].Result()
+		}
+	}
+
+	return r
+}
+
+func errorStrings(errs []error) []string {
+	if len(errs) == 0 {
+		return nil
+	}
+	s := make([]string, 0, len(errs))
+	for _, err := range errs {
+		s = append(s, err.Error())
+	}
+
+	return s
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	o := Output{}
+	o.Metadata().Provider = "aws"
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443"})
+
+	r := o.Result()
+	if r.Success || !strings.HasPrefix(r.Verdict, "FAIL") {
+		t.Errorf("expected a failed result, got %+v", r)
+	}
+	if len(r.Failures) != 1 || len(r.Endpoints) != 1 || r.Endpoints[0].Endpoint != "quay.io:443" {
+		t.Errorf("expected the unreachable endpoint in the result, got %+v", r)
+	}
+	if r.Metadata["provider"] != "aws" {
+		t.Errorf("expected the metadata in the result, got %v", r.Metadata)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"endpoint":"quay.io:443"`) {
+		t.Errorf("expected snake_case keys, got %s", b)
+	}
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// SoakRound is the outcome of a round of re-testing the endpoints while the probe soaks
+type SoakRound struct {
+	Round int       `json:"round"`
+	Time  time.Time `json:"time"`
+	// Endpoints is the number of endpoints tested
+	Endpoints int `json:"endpoints"`
+	// Unreachable are the endpoints that failed the round
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// String describes the round in a line, e.g. for streaming rounds as they're reported
+func (r SoakRound) String() string {
+	if len(r.Unreachable) == 0 {
+		return fmt.Sprintf("Soak round %d at %s: all %d endpoints reachable", r.Round, r.Time.UTC().Format(time.RFC3339), r.Endpoints)
+	}
+
+	return fmt.Sprintf("Soak round %d at %s: %d of %d endpoints unreachable: %s", r.Round, r.Time.UTC().Format(time.RFC3339), len(r.Unreachable), r.Endpoints, strings.Join(r.Unreachable, ", "))
+}
+
+// SetSoakRounds stores the soak rounds, and records every endpoint unreachable in any of them as a failure, however
+// rarely, as intermittent failures are what soaking is meant to catch
+func (o *Output) SetSoakRounds(rounds []SoakRound) {
+	o.soakRounds = rounds
+	failures := soakFailures(rounds)
+	for _, endpoint := range soakFailureOrder(rounds) {
+		failed := failures[endpoint]
+		o.failures = append(o.failures, handledErrors.NewEgressURLError(fmt.Sprintf("Unable to reach %s in %d of %d soak rounds, first at %s", endpoint, len(failed), len(rounds), failed[0].UTC().Format(time.RFC3339))))
+	}
+}
+
+// SoakRounds returns the soak rounds
+func (o *Output) SoakRounds() []SoakRound {
+	return o.soakRounds
+}
+
+// soakFailures returns the times of the rounds each endpoint was unreachable in, keyed by endpoint
+func soakFailures(rounds []SoakRound) map[string][]time.Time {
+	failures := map[string][]time.Time{}
+	for _, r := range rounds {
+		for _, endpoint := range r.Unreachable {
+			failures[endpoint] = append(failures[endpoint], r.Time)
+		}
+	}
+
+	return failures
+}
+
+// soakFailureOrder returns the endpoints unreachable in any round, in the order they first failed
+func soakFailureOrder(rounds []SoakRound) []string {
+	var order []string
+	seen := map[string]bool{}
+	for _, r := range rounds {
+		for _, endpoint := range r.Unreachable {
+			if !seen[endpoint] {
+				seen[endpoint] = true
+				order = append(order, endpoint)
+			}
+		}
+	}
+
+	return order
+}
+
+// printSoakRounds summarizes the soak: its span, and how often and when each endpoint was unreachable
+func (o *Output) printSoakRounds(w io.Writer) {
+	if len(o.soakRounds) == 0 {
+		return
+	}
+
+	first, last := o.soakRounds[0], o.soakRounds[len(o.soakRounds)-1]
+	fmt.Fprintf(w, "Soak: %d rounds from %s to %s\n", len(o.soakRounds), first.Time.UTC().Format(time.RFC3339), last.Time.UTC().Format(time.RFC3339))
+	failures := soakFailures(o.soakRounds)
+	for _, endpoint := range soakFailureOrder(o.soakRounds) {
+		failed := failures[endpoint]
+		fmt.Fprintf(w, " - %s unreachable in %d of %d rounds, first at %s, last at %s\n",
+			endpoint, len(failed), len(o.soakRounds), failed[0].UTC().Format(time.RFC3339), failed[len(failed)-1].UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,31 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSoakRounds(t *testing.T) {
+	start := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	o := Output{}
+	o.SetSoakRounds([]SoakRound{
+		{Round: 1, Time: start, Endpoints: 3},
+		{Round: 2, Time: start.Add(time.Minute), Endpoints: 3, Unreachable: []string{"quay.io:443"}},
+		{Round: 3, Time: start.Add(2 * time.Minute), Endpoints: 3, Unreachable: []string{"sso.redhat.com:443", "quay.io:443"}},
+	})
+
+	failures, _, _ := o.Parse()
+	assert.Len(t, failures, 2, "intermittent failures fail the verification")
+	assert.EqualError(t, failures[0], "egressURL error: Unable to reach quay.io:443 in 2 of 3 soak rounds, first at 2022-09-01T12:01:00Z")
+	assert.Equal(t, "Soak round 1 at 2022-09-01T12:00:00Z: all 3 endpoints reachable", o.SoakRounds()[0].String())
+
+	var buf bytes.Buffer
+	o.printSoakRounds(&buf)
+	assert.Equal(t, `Soak: 3 rounds from 2022-09-01T12:00:00Z to 2022-09-01T12:02:00Z
+ - quay.io:443 unreachable in 2 of 3 rounds, first at 2022-09-01T12:01:00Z, last at 2022-09-01T12:02:00Z
+ - sso.redhat.com:443 unreachable in 1 of 3 rounds, first at 2022-09-01T12:02:00Z, last at 2022-09-01T12:02:00Z
+`, buf.String())
+}
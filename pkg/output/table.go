@@ -0,0 +1,245 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/endpoints"
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+)
+
+// EndpointResult is the outcome of verifying egress to a single endpoint from a single subnet
+type EndpointResult struct {
+	// Endpoint is the host:port that was probed
+	Endpoint string `json:"endpoint"`
+	// Category groups endpoints by purpose, if known
+	Category string `json:"category,omitempty"`
+	// Subnet is the subnet the endpoint was probed from
+	Subnet string `json:"subnet,omitempty"`
+	// Success is true if the endpoint was reachable
+	Success bool `json:"success"`
+	// Latency is the time taken to reach the endpoint, zero if unknown
+	Latency time.Duration `json:"latency,omitempty"`
+	// Note holds any additional detail about the result
+	Note string `json:"note,omitempty"`
+	// DocsURL explains why the endpoint is required, set for unreachable endpoints
+	DocsURL string `json:"docs_url,omitempty"`
+	// RequiredBy is the cluster service that depends on the endpoint, if known
+	RequiredBy string `json:"required_by,omitempty"`
+	// LastHop is the last router that responded when tracing the route to an unreachable endpoint
+	LastHop string `json:"last_hop,omitempty"`
+	// TLSIssuer is the issuer of the untrusted certificate an unreachable endpoint presented, e.g. the CA of a proxy or
+	// firewall intercepting TLS
+	TLSIssuer string `json:"tls_issuer,omitempty"`
+	// TLS details the failed TLS handshake of an endpoint failing at the tls stage
+	TLS *TLSDiagnostics `json:"tls,omitempty"`
+	// HTTPStatus is the status code the endpoint answered an HTTPS request with, zero if there was no response
+	HTTPStatus int `json:"http_status,omitempty"`
+	// RedirectURL is where the endpoint's redirect response pointed to
+	RedirectURL string `json:"redirect_url,omitempty"`
+	// FailureStage is where an unreachable endpoint failed, one of the FailureStage constants for DNS resolution,
+	// TCP connect, TLS handshake and the HTTP request, empty if unknown
+	FailureStage string `json:"failure_stage,omitempty"`
+	// IPVersion is the IP version the endpoint was probed over, empty if unknown
+	IPVersion string `json:"ip_version,omitempty"`
+	// Attempts and Successes count the requests made to the endpoint and those that got through, when probed more
+	// than once
+	Attempts  int `json:"attempts,omitempty"`
+	Successes int `json:"successes,omitempty"`
+	// Flaky is set when only some of the attempts got through, which is neither reachable nor a hard block
+	Flaky bool `json:"flaky,omitempty"`
+	// Ignored is set when the endpoint was excluded from the verdict, e.g. as a waived requirement
+	Ignored bool `json:"ignored,omitempty"`
+}
+
+// SetCategories records the categories of endpoints beyond the catalog, keyed by endpoint, e.g. the customer's log
+// forwarding destinations. Their results are reported under their category rather than as required by a cluster
+// service.
+func (o *Output) SetCategories(categories map[string]string) {
+	o.categories = categories
+}
+
+// AddEndpointResult records the result of probing an endpoint
+func (o *Output) AddEndpointResult(result EndpointResult) {
+	if category, ok := o.categories[result.Endpoint]; ok {
+		if result.Category == "" {
+			result.Category = category
+		}
+		o.endpointResults = append(o.endpointResults, result)
+		return
+	}
+
+	known := endpoints.Lookup(result.Endpoint)
+	if !result.Success && result.DocsURL == "" {
+		result.DocsURL = known.DocsURL
+	}
+	if result.RequiredBy == "" {
+		result.RequiredBy = known.RequiredBy
+	}
+	o.endpointResults = append(o.endpointResults, result)
+}
+
+// SetLastHops records the last responsive hop, keyed by endpoint, on the matching unreachable endpoints
+func (o *Output) SetLastHops(hops map[string]string) {
+	for i, r := range o.endpointResults {
+		if hop, ok := hops[r.Endpoint]; ok && !r.Success {
+			o.endpointResults[i].LastHop = hop
+			if r.Note == "" {
+				o.endpointResults[i].Note = "last hop: " + hop
+			}
+		}
+	}
+}
+
+// SetTLSIssuers records the issuer of the untrusted certificate, keyed by endpoint, on the matching unreachable
+// endpoints, whose TLS is being intercepted
+func (o *Output) SetTLSIssuers(issuers map[string]string) {
+	for i, r := range o.endpointResults {
+		if issuer, ok := issuers[r.Endpoint]; ok && !r.Success {
+			o.endpointResults[i].TLSIssuer = issuer
+			if r.Note == "" {
+				o.endpointResults[i].Note = "TLS intercepted, certificate issued by " + issuer
+			}
+		}
+	}
+}
+
+// SetTLSDiagnostics records the details of the failed TLS handshakes, keyed by endpoint, on the matching unreachable
+// endpoints, noting those without a note with the handshake error
+func (o *Output) SetTLSDiagnostics(diagnostics map[string]*TLSDiagnostics) {
+	for i, r := range o.endpointResults {
+		if d, ok := diagnostics[r.Endpoint]; ok && !r.Success {
+			o.endpointResults[i].TLS = d
+			if r.Note == "" && d.Error != "" {
+				o.endpointResults[i].Note = "TLS handshake failed: " + d.Error
+			}
+		}
+	}
+}
+
+// MarkRecovered clears the failures of unreachable endpoints that were reached when re-probed, as transient blips
+// rather than blocked egress. They're called out as warnings instead.
+func (o *Output) MarkRecovered(recovered []string) {
+	for _, endpoint := range recovered {
+		for i, r := range o.endpointResults {
+			if r.Endpoint == endpoint && !r.Success {
+				o.endpointResults[i].Success = true
+				o.endpointResults[i].Note = "reachable on retry"
+				o.endpointResults[i].DocsURL = ""
+			}
+		}
+
+		o.clearEgressFailure(endpoint)
+		o.AddWarning(fmt.Sprintf("%s was unreachable but reachable on retry, the failure was likely transient", endpoint))
+	}
+}
+
+// clearEgressFailure removes the failure of the endpoint being unreachable
+func (o *Output) clearEgressFailure(endpoint string) {
+	failure := handledErrors.NewEgressURLError("Unable to reach " + endpoint).Error()
+	failures := o.failures[:0]
+	for _, f := range o.failures {
+		if f.Error() != failure {
+			failures = append(failures, f)
+		}
+	}
+	o.failures = failures
+}
+
+// EndpointResults returns the per-endpoint results recorded so far
+func (o *Output) EndpointResults() []EndpointResult {
+	return o.endpointResults
+}
+
+// Verdict returns a one-line description of the overall outcome
+func (o *Output) Verdict() string {
+	if o.IsSuccessful() {
+		return "PASS: all egress checks succeeded"
+	}
+	if o.incomplete != "" {
+		return fmt.Sprintf("INCOMPLETE: %d failures so far, %d exceptions, %d errors", len(o.failures), len(o.exceptions), len(o.errors))
+	}
+
+	return fmt.Sprintf("FAIL: %d failures, %d exceptions, %d errors", len(o.failures), len(o.exceptions), len(o.errors))
+}
+
+// PrintTable writes a human-readable table of the endpoint results followed by the verdict.
+// Machine-readable formats are produced separately and never include this table.
+func (o *Output) PrintTable(w io.Writer) {
+	if len(o.endpointResults) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ENDPOINT\tCATEGORY\tSUBNET\tRESULT\tLATENCY\tNOTE")
+		for _, r := range o.endpointResults {
+			endpoint := r.Endpoint
+			if r.IPVersion == probe.IPVersion6 {
+				endpoint += " (IPv6)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				endpoint, valueOrDash(r.Category), valueOrDash(r.Subnet), endpointResultString(r), latencyString(r.Latency), valueOrDash(r.Note))
+		}
+		tw.Flush()
+		o.printReferences(w)
+	}
+
+	fmt.Fprintln(w, o.Verdict())
+}
+
+// printReferences lists the documentation explaining each unreachable endpoint, so that it can be passed
+// on to whoever manages the firewall
+func (o *Output) printReferences(w io.Writer) {
+	printed := false
+	for _, r := range o.endpointResults {
+		if r.Success || r.DocsURL == "" {
+			continue
+		}
+		if !printed {
+			fmt.Fprintln(w, "Why each unreachable endpoint is required:")
+			printed = true
+		}
+		if r.RequiredBy != "" {
+			fmt.Fprintf(w, " - %s (required by %s): %s\n", r.Endpoint, r.RequiredBy, r.DocsURL)
+		} else {
+			fmt.Fprintf(w, " - %s: %s\n", r.Endpoint, r.DocsURL)
+		}
+	}
+}
+
+func resultString(success bool) string {
+	if success {
+		return "reachable"
+	}
+
+	return "unreachable"
+}
+
+// endpointResultString describes the outcome of an endpoint, flaky and ignored unreachable ones apart from the
+// reachable and unreachable
+func endpointResultString(r EndpointResult) string {
+	if r.Ignored && !r.Success {
+		return "ignored"
+	}
+	if r.Flaky {
+		return "flaky"
+	}
+
+	return resultString(r.Success)
+}
+
+func latencyString(latency time.Duration) string {
+	if latency == 0 {
+		return "-"
+	}
+
+	return latency.String()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
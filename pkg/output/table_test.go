@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintTable(t *testing.T) {
+	o := Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443", Subnet: "subnet-a"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "api.openshift.com:443", Subnet: "subnet-a", Success: true, Latency: 20 * time.Millisecond})
+
+	var buf bytes.Buffer
+	o.PrintTable(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 6 {
+		t.Fatalf("expected header, 2 rows, references and verdict, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "ENDPOINT") {
+		t.Errorf("expected header row, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "unreachable") || !strings.Contains(lines[1], "subnet-a") {
+		t.Errorf("unexpected row for failed endpoint: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "20ms") {
+		t.Errorf("expected latency in row: %q", lines[2])
+	}
+	if !strings.Contains(lines[4], "quay.io:443 (required by image registry): https://") {
+		t.Errorf("expected a reference for the unreachable endpoint: %q", lines[4])
+	}
+	if lines[5] != "FAIL: 1 failures, 0 exceptions, 0 errors" {
+		t.Errorf("unexpected verdict: %q", lines[5])
+	}
+}
+
+func TestMarkRecovered(t *testing.T) {
+	o := Output{}
+	o.SetEgressFailures([]string{"Unable to reach quay.io:443", "Unable to reach sso.redhat.com:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "sso.redhat.com:443"})
+
+	o.MarkRecovered([]string{"quay.io:443"})
+
+	if len(o.failures) != 1 || o.failures[0].Error() != "egressURL error: Unable to reach sso.redhat.com:443" {
+		t.Errorf("expected only the unrecovered endpoint to fail, got %v", o.failures)
+	}
+	if !o.EndpointResults()[0].Success || o.EndpointResults()[1].Success {
+		t.Errorf("unexpected endpoint results %v", o.EndpointResults())
+	}
+	if len(o.Warnings()) != 1 {
+		t.Errorf("expected the recovered endpoint to be called out, got %v", o.Warnings())
+	}
+
+	o.MarkRecovered([]string{"sso.redhat.com:443"})
+	if !o.IsSuccessful() {
+		t.Errorf("expected the run to pass once every endpoint recovered")
+	}
+}
+
+func TestSetCategories(t *testing.T) {
+	o := Output{}
+	o.SetCategories(map[string]string{"http-inputs-acme.splunkcloud.com:443": "log forwarding (splunk-hec)"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "http-inputs-acme.splunkcloud.com:443"})
+	o.AddEndpointResult(EndpointResult{Endpoint: "quay.io:443"})
+
+	results := o.EndpointResults()
+	if results[0].Category != "log forwarding (splunk-hec)" || results[0].RequiredBy != "" || results[0].DocsURL != "" {
+		t.Errorf("expected the destination to be reported under its category alone, got %+v", results[0])
+	}
+	if results[1].Category != "" || results[1].RequiredBy == "" {
+		t.Errorf("expected the catalog endpoint to be reported as required by a cluster service, got %+v", results[1])
+	}
+}
@@ -0,0 +1,59 @@
+package output
+
+// AddTarget stores the results of verifying a single target, e.g. a subnet, keyed by the target's name. Adding a
+// target again replaces its results.
+func (o *Output) AddTarget(target string, result *Output) {
+	if o.targets == nil {
+		o.targets = map[string]*Output{}
+	}
+	if _, ok := o.targets[target]; !ok {
+		o.targetOrder = append(o.targetOrder, target)
+	}
+	o.targets[target] = result
+}
+
+// Target returns the results of the named target, or nil if there are none
+func (o *Output) Target(target string) *Output {
+	return o.targets[target]
+}
+
+// Targets returns the names of the targets, in the order they were added
+func (o *Output) Targets() []string {
+	return o.targetOrder
+}
+
+// TargetResults returns the results of every target, in the order they were added
+func (o *Output) TargetResults() []*Output {
+	results := make([]*Output, 0, len(o.targetOrder))
+	for _, target := range o.targetOrder {
+		results = append(results, o.targets[target])
+	}
+
+	return results
+}
+
+// AllSucceeded reports whether the output itself and every target's results are successful
+func (o *Output) AllSucceeded() bool {
+	if !o.IsSuccessful() {
+		return false
+	}
+	for _, result := range o.targets {
+		if !result.IsSuccessful() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FailuresByTarget returns the failures of each target that has any, keyed by target
+func (o *Output) FailuresByTarget() map[string][]error {
+	failures := map[string][]error{}
+	for target, result := range o.targets {
+		if len(result.failures) > 0 {
+			failures[target] = result.failures
+		}
+	}
+
+	return failures
+}
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTargets(t *testing.T) {
+	passed, failed := &Output{}, &Output{}
+	failed.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+
+	o := Output{}
+	o.AddTarget("subnet-b", failed)
+	o.AddTarget("subnet-a", passed)
+
+	if targets := o.Targets(); len(targets) != 2 || targets[0] != "subnet-b" {
+		t.Errorf("expected targets in the order added, got %v", targets)
+	}
+	if o.AllSucceeded() {
+		t.Errorf("expected a failed target to fail the roll-up")
+	}
+
+	failures := o.FailuresByTarget()
+	if len(failures) != 1 || len(failures["subnet-b"]) != 1 {
+		t.Errorf("expected only subnet-b to have failures, got %v", failures)
+	}
+
+	o.AddTarget("subnet-b", passed)
+	if !o.AllSucceeded() || len(o.TargetResults()) != 2 {
+		t.Errorf("expected replacing the failed target to succeed")
+	}
+
+	o.AddException(errors.New("no subnets found"))
+	if o.AllSucceeded() {
+		t.Errorf("expected the output's own exceptions to fail the roll-up")
+	}
+}
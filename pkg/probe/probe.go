@@ -0,0 +1,351 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/events"
+	"github.com/openshift/osd-network-verifier/pkg/failurepatterns"
+	"github.com/openshift/osd-network-verifier/pkg/imagecache"
+	"github.com/openshift/osd-network-verifier/pkg/logforwarding"
+	"github.com/openshift/osd-network-verifier/pkg/redact"
+	"github.com/openshift/osd-network-verifier/pkg/resourcepolicy"
+)
+
+// CPU architectures the probe instance can run on
+const (
+	ArchitectureX86_64 = "x86_64"
+	ArchitectureArm64  = "arm64"
+)
+
+// IP versions the probe can verify egress over. IPVersionBoth requests both of them.
+const (
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+	IPVersionBoth = "both"
+)
+
+// IPVersionOptions are the IP versions egress can be pinned to
+var IPVersionOptions = []string{IPVersion4, IPVersion6, IPVersionBoth}
+
+// DefaultLaunchTimeout bounds the wait for the probe instance to be running
+const DefaultLaunchTimeout = 2 * time.Minute
+
+// DefaultConsolePollInterval and DefaultConsoleTimeout pace and bound the polling of the probe instance's console
+// output for the probe's results
+const (
+	DefaultConsolePollInterval = 30 * time.Second
+	DefaultConsoleTimeout      = 4 * time.Minute
+)
+
+// DefaultBeginMarker and DefaultEndMarker are the lines the userdata script prints before and after the probe's output,
+// followed by the run's ID
+const (
+	DefaultBeginMarker = "USERDATA BEGIN"
+	DefaultEndMarker   = "USERDATA END"
+)
+
+// DefaultSoakInterval paces the rounds of re-testing the endpoints while soaking
+const DefaultSoakInterval = time.Minute
+
+// DefaultMaxInstances caps the verifier's probe instances that may exist at once before launching another is refused
+const DefaultMaxInstances = 10
+
+// Channels the probe can report its results through, besides the instance's console output
+const (
+	ResultChannelConsole      = "console"
+	ResultChannelCloudLogging = "cloud-logging"
+	ResultChannelCloudWatch   = "cloudwatch"
+	ResultChannelCallback     = "callback"
+)
+
+// Instance metadata keys the probe's script can be passed to the instance under (GCP only): cloud-init runs the
+// user-data of Container-Optimized OS images, the guest environment the startup-script of the others, e.g. RHEL or
+// Rocky Linux
+const (
+	ScriptMetadataUserData      = "user-data"
+	ScriptMetadataStartupScript = "startup-script"
+)
+
+// ScriptMetadataOptions are the instance metadata keys the probe's script can be passed under
+var ScriptMetadataOptions = []string{ScriptMetadataUserData, ScriptMetadataStartupScript}
+
+// ResultReceiver collects the results probes post back over HTTPS when they're done, see pkg/callback
+type ResultReceiver interface {
+	// URL is where the probes post their results
+	URL() string
+	// Token is the bearer token the probes authenticate with
+	Token() string
+	// CACertificate is the PEM certificate the probes verify the receiver with, so neither the token nor the results
+	// are handed to a TLS-intercepting proxy
+	CACertificate() string
+	// Result returns the output the probe of the given run posted, if it has yet
+	Result(runID string) (string, bool)
+}
+
+// DefaultResultLogGroup is the CloudWatch Logs group the probe reports its results to, when requested
+const DefaultResultLogGroup = "osd-network-verifier"
+
+// Options configures the behaviour of the probe instance launched to verify egress
+type Options struct {
+	// CapturePackets enables a bounded packet capture of the traffic to unreachable endpoints
+	CapturePackets bool
+	// DNSServers are resolver IPs, e.g. the ones the cluster will use, to check the required domains against
+	DNSServers []string
+	// ConsoleSanitizer masks the secrets of the console output stored for reports and the files written, e.g. the
+	// environment dumps and internal hostnames, beyond the credentials and tokens always masked. Defaults to the zero
+	// redact.Sanitizer.
+	ConsoleSanitizer *redact.Sanitizer
+	// Nameservers replace the probe's own resolvers for every lookup it makes, e.g. to validate the cluster's DNS
+	// forwarder before the VPC's DHCP options are changed to point at it. Defaults to the VPC's resolvers.
+	Nameservers []string
+	// PrivateServiceConnect verifies that Google APIs are reached through the VPC's Private Service Connect
+	// endpoint (GCP only)
+	PrivateServiceConnect bool
+	// ValidatorImage overrides the provider's default validator container image, e.g. to test a release
+	// candidate or to pull from an internal mirror
+	ValidatorImage string
+	// CPUArchitecture selects the default instance type when none was requested, one of the Architecture
+	// constants. Defaults to x86_64.
+	CPUArchitecture string
+	// Spot launches the probe instance on spot (AWS) or preemptible (GCP) capacity, falling back to on-demand
+	// capacity when none is available or the instance is reclaimed mid-run
+	Spot bool
+	// LaunchTimeout bounds the wait for the probe instance to be running. Defaults to DefaultLaunchTimeout.
+	LaunchTimeout time.Duration
+	// ConsolePollInterval is how often the probe instance's console output is checked for the probe's results.
+	// Defaults to DefaultConsolePollInterval.
+	ConsolePollInterval time.Duration
+	// ConsoleTimeout bounds the wait for the probe's results, e.g. slow proxies and large endpoint lists need longer.
+	// Defaults to DefaultConsoleTimeout.
+	ConsoleTimeout time.Duration
+	// RetryFailedEndpoints re-probes the unreachable endpoints once more after a pause, so transient blips don't
+	// fail the verification
+	RetryFailedEndpoints bool
+	// ResultChannel is how the probe reports its results, one of the ResultChannel constants. Channels other than the
+	// console avoid its truncation and report sooner, falling back to the console when unusable. Defaults to the
+	// console.
+	ResultChannel string
+	// ResultLogGroup is the existing CloudWatch Logs group the probe reports its results to (AWS only). Defaults to
+	// DefaultResultLogGroup.
+	ResultLogGroup string
+	// InstanceProfile is the IAM instance profile the probe instance runs with (AWS only), e.g. one allowed to
+	// write its results to CloudWatch Logs
+	InstanceProfile string
+	// ResultReceiver collects the results with ResultChannelCallback, it must be reachable from the probe's subnet
+	ResultReceiver ResultReceiver
+	// Preset selects the endpoints of a flavour of cluster to probe beyond the validator's own list, one of the
+	// endpoints.Preset constants, along with the preset's own pre-flight checks
+	Preset string
+	// OCPVersion is the OpenShift version being installed or upgraded to, whose egress list is probed beyond the
+	// validator's own list. Defaults to the newest version with an egress list.
+	OCPVersion string
+	// SubnetVPC is the VPC the subnet is expected to be in, e.g. as stated by terraform, the VPC network name on GCP
+	SubnetVPC string
+	// RequireSubnetRegion fails the pre-flight if the subnet isn't in the requested region, rather than looking for
+	// it in the other regions (AWS only)
+	RequireSubnetRegion bool
+	// RequirePrivateSubnet fails the pre-flight if the subnet is public, i.e. routes to an internet gateway
+	RequirePrivateSubnet bool
+	// WorkerSecurityGroups are the security groups of the cluster's workers, whose egress rules are checked along
+	// with the probe's (AWS only)
+	WorkerSecurityGroups []string
+	// NetworkTags are the network tags the probe instance is given, e.g. the cluster's workers', so the firewall rules
+	// targeting them apply to the probe as well (GCP only)
+	NetworkTags []string
+	// ScriptMetadataKey is the instance metadata key the probe's script is passed under, one of ScriptMetadataOptions
+	// (GCP only). Defaults to the one the probe instance's image runs, by its family or project.
+	ScriptMetadataKey string
+	// IPVersion pins the IP version egress is verified over, one of IPVersionOptions, to isolate a broken IPv4 or
+	// IPv6 path. Defaults to IPv4 and, where the subnet has IPv6 addresses, IPv6, with failures over IPv6 only warned
+	// about.
+	IPVersion string
+	// LogForwarding lists the customer's log and metric forwarding destinations, probed beyond the validator's own
+	// list and reported under their own category
+	LogForwarding *logforwarding.Config
+	// SoakDuration keeps the probe instance up re-testing the endpoints for this long after the probe, to catch
+	// intermittent failures that only appear at specific times or under load. The console timeout is extended by it.
+	SoakDuration time.Duration
+	// SoakInterval is how often the endpoints are re-tested while soaking. Defaults to DefaultSoakInterval.
+	SoakInterval time.Duration
+	// Attempts is how many times each endpoint is probed within the run, so endpoints only some attempts get through to
+	// are told apart as flaky rather than unreachable. Defaults to once.
+	Attempts int
+	// ResourcePolicy is the naming and labeling policy enforced on the resources created, e.g. the probe instance.
+	// Resources that don't comply aren't created, failing the verification.
+	ResourcePolicy *resourcepolicy.Policy
+	// VerifyCleanup checks no resource of the run, e.g. the probe instance, its disk or network interface, survived
+	// the teardown, waiting for them to be gone for up to the teardown timeout, and reports those that did
+	VerifyCleanup bool
+	// MaxInstances caps the verifier's probe instances that may exist in the account's region, or the project, at
+	// once: launching another is refused when as many already do, as a safety brake for runaway automation. Defaults
+	// to DefaultMaxInstances, a negative cap disables the check.
+	MaxInstances int
+	// BatchID identifies the verifications launched together, e.g. of a cluster's subnets --max-parallel at a time.
+	// Their probe instances are tagged with it, so the instance cap counts them apart from those left behind.
+	BatchID string
+	// PoolWindow keeps the probe instance for this long after it's launched, for later verifications of the same
+	// subnet, with the same image, instance type and the like, to re-run the probe on rather than launch their own.
+	// The instance shuts itself down, and is terminated, once the window passes. Zero disables pooling.
+	PoolWindow time.Duration
+	// ImageCache caches the images resolved for the probe instance, e.g. the image an image family points to, on disk,
+	// so later runs in the same region skip the lookups. Nil leaves image families to the cloud to resolve on creation.
+	ImageCache *imagecache.Cache
+	// FailurePatterns tell the lines of the console output reporting the probe's setup or execution failed, e.g. as
+	// loaded with failurepatterns.Load for a custom validator image. Defaults to the validator image's built-in ones.
+	FailurePatterns *failurepatterns.Patterns
+	// BeginMarker and EndMarker override the lines the userdata script prints before and after the probe's output,
+	// e.g. when the customer's own scripts print the default ones to the console. The run's ID is appended to them, so
+	// the output of an earlier run on a reused instance isn't taken for this run's. Default to DefaultBeginMarker and
+	// DefaultEndMarker.
+	BeginMarker string
+	EndMarker   string
+	// Events receives the lifecycle events and endpoint results of the verification as they happen, e.g. to stream
+	// them to a wrapper. Nil discards them.
+	Events events.Sink
+}
+
+// ValidatorImageOrDefault returns the validator image to run, defaultImage unless overridden
+func (o Options) ValidatorImageOrDefault(defaultImage string) string {
+	if o.ValidatorImage != "" {
+		return o.ValidatorImage
+	}
+
+	return defaultImage
+}
+
+// FailurePatternsOrDefault returns the patterns telling the probe's failures, the built-in ones of the validator image
+// run unless overridden
+func (o Options) FailurePatternsOrDefault(defaultImage string) *failurepatterns.Patterns {
+	if o.FailurePatterns != nil {
+		return o.FailurePatterns
+	}
+
+	return failurepatterns.ForValidator(o.ValidatorImageOrDefault(defaultImage))
+}
+
+// BeginMarkerOrDefault returns the line printed before the probe's output, without the run's ID
+func (o Options) BeginMarkerOrDefault() string {
+	if o.BeginMarker != "" {
+		return o.BeginMarker
+	}
+
+	return DefaultBeginMarker
+}
+
+// EndMarkerOrDefault returns the line printed after the probe's output, without the run's ID
+func (o Options) EndMarkerOrDefault() string {
+	if o.EndMarker != "" {
+		return o.EndMarker
+	}
+
+	return DefaultEndMarker
+}
+
+// ValidateMarker checks a begin or end marker can be printed verbatim by the userdata script, as a single line
+// without the characters the shell expands in double quotes
+func ValidateMarker(marker string) error {
+	if i := strings.IndexAny(marker, "\"$`\\\r\n"); i >= 0 {
+		return fmt.Errorf("marker %q contains %q, which the userdata script can't print verbatim", marker, marker[i])
+	}
+
+	return nil
+}
+
+// Architecture returns the requested CPU architecture, x86_64 unless overridden
+func (o Options) Architecture() string {
+	if o.CPUArchitecture != "" {
+		return o.CPUArchitecture
+	}
+
+	return ArchitectureX86_64
+}
+
+// IPVersions returns the IP versions to verify egress over, those pinned or the ones the subnet has addresses of
+func (o Options) IPVersions(ipv6Available bool) []string {
+	switch o.IPVersion {
+	case IPVersion4, IPVersion6:
+		return []string{o.IPVersion}
+	case IPVersionBoth:
+		return []string{IPVersion4, IPVersion6}
+	}
+	if ipv6Available {
+		return []string{IPVersion4, IPVersion6}
+	}
+
+	return []string{IPVersion4}
+}
+
+// VerifiesIPv6 returns whether egress is verified over IPv6, as pinned or where the subnet has IPv6 addresses
+func (o Options) VerifiesIPv6(ipv6Available bool) bool {
+	for _, version := range o.IPVersions(ipv6Available) {
+		if version == IPVersion6 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LaunchTimeoutOrDefault returns how long to wait for the probe instance to be running
+func (o Options) LaunchTimeoutOrDefault() time.Duration {
+	if o.LaunchTimeout > 0 {
+		return o.LaunchTimeout
+	}
+
+	return DefaultLaunchTimeout
+}
+
+// ConsolePollIntervalOrDefault returns how often to check the probe instance's console output
+func (o Options) ConsolePollIntervalOrDefault() time.Duration {
+	if o.ConsolePollInterval > 0 {
+		return o.ConsolePollInterval
+	}
+
+	return DefaultConsolePollInterval
+}
+
+// ConsoleTimeoutOrDefault returns how long to wait for the probe's results in the console output, extended by the
+// soak duration
+func (o Options) ConsoleTimeoutOrDefault() time.Duration {
+	if o.ConsoleTimeout > 0 {
+		return o.ConsoleTimeout + o.SoakDuration
+	}
+
+	return DefaultConsoleTimeout + o.SoakDuration
+}
+
+// SoakIntervalOrDefault returns how often the endpoints are re-tested while soaking
+func (o Options) SoakIntervalOrDefault() time.Duration {
+	if o.SoakInterval > 0 {
+		return o.SoakInterval
+	}
+
+	return DefaultSoakInterval
+}
+
+// MaxInstancesOrDefault returns the cap on the probe instances existing at once, negative if there's none
+func (o Options) MaxInstancesOrDefault() int {
+	if o.MaxInstances != 0 {
+		return o.MaxInstances
+	}
+
+	return DefaultMaxInstances
+}
+
+// ResultLogGroupOrDefault returns the CloudWatch Logs group the probe reports its results to
+func (o Options) ResultLogGroupOrDefault() string {
+	if o.ResultLogGroup != "" {
+		return o.ResultLogGroup
+	}
+
+	return DefaultResultLogGroup
+}
+
+// Emit sends the event to the Events sink, if there's one
+func (o Options) Emit(e events.Event) {
+	if o.Events != nil {
+		o.Events.Emit(e)
+	}
+}
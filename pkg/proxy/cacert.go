@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	handledErrors "github.com/openshift/osd-network-verifier/pkg/errors"
+)
+
+// caCertExpiryWarning is how close to expiring a certificate of the CA bundle is warned about
+const caCertExpiryWarning = 30 * 24 * time.Hour
+
+// VerifyCACert checks the CA bundle is usable before it's handed to the probe, as a bad bundle fails every TLS
+// probe, which is then blamed on the network: it must be PEM-encoded certificates only, every one a CA's, valid at
+// now. Problems are returned as failures, while certificates about to expire and a bundle without any root CA,
+// e.g. holding only the proxy's intermediate, are warned about. A config without a bundle has no problems.
+func (p ProxyConfig) VerifyCACert(now time.Time) ([]error, []string) {
+	if p.Cacert == "" {
+		return nil, nil
+	}
+
+	var failures []error
+	var warnings []string
+	var certs []*x509.Certificate
+	rest := []byte(p.Cacert)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			failures = append(failures, handledErrors.NewCACertError(fmt.Sprintf("the CA bundle holds a %s block, it must only hold certificates", block.Type)))
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			failures = append(failures, handledErrors.NewCACertError(fmt.Sprintf("certificate %d of the CA bundle can't be parsed: %v", len(certs)+1, err)))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return append(failures, handledErrors.NewCACertError("the CA bundle holds no PEM-encoded certificates")), warnings
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		failures = append(failures, handledErrors.NewCACertError("the CA bundle holds text that isn't PEM-encoded, e.g. a truncated or mangled certificate"))
+	}
+
+	root := false
+	for _, cert := range certs {
+		subject := cert.Subject.String()
+		if !cert.IsCA {
+			failures = append(failures, handledErrors.NewCACertError(fmt.Sprintf("certificate %s of the CA bundle is not a CA certificate, e.g. the proxy's own certificate rather than its issuer's", subject)))
+		}
+		switch {
+		case now.After(cert.NotAfter):
+			failures = append(failures, handledErrors.NewCACertError(fmt.Sprintf("certificate %s of the CA bundle expired on %s", subject, cert.NotAfter.Format(time.RFC3339))))
+		case now.Before(cert.NotBefore):
+			failures = append(failures, handledErrors.NewCACertError(fmt.Sprintf("certificate %s of the CA bundle is not valid before %s", subject, cert.NotBefore.Format(time.RFC3339))))
+		case now.Add(caCertExpiryWarning).After(cert.NotAfter):
+			warnings = append(warnings, fmt.Sprintf("Certificate %s of the CA bundle expires on %s", subject, cert.NotAfter.Format(time.RFC3339)))
+		}
+		if bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+			root = true
+		}
+	}
+	if !root {
+		warnings = append(warnings, fmt.Sprintf("The CA bundle holds %d certificate(s) but no root CA, TLS through the proxy only verifies if its root is among the probe's system CAs", len(certs)))
+	}
+
+	return failures, warnings
+}
+
+// reDNSeparator splits distinguished names as printed by Go, e.g. CN=Proxy CA,O=Example,C=US, on the commas not
+// escaped within values
+var reDNSeparator = regexp.MustCompile(`([^\\]),\s*`)
+
+// HasIssuer returns whether the CA bundle holds a certificate of the issuer, a distinguished name as printed by curl
+// or Go, e.g. the CA of a proxy intercepting TLS. Certificates are matched on their common name and organization.
+func (p ProxyConfig) HasIssuer(issuer string) bool {
+	cn, o := dnAttributes(issuer)
+	if cn == "" {
+		return false
+	}
+
+	rest := []byte(p.Cacert)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return false
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if cert.Subject.CommonName == cn && strings.Join(cert.Subject.Organization, ",") == o {
+			return true
+		}
+	}
+}
+
+// dnAttributes returns the common name and organization of a distinguished name. curl separates attributes with
+// semicolons, e.g. C=US; O=Example, Inc.; CN=Proxy CA, leaving commas within values unescaped.
+func dnAttributes(dn string) (string, string) {
+	attributes := strings.Split(dn, ";")
+	if !strings.Contains(dn, ";") {
+		attributes = strings.Split(reDNSeparator.ReplaceAllString(dn, "$1\x00"), "\x00")
+	}
+
+	var cn string
+	var organizations []string
+	for _, attribute := range attributes {
+		kv := strings.SplitN(strings.TrimSpace(attribute), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.ReplaceAll(strings.TrimSpace(kv[1]), `\,`, ",")
+		switch strings.ToUpper(strings.TrimSpace(kv[0])) {
+		case "CN":
+			cn = value
+		case "O":
+			organizations = append(organizations, value)
+		}
+	}
+
+	return cn, strings.Join(organizations, ",")
+}
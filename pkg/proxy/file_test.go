@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0o600))
+	configFile := filepath.Join(dir, "proxy.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte(`http_proxy: http://proxy.example.com:3128
+https_proxy: https://proxy.example.com:3129
+ca_cert_file: ca.pem
+`), 0o600))
+
+	p, err := Load(configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, ProxyConfig{
+		HttpProxy:  "http://proxy.example.com:3128",
+		HttpsProxy: "https://proxy.example.com:3129",
+		Cacert:     "-----BEGIN CERTIFICATE-----\n",
+	}, p, "the CA bundle is read relative to the configuration file")
+
+	p, err = Parse([]byte("https_proxy: https://proxy.example.com\nca_cert: |\n  -----BEGIN CERTIFICATE-----\nno_tls: true\n"), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\n", p.Cacert)
+	assert.True(t, p.NoTls)
+
+	_, err = Parse([]byte("httpsproxy: https://proxy.example.com\n"), dir)
+	assert.Error(t, err, "unknown fields are rejected")
+	_, err = Parse([]byte("ca_cert: pem\nca_cert_file: ca.pem\n"), dir)
+	assert.Error(t, err)
+	_, err = Parse([]byte("ca_cert_file: missing.pem\n"), dir)
+	assert.Error(t, err)
+}
@@ -1,8 +1,69 @@
 package proxy
 
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+)
+
 type ProxyConfig struct {
 	HttpProxy  string
 	HttpsProxy string
 	Cacert     string
 	NoTls      bool
 }
+
+// Address is an IPv4 address and port a proxy listens on
+type Address struct {
+	IP   string
+	Port int32
+}
+
+// Configured returns whether egress goes through a proxy
+func (p ProxyConfig) Configured() bool {
+	return p.HttpProxy != "" || p.HttpsProxy != ""
+}
+
+// Addresses resolves the hosts of the proxies to the IPv4 addresses and ports egress traffic is sent to. The hosts
+// that can't be resolved, e.g. names only resolvable within the VPC, are returned separately.
+func (p ProxyConfig) Addresses(ctx context.Context) ([]Address, []string) {
+	var addresses []Address
+	var unresolved []string
+	seen := map[Address]bool{}
+	for _, proxyURL := range []string{p.HttpsProxy, p.HttpProxy} {
+		u, err := url.Parse(proxyURL)
+		if proxyURL == "" || err != nil || u.Hostname() == "" {
+			continue
+		}
+		port := int64(80)
+		if u.Scheme == "https" {
+			port = 443
+		}
+		if u.Port() != "" {
+			if port, err = strconv.ParseInt(u.Port(), 10, 32); err != nil {
+				continue
+			}
+		}
+
+		hosts := []string{u.Hostname()}
+		if net.ParseIP(u.Hostname()) == nil {
+			if hosts, err = net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+				unresolved = append(unresolved, u.Hostname())
+				continue
+			}
+		}
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip == nil || ip.To4() == nil {
+				continue
+			}
+			address := Address{IP: host, Port: int32(port)}
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+
+	return addresses, unresolved
+}
@@ -0,0 +1,105 @@
+package redact
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+)
+
+// Mask replaces the secrets removed from logs and artifacts
+const Mask = "REDACTED"
+
+var (
+	// reURLCredentials matches the user and password of a URL, e.g. a proxy's
+	reURLCredentials = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/\s@"']+@`)
+	// reAuthorization matches the credentials of authorization headers, e.g. a proxy's basic authentication
+	reAuthorization = regexp.MustCompile(`(?i)\b((?:proxy-)?authorization:\s*(?:(?:basic|bearer|digest|negotiate|ntlm)\s+)?)[^\s"']+`)
+	// reBearer matches the token of a bearer authorization header
+	reBearer = regexp.MustCompile(`(?i)\b(Bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	// reMetadataToken matches the session tokens of the instance metadata services: IMDSv2's header and tokens, and
+	// the OAuth access tokens of GCP's
+	reMetadataToken = regexp.MustCompile(`(?i)\b(x-aws-ec2-metadata-token:\s*)[^\s"']+`)
+	reIMDSToken     = regexp.MustCompile(`\bAQAE[A-Za-z0-9_=-]{40,}`)
+	reAccessToken   = regexp.MustCompile(`\bya29\.[A-Za-z0-9._-]+`)
+	// reToken matches token assignments, e.g. OCM_TOKEN=... or "access_token": "..."
+	reToken = regexp.MustCompile(`(?i)\b([a-z_]*token["']?\s*[=:]\s*["']?)[^\s"'&,]+`)
+	// rePrivateKey matches PEM private key blocks, as pasted into a CA bundle by mistake
+	rePrivateKey = regexp.MustCompile(`(?s)-----BEGIN ([A-Z ]*PRIVATE KEY)-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// String masks the secrets found in s: the credentials of URLs and authorization headers, bearer, instance metadata and
+// other tokens, and private keys
+func String(s string) string {
+	s = rePrivateKey.ReplaceAllString(s, "-----BEGIN $1-----\n"+Mask+"\n-----END $1-----")
+	s = reURLCredentials.ReplaceAllString(s, "${1}"+Mask+"@")
+	s = reAuthorization.ReplaceAllString(s, "${1}"+Mask)
+	s = reBearer.ReplaceAllString(s, "${1}"+Mask)
+	s = reMetadataToken.ReplaceAllString(s, "${1}"+Mask)
+	s = reIMDSToken.ReplaceAllLiteralString(s, Mask)
+	s = reAccessToken.ReplaceAllLiteralString(s, Mask)
+	return reToken.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Variables returns a copy of the userdata variables with the secrets of the sensitive ones masked, for the userdata
+// generated from them to be logged. The CA certificate is base64-encoded in the userdata, so it's decoded to be
+// masked.
+func Variables(variables map[string]string) map[string]string {
+	redacted := make(map[string]string, len(variables))
+	for name, value := range variables {
+		switch name {
+		case "CALLBACK_TOKEN":
+			if value != "" {
+				value = Mask
+			}
+		case "CACERT":
+			if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+				value = base64.StdEncoding.EncodeToString([]byte(String(string(decoded))))
+			}
+		default:
+			value = String(value)
+		}
+		redacted[name] = value
+	}
+
+	return redacted
+}
+
+// logger masks the secrets of the messages it logs
+type logger struct {
+	logging.Logger
+}
+
+// Logger wraps l so the secrets of every message logged are masked
+func Logger(l logging.Logger) logging.Logger {
+	if _, ok := l.(logger); ok || l == nil {
+		return l
+	}
+	return logger{l}
+}
+
+func (l logger) Debug(ctx context.Context, format string, args ...interface{}) {
+	// Debug messages include the whole console output, not worth scanning when they're discarded
+	if !l.DebugEnabled() {
+		return
+	}
+	l.Logger.Debug(ctx, "%s", String(fmt.Sprintf(format, args...)))
+}
+
+func (l logger) Info(ctx context.Context, format string, args ...interface{}) {
+	l.Logger.Info(ctx, "%s", String(fmt.Sprintf(format, args...)))
+}
+
+func (l logger) Warn(ctx context.Context, format string, args ...interface{}) {
+	l.Logger.Warn(ctx, "%s", String(fmt.Sprintf(format, args...)))
+}
+
+func (l logger) Error(ctx context.Context, format string, args ...interface{}) {
+	l.Logger.Error(ctx, "%s", String(fmt.Sprintf(format, args...)))
+}
+
+func (l logger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	l.Logger.Fatal(ctx, "%s", String(fmt.Sprintf(format, args...)))
+}
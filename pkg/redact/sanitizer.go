@@ -0,0 +1,56 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// reEnvironment matches the variables of environment dumps, e.g. the output of env, export -p or declare -x
+var reEnvironment = regexp.MustCompile(`(?m)^((?:declare -x |export )?[A-Z_][A-Z0-9_]*=)[^\r\n]+`)
+
+// Sanitizer masks what shouldn't leave the probe's console output when it's stored, in reports and in the files
+// written, beyond the secrets String masks. The zero value masks environment dumps as well.
+type Sanitizer struct {
+	// KeepEnvironment leaves the values of environment dumps as they are, e.g. for debugging the probe's own
+	// environment
+	KeepEnvironment bool
+	// Patterns are masked wherever they match, e.g. internal hostnames. A pattern with groups keeps its first group,
+	// so `(password: )\S+` masks the password alone.
+	Patterns []*regexp.Regexp
+}
+
+// NewSanitizer returns a sanitizer masking the regular expressions given, besides environment dumps unless they're
+// to be kept
+func NewSanitizer(patterns []string, keepEnvironment bool) (*Sanitizer, error) {
+	s := &Sanitizer{KeepEnvironment: keepEnvironment}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sanitizer pattern %q: %w", pattern, err)
+		}
+		s.Patterns = append(s.Patterns, re)
+	}
+
+	return s, nil
+}
+
+// Sanitize returns the console output given with its secrets masked. A nil sanitizer masks them as the zero value.
+func (s *Sanitizer) Sanitize(consoleLogs string) string {
+	if s == nil {
+		s = &Sanitizer{}
+	}
+
+	consoleLogs = String(consoleLogs)
+	if !s.KeepEnvironment {
+		consoleLogs = reEnvironment.ReplaceAllString(consoleLogs, "${1}"+Mask)
+	}
+	for _, re := range s.Patterns {
+		if re.NumSubexp() > 0 {
+			consoleLogs = re.ReplaceAllString(consoleLogs, "${1}"+Mask)
+		} else {
+			consoleLogs = re.ReplaceAllLiteralString(consoleLogs, Mask)
+		}
+	}
+
+	return consoleLogs
+}
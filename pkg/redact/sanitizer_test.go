@@ -0,0 +1,61 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	consoleLogs := `USERDATA BEGIN
+declare -x HTTPS_PROXY="http://proxy:3128"
+AWS_SECRET_ACCESS_KEY=abc123
+curl -H "X-aws-ec2-metadata-token: AQAEAbcdefghijklmnopqrstuvwxyz0123456789ABCDEFG==" http://169.254.169.254/
+Proxy-Authorization: Basic dXNlcjpwYXNz
+Unable to reach registry.internal.example.com:443
+USERDATA END`
+
+	tests := []struct {
+		name     string
+		patterns []string
+		keepEnv  bool
+		expected string
+	}{
+		{
+			name: "default",
+			expected: `USERDATA BEGIN
+declare -x HTTPS_PROXY=REDACTED
+AWS_SECRET_ACCESS_KEY=REDACTED
+curl -H "X-aws-ec2-metadata-token: REDACTED" http://169.254.169.254/
+Proxy-Authorization: Basic REDACTED
+Unable to reach registry.internal.example.com:443
+USERDATA END`,
+		},
+		{
+			name:     "patterns and environment kept",
+			patterns: []string{`[a-z]+\.internal\.example\.com`, `(reach )\S+`},
+			keepEnv:  true,
+			expected: `USERDATA BEGIN
+declare -x HTTPS_PROXY="http://proxy:3128"
+AWS_SECRET_ACCESS_KEY=abc123
+curl -H "X-aws-ec2-metadata-token: REDACTED" http://169.254.169.254/
+Proxy-Authorization: Basic REDACTED
+Unable to reach REDACTED
+USERDATA END`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewSanitizer(test.patterns, test.keepEnv)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s.Sanitize(consoleLogs))
+		})
+	}
+
+	var nilSanitizer *Sanitizer
+	assert.Equal(t, tests[0].expected, nilSanitizer.Sanitize(consoleLogs), "a nil sanitizer sanitizes as the zero value")
+
+	_, err := NewSanitizer([]string{"("}, false)
+	assert.Error(t, err)
+}
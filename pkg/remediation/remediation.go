@@ -0,0 +1,218 @@
+// Package remediation suggests next steps for the failures of a verification. Its API is an implementation detail of
+// the verifier, not covered by the compatibility promise of pkg/verifier.
+package remediation
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+)
+
+// minServicesForTotalFailure is how many distinct cluster services must be affected, with nothing reachable,
+// before a failure is considered total rather than a set of individually blocked endpoints
+const minServicesForTotalFailure = 3
+
+var (
+	// reTLSFailure matches the certificate errors of an endpoint's failure lines, rather than a handshake that failed
+	// otherwise, e.g. with the connection reset
+	reTLSFailure          = regexp.MustCompile(`(?i)x509|certificate|tls: `)
+	reProxyConnectRefused = regexp.MustCompile(`(?i)(CONNECT|proxy)[^\n]*(403|407|refused|denied)|(403|407|refused|denied)[^\n]*(CONNECT|proxy)`)
+)
+
+// Input is the evidence the rules are evaluated against
+type Input struct {
+	Results     []output.EndpointResult
+	ConsoleLogs string
+	Proxy       proxy.ProxyConfig
+	ResolvConf  output.ResolvConf
+}
+
+// Rule maps a recognizable failure pattern to a suggested next step
+type Rule struct {
+	Name       string
+	Matches    func(in Input) bool
+	Suggestion string
+}
+
+// Rules are evaluated in order, and every matching rule contributes its suggestion
+var Rules = []Rule{
+	{
+		Name:       "proxy-acl",
+		Matches:    proxyConnectRefused,
+		Suggestion: "The proxy refused CONNECT requests: check the proxy's allowlist/ACLs permit the failing endpoints for the verifier's source IP",
+	},
+	{
+		Name:       "all-unreachable",
+		Matches:    allUnreachable,
+		Suggestion: "No endpoint was reachable: check the subnet's route table has a default route to a NAT gateway, internet gateway or firewall, and that the security group and network ACLs allow outbound traffic",
+	},
+	{
+		Name:       "tls-interception",
+		Matches:    tlsInterception,
+		Suggestion: "Only TLS endpoints failed with certificate errors: traffic is likely being intercepted by a proxy or firewall, either exempt these endpoints from TLS inspection or pass the intercepting CA with --cacert",
+	},
+	{
+		Name:       "custom-dns",
+		Matches:    customDNSWithFailures,
+		Suggestion: "The subnet uses custom DNS servers (via its DHCP options set): ensure they forward queries they aren't authoritative for to a resolver that can resolve the required endpoints",
+	},
+	{
+		Name:       "registry-blocked",
+		Matches:    onlyRegistryUnreachable,
+		Suggestion: "Only image registry endpoints failed: allow quay.io and its CDN subdomains (*.quay.io) through the firewall, wildcards are required as CDN hostnames change",
+	},
+}
+
+// Suggest returns the suggestions of every rule matching the input, followed by those naming the CAs found
+// intercepting TLS
+func Suggest(in Input) []string {
+	var suggestions []string
+	for _, rule := range Rules {
+		if rule.Matches(in) {
+			suggestions = append(suggestions, rule.Suggestion)
+		}
+	}
+
+	return append(suggestions, interceptingCAs(in)...)
+}
+
+// interceptingCAs suggests trusting each CA that issued the certificates of endpoints whose TLS is intercepted,
+// telling apart CAs missing from the CA bundle given from those already in it
+func interceptingCAs(in Input) []string {
+	endpoints := map[string][]string{}
+	for _, r := range failed(in) {
+		if r.TLSIssuer != "" {
+			endpoints[r.TLSIssuer] = append(endpoints[r.TLSIssuer], r.Endpoint)
+		}
+	}
+	issuers := make([]string, 0, len(endpoints))
+	for issuer := range endpoints {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+
+	var suggestions []string
+	for _, issuer := range issuers {
+		intercepted := strings.Join(endpoints[issuer], ", ")
+		if in.Proxy.HasIssuer(issuer) {
+			suggestions = append(suggestions, fmt.Sprintf("TLS to %s is intercepted with certificates issued by %q, which is in the CA bundle given but isn't trusted: check the bundle holds the whole chain up to its root CA, and that the certificate is valid", intercepted, issuer))
+		} else {
+			suggestions = append(suggestions, fmt.Sprintf("TLS to %s is intercepted with certificates issued by %q: add that CA's certificate to the cluster's additionalTrustBundle and pass it with --cacert, or exempt these endpoints from TLS inspection", intercepted, issuer))
+		}
+	}
+
+	return suggestions
+}
+
+// Apply evaluates the rules against a verification's results and records any suggestions on it
+func Apply(out *output.Output, p proxy.ProxyConfig) {
+	for _, s := range Suggest(Input{Results: out.EndpointResults(), ConsoleLogs: out.ConsoleLogs(), Proxy: p, ResolvConf: out.Metadata().ResolvConf}) {
+		out.AddSuggestion(s)
+	}
+}
+
+func failed(in Input) []output.EndpointResult {
+	var failures []output.EndpointResult
+	for _, r := range in.Results {
+		if !r.Success {
+			failures = append(failures, r)
+		}
+	}
+
+	return failures
+}
+
+func allUnreachable(in Input) bool {
+	failures := failed(in)
+	if len(failures) == 0 || len(failures) != len(in.Results) {
+		return false
+	}
+
+	services := map[string]bool{}
+	for _, r := range failures {
+		services[r.RequiredBy] = true
+	}
+
+	return len(services) >= minServicesForTotalFailure
+}
+
+func tlsInterception(in Input) bool {
+	failures := failed(in)
+	if len(failures) == 0 {
+		return false
+	}
+
+	certificateErrors := false
+	for _, r := range failures {
+		if port(r.Endpoint) != "443" {
+			return false
+		}
+		if r.TLSIssuer != "" || reTLSFailure.MatchString(strings.Join(failureLines(in.ConsoleLogs, r.Endpoint), "\n")) {
+			certificateErrors = true
+		}
+	}
+
+	return certificateErrors
+}
+
+// failureLines returns the lines the validator reports the endpoint's failure with, "Unable to reach <endpoint>" and
+// the "TLS_ERROR <endpoint>" of its handshake, so errors of other endpoints or the rest of the console don't count
+func failureLines(consoleLogs, endpoint string) []string {
+	var lines []string
+	for _, line := range strings.Split(consoleLogs, "\n") {
+		for _, prefix := range []string{"Unable to reach ", "TLS_ERROR "} {
+			if i := strings.Index(line, prefix+endpoint); i >= 0 {
+				rest := line[i+len(prefix)+len(endpoint):]
+				if rest == "" || rest[0] == ' ' || rest[0] == ':' {
+					lines = append(lines, line[i:])
+				}
+			}
+		}
+	}
+
+	return lines
+}
+
+func onlyRegistryUnreachable(in Input) bool {
+	failures := failed(in)
+	if len(failures) == 0 {
+		return false
+	}
+
+	for _, r := range failures {
+		host := r.Endpoint
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host != "quay.io" && !strings.HasSuffix(host, ".quay.io") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func proxyConnectRefused(in Input) bool {
+	if in.Proxy.HttpProxy == "" && in.Proxy.HttpsProxy == "" {
+		return false
+	}
+
+	return len(failed(in)) > 0 && reProxyConnectRefused.MatchString(in.ConsoleLogs)
+}
+
+func customDNSWithFailures(in Input) bool {
+	return len(in.ResolvConf.CustomNameservers()) > 0 && len(failed(in)) > 0
+}
+
+func port(endpoint string) string {
+	if _, p, err := net.SplitHostPort(endpoint); err == nil {
+		return p
+	}
+
+	return ""
+}
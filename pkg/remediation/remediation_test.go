@@ -0,0 +1,124 @@
+package remediation
+
+import (
+	"testing"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func unreachable(endpoint, requiredBy string) output.EndpointResult {
+	return output.EndpointResult{Endpoint: endpoint, RequiredBy: requiredBy}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    Input
+		expected []string
+	}{
+		{
+			name:  "no failures",
+			input: Input{Results: []output.EndpointResult{{Endpoint: "quay.io:443", Success: true}}},
+		},
+		{
+			name: "everything unreachable",
+			input: Input{Results: []output.EndpointResult{
+				unreachable("quay.io:443", "image registry"),
+				unreachable("api.openshift.com:443", "update service"),
+				unreachable("sso.redhat.com:443", "OIDC"),
+				unreachable("api.pagerduty.com:443", "support"),
+			}},
+			expected: []string{"all-unreachable"},
+		},
+		{
+			name: "only tls endpoints with certificate errors",
+			input: Input{
+				Results: []output.EndpointResult{
+					unreachable("api.openshift.com:443", "update service"),
+					{Endpoint: "mirror.openshift.com:80", Success: true},
+				},
+				ConsoleLogs: "Unable to reach api.openshift.com:443: x509: certificate signed by unknown authority",
+			},
+			expected: []string{"tls-interception"},
+		},
+		{
+			name: "tls handshake of the endpoint failed with a certificate error",
+			input: Input{
+				Results:     []output.EndpointResult{unreachable("quay.io:443", "image registry"), {Endpoint: "api.openshift.com:443", Success: true}},
+				ConsoleLogs: "Unable to reach quay.io:443\nTLS_ERROR quay.io:443 SSL certificate problem: self-signed certificate in certificate chain",
+			},
+			expected: []string{"tls-interception", "registry-blocked"},
+		},
+		{
+			name: "ssl elsewhere in the console, the handshake reset",
+			input: Input{
+				Results: []output.EndpointResult{unreachable("api.openshift.com:443", "update service"), {Endpoint: "quay.io:443", Success: true}},
+				ConsoleLogs: "Installing openssl-libs\n[    2.1] x509: loaded certificate 'Amazon Linux kernel signing key'\n" +
+					"Unable to reach api.openshift.com:443\nTLS_ERROR api.openshift.com:443 OpenSSL SSL_connect: Connection reset by peer\n" +
+					"TLS_ERROR sso.redhat.com:443 x509: certificate signed by unknown authority",
+			},
+		},
+		{
+			name: "only quay",
+			input: Input{Results: []output.EndpointResult{
+				unreachable("quay.io:443", "image registry"),
+				unreachable("cdn02.quay.io:443", "image registry"),
+				{Endpoint: "api.openshift.com:443", Success: true},
+			}},
+			expected: []string{"registry-blocked"},
+		},
+		{
+			name: "proxy refuses connect",
+			input: Input{
+				Results:     []output.EndpointResult{unreachable("api.openshift.com:443", "update service"), {Endpoint: "quay.io:443", Success: true}},
+				ConsoleLogs: "Received HTTP code 403 from proxy after CONNECT",
+				Proxy:       proxy.ProxyConfig{HttpsProxy: "https://proxy:3128"},
+			},
+			expected: []string{"proxy-acl"},
+		},
+		{
+			name: "custom dns",
+			input: Input{
+				Results:    []output.EndpointResult{unreachable("api.openshift.com:443", "update service"), {Endpoint: "quay.io:443", Success: true}},
+				ResolvConf: output.ResolvConf{Nameservers: []string{"192.168.1.53"}},
+			},
+			expected: []string{"custom-dns"},
+		},
+		{
+			name: "connect refused without a proxy configured",
+			input: Input{
+				Results:     []output.EndpointResult{unreachable("api.openshift.com:443", "update service"), {Endpoint: "quay.io:443", Success: true}},
+				ConsoleLogs: "Received HTTP code 403 from proxy after CONNECT",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var matched []string
+		for _, rule := range Rules {
+			if rule.Matches(test.input) {
+				matched = append(matched, rule.Name)
+			}
+		}
+		assert.Equal(t, test.expected, matched, test.name)
+	}
+}
+
+func TestInterceptingCAs(t *testing.T) {
+	issuer := "C=US; O=Example Corp; CN=Example Proxy CA"
+	in := Input{Results: []output.EndpointResult{
+		{Endpoint: "quay.io:443", TLSIssuer: issuer},
+		{Endpoint: "api.openshift.com:443", TLSIssuer: issuer},
+		{Endpoint: "sso.redhat.com:443", TLSIssuer: issuer, Success: true},
+		unreachable("mirror.openshift.com:443", "update service"),
+	}}
+
+	suggestions := interceptingCAs(in)
+	if assert.Len(t, suggestions, 1, "endpoints are grouped by the CA intercepting them") {
+		assert.Contains(t, suggestions[0], "TLS to quay.io:443, api.openshift.com:443 is intercepted")
+		assert.Contains(t, suggestions[0], "add that CA's certificate to the cluster's additionalTrustBundle")
+	}
+	assert.Empty(t, interceptingCAs(Input{Results: []output.EndpointResult{unreachable("quay.io:443", "image registry")}}))
+}
@@ -0,0 +1,125 @@
+package resourcepolicy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Kinds of resources the verifier creates
+const (
+	// KindInstance is the probe instance, along with what's created with it and carries its tags, e.g. its volume
+	// and network interface on AWS, or its boot disk on GCP
+	KindInstance = "instance"
+)
+
+// Policy is a naming and labeling policy enforced on everything the verifier creates, so it can run in accounts and
+// projects with strict governance policies, e.g. SCPs or organization policies requiring tags or name prefixes
+type Policy struct {
+	// RequiredTags are tags, or labels on GCP, every resource must carry. Those with a value are added to the tags
+	// given, and those without one must be among them with any value.
+	RequiredTags map[string]string `json:"required_tags,omitempty"`
+	// NamePrefix is prepended to the names of resources that don't already start with it
+	NamePrefix string `json:"name_prefix,omitempty"`
+	// NamePattern is a regular expression the names of resources must match, after prefixing
+	NamePattern string `json:"name_pattern,omitempty"`
+	// Hook, if set, is called with every resource before it's created, after the rest of the policy is applied, e.g.
+	// to enforce constraints of the caller's own. An error rejects the resource, failing the verification.
+	Hook func(resource Resource) error `json:"-"`
+}
+
+// Resource describes a resource about to be created
+type Resource struct {
+	// Kind is one of the Kind constants
+	Kind string
+	// Name is the resource's name, the Name tag on AWS
+	Name string
+	// Tags are the resource's tags, or labels on GCP
+	Tags map[string]string
+}
+
+// Load reads a policy file
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read resource policy %s: %w", file, err)
+	}
+
+	policy, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("resource policy %s: %w", file, err)
+	}
+
+	return policy, nil
+}
+
+// Parse parses a policy, rejecting unknown fields and invalid name patterns
+func Parse(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("unable to parse resource policy: %w", err)
+	}
+	if policy.NamePattern != "" {
+		if _, err := regexp.Compile(policy.NamePattern); err != nil {
+			return nil, fmt.Errorf("invalid name_pattern: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// Apply prefixes the resource's name and adds the required tags to its own, leaving those given untouched, then
+// checks the result complies with the policy and passes it to the hook. It returns the resource to create, or an
+// error naming what doesn't comply. A nil policy leaves the resource as is.
+func (p *Policy) Apply(r Resource) (Resource, error) {
+	if p == nil {
+		return r, nil
+	}
+
+	tags := make(map[string]string, len(r.Tags)+len(p.RequiredTags))
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+	var missing []string
+	for k, v := range p.RequiredTags {
+		current, ok := tags[k]
+		switch {
+		case v == "" && !ok:
+			missing = append(missing, k)
+		case v != "" && ok && current != v:
+			return r, fmt.Errorf("the %s's tag %s=%s conflicts with the resource policy's %s=%s", r.Kind, k, current, k, v)
+		case v != "":
+			tags[k] = v
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return r, fmt.Errorf("the %s is missing the tags %s required by the resource policy", r.Kind, strings.Join(missing, ", "))
+	}
+	r.Tags = tags
+
+	if p.NamePrefix != "" && !strings.HasPrefix(r.Name, p.NamePrefix) {
+		r.Name = p.NamePrefix + r.Name
+	}
+	if p.NamePattern != "" {
+		pattern, err := regexp.Compile(p.NamePattern)
+		if err != nil {
+			return r, fmt.Errorf("invalid name_pattern: %w", err)
+		}
+		if !pattern.MatchString(r.Name) {
+			return r, fmt.Errorf("the %s's name %s doesn't match the resource policy's pattern %s", r.Kind, r.Name, p.NamePattern)
+		}
+	}
+
+	if p.Hook != nil {
+		if err := p.Hook(r); err != nil {
+			return r, fmt.Errorf("the %s %s was rejected by the resource policy: %w", r.Kind, r.Name, err)
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,59 @@
+package resourcepolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte("required_tags:\n  cost-center: \"1234\"\n  owner: \"\"\nname_prefix: acme-\nname_pattern: ^acme-[a-z0-9-]+$\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cost-center": "1234", "owner": ""}, p.RequiredTags)
+	assert.Equal(t, "acme-", p.NamePrefix)
+
+	_, err = Parse([]byte("name_pattern: \"[\""))
+	assert.Error(t, err)
+	_, err = Parse([]byte("prefix: acme-"))
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestApply(t *testing.T) {
+	var none *Policy
+	r := Resource{Kind: KindInstance, Name: "verifier-1", Tags: map[string]string{"owner": "sre"}}
+	applied, err := none.Apply(r)
+	assert.NoError(t, err)
+	assert.Equal(t, r, applied)
+
+	p := &Policy{
+		RequiredTags: map[string]string{"cost-center": "1234", "owner": ""},
+		NamePrefix:   "acme-",
+		NamePattern:  "^acme-[a-z0-9-]+$",
+	}
+	applied, err = p.Apply(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-verifier-1", applied.Name)
+	assert.Equal(t, map[string]string{"cost-center": "1234", "owner": "sre"}, applied.Tags)
+	assert.Equal(t, map[string]string{"owner": "sre"}, r.Tags, "the resource's own tags aren't modified")
+
+	applied, err = p.Apply(Resource{Kind: KindInstance, Name: "acme-verifier-1", Tags: map[string]string{"owner": "sre"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-verifier-1", applied.Name, "names already prefixed aren't prefixed again")
+
+	_, err = p.Apply(Resource{Kind: KindInstance, Name: "verifier-1"})
+	assert.EqualError(t, err, "the instance is missing the tags owner required by the resource policy")
+	_, err = p.Apply(Resource{Kind: KindInstance, Name: "verifier-1", Tags: map[string]string{"owner": "sre", "cost-center": "5678"}})
+	assert.Error(t, err, "conflicting tags are rejected rather than overwritten")
+	_, err = p.Apply(Resource{Kind: KindInstance, Name: "Verifier_1", Tags: map[string]string{"owner": "sre"}})
+	assert.EqualError(t, err, "the instance's name acme-Verifier_1 doesn't match the resource policy's pattern ^acme-[a-z0-9-]+$")
+
+	p.Hook = func(r Resource) error {
+		if r.Tags["owner"] != "platform" {
+			return errors.New("only the platform team may create instances")
+		}
+		return nil
+	}
+	_, err = p.Apply(r)
+	assert.EqualError(t, err, "the instance acme-verifier-1 was rejected by the resource policy: only the platform team may create instances")
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// authorized reports whether an Authorization header carries the bearer token. An empty token authorizes every call,
+// for servers authenticating their clients with mutual TLS instead.
+func authorized(header, token string) bool {
+	if token == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(header), []byte("Bearer "+token)) == 1
+}
+
+// ReadToken reads the bearer token clients authenticate with from file, surrounding whitespace trimmed
+func ReadToken(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the API token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("the API token file %s is empty", file)
+	}
+
+	return token, nil
+}
+
+// TLSConfig returns the TLS configuration the APIs are served with, from a PEM certificate and key. With clientCAFile,
+// clients must present a certificate issued by one of its CAs.
+func TLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the API's TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the client CAs: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no PEM certificate found among the client CAs")
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return config, nil
+}
+
+// IsLoopback reports whether a listen address only accepts connections from the host itself, e.g. 127.0.0.1:8080
+func IsLoopback(listenAddr string) bool {
+	host, _, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
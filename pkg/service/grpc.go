@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/service/verifierpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The gRPC API is defined by verifierpb/verifier.proto, from which clients in any language can be generated. Go
+// clients use verifierpb.NewVerifierClient.
+
+// GRPCServerOptions returns the options of a gRPC server serving the API to clients authenticating with the bearer
+// token, in the "authorization" metadata, over TLS unless tlsConfig is nil. An empty token lets every client through,
+// e.g. when they're authenticated with mutual TLS.
+func GRPCServerOptions(token string, tlsConfig *tls.Config) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := authorizeGRPC(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorizeGRPC(stream.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	return opts
+}
+
+func authorizeGRPC(ctx context.Context, token string) error {
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		header = md.Get("authorization")[0]
+	}
+	if !authorized(header, token) {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	return nil
+}
+
+// RegisterVerifierServer serves the Manager's verifications over gRPC
+func RegisterVerifierServer(s *grpc.Server, m *Manager) {
+	verifierpb.RegisterVerifierServer(s, &grpcServer{m: m})
+}
+
+type grpcServer struct {
+	verifierpb.UnimplementedVerifierServer
+	m *Manager
+}
+
+func (s *grpcServer) StartVerification(ctx context.Context, req *verifierpb.VerificationRequest) (*verifierpb.Status, error) {
+	st, err := s.m.Start(Request{
+		Platform:        req.GetPlatform(),
+		SubnetID:        req.GetSubnetId(),
+		Region:          req.GetRegion(),
+		ImageID:         req.GetImageId(),
+		InstanceType:    req.GetInstanceType(),
+		SecurityGroupID: req.GetSecurityGroupId(),
+		KMSKeyID:        req.GetKmsKeyId(),
+		Timeout:         req.GetTimeout(),
+		HTTPProxy:       req.GetHttpProxy(),
+		HTTPSProxy:      req.GetHttpsProxy(),
+		CACert:          req.GetCaCert(),
+		NoTLS:           req.GetNoTls(),
+		CloudTags:       req.GetCloudTags(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return statusMessage(st), nil
+}
+
+func (s *grpcServer) GetStatus(ctx context.Context, req *verifierpb.VerificationID) (*verifierpb.Status, error) {
+	st, err := s.m.Status(req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return statusMessage(st), nil
+}
+
+func (s *grpcServer) GetResult(ctx context.Context, req *verifierpb.VerificationID) (*verifierpb.GetResultResponse, error) {
+	st, result, err := s.m.Result(req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	// The result is passed as a Struct of its JSON encoding
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resultStruct, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &verifierpb.GetResultResponse{Status: statusMessage(st), Result: resultStruct}, nil
+}
+
+func (s *grpcServer) StreamEvents(req *verifierpb.VerificationID, stream verifierpb.Verifier_StreamEventsServer) error {
+	err := s.m.Events(stream.Context(), req.GetId(), func(e Event) error {
+		return stream.Send(&verifierpb.Event{Time: timestamp(e.Time), Level: e.Level, Message: e.Message})
+	})
+
+	return grpcError(err)
+}
+
+func statusMessage(st Status) *verifierpb.Status {
+	return &verifierpb.Status{
+		Id:       st.ID,
+		State:    st.State,
+		Verdict:  st.Verdict,
+		Created:  timestamp(st.Created),
+		Started:  timestamp(st.Started),
+		Finished: timestamp(st.Finished),
+	}
+}
+
+// timestamp converts t, leaving zero times unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// grpcError maps the Manager's errors to gRPC status codes
+func grpcError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrNotFinished):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
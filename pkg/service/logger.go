@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+)
+
+// eventLogger records a verification's log messages as its events, and passes them on to the server's logger
+type eventLogger struct {
+	v      *verification
+	logger logging.Logger
+	id     string
+}
+
+var _ logging.Logger = &eventLogger{}
+
+func (l *eventLogger) DebugEnabled() bool { return l.logger.DebugEnabled() }
+func (l *eventLogger) InfoEnabled() bool  { return true }
+func (l *eventLogger) WarnEnabled() bool  { return true }
+func (l *eventLogger) ErrorEnabled() bool { return true }
+
+func (l *eventLogger) Debug(ctx context.Context, format string, args ...interface{}) {
+	if l.logger.DebugEnabled() {
+		l.log("debug", format, args...)
+	}
+	l.logger.Debug(ctx, "[%s] "+format, append([]interface{}{l.id}, args...)...)
+}
+
+func (l *eventLogger) Info(ctx context.Context, format string, args ...interface{}) {
+	l.log("info", format, args...)
+	l.logger.Info(ctx, "[%s] "+format, append([]interface{}{l.id}, args...)...)
+}
+
+func (l *eventLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+	l.log("warn", format, args...)
+	l.logger.Warn(ctx, "[%s] "+format, append([]interface{}{l.id}, args...)...)
+}
+
+func (l *eventLogger) Error(ctx context.Context, format string, args ...interface{}) {
+	l.log("error", format, args...)
+	l.logger.Error(ctx, "[%s] "+format, append([]interface{}{l.id}, args...)...)
+}
+
+// Fatal is logged as an error, a verification must never take the server down with it
+func (l *eventLogger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	l.Error(ctx, format, args...)
+}
+
+func (l *eventLogger) log(level, format string, args ...interface{}) {
+	l.v.update(func() {
+		l.v.events = append(l.v.events, Event{Time: time.Now(), Level: level, Message: fmt.Sprintf(format, args...)})
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// States a verification goes through
+const (
+	StatePending = "Pending"
+	StateRunning = "Running"
+	StatePassed  = "Passed"
+	StateFailed  = "Failed"
+)
+
+var (
+	// ErrNotFound is returned for verification IDs the Manager doesn't know
+	ErrNotFound = errors.New("verification not found")
+	// ErrNotFinished is returned when asking for the result of a verification that's still running
+	ErrNotFinished = errors.New("verification has not finished")
+)
+
+// Status describes where a verification is at
+type Status struct {
+	ID       string    `json:"id"`
+	State    string    `json:"state"`
+	Verdict  string    `json:"verdict,omitempty"`
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// Done reports whether the verification has finished
+func (s Status) Done() bool {
+	return s.State == StatePassed || s.State == StateFailed
+}
+
+// Event is a progress message logged by a verification
+type Event struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// Runner runs a verification, logging its progress to logger
+type Runner func(ctx context.Context, logger logging.Logger, req Request) *output.Output
+
+// Manager runs verifications in the background, each with its own cloud client and output, and keeps their status,
+// events and results for clients to poll until they've been finished for the retention period
+type Manager struct {
+	ctx    context.Context
+	run    Runner
+	logger logging.Logger
+	// slots bounds the number of verifications running at once, the rest wait their turn
+	slots     chan struct{}
+	retention time.Duration
+
+	mu            sync.Mutex
+	verifications map[string]*verification
+}
+
+type verification struct {
+	mu      sync.Mutex
+	status  Status
+	events  []Event
+	changed chan struct{}
+	result  *output.Output
+}
+
+// NewManager returns a Manager running up to maxConcurrent verifications at once until ctx is done. Finished
+// verifications are forgotten once retention has passed, a non-positive retention keeping them until the server stops.
+func NewManager(ctx context.Context, logger logging.Logger, run Runner, maxConcurrent int, retention time.Duration) *Manager {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	return &Manager{
+		ctx:           ctx,
+		run:           run,
+		logger:        logger,
+		slots:         make(chan struct{}, maxConcurrent),
+		retention:     retention,
+		verifications: map[string]*verification{},
+	}
+}
+
+// Start validates req and queues the verification, returning its initial status
+func (m *Manager) Start(req Request) (Status, error) {
+	if err := req.Validate(); err != nil {
+		return Status{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Status{}, err
+	}
+
+	v := &verification{
+		status:  Status{ID: id, State: StatePending, Created: time.Now()},
+		changed: make(chan struct{}),
+	}
+	m.mu.Lock()
+	m.evict(time.Now())
+	m.verifications[id] = v
+	m.mu.Unlock()
+
+	started := v.status
+	go m.execute(v, req)
+
+	return started, nil
+}
+
+// Status returns the status of the verification with the given ID
+func (m *Manager) Status(id string) (Status, error) {
+	v, err := m.get(id)
+	if err != nil {
+		return Status{}, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.status, nil
+}
+
+// Result returns the result of the finished verification with the given ID
+func (m *Manager) Result(id string) (Status, output.Result, error) {
+	v, err := m.get(id)
+	if err != nil {
+		return Status{}, output.Result{}, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.status.Done() {
+		return v.status, output.Result{}, ErrNotFinished
+	}
+
+	return v.status, v.result.Result(), nil
+}
+
+// Events calls send with each of the verification's events, the ones logged so far and then the new ones as they're
+// logged, until the verification finishes, ctx is done or send fails
+func (m *Manager) Events(ctx context.Context, id string, send func(Event) error) error {
+	v, err := m.get(id)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for {
+		v.mu.Lock()
+		events, done, changed := v.events[sent:], v.status.Done(), v.changed
+		v.mu.Unlock()
+
+		for _, e := range events {
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+		sent += len(events)
+		if done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *Manager) get(id string) (*verification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evict(time.Now())
+	v, ok := m.verifications[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return v, nil
+}
+
+// evict forgets the verifications finished longer than the retention period ago, with their results and console
+// output. It's called with m.mu held.
+func (m *Manager) evict(now time.Time) {
+	if m.retention <= 0 {
+		return
+	}
+	for id, v := range m.verifications {
+		v.mu.Lock()
+		expired := v.status.Done() && now.Sub(v.status.Finished) > m.retention
+		v.mu.Unlock()
+		if expired {
+			delete(m.verifications, id)
+		}
+	}
+}
+
+// execute runs the verification once a slot is free
+func (m *Manager) execute(v *verification, req Request) {
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-m.ctx.Done():
+		v.finish(m.ctx.Err())
+		return
+	}
+
+	v.update(func() {
+		v.status.State = StateRunning
+		v.status.Started = time.Now()
+	})
+	m.logger.Info(m.ctx, "Starting verification %s of %s subnet %s", v.status.ID, req.Platform, req.SubnetID)
+
+	out := m.run(m.ctx, &eventLogger{v: v, logger: m.logger, id: v.status.ID}, req)
+
+	v.update(func() {
+		v.result = out
+		v.status.Verdict = out.Verdict()
+		v.status.State = StateFailed
+		if out.IsSuccessful() {
+			v.status.State = StatePassed
+		}
+		v.status.Finished = time.Now()
+	})
+	m.logger.Info(m.ctx, "Finished verification %s: %s", v.status.ID, out.Verdict())
+}
+
+// finish fails a verification that never got to run
+func (v *verification) finish(err error) {
+	v.update(func() {
+		v.result = (&output.Output{}).AddError(err)
+		v.status.Verdict = v.result.Verdict()
+		v.status.State = StateFailed
+		v.status.Finished = time.Now()
+	})
+}
+
+// update changes the verification under its lock and wakes up anyone waiting on its events
+func (v *verification) update(change func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	change()
+	close(v.changed)
+	v.changed = make(chan struct{})
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate a verification ID: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
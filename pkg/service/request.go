@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"golang.org/x/oauth2/google"
+)
+
+// defaultTimeout and defaultAWSRegion match the egress command's defaults
+const (
+	defaultTimeout   = 2 * time.Second
+	defaultAWSRegion = "us-east-2"
+)
+
+// defaultTags are assigned to the cloud resources of verifications that don't set their own
+var defaultTags = map[string]string{"osd-network-verifier": "owned", "red-hat-managed": "true", "Name": "osd-network-verifier"}
+
+// Request describes a verification of egress from a subnet, in the terms of the egress command's flags
+type Request struct {
+	// Platform is the cloud provider, one of cloudclient.SupportedPlatforms
+	Platform string `json:"platform"`
+	SubnetID string `json:"subnet_id"`
+	Region   string `json:"region,omitempty"`
+	// ImageID, InstanceType, SecurityGroupID and KMSKeyID configure the probe instance
+	ImageID         string `json:"image_id,omitempty"`
+	InstanceType    string `json:"instance_type,omitempty"`
+	SecurityGroupID string `json:"security_group_id,omitempty"`
+	KMSKeyID        string `json:"kms_key_id,omitempty"`
+	// Timeout is the timeout of individual egress requests, as a duration e.g. "5s"
+	Timeout    string            `json:"timeout,omitempty"`
+	HTTPProxy  string            `json:"http_proxy,omitempty"`
+	HTTPSProxy string            `json:"https_proxy,omitempty"`
+	CACert     string            `json:"ca_cert,omitempty"`
+	NoTLS      bool              `json:"no_tls,omitempty"`
+	CloudTags  map[string]string `json:"cloud_tags,omitempty"`
+}
+
+// Validate checks the request is complete before any cloud resources are created for it
+func (r Request) Validate() error {
+	switch cloudclient.Provider(r.Platform) {
+	case cloudclient.PlatformAWS, cloudclient.PlatformGCP:
+	default:
+		return fmt.Errorf("unsupported platform %q, must be one of %v", r.Platform, cloudclient.SupportedPlatforms)
+	}
+	if r.SubnetID == "" {
+		return errors.New("a subnet ID is required")
+	}
+	if cloudclient.Provider(r.Platform) == cloudclient.PlatformGCP {
+		// A subnetwork self-link carries its own region
+		region, err := gcpCloudClient.RegionFromSubnet(r.SubnetID, r.Region)
+		if err != nil {
+			return err
+		}
+		if region == "" {
+			return errors.New("a region, or a subnetwork self-link, is required on GCP")
+		}
+	}
+	if r.Timeout != "" {
+		if d, err := time.ParseDuration(r.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout %q, must be a positive duration e.g. 5s", r.Timeout)
+		}
+	}
+
+	return nil
+}
+
+// timeout returns the per-request timeout to verify with
+func (r Request) timeout() time.Duration {
+	if d, err := time.ParseDuration(r.Timeout); err == nil && d > 0 {
+		return d
+	}
+
+	return defaultTimeout
+}
+
+// RunWithEnvironmentCredentials is a Runner verifying with the server's own cloud credentials, taken from the
+// environment as by the egress command: AWS_PROFILE or AWS_ACCESS_KEY_ID and friends on AWS, GCP_PROJECT_ID (and
+// GCP_VPC_NAME) with application default credentials on GCP.
+func RunWithEnvironmentCredentials(ctx context.Context, logger logging.Logger, req Request) *output.Output {
+	var creds interface{}
+	region := req.Region
+	switch cloudclient.Provider(req.Platform) {
+	case cloudclient.PlatformAWS:
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		if region == "" {
+			region = defaultAWSRegion
+		}
+		if profile := os.Getenv("AWS_PROFILE"); profile != "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+			creds = profile
+		} else {
+			creds = credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"))
+		}
+	case cloudclient.PlatformGCP:
+		region, _ = gcpCloudClient.RegionFromSubnet(req.SubnetID, region)
+		creds = &google.Credentials{ProjectID: os.Getenv("GCP_PROJECT_ID")}
+	}
+
+	tags := req.CloudTags
+	if len(tags) == 0 {
+		tags = defaultTags
+	}
+	cli, err := cloudclient.NewClient(ctx, logger, creds, region, req.InstanceType, tags)
+	if err != nil {
+		return (&output.Output{}).AddError(err)
+	}
+
+	p := proxy.ProxyConfig{
+		HttpProxy:  req.HTTPProxy,
+		HttpsProxy: req.HTTPSProxy,
+		Cacert:     req.CACert,
+		NoTls:      req.NoTLS,
+	}
+
+	opts := probe.Options{}
+	if cloudclient.Provider(req.Platform) != req.Platform {
+		opts.Preset = req.Platform
+	}
+
+	return cli.ValidateEgress(ctx, req.SubnetID, req.ImageID, req.KMSKeyID, req.SecurityGroupID, req.timeout(), p, opts)
+}
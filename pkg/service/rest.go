@@ -0,0 +1,88 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/openshift/osd-network-verifier/pkg/output"
+)
+
+// maxRequestSize bounds the verification requests accepted by the REST API, which include any CA certificate
+const maxRequestSize = 1 << 20
+
+// VerificationResponse is what the REST API returns for a verification, its result once finished
+type VerificationResponse struct {
+	Status Status         `json:"status"`
+	Result *output.Result `json:"result,omitempty"`
+}
+
+// errorResponse is what the REST API returns for failed calls
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewRESTHandler serves the Manager's verifications as a REST API, to clients authenticating with the bearer token,
+// every client being let through with an empty token, e.g. when they're authenticated with mutual TLS:
+//
+//	POST /verifications       queues a verification, returning 202 Accepted with its status and location
+//	GET  /verifications/{id}  returns a verification's status, and its result once finished
+func NewRESTHandler(m *Manager, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/verifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		var req Request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid verification request: " + err.Error()})
+			return
+		}
+		st, err := m.Start(req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		w.Header().Set("Location", "/verifications/"+st.ID)
+		writeJSON(w, http.StatusAccepted, VerificationResponse{Status: st})
+	})
+	mux.HandleFunc("/verifications/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/verifications/")
+		st, result, err := m.Result(id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+		case errors.Is(err, ErrNotFinished):
+			writeJSON(w, http.StatusOK, VerificationResponse{Status: st})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, VerificationResponse{Status: st, Result: &result})
+		}
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	// An encoding error means the client has gone away, there is no one left to tell
+	_ = json.NewEncoder(w).Encode(v)
+}
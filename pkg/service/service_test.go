@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ocmlog "github.com/openshift-online/ocm-sdk-go/logging"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/service/verifierpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeRunner fails verifications of subnets named "fail", once released
+func fakeRunner(release <-chan struct{}) Runner {
+	return func(ctx context.Context, logger logging.Logger, req Request) *output.Output {
+		logger.Info(ctx, "Verifying %s", req.SubnetID)
+		<-release
+		out := &output.Output{}
+		if req.SubnetID == "fail" {
+			out.SetEgressFailures([]string{"Unable to reach quay.io:443"})
+		}
+		return out
+	}
+}
+
+func waitDone(t *testing.T, m *Manager, id string) Status {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		st, err := m.Status(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Done() {
+			return st
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("verification %s did not finish", id)
+	return Status{}
+}
+
+func TestManager(t *testing.T) {
+	release := make(chan struct{})
+	m := NewManager(context.TODO(), &ocmlog.StdLogger{}, fakeRunner(release), 1, 0)
+
+	if _, err := m.Start(Request{Platform: "azure", SubnetID: "pass"}); err == nil {
+		t.Errorf("expected an unsupported platform to be rejected")
+	}
+
+	passed, err := m.Start(Request{Platform: "aws", SubnetID: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := m.Start(Request{Platform: "aws", SubnetID: "fail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passed.ID == failed.ID {
+		t.Fatalf("expected each verification to get its own ID")
+	}
+	if _, _, err := m.Result(passed.ID); !errors.Is(err, ErrNotFinished) {
+		t.Errorf("expected no result before the verification finished, got %v", err)
+	}
+	close(release)
+
+	if st := waitDone(t, m, passed.ID); st.State != StatePassed {
+		t.Errorf("expected %s to pass, got %+v", passed.ID, st)
+	}
+	if st := waitDone(t, m, failed.ID); st.State != StateFailed {
+		t.Errorf("expected %s to fail, got %+v", failed.ID, st)
+	}
+	_, result, err := m.Result(failed.ID)
+	if err != nil || len(result.Failures) != 1 {
+		t.Errorf("expected the failed verification's own result, got %+v, %v", result, err)
+	}
+
+	var events []Event
+	if err := m.Events(context.TODO(), failed.ID, func(e Event) error { events = append(events, e); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Message != "Verifying fail" {
+		t.Errorf("expected the verification's own events, got %v", events)
+	}
+
+	if _, err := m.Status("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an unknown ID not to be found, got %v", err)
+	}
+}
+
+func TestManagerRetention(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	m := NewManager(context.TODO(), &ocmlog.StdLogger{}, fakeRunner(release), 1, time.Hour)
+
+	st, err := m.Start(Request{Platform: "aws", SubnetID: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitDone(t, m, st.ID)
+
+	m.mu.Lock()
+	m.evict(time.Now())
+	kept := len(m.verifications)
+	m.evict(time.Now().Add(2 * time.Hour))
+	evicted := len(m.verifications)
+	m.mu.Unlock()
+	if kept != 1 || evicted != 0 {
+		t.Errorf("expected the verification kept for the retention period and forgotten after, got %d then %d", kept, evicted)
+	}
+	if _, err := m.Status(st.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an evicted verification not to be found, got %v", err)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{"127.0.0.1:8080": true, "localhost:8080": true, "[::1]:8080": true, ":8080": false, "0.0.0.0:8080": false, "10.0.0.5:8080": false} {
+		if got := IsLoopback(addr); got != want {
+			t.Errorf("IsLoopback(%q) = %v, expected %v", addr, got, want)
+		}
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	release := make(chan struct{})
+	m := NewManager(context.TODO(), &ocmlog.StdLogger{}, fakeRunner(release), 1, 0)
+
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(GRPCServerOptions("s3cr3t", nil)...)
+	RegisterVerifierServer(s, m)
+	go s.Serve(listener)
+	defer s.Stop()
+
+	conn, err := grpc.DialContext(context.TODO(), "bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := verifierpb.NewVerifierClient(conn)
+
+	if _, err := client.GetStatus(context.TODO(), &verifierpb.VerificationID{Id: "unknown"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected a call without the token to be rejected, got %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "authorization", "Bearer s3cr3t")
+
+	if _, err := client.StartVerification(ctx, &verifierpb.VerificationRequest{Platform: "aws"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected a request without a subnet to be invalid, got %v", err)
+	}
+	st, err := client.StartVerification(ctx, &verifierpb.VerificationRequest{Platform: "aws", SubnetId: "fail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetResult(ctx, &verifierpb.VerificationID{Id: st.Id}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected no result before the verification finished, got %v", err)
+	}
+	close(release)
+
+	stream, err := client.StreamEvents(ctx, &verifierpb.VerificationID{Id: st.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*verifierpb.Event
+	for {
+		e, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 1 || events[0].Message != "Verifying fail" || events[0].Time == nil {
+		t.Errorf("expected the verification's events, got %v", events)
+	}
+
+	resp, err := client.GetResult(ctx, &verifierpb.VerificationID{Id: st.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := resp.Result.AsMap()
+	if resp.Status.State != StateFailed || result["success"] != false || len(result["failures"].([]interface{})) != 1 {
+		t.Errorf("unexpected result: %+v", resp)
+	}
+	if _, err := client.GetStatus(ctx, &verifierpb.VerificationID{Id: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected an unknown ID not to be found, got %v", err)
+	}
+}
+
+func TestREST(t *testing.T) {
+	release := make(chan struct{})
+	m := NewManager(context.TODO(), &ocmlog.StdLogger{}, fakeRunner(release), 1, 0)
+	server := httptest.NewServer(NewRESTHandler(m, "s3cr3t"))
+	defer server.Close()
+
+	do := func(method, path, token, body string) (*http.Response, error) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return http.DefaultClient.Do(req)
+	}
+	get := func(path string) (int, VerificationResponse) {
+		resp, err := do(http.MethodGet, path, "s3cr3t", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var v VerificationResponse
+		if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, v
+	}
+
+	resp, err := do(http.MethodPost, "/verifications", "nope", `{"platform":"aws","subnet_id":"fail"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a request without the token to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp, err = do(http.MethodPost, "/verifications", "s3cr3t", `{"platform":"aws"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a request without a subnet to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp, err = do(http.MethodPost, "/verifications", "s3cr3t", `{"platform":"aws","subnet_id":"fail"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started VerificationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&started); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/verifications/"+started.Status.ID {
+		t.Errorf("expected the verification to be accepted, got %d at %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if code, v := get("/verifications/" + started.Status.ID); code != http.StatusOK || v.Result != nil {
+		t.Errorf("expected no result before the verification finished, got %d %+v", code, v)
+	}
+	close(release)
+	waitDone(t, m, started.Status.ID)
+
+	code, v := get("/verifications/" + started.Status.ID)
+	if code != http.StatusOK || v.Status.State != StateFailed || v.Result == nil || len(v.Result.Failures) != 1 {
+		t.Errorf("expected the failed result, got %d %+v", code, v)
+	}
+	if code, _ := get("/verifications/unknown"); code != http.StatusNotFound {
+		t.Errorf("expected an unknown ID not to be found, got %d", code)
+	}
+}
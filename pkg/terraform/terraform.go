@@ -0,0 +1,186 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Output names recognized as each setting, in order of preference. Private subnets are preferred, as they're the ones
+// cluster nodes egress from.
+var (
+	subnetOutputs = []string{"private_subnet_ids", "private_subnets", "subnet_ids", "subnets", "private_subnet_id", "subnet_id"}
+	vpcOutputs    = []string{"vpc_id", "vpc_name", "network_name", "vpc"}
+	regionOutputs = []string{"region", "aws_region", "gcp_region"}
+	httpOutputs   = []string{"http_proxy"}
+	httpsOutputs  = []string{"https_proxy"}
+	caCertOutputs = []string{"additional_trust_bundle", "ca_cert"}
+)
+
+// Network describes the network settings found in terraform outputs or state
+type Network struct {
+	// Platform is aws or gcp when known from the state's resources or the subnet IDs, and empty otherwise
+	Platform  string
+	SubnetIDs []string
+	// VPC is the VPC ID on AWS, or the VPC network name on GCP
+	VPC        string
+	Region     string
+	HTTPProxy  string
+	HTTPSProxy string
+	// CACert is the PEM-encoded CA certificate of the proxy
+	CACert string
+}
+
+type outputValue struct {
+	Value interface{} `json:"value"`
+}
+
+type state struct {
+	Version   *int                   `json:"version"`
+	Outputs   map[string]outputValue `json:"outputs"`
+	Resources []struct {
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Instances []struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// Load reads the network settings from a file holding either `terraform output -json` or a terraform state
+func Load(file string) (*Network, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read terraform file %s: %w", file, err)
+	}
+
+	network, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("terraform file %s: %w", file, err)
+	}
+
+	return network, nil
+}
+
+// Parse extracts the network settings from either `terraform output -json` or a terraform state. Settings are taken
+// from well-known output names, e.g. private_subnet_ids, vpc_id, region and https_proxy; a state's subnet resources
+// are used when none of its outputs name the subnets.
+func Parse(data []byte) (*Network, error) {
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("unable to parse terraform JSON: %w", err)
+	}
+
+	// Output JSON is a bare map of outputs, while a state has a version alongside its outputs
+	outputs := s.Outputs
+	if s.Version == nil {
+		outputs = map[string]outputValue{}
+		if err := json.Unmarshal(data, &outputs); err != nil {
+			return nil, fmt.Errorf("unable to parse terraform outputs: %w", err)
+		}
+	}
+
+	network := &Network{
+		SubnetIDs:  stringsOutput(outputs, subnetOutputs),
+		VPC:        stringOutput(outputs, vpcOutputs),
+		Region:     stringOutput(outputs, regionOutputs),
+		HTTPProxy:  stringOutput(outputs, httpOutputs),
+		HTTPSProxy: stringOutput(outputs, httpsOutputs),
+		CACert:     stringOutput(outputs, caCertOutputs),
+	}
+
+	subnetsFromOutputs := len(network.SubnetIDs) > 0
+	for _, resource := range s.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+		switch resource.Type {
+		case "aws_subnet":
+			network.Platform = "aws"
+		case "google_compute_subnetwork":
+			network.Platform = "gcp"
+		default:
+			continue
+		}
+		if subnetsFromOutputs {
+			continue
+		}
+		for _, instance := range resource.Instances {
+			network.addSubnet(resource.Type, instance.Attributes)
+		}
+	}
+
+	if len(network.SubnetIDs) == 0 {
+		return nil, fmt.Errorf("no subnets found, expected one of the outputs %v or subnet resources", subnetOutputs)
+	}
+	if network.Platform == "" && strings.HasPrefix(network.SubnetIDs[0], "subnet-") {
+		network.Platform = "aws"
+	}
+
+	return network, nil
+}
+
+// addSubnet adds a subnet resource of the state, along with its VPC and region when no output gave them
+func (n *Network) addSubnet(resourceType string, attributes map[string]interface{}) {
+	attribute := func(name string) string {
+		value, _ := attributes[name].(string)
+		return value
+	}
+
+	switch resourceType {
+	case "aws_subnet":
+		n.SubnetIDs = append(n.SubnetIDs, attribute("id"))
+		if n.VPC == "" {
+			n.VPC = attribute("vpc_id")
+		}
+		// arn:aws:ec2:REGION:ACCOUNT:subnet/ID
+		if arn := strings.Split(attribute("arn"), ":"); n.Region == "" && len(arn) > 3 {
+			n.Region = arn[3]
+		}
+	case "google_compute_subnetwork":
+		n.SubnetIDs = append(n.SubnetIDs, attribute("name"))
+		if n.VPC == "" && attribute("network") != "" {
+			n.VPC = path.Base(attribute("network"))
+		}
+		if n.Region == "" {
+			n.Region = attribute("region")
+		}
+	}
+}
+
+// stringOutput returns the value of the first of the named outputs with a string value
+func stringOutput(outputs map[string]outputValue, names []string) string {
+	for _, name := range names {
+		if value, ok := outputs[name].Value.(string); ok && value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// stringsOutput returns the value of the first of the named outputs with a string or list of strings value
+func stringsOutput(outputs map[string]outputValue, names []string) []string {
+	for _, name := range names {
+		switch value := outputs[name].Value.(type) {
+		case string:
+			if value != "" {
+				return []string{value}
+			}
+		case []interface{}:
+			var values []string
+			for _, v := range value {
+				if s, ok := v.(string); ok && s != "" {
+					values = append(values, s)
+				}
+			}
+			if len(values) > 0 {
+				return values
+			}
+		}
+	}
+
+	return nil
+}
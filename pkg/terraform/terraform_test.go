@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputs(t *testing.T) {
+	network, err := Parse([]byte(`{
+  "private_subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-a", "subnet-b"]},
+  "public_subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-c"]},
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-1"},
+  "region": {"sensitive": false, "type": "string", "value": "us-east-1"},
+  "https_proxy": {"sensitive": false, "type": "string", "value": "http://proxy:3128"}
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Network{
+		Platform:   "aws",
+		SubnetIDs:  []string{"subnet-a", "subnet-b"},
+		VPC:        "vpc-1",
+		Region:     "us-east-1",
+		HTTPSProxy: "http://proxy:3128",
+	}, network)
+}
+
+func TestParseState(t *testing.T) {
+	network, err := Parse([]byte(`{
+  "version": 4,
+  "outputs": {"http_proxy": {"type": "string", "value": "http://proxy:3128"}},
+  "resources": [
+    {"mode": "data", "type": "google_compute_subnetwork", "instances": [{"attributes": {"name": "other"}}]},
+    {"mode": "managed", "type": "google_compute_network", "instances": [{"attributes": {"name": "vpc"}}]},
+    {"mode": "managed", "type": "google_compute_subnetwork", "instances": [{"attributes": {
+      "name": "compute",
+      "network": "https://www.googleapis.com/compute/v1/projects/p/global/networks/vpc",
+      "region": "us-east1"
+    }}]}
+  ]
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Network{
+		Platform:  "gcp",
+		SubnetIDs: []string{"compute"},
+		VPC:       "vpc",
+		Region:    "us-east1",
+		HTTPProxy: "http://proxy:3128",
+	}, network)
+
+	network, err = Parse([]byte(`{
+  "version": 4,
+  "outputs": {},
+  "resources": [
+    {"mode": "managed", "type": "aws_subnet", "instances": [
+      {"attributes": {"id": "subnet-a", "vpc_id": "vpc-1", "arn": "arn:aws:ec2:us-west-2:123456789012:subnet/subnet-a"}},
+      {"attributes": {"id": "subnet-b", "vpc_id": "vpc-1", "arn": "arn:aws:ec2:us-west-2:123456789012:subnet/subnet-b"}}
+    ]}
+  ]
+}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &Network{
+		Platform:  "aws",
+		SubnetIDs: []string{"subnet-a", "subnet-b"},
+		VPC:       "vpc-1",
+		Region:    "us-west-2",
+	}, network)
+
+	_, err = Parse([]byte(`{"version": 4, "outputs": {"vpc_id": {"value": "vpc-1"}}, "resources": []}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,413 @@
+// Package verifier is the stable Go API of the network verifier, for services verifying egress and DNS from their own
+// code rather than running the CLI.
+//
+// The types and functions of this package follow semantic versioning: they're only changed compatibly within a major
+// version, fields being added but not removed or retyped. They're defined here rather than borrowed from the packages
+// implementing the verifier, which aren't held to that promise.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient"
+	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/incluster"
+	"github.com/openshift/osd-network-verifier/pkg/logging"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"golang.org/x/oauth2/google"
+)
+
+// DefaultTimeout bounds each egress request when the input doesn't, as the CLI's --timeout does
+const DefaultTimeout = 2 * time.Second
+
+var (
+	// ErrNoCredentials is returned by New when the config has no credentials
+	ErrNoCredentials = errors.New("no cloud credentials given")
+	// ErrNoSubnet is among the errors of an egress verification without a subnet
+	ErrNoSubnet = errors.New("no subnet given to verify egress from")
+	// ErrNoVPC is among the errors of a DNS verification without a VPC
+	ErrNoVPC = errors.New("no VPC given to verify the DNS of")
+)
+
+// Logger is what the verifier logs its progress through. Loggers of ocm-sdk-go are Loggers as they are.
+type Logger interface {
+	DebugEnabled() bool
+	InfoEnabled() bool
+	WarnEnabled() bool
+	ErrorEnabled() bool
+
+	Debug(ctx context.Context, format string, args ...interface{})
+	Info(ctx context.Context, format string, args ...interface{})
+	Warn(ctx context.Context, format string, args ...interface{})
+	Error(ctx context.Context, format string, args ...interface{})
+	Fatal(ctx context.Context, format string, args ...interface{})
+}
+
+// Config configures a Verifier
+type Config struct {
+	// Logger defaults to logging at the info level to stderr
+	Logger Logger
+	// AWS or GCP are the credentials of the cloud account verified, exactly one of them being set
+	AWS *AWSCredentials
+	GCP *GCPCredentials
+	// Region is the region of the subnets verified
+	Region string
+	// InstanceType is the probe instance's type, or a comma-separated preference list. Defaults to the first type
+	// offered in the region for the probe's CPU architecture.
+	InstanceType string
+	// Tags are given to the cloud resources created
+	Tags map[string]string
+}
+
+// AWSCredentials are the credentials of an AWS account: a profile of the shared config and credentials files, or an
+// access key. A role, if given, is assumed with them, as the CLI's --role-arn does.
+type AWSCredentials struct {
+	Profile         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// RoleARN is the role assumed with the credentials, through IntermediateRoleARN first if set, e.g. a role of a
+	// jump account the target role trusts
+	RoleARN             string
+	IntermediateRoleARN string
+	// ExternalID is passed assuming RoleARN, for trust policies requiring one
+	ExternalID string
+	// RoleSessionName names the role's sessions, as seen in CloudTrail. Defaults to the CLI's.
+	RoleSessionName string
+}
+
+// GCPCredentials are the GCP project verified, with the application default credentials or, when
+// ImpersonateServiceAccounts is set, a service account they impersonate
+type GCPCredentials struct {
+	// ProjectID is the project of the credentials, defaulting to InstanceProject
+	ProjectID string
+	// ImpersonateServiceAccounts is the delegation chain to the impersonated service account, as the CLI's
+	// --impersonate takes it, the last account being the one impersonated
+	ImpersonateServiceAccounts []string
+	// InstanceProject is where the probe instance is created, QuotaProject is charged for the API calls, and
+	// NetworkProject holds the subnetworks named without a project, e.g. a Shared VPC host project. They default to
+	// ProjectID, and quota to the credentials' own quota project.
+	InstanceProject string
+	QuotaProject    string
+	NetworkProject  string
+}
+
+// ProxyConfig is the proxy egress goes through, and the CA bundle it's trusted with
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	// CACert is the PEM bundle of the CAs the proxy's certificates are issued by
+	CACert string
+	// NoTLS skips verifying the certificates of the endpoints, e.g. behind a proxy intercepting TLS
+	NoTLS bool
+}
+
+// ProbeOptions configures the probe instance launched to verify egress. The zero value probes the default endpoints
+// once, from an on-demand instance.
+type ProbeOptions struct {
+	// ValidatorImage overrides the validator container image, e.g. to pull from an internal mirror
+	ValidatorImage string
+	// CPUArchitecture picks the default instance type when none is configured, "x86_64" or "arm64". Defaults to
+	// x86_64.
+	CPUArchitecture string
+	// InstanceProfile is the IAM instance profile the probe instance runs with (AWS only)
+	InstanceProfile string
+	// Spot launches the probe instance on spot (AWS) or preemptible (GCP) capacity, falling back to on-demand
+	Spot bool
+	// LaunchTimeout bounds the wait for the probe instance to run, ConsoleTimeout the wait for its results. Zero
+	// picks the CLI's defaults.
+	LaunchTimeout  time.Duration
+	ConsoleTimeout time.Duration
+	// RetryFailedEndpoints probes the unreachable endpoints once more after a pause
+	RetryFailedEndpoints bool
+	// Attempts is how many times each endpoint is probed, telling flaky endpoints apart. Defaults to once.
+	Attempts int
+	// Preset adds the endpoints of a flavour of cluster to those probed, "rosa-hcp" or "osd-gcp", as the CLI's
+	// --platform of the same names does. OCPVersion probes the egress list of an OpenShift version, e.g. "4.11", as
+	// --ocp-version does.
+	Preset     string
+	OCPVersion string
+	// IPVersion pins the IP version egress is verified over, "4" or "6". Defaults to both where the subnet has IPv6.
+	IPVersion string
+	// DNSServers are resolvers to check the required domains against, Nameservers replace the probe's own
+	DNSServers  []string
+	Nameservers []string
+	// RequirePrivateSubnet fails the verification of a subnet routing to an internet gateway
+	RequirePrivateSubnet bool
+	// WorkerSecurityGroups are the security groups of the cluster's workers, whose egress rules are checked too (AWS
+	// only)
+	WorkerSecurityGroups []string
+	// NetworkTags are given to the probe instance, so the firewall rules targeting them apply (GCP only)
+	NetworkTags []string
+	// MaxInstances caps the verifier's probe instances existing at once, refusing to launch beyond it. Zero picks the
+	// CLI's default, a negative cap disables the check.
+	MaxInstances int
+	// BatchID tags the probe instances of verifications run concurrently, so the cap counts them apart from leftovers
+	BatchID string
+}
+
+// EgressInput is the subnet to verify egress from, and how
+type EgressInput struct {
+	// SubnetID is the subnet, or on GCP the subnetwork's name or self-link. Ignored by in-cluster verifiers.
+	SubnetID string
+	// CloudImageID is the probe instance's image. Defaults to the verifier's image for the region.
+	CloudImageID string
+	// KMSKeyID encrypts the probe instance's disk. Defaults to the account's default key.
+	KMSKeyID string
+	// SecurityGroupID is the probe instance's security group (AWS only). Defaults to one created for the probe.
+	SecurityGroupID string
+	// Timeout bounds each egress request. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Proxy is the cluster-wide proxy egress goes through, if any
+	Proxy ProxyConfig
+	// Options configures the probe
+	Options ProbeOptions
+}
+
+// DNSInput is the VPC to verify the DNS configuration of
+type DNSInput struct {
+	VPCID string
+}
+
+// EndpointResult is the outcome of probing one egress endpoint
+type EndpointResult struct {
+	// Endpoint is the host:port probed, from Subnet
+	Endpoint string
+	Subnet   string
+	// Category groups endpoints by purpose, RequiredBy names the cluster service needing it, if known
+	Category   string
+	RequiredBy string
+	Success    bool
+	// Latency is how long reaching the endpoint took, zero if unknown
+	Latency time.Duration
+	// FailureStage is where an unreachable endpoint failed: "dns", "tcp", "tls" or "http", empty if unknown
+	FailureStage string
+	// HTTPStatus is what the endpoint answered an HTTPS request with, zero without a response
+	HTTPStatus int
+	IPVersion  string
+	// Flaky is set when only some of the attempts got through, Ignored when the endpoint was left out of the verdict
+	Flaky   bool
+	Ignored bool
+	// Note details the result, DocsURL explains why an unreachable endpoint is required
+	Note    string
+	DocsURL string
+}
+
+// Result is the outcome of a verification. The errors of Failures, Exceptions and Errors may wrap this package's
+// sentinel errors, matched with errors.Is.
+type Result struct {
+	// Failures are the network's shortcomings, e.g. unreachable endpoints
+	Failures []error
+	// Exceptions are the conditions the verification couldn't be run under, e.g. missing permissions
+	Exceptions []error
+	// Errors are what went wrong running the verification
+	Errors []error
+	// Incomplete explains why the verification stopped short, e.g. timing out waiting for the probe's results
+	Incomplete  string
+	Warnings    []string
+	Suggestions []string
+	Endpoints   []EndpointResult
+}
+
+// IsSuccessful reports whether the verification ran to the end without failures, exceptions or errors
+func (r *Result) IsSuccessful() bool {
+	return len(r.Failures) == 0 && len(r.Exceptions) == 0 && len(r.Errors) == 0 && r.Incomplete == ""
+}
+
+// Verifier verifies the network of a cloud account, or of the pod it runs in, meets the cluster's requirements
+type Verifier struct {
+	client cloudclient.CloudClient
+}
+
+// New returns a verifier of the cloud the credentials of the config are for
+func New(ctx context.Context, cfg Config) (*Verifier, error) {
+	creds, err := cfg.cloudCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cloudclient.NewClient(ctx, loggerOrDefault(cfg.Logger), creds, cfg.Region, cfg.InstanceType, cfg.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{client: client}, nil
+}
+
+// NewInCluster returns a verifier of the egress of the pod network it runs in, probing the endpoints directly rather
+// than from a probe instance. A nil logger logs at the info level to stderr.
+func NewInCluster(logger Logger) *Verifier {
+	return &Verifier{client: incluster.NewClient(loggerOrDefault(logger))}
+}
+
+// ValidateEgress verifies the endpoints the cluster needs are reachable from the subnet of the input
+func (v *Verifier) ValidateEgress(ctx context.Context, in EgressInput) *Result {
+	if in.SubnetID == "" {
+		if _, inCluster := v.client.(*incluster.Client); !inCluster {
+			return &Result{Errors: []error{ErrNoSubnet}}
+		}
+	}
+	if in.Timeout <= 0 {
+		in.Timeout = DefaultTimeout
+	}
+
+	proxyConfig := proxy.ProxyConfig{
+		HttpProxy:  in.Proxy.HTTPProxy,
+		HttpsProxy: in.Proxy.HTTPSProxy,
+		Cacert:     in.Proxy.CACert,
+		NoTls:      in.Proxy.NoTLS,
+	}
+
+	return newResult(v.client.ValidateEgress(ctx, in.SubnetID, in.CloudImageID, in.KMSKeyID, in.SecurityGroupID, in.Timeout, proxyConfig, in.Options.probeOptions()))
+}
+
+// VerifyDNS verifies the VPC of the input resolves and names its instances as the cluster needs
+func (v *Verifier) VerifyDNS(ctx context.Context, in DNSInput) *Result {
+	if in.VPCID == "" {
+		return &Result{Errors: []error{ErrNoVPC}}
+	}
+
+	return newResult(v.client.VerifyDns(ctx, in.VPCID))
+}
+
+// ValidateBYOVPC validates the configuration of the customer's VPC
+func (v *Verifier) ValidateBYOVPC(ctx context.Context) error {
+	return v.client.ByoVPCValidator(ctx)
+}
+
+// cloudCredentials converts the credentials of the config to those the cloud clients take
+func (cfg Config) cloudCredentials(ctx context.Context) (interface{}, error) {
+	switch {
+	case cfg.AWS == nil && cfg.GCP == nil:
+		return nil, ErrNoCredentials
+	case cfg.AWS != nil && cfg.GCP != nil:
+		return nil, errors.New("both AWS and GCP credentials given, the verifier verifies one cloud account")
+	case cfg.AWS != nil:
+		return cfg.AWS.cloudCredentials()
+	}
+
+	return cfg.GCP.cloudCredentials(ctx)
+}
+
+// cloudCredentials returns the profile name or static credentials of c, wrapped in the role to assume if any
+func (c *AWSCredentials) cloudCredentials() (interface{}, error) {
+	var source interface{}
+	switch {
+	case c.Profile != "" && c.AccessKeyID != "":
+		return nil, errors.New("both an AWS profile and an access key given")
+	case c.Profile != "":
+		source = c.Profile
+	case c.AccessKeyID != "":
+		source = awscredsv2.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+	default:
+		return nil, fmt.Errorf("%w: the AWS credentials need a profile or an access key", ErrNoCredentials)
+	}
+	if c.RoleARN == "" {
+		return source, nil
+	}
+
+	return awsCloudClient.AssumeRoleCredentials{
+		Source:              source,
+		RoleARN:             c.RoleARN,
+		IntermediateRoleARN: c.IntermediateRoleARN,
+		ExternalID:          c.ExternalID,
+		SessionName:         c.RoleSessionName,
+	}, nil
+}
+
+// cloudCredentials returns the Google credentials of c's project, impersonating its service account if any
+func (c *GCPCredentials) cloudCredentials(ctx context.Context) (interface{}, error) {
+	projectID := c.ProjectID
+	if projectID == "" {
+		projectID = c.InstanceProject
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("%w: the GCP credentials need a project ID", ErrNoCredentials)
+	}
+
+	creds := &google.Credentials{ProjectID: projectID}
+	if len(c.ImpersonateServiceAccounts) > 0 {
+		var err error
+		if creds, err = gcpCloudClient.ImpersonatedCredentials(ctx, projectID, c.ImpersonateServiceAccounts); err != nil {
+			return nil, err
+		}
+	}
+
+	return gcpCloudClient.ProjectCredentials{
+		Credentials:     creds,
+		InstanceProject: c.InstanceProject,
+		QuotaProject:    c.QuotaProject,
+		NetworkProject:  c.NetworkProject,
+	}, nil
+}
+
+// probeOptions converts the options to the probe's own
+func (o ProbeOptions) probeOptions() probe.Options {
+	return probe.Options{
+		ValidatorImage:       o.ValidatorImage,
+		CPUArchitecture:      o.CPUArchitecture,
+		InstanceProfile:      o.InstanceProfile,
+		Spot:                 o.Spot,
+		LaunchTimeout:        o.LaunchTimeout,
+		ConsoleTimeout:       o.ConsoleTimeout,
+		RetryFailedEndpoints: o.RetryFailedEndpoints,
+		Attempts:             o.Attempts,
+		Preset:               o.Preset,
+		OCPVersion:           o.OCPVersion,
+		IPVersion:            o.IPVersion,
+		DNSServers:           o.DNSServers,
+		Nameservers:          o.Nameservers,
+		RequirePrivateSubnet: o.RequirePrivateSubnet,
+		WorkerSecurityGroups: o.WorkerSecurityGroups,
+		NetworkTags:          o.NetworkTags,
+		MaxInstances:         o.MaxInstances,
+		BatchID:              o.BatchID,
+	}
+}
+
+// newResult copies the output of the cloud client into a Result
+func newResult(out *output.Output) *Result {
+	failures, exceptions, errs := out.Parse()
+	r := &Result{
+		Failures:    failures,
+		Exceptions:  exceptions,
+		Errors:      errs,
+		Incomplete:  out.Incomplete(),
+		Warnings:    out.Warnings(),
+		Suggestions: out.Suggestions(),
+	}
+	for _, e := range out.EndpointResults() {
+		r.Endpoints = append(r.Endpoints, EndpointResult{
+			Endpoint:     e.Endpoint,
+			Subnet:       e.Subnet,
+			Category:     e.Category,
+			RequiredBy:   e.RequiredBy,
+			Success:      e.Success,
+			Latency:      e.Latency,
+			FailureStage: e.FailureStage,
+			HTTPStatus:   e.HTTPStatus,
+			IPVersion:    e.IPVersion,
+			Flaky:        e.Flaky,
+			Ignored:      e.Ignored,
+			Note:         e.Note,
+			DocsURL:      e.DocsURL,
+		})
+	}
+
+	return r
+}
+
+// loggerOrDefault returns the logger given, or one logging at the info level to stderr
+func loggerOrDefault(logger Logger) logging.Logger {
+	if logger != nil {
+		return logger
+	}
+
+	return logging.NewStdLogger(nil, false)
+}
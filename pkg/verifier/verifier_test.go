@@ -0,0 +1,110 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awscredsv2 "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/golang/mock/gomock"
+	awsCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/aws"
+	gcpCloudClient "github.com/openshift/osd-network-verifier/pkg/cloudclient/gcp"
+	"github.com/openshift/osd-network-verifier/pkg/cloudclient/mocks"
+	"github.com/openshift/osd-network-verifier/pkg/output"
+	"github.com/openshift/osd-network-verifier/pkg/probe"
+	"github.com/openshift/osd-network-verifier/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2/google"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(context.TODO(), Config{Region: "us-east-1"})
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+
+	_, err = New(context.TODO(), Config{AWS: &AWSCredentials{Profile: "default"}, GCP: &GCPCredentials{ProjectID: "p"}})
+	assert.Error(t, err, "credentials of two clouds are refused")
+
+	_, err = New(context.TODO(), Config{AWS: &AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/r"}})
+	assert.True(t, errors.Is(err, ErrNoCredentials), "a role is assumed with credentials")
+}
+
+func TestCloudCredentials(t *testing.T) {
+	creds, err := Config{AWS: &AWSCredentials{Profile: "default"}}.cloudCredentials(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "default", creds)
+
+	creds, err = Config{AWS: &AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "ext"}}.cloudCredentials(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, awsCloudClient.AssumeRoleCredentials{
+		Source:     awscredsv2.NewStaticCredentialsProvider("AKID", "secret", ""),
+		RoleARN:    "arn:aws:iam::123456789012:role/r",
+		ExternalID: "ext",
+	}, creds)
+
+	_, err = Config{AWS: &AWSCredentials{Profile: "default", AccessKeyID: "AKID"}}.cloudCredentials(context.TODO())
+	assert.Error(t, err, "a profile and an access key are ambiguous")
+
+	creds, err = Config{GCP: &GCPCredentials{InstanceProject: "service", NetworkProject: "host"}}.cloudCredentials(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, gcpCloudClient.ProjectCredentials{
+		Credentials:     &google.Credentials{ProjectID: "service"},
+		InstanceProject: "service",
+		NetworkProject:  "host",
+	}, creds, "the project defaults to the instance's")
+
+	_, err = Config{GCP: &GCPCredentials{}}.cloudCredentials(context.TODO())
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+}
+
+func TestValidateEgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mocks.NewMockCloudClient(ctrl)
+	v := &Verifier{client: client}
+
+	out := &output.Output{}
+	out.AddFailure(errors.New("egress failures found"))
+	out.AddWarning("slow proxy")
+	out.AddEndpointResult(output.EndpointResult{Endpoint: "registry.example.com:443", Subnet: "subnet-1", FailureStage: output.FailureStageTLS, Latency: time.Second})
+	client.EXPECT().ValidateEgress(gomock.Any(), "subnet-1", "", "", "sg-1", DefaultTimeout,
+		proxy.ProxyConfig{HttpProxy: "http://proxy:3128", Cacert: "pem"}, probe.Options{Spot: true, Attempts: 3}).Return(out)
+	result := v.ValidateEgress(context.TODO(), EgressInput{
+		SubnetID:        "subnet-1",
+		SecurityGroupID: "sg-1",
+		Proxy:           ProxyConfig{HTTPProxy: "http://proxy:3128", CACert: "pem"},
+		Options:         ProbeOptions{Spot: true, Attempts: 3},
+	})
+	assert.False(t, result.IsSuccessful(), "the timeout defaults to DefaultTimeout")
+	assert.Equal(t, []error{errors.New("egress failures found")}, result.Failures)
+	assert.Equal(t, []string{"slow proxy"}, result.Warnings)
+	if assert.Len(t, result.Endpoints, 1) {
+		e := result.Endpoints[0]
+		assert.Equal(t, "registry.example.com:443", e.Endpoint)
+		assert.Equal(t, "subnet-1", e.Subnet)
+		assert.Equal(t, "tls", e.FailureStage)
+		assert.Equal(t, time.Second, e.Latency)
+		assert.False(t, e.Success)
+	}
+
+	result = v.ValidateEgress(context.TODO(), EgressInput{})
+	if assert.Len(t, result.Errors, 1) {
+		assert.True(t, errors.Is(result.Errors[0], ErrNoSubnet))
+	}
+}
+
+func TestVerifyDNS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mocks.NewMockCloudClient(ctrl)
+	v := &Verifier{client: client}
+
+	client.EXPECT().VerifyDns(gomock.Any(), "vpc-1").Return(&output.Output{})
+	assert.True(t, v.VerifyDNS(context.TODO(), DNSInput{VPCID: "vpc-1"}).IsSuccessful())
+
+	result := v.VerifyDNS(context.TODO(), DNSInput{})
+	assert.False(t, result.IsSuccessful())
+	if assert.Len(t, result.Errors, 1) {
+		assert.True(t, errors.Is(result.Errors[0], ErrNoVPC))
+	}
+}
@@ -0,0 +1,15 @@
+package version
+
+import "fmt"
+
+var (
+	// Version is the tag version of the verifier build
+	Version string
+	// GitCommit is the short git commit hash of the verifier build
+	GitCommit string
+)
+
+// String describes the verifier build, e.g. "v0.2.0, GitCommit: 1a2b3c4"
+func String() string {
+	return fmt.Sprintf("%s, GitCommit: %s", Version, GitCommit)
+}